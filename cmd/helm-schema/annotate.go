@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/templates"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+func newAnnotateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "annotate [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "write the inferred keywords as @schema annotations into the values files",
+		Long: `Writes the keywords inferred from the values (type, default and title) as @schema annotations above
+every key of the values files, which isn't annotated yet. The formatting and comments of the values files are kept
+and running it again doesn't change them. The generated jsonschemas stay the same, so the annotations can be
+refined by hand afterwards. With --template-types, values without a type (e.g. null placeholders) get the type
+inferred from their usage in the templates of the chart, which changes the jsonschemas.`,
+		RunE:          annotate,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().StringSlice("keywords", schema.InferredKeywords, "keywords to write into the annotations")
+	cmd.Flags().Bool("template-types", false, "infer the types of values without a type from their usage in the templates")
+
+	return cmd
+}
+
+func annotate(cmd *cobra.Command, _ []string) error {
+	keywords, _ := cmd.Flags().GetStringSlice("keywords")
+	templateTypes, _ := cmd.Flags().GetBool("template-types")
+	dryRun := viper.GetBool("dry-run")
+
+	var valueFileNames []string
+	if err := viper.UnmarshalKey("value-files", &valueFileNames); err != nil {
+		return err
+	}
+	opts, err := newBufferOptions()
+	if err != nil {
+		return err
+	}
+
+	valuesPaths, foundErrors := discoverValuesFiles(valueFileNames)
+	for _, valuesPath := range valuesPaths {
+		if err := annotateValuesFile(opts, valuesPath, keywords, templateTypes, dryRun); err != nil {
+			foundErrors = true
+			log.Errorf("Could not annotate %s: %s", valuesPath, err)
+		}
+	}
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// discoverValuesFiles returns the values files of the charts, which would be processed by a run
+func discoverValuesFiles(valueFileNames []string) ([]string, bool) {
+	results, foundErrors := discoverCharts()
+	valuesPaths := []string{}
+	for _, result := range results {
+		valuesPath := result.ChartPath
+		if filepath.Base(valuesPath) == "Chart.yaml" {
+			if valuesPath = findValuesFile(filepath.Dir(valuesPath), valueFileNames); valuesPath == "" {
+				chartLog(result).Debugf("Skipping %s, there is no values file", result.ChartPath)
+				continue
+			}
+		}
+		valuesPaths = append(valuesPaths, valuesPath)
+	}
+	return valuesPaths, foundErrors
+}
+
+// findValuesFile returns the path of the first existing values file in the chart directory, the
+// annotation helm-schema/values of its Chart.yaml overrides the names of the values files
+func findValuesFile(chartDir string, valueFileNames []string) string {
+	if chartFile, err := readChartFile(filepath.Join(chartDir, "Chart.yaml")); err == nil {
+		valueFileNames = schema.ValueFileNames(chartFile, valueFileNames)
+	}
+	for _, valueFileName := range valueFileNames {
+		if _, err := os.Stat(filepath.Join(chartDir, valueFileName)); err == nil {
+			return filepath.Join(chartDir, valueFileName)
+		}
+	}
+	return ""
+}
+
+// annotateValuesFile writes the inferred annotations into the values file
+func annotateValuesFile(opts *bufferOptions, valuesPath string, keywords []string, templateTypes, dryRun bool) error {
+	content, err := os.ReadFile(valuesPath)
+	if err != nil {
+		return err
+	}
+	valuesSchema, err := opts.generateValues(valuesPath, content)
+	if err != nil {
+		return err
+	}
+	if templateTypes {
+		references, err := templates.ChartReferences(filepath.Dir(valuesPath))
+		if err != nil {
+			return err
+		}
+		if count := templates.RefineTypes(valuesSchema, references); count > 0 {
+			log.Debugf("Inferred the types of %d values of %s from the templates", count, valuesPath)
+		}
+	}
+	annotated, count, err := schema.InferAnnotations(content, valuesSchema, keywords)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		log.Infof("Printing annotated values of %s", valuesPath)
+		fmt.Println(string(annotated))
+		return nil
+	}
+	if count == 0 {
+		log.Infof("All keys of %s are annotated already", valuesPath)
+		return nil
+	}
+	info, err := os.Stat(valuesPath)
+	if err != nil {
+		return err
+	}
+	if err := util.WriteFileAtomic(valuesPath, annotated, info.Mode().Perm()); err != nil {
+		return err
+	}
+	log.Infof("Annotated %d keys of %s", count, valuesPath)
+	return nil
+}
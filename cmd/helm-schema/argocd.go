@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// argoGroup is the api group of the ArgoCD objects
+const argoGroup = "argoproj.io"
+
+type argoApplicationSpec struct {
+	Source  *argoSource  `yaml:"source"`
+	Sources []argoSource `yaml:"sources"`
+}
+
+type argoSource struct {
+	RepoURL        string    `yaml:"repoURL"`
+	Chart          string    `yaml:"chart"`
+	Path           string    `yaml:"path"`
+	TargetRevision string    `yaml:"targetRevision"`
+	Ref            string    `yaml:"ref"`
+	Helm           *argoHelm `yaml:"helm"`
+}
+
+type argoHelm struct {
+	ValueFiles              []string        `yaml:"valueFiles"`
+	Values                  string          `yaml:"values"`
+	ValuesObject            yaml.Node       `yaml:"valuesObject"`
+	Parameters              []argoParameter `yaml:"parameters"`
+	IgnoreMissingValueFiles bool            `yaml:"ignoreMissingValueFiles"`
+}
+
+type argoParameter struct {
+	Name        string `yaml:"name"`
+	Value       string `yaml:"value"`
+	ForceString bool   `yaml:"forceString"`
+}
+
+func newArgoCDCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "argocd [manifests...]",
+		Short: "validate the values of ArgoCD Applications against the jsonschemas of their charts",
+		Long: `Reads the ArgoCD Applications of the manifests (files or directories, - reads stdin) and validates the
+helm values of their sources against the schemas of their charts. Without arguments, the whole repository
+below the current directory is scanned. The valueFiles, values, valuesObject and parameters are merged in
+this order into the values of the chart like ArgoCD does. The charts of git sources are local charts below
+--source-root, the ones of helm repositories and OCI registries are downloaded into the local store.`,
+		RunE:          validateArgoCD,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("source-root", ".", "directory of the git repository of the sources, the paths of their charts and values files are relative to it")
+	addReleaseFlags(cmd)
+
+	return cmd
+}
+
+func validateArgoCD(cmd *cobra.Command, args []string) error {
+	sourceRoot, _ := cmd.Flags().GetString("source-root")
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	manifests, err := readManifests(args)
+	if err != nil {
+		return err
+	}
+
+	releases := []*release{}
+	foundErrors := false
+	for _, m := range manifests {
+		if m.Kind != "Application" || m.group() != argoGroup {
+			continue
+		}
+		applicationReleases, err := argoReleases(m, sourceRoot)
+		if err != nil {
+			foundErrors = true
+			log.Errorf("Invalid %s (%s): %s", m, m.Path, err)
+			continue
+		}
+		releases = append(releases, applicationReleases...)
+	}
+	if len(releases) == 0 && !foundErrors {
+		log.Warnf("No Applications with helm sources found in %s", strings.Join(args, ", "))
+		return nil
+	}
+
+	if err := validateReleases(cmd, releases, false); err != nil {
+		return err
+	}
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// argoReleases returns the releases of the helm sources of the Application. Sources without chart and helm
+// parameters (e.g. plain manifests or the ref sources of values files) aren't releases.
+func argoReleases(m *manifest, sourceRoot string) ([]*release, error) {
+	var spec argoApplicationSpec
+	if err := m.Spec.Decode(&spec); err != nil {
+		return nil, err
+	}
+	// ArgoCD ignores source once sources is set
+	sources := spec.Sources
+	if len(sources) == 0 && spec.Source != nil {
+		sources = []argoSource{*spec.Source}
+	}
+
+	// the values files of other sources are referenced with $ref/path, their repositories must be the one
+	// of --source-root
+	refs := make(map[string]bool)
+	for _, source := range sources {
+		if source.Ref != "" {
+			refs[source.Ref] = true
+		}
+	}
+
+	name := strings.TrimPrefix(m.String(), m.Kind+"/")
+	releases := []*release{}
+	for i, source := range sources {
+		if source.Chart == "" && source.Helm == nil {
+			continue
+		}
+		releaseName := name
+		if len(sources) > 1 {
+			releaseName = fmt.Sprintf("%s (source %d)", name, i+1)
+		}
+		r, err := argoRelease(releaseName, m.Path, source, refs, sourceRoot)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, r)
+	}
+	return releases, nil
+}
+
+// argoRelease returns the release of a helm source with its values
+func argoRelease(name, manifestPath string, source argoSource, refs map[string]bool, sourceRoot string) (*release, error) {
+	r := &release{Name: name, Source: manifestPath}
+	switch {
+	case source.Chart != "":
+		repository := source.RepoURL
+		if !strings.HasPrefix(repository, "https://") && !strings.HasPrefix(repository, "http://") && !strings.HasPrefix(repository, "oci://") {
+			// helm repositories without a scheme are OCI registries
+			repository = "oci://" + repository
+		}
+		r.Chart = releaseChart{Repository: repository, Name: source.Chart, Version: source.TargetRevision}
+	case source.Path != "":
+		r.Chart = releaseChart{Path: filepath.Join(sourceRoot, filepath.FromSlash(source.Path))}
+	default:
+		return nil, errors.New("a helm source has neither a chart nor a path")
+	}
+	if source.Helm == nil {
+		return r, nil
+	}
+
+	for _, valueFile := range source.Helm.ValueFiles {
+		valuesPath, err := argoValuesPath(valueFile, source, refs, sourceRoot)
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(valuesPath)
+		if errors.Is(err, os.ErrNotExist) && source.Helm.IgnoreMissingValueFiles {
+			log.Debugf("Skipping the missing values file %s of %s", valuesPath, name)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.Values = append(r.Values, releaseValues{Source: valuesPath, Content: content})
+	}
+
+	// valuesObject takes precedence over values
+	if source.Helm.ValuesObject.Kind != 0 {
+		content, err := yaml.Marshal(&source.Helm.ValuesObject)
+		if err != nil {
+			return nil, err
+		}
+		r.Values = append(r.Values, releaseValues{Source: "helm.valuesObject", Content: content})
+	} else if source.Helm.Values != "" {
+		r.Values = append(r.Values, releaseValues{Source: "helm.values", Content: []byte(source.Helm.Values)})
+	}
+
+	if len(source.Helm.Parameters) > 0 {
+		values := map[string]interface{}{}
+		for _, parameter := range source.Helm.Parameters {
+			if parameter.Name == "" {
+				return nil, errors.New("a helm parameter has no name")
+			}
+			var value interface{} = parameter.Value
+			if !parameter.ForceString {
+				value = scalarValue(parameter.Value)
+			}
+			setValue(values, splitSetName(parameter.Name), value)
+		}
+		content, err := yaml.Marshal(values)
+		if err != nil {
+			return nil, err
+		}
+		r.Values = append(r.Values, releaseValues{Source: "helm.parameters", Content: content})
+	}
+	return r, nil
+}
+
+// argoValuesPath returns the path of a values file of a source: $ref/path is relative to the repository of
+// the ref source, other paths are relative to the chart of a git source
+func argoValuesPath(valueFile string, source argoSource, refs map[string]bool, sourceRoot string) (string, error) {
+	if strings.HasPrefix(valueFile, "$") {
+		ref, valuesPath, _ := strings.Cut(strings.TrimPrefix(valueFile, "$"), "/")
+		if !refs[ref] {
+			return "", fmt.Errorf("the values file %s references the unknown source %s", valueFile, ref)
+		}
+		return filepath.Join(sourceRoot, filepath.FromSlash(valuesPath)), nil
+	}
+	if strings.Contains(valueFile, "://") {
+		return "", fmt.Errorf("the remote values file %s isn't supported", valueFile)
+	}
+	if source.Path == "" {
+		return "", fmt.Errorf("the values file %s of the chart %s must reference a source with $ref", valueFile, source.Chart)
+	}
+	return filepath.Join(sourceRoot, filepath.FromSlash(source.Path), filepath.FromSlash(valueFile)), nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestArgoReleases(t *testing.T) {
+	applications := filepath.Join("testdata", "argocd", "applications.yaml")
+	sourceRoot := filepath.Join("testdata", "argocd", "sources")
+	manifests, err := readManifests([]string{applications})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	redis := releaseChart{Repository: "https://charts.bitnami.com/bitnami", Name: "redis"}
+
+	tests := []struct {
+		name     string
+		releases []*release
+		err      bool
+	}{
+		{
+			name: "git",
+			releases: []*release{{
+				Name:   "argocd/git",
+				Source: applications,
+				Chart:  releaseChart{Path: filepath.Join(sourceRoot, "charts", "web")},
+				Values: []releaseValues{{
+					Source:  filepath.Join(sourceRoot, "charts", "web", "values-prod.yaml"),
+					Content: []byte("ingress:\n  enabled: true\n"),
+				}},
+			}},
+		},
+		{
+			name: "helm-repository",
+			releases: []*release{{
+				Name:   "argocd/helm-repository",
+				Source: applications,
+				Chart:  releaseChart{Repository: "https://charts.bitnami.com/bitnami", Name: "redis", Version: "18.x"},
+			}},
+		},
+		{
+			// repositories without a scheme are OCI registries
+			name: "oci",
+			releases: []*release{{
+				Name:   "argocd/oci",
+				Source: applications,
+				Chart:  releaseChart{Repository: "oci://ghcr.io/example/charts", Name: "web", Version: "1.0.0"},
+			}},
+		},
+		{
+			name: "values-object",
+			releases: []*release{{
+				Name:   "argocd/values-object",
+				Source: applications,
+				Chart:  redis,
+				Values: []releaseValues{{Source: "helm.valuesObject", Content: []byte("replicas: 2\n")}},
+			}},
+		},
+		{
+			name: "values",
+			releases: []*release{{
+				Name:   "argocd/values",
+				Source: applications,
+				Chart:  redis,
+				Values: []releaseValues{{Source: "helm.values", Content: []byte("replicas: 1\n")}},
+			}},
+		},
+		{
+			name: "parameters",
+			releases: []*release{{
+				Name:   "argocd/parameters",
+				Source: applications,
+				Chart:  redis,
+				Values: []releaseValues{{
+					Source:  "helm.parameters",
+					Content: []byte("image:\n    tag: \"1.0\"\npodAnnotations:\n    prometheus.io/scrape: true\nreplicas: 2\n"),
+				}},
+			}},
+		},
+		{
+			name: "multi-source",
+			releases: []*release{{
+				Name:   "argocd/multi-source (source 1)",
+				Source: applications,
+				Chart:  redis,
+				Values: []releaseValues{{Source: filepath.Join(sourceRoot, "envs", "prod.yaml"), Content: []byte("replicas: 3\n")}},
+			}},
+		},
+		{
+			// source is ignored once sources is set
+			name: "source-and-sources",
+			releases: []*release{{
+				Name:   "argocd/source-and-sources",
+				Source: applications,
+				Chart:  redis,
+			}},
+		},
+		{
+			name: "unknown-ref",
+			err:  true,
+		},
+		{
+			name: "missing-values-file",
+			err:  true,
+		},
+		{
+			name: "unnamed-parameter",
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := findManifest(manifests, "Application", "argocd", test.name)
+			if m == nil {
+				t.Fatalf("The Application %s isn't in the testdata", test.name)
+			}
+			releases, err := argoReleases(m, sourceRoot)
+			if test.err {
+				if err == nil {
+					t.Fatalf("Expected an error for the Application %s", test.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+			}
+			assert.Equal(t, releases, test.releases)
+		})
+	}
+}
+
+func TestArgoValuesPath(t *testing.T) {
+	gitSource := argoSource{Path: "charts/web"}
+	chartSource := argoSource{Chart: "redis"}
+	refs := map[string]bool{"values": true}
+
+	tests := []struct {
+		valueFile string
+		source    argoSource
+		path      string
+		err       bool
+	}{
+		// the values files of git sources are relative to their chart
+		{valueFile: "values-prod.yaml", source: gitSource, path: filepath.Join("root", "charts", "web", "values-prod.yaml")},
+		{valueFile: "envs/prod.yaml", source: gitSource, path: filepath.Join("root", "charts", "web", "envs", "prod.yaml")},
+		// $ref/path is relative to the repository of the ref source
+		{valueFile: "$values/envs/prod.yaml", source: chartSource, path: filepath.Join("root", "envs", "prod.yaml")},
+		{valueFile: "$values/envs/prod.yaml", source: gitSource, path: filepath.Join("root", "envs", "prod.yaml")},
+		{valueFile: "$unknown/envs/prod.yaml", source: chartSource, err: true},
+		{valueFile: "https://example.com/values.yaml", source: gitSource, err: true},
+		// the values files of charts of repositories must reference a source
+		{valueFile: "values-prod.yaml", source: chartSource, err: true},
+	}
+	for _, test := range tests {
+		path, err := argoValuesPath(test.valueFile, test.source, refs, "root")
+		if test.err {
+			if err == nil {
+				t.Errorf("Expected an error for the values file %s", test.valueFile)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		assert.Equal(t, path, test.path, test.valueFile)
+	}
+}
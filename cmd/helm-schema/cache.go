@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/store"
+)
+
+func newCacheCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "manage the local store of the remote dependencies and documents used with --offline",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:         "warm [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "download the remote dependencies and documents of the charts into the local store",
+		Long: `Downloads the charts of the dependencies with a chart repository (http or https), which aren't in the
+charts directories, and the remote documents referenced by $refs into the local store (--store-dir).
+Copy the store to a machine without network access and generate the schemas there with --offline.`,
+		RunE:          warmCache,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	})
+	return cmd
+}
+
+func warmCache(_ *cobra.Command, _ []string) error {
+	if viper.GetBool("offline") {
+		return errors.New("warming the local store needs network access and can't be used with --offline")
+	}
+	client, err := newHTTPClient()
+	if err != nil {
+		return err
+	}
+	chartStore := localStore()
+
+	charts, foundErrors := discoverCharts()
+	workersCount, err := getWorkersCount(viper.GetInt("workers"))
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, result := range charts {
+		names[result.Chart.Name] = true
+	}
+
+	// the dependencies are downloaded in parallel, --fetch-concurrency limits the requests to every repository
+	var mu sync.Mutex
+	wg := sync.WaitGroup{}
+	workers := make(chan struct{}, workersCount)
+	queued := make(map[string]bool)
+	for _, result := range charts {
+		for _, dep := range result.Chart.Dependencies {
+			key := strings.Join([]string{dep.Repository, dep.Name, dep.Version}, "|")
+			if !store.IsRemote(dep) || names[dep.Name] || queued[key] {
+				continue
+			}
+			if packaged, err := schema.ReadPackagedDependency(filepath.Dir(result.ChartPath), dep); err == nil && packaged != nil {
+				continue
+			}
+			queued[key] = true
+			wg.Add(1)
+			workers <- struct{}{}
+			go func() {
+				defer func() {
+					<-workers
+					wg.Done()
+				}()
+				archive, err := chartStore.Warm(client, dep)
+				if err != nil {
+					mu.Lock()
+					foundErrors = true
+					mu.Unlock()
+					chartLog(result).Errorf("Could not download dependency %s of chart %s (%s): %s", dep.Name, result.Chart.Name, result.ChartPath, err)
+					return
+				}
+				chartLog(result).Infof("Stored dependency %s of chart %s in %s", dep.Name, result.Chart.Name, archive)
+				if packaged, err := schema.ReadPackagedChart(archive); err == nil && packaged == nil {
+					chartLog(result).Warnf("Dependency %s of chart %s doesn't ship a %s, so it's not added to the schema", dep.Name, result.Chart.Name, schema.PublishedSchemaFile)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	// the $refs are kept, so the remote documents are fetched by flattening the schemas below
+	viper.Set("flatten", false)
+	results, err := run(false)
+	if err != nil {
+		foundErrors = true
+	}
+	recorder := &http.Client{Transport: chartStore.Recorder(client.Transport), Timeout: client.Timeout}
+	for _, result := range results {
+		if !hasRemoteRefs(&result.Schema) {
+			continue
+		}
+		if err := result.Schema.Flatten(valuesURL(result.ValuesPath), recorder); err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not store the remote documents of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+			continue
+		}
+		chartLog(result).Infof("Stored the remote documents of chart %s in %s", result.Chart.Name, chartStore.Dir)
+	}
+
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// hasRemoteRefs returns true, if the schema references remote documents (http or https)
+func hasRemoteRefs(s *schema.Schema) bool {
+	found := false
+	s.Walk(func(subSchema *schema.Schema) {
+		if strings.HasPrefix(subSchema.Ref, "http://") || strings.HasPrefix(subSchema.Ref, "https://") {
+			found = true
+		}
+	})
+	return found
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// chartsChangedSince returns the paths of all charts, which changed since the given git ref,
+// and all charts depending on them
+func chartsChangedSince(ref, chartSearchRoot string, results []*schema.Result) (map[string]bool, error) {
+	changedFiles, err := util.GitChangedFiles(chartSearchRoot, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	chartDirs := make(map[string]*schema.Result)
+	for _, result := range results {
+		// the changed files don't contain symlinks
+		chartDir, err := filepath.Abs(filepath.Dir(result.ChartPath))
+		if err != nil {
+			return nil, err
+		}
+		if chartDir, err = filepath.EvalSymlinks(chartDir); err != nil {
+			return nil, err
+		}
+		chartDirs[chartDir] = result
+	}
+
+	affected := make(map[string]bool)
+	for _, file := range changedFiles {
+		// a file belongs to the chart with the deepest directory containing it
+		for dir := filepath.Dir(file); ; dir = filepath.Dir(dir) {
+			if result, ok := chartDirs[dir]; ok {
+				if !affected[result.ChartPath] {
+					chartLog(result).Debugf("Chart %s changed since %s", result.ChartPath, ref)
+				}
+				affected[result.ChartPath] = true
+				break
+			}
+			if dir == filepath.Dir(dir) {
+				break
+			}
+		}
+	}
+
+	// add every chart which (transitively) depends on a changed chart
+	for added := true; added; {
+		added = false
+		affectedNames := make(map[string]bool)
+		for _, result := range results {
+			if affected[result.ChartPath] && result.Chart != nil {
+				affectedNames[result.Chart.Name] = true
+			}
+		}
+		for _, result := range results {
+			if affected[result.ChartPath] || result.Chart == nil {
+				continue
+			}
+			for _, dep := range result.Chart.Dependencies {
+				if affectedNames[dep.Name] {
+					chartLog(result).Debugf("Chart %s depends on the changed chart %s", result.ChartPath, dep.Name)
+					affected[result.ChartPath] = true
+					added = true
+					break
+				}
+			}
+		}
+	}
+
+	return affected, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+func TestChartsChangedSinceSymlink(t *testing.T) {
+	if _, err := osexec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) {
+		cmd := osexec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+	writeFile := func(path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+	}
+	for _, name := range []string{"foo", "bar"} {
+		writeFile(filepath.Join(repo, "charts", name, "Chart.yaml"), "name: "+name+"\n")
+		writeFile(filepath.Join(repo, "charts", name, "values.yaml"), "foo: bar\n")
+	}
+	git("init", "--quiet")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "initial")
+	writeFile(filepath.Join(repo, "charts", "foo", "values.yaml"), "foo: baz\n")
+
+	// the charts are found below a symlink to the repository
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(repo, link); err != nil {
+		t.Skipf("Could not create a symlink: %v", err)
+	}
+	results := []*schema.Result{}
+	for _, name := range []string{"foo", "bar"} {
+		results = append(results, &schema.Result{
+			ChartPath: filepath.Join(link, "charts", name, "Chart.yaml"),
+			Chart:     &chart.ChartFile{Name: name},
+		})
+	}
+
+	affected, err := chartsChangedSince("HEAD", link, results)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, affected, map[string]bool{results[0].ChartPath: true})
+}
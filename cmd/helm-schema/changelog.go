@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+func newChangelogCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "changelog [chart]",
+		Short: "print a changelog of the values between two revisions",
+		Long: `Prints a markdown changelog fragment of the added, removed and changed values (with their old and new defaults)
+of every chart between two git revisions. The schemas are generated from the values files at the revisions,
+so the schema files don't need to be committed. Without --to, the values files of the working tree are used.`,
+		Args:          cobra.MaximumNArgs(1),
+		Annotations:   map[string]string{chartArgumentAnnotation: "true"},
+		RunE:          changelog,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("from", "", "git ref of the old revision (e.g. the tag of the last release)")
+	cmd.Flags().String("to", "", "git ref of the new revision (default: the working tree)")
+
+	return cmd
+}
+
+func changelog(cmd *cobra.Command, _ []string) error {
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+
+	if from == "" {
+		return errors.New("the --from flag is required")
+	}
+	opts, err := newBufferOptions()
+	if err != nil {
+		return err
+	}
+	// the charts and their values files are taken from the working tree
+	results, err := run(false)
+	if err != nil {
+		return err
+	}
+
+	first := true
+	for _, result := range results {
+		newSchema, exists, err := valuesSchemaAt(opts, result.ValuesPath, to)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			chartLog(result).Infof("The values file %s of chart %s didn't exist at %s", result.ValuesPath, result.Chart.Name, revisionName(to))
+			continue
+		}
+		oldSchema, exists, err := valuesSchemaAt(opts, result.ValuesPath, from)
+		if err != nil {
+			return err
+		}
+		var changes []schema.Change
+		if exists {
+			changes = schema.Diff(oldSchema, newSchema)
+		} else {
+			// a new chart, all values were added and nothing can break
+			changes = schema.Diff(&schema.Schema{Type: newSchema.Type, AdditionalProperties: newSchema.AdditionalProperties}, newSchema)
+			for i := range changes {
+				changes[i].Kind = schema.ChangeAdditive
+			}
+		}
+
+		fragment := schema.Changelog(result.Chart.Name, changes)
+		if fragment == "" {
+			continue
+		}
+		if !first {
+			fmt.Println()
+		}
+		first = false
+		fmt.Print(fragment)
+	}
+	return nil
+}
+
+// valuesSchemaAt generates the schema of the values file at the git ref or of the working tree
+// if the ref is empty. The schema is converted like it would be written.
+func valuesSchemaAt(opts *bufferOptions, valuesPath, ref string) (*schema.Schema, bool, error) {
+	var content []byte
+	if ref == "" {
+		var err error
+		if content, err = os.ReadFile(valuesPath); err != nil {
+			return nil, false, err
+		}
+	} else {
+		var exists bool
+		var err error
+		content, exists, err = util.GitFileAt(filepath.Dir(valuesPath), ref, filepath.Base(valuesPath))
+		if err != nil || !exists {
+			return nil, exists, err
+		}
+	}
+
+	result, err := opts.generate("", content)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not generate the schema of %s at %s: %w", valuesPath, revisionName(ref), err)
+	}
+	schemaJSON, err := result.Schema.ToJson()
+	if err != nil {
+		return nil, false, err
+	}
+	s, err := schema.ReadSchemaFile(schemaJSON)
+	return s, true, err
+}
+
+func revisionName(ref string) string {
+	if ref == "" {
+		return "the working tree"
+	}
+	return ref
+}
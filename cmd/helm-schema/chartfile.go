@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+func newLintChartFileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "lint-chartfile [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "validate the Chart.yaml files against the jsonschema of Chart.yaml",
+		Long: `Validates the Chart.yaml files of the charts against the jsonschema of Chart.yaml files, before they are
+parsed. Missing or misspelled keys, versions which aren't semantic versions and keys, which the apiVersion of
+the chart doesn't support (e.g. the dependencies of charts with apiVersion v1), are reported with the json
+pointer of the invalid value. With --print-schema, the jsonschema of the --api-version is printed instead, e.g.
+for editors.`,
+		RunE:          lintChartFiles,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().Bool("print-schema", false, "print the jsonschema of Chart.yaml files instead of validating them")
+	cmd.Flags().String("api-version", chart.APIVersionV2, "apiVersion of the charts of the printed jsonschema, one of (v1, v2)")
+
+	return cmd
+}
+
+func lintChartFiles(cmd *cobra.Command, _ []string) error {
+	if printSchema, _ := cmd.Flags().GetBool("print-schema"); printSchema {
+		apiVersion, _ := cmd.Flags().GetString("api-version")
+		chartFileSchema, err := schema.ChartFileSchema(apiVersion)
+		if err != nil {
+			return usageError{err}
+		}
+		indent, err := jsonIndent()
+		if err != nil {
+			return usageError{err}
+		}
+		content, err := chartFileSchema.ToJsonIndent(indent)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(content))
+		return nil
+	}
+
+	// the chart files are validated before they're parsed, so the ones helm-schema can't read are reported too
+	queue := make(chan string)
+	errs := make(chan error)
+	done := make(chan struct{})
+	foundErrors := false
+	go func() {
+		for err := range errs {
+			foundErrors = true
+			log.Error(err)
+		}
+		close(done)
+	}()
+	queueCharts(queue, errs)
+
+	for chartPath := range queue {
+		if filepath.Base(chartPath) != "Chart.yaml" {
+			log.Debugf("Skipping %s, it isn't a Chart.yaml", chartPath)
+			continue
+		}
+		content, err := os.ReadFile(chartPath)
+		if err != nil {
+			foundErrors = true
+			log.WithField("chartPath", chartPath).Errorf("Could not read chart file %s: %s", chartPath, err)
+			continue
+		}
+		if err := schema.ValidateChartFile(content); err != nil {
+			if reportRule(log.WithField("chartPath", chartPath), ruleInvalidChartFile, "Chart file %s is invalid: %s", chartPath, valuesViolations(err)) {
+				foundErrors = true
+			}
+			continue
+		}
+		log.WithField("chartPath", chartPath).Infof("Chart file %s is valid", chartPath)
+	}
+	close(errs)
+	<-done
+
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
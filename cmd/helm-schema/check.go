@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newCheckCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:         "check [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "check if the jsonschemas are up to date",
+		Long: `Generates the jsonschemas (without writing them) and fails if one of the existing schema files
+is missing or differs from the generated one, e.g. to verify in CI that the schemas were regenerated.`,
+		RunE:          check,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+}
+
+func check(_ *cobra.Command, _ []string) error {
+	outputFormat := viper.GetString("format")
+	appendNewline := viper.GetBool("append-newline")
+	indent, err := jsonIndent()
+	if err != nil {
+		return err
+	}
+
+	// check the charts which could be generated, even if others failed
+	results, runErr := run(false)
+	foundErrors := runErr != nil
+	foundOutdated := false
+
+	for _, result := range results {
+		generated, err := schemaContent(result, outputFormat, indent, appendNewline)
+		if err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not serialize schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+			continue
+		}
+
+		existing, err := os.ReadFile(result.OutputPath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			foundOutdated = true
+			chartLog(result).Errorf("The schema %s of chart %s is missing", result.OutputPath, result.Chart.Name)
+		case err != nil:
+			foundErrors = true
+			chartLog(result).Errorf("Could not read schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+		case !bytes.Equal(existing, generated):
+			foundOutdated = true
+			chartLog(result).Errorf("The schema %s of chart %s is outdated", result.OutputPath, result.Chart.Name)
+		default:
+			chartLog(result).Infof("The schema %s of chart %s is up to date", result.OutputPath, result.Chart.Name)
+		}
+	}
+
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	if foundOutdated {
+		return errOutdated
+	}
+	return nil
+}
@@ -2,12 +2,19 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ojsef39/helm-schema/pkg/httpclient"
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/util"
 )
 
 func possibleLogLevels() []string {
@@ -20,23 +27,143 @@ func possibleLogLevels() []string {
 	return levels
 }
 
+// loadConfigFile reads the config file given by --config. If no file is given,
+// .helm-schema.yaml in the current directory is used if present.
+func loadConfigFile() error {
+	if configFile := viper.GetString("config"); configFile != "" {
+		viper.SetConfigFile(configFile)
+		if filepath.Ext(configFile) == "" {
+			viper.SetConfigType("yaml")
+		}
+	} else {
+		viper.SetConfigName(".helm-schema")
+		viper.SetConfigType("yaml")
+		viper.AddConfigPath(".")
+	}
+
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			return nil
+		}
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+	return nil
+}
+
+// configSection returns the raw content of the given key in the config file.
+// In contrast to viper, the case of nested keys is preserved.
+func configSection(key string) (map[string]interface{}, error) {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, err
+	}
+
+	section, ok := config[key]
+	if !ok || section == nil {
+		return nil, nil
+	}
+	sectionMap, ok := section.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s in config file %s must be a map", key, configFile)
+	}
+	return sectionMap, nil
+}
+
 func configureLogging() {
 	logLevelName := viper.GetString("log-level")
+	// helm passes its --debug flag to plugins in the environment
+	if !viper.IsSet("log-level") && os.Getenv("HELM_DEBUG") == "true" {
+		logLevelName = log.DebugLevel.String()
+	}
 	logLevel, err := log.ParseLevel(logLevelName)
 	if err != nil {
 		log.Errorf("Failed to parse provided log level %s: %s", logLevelName, err)
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
-	log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	switch logFormat := viper.GetString("log-format"); logFormat {
+	case logFormatText:
+		log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	case logFormatJSON:
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		log.Errorf("Unsupported log format %s, use %s or %s", logFormat, logFormatText, logFormatJSON)
+		os.Exit(exitUsage)
+	}
 	log.SetLevel(logLevel)
+
+	// the logs are written to stderr and appended to the log file
+	if logFile := viper.GetString("log-file"); logFile != "" {
+		file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			log.Errorf("Failed to open log file %s: %s", logFile, err)
+			os.Exit(1)
+		}
+		log.SetOutput(io.MultiWriter(os.Stderr, file))
+	}
 }
 
+// The formats of the logs
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// chartLog returns a log entry with the name and path of the chart of the result as fields
+func chartLog(result *schema.Result) *log.Entry {
+	fields := log.Fields{"chartPath": result.ChartPath}
+	if result.Chart != nil {
+		fields["chart"] = result.Chart.Name
+	}
+	return log.WithFields(fields)
+}
+
+// chartArgumentAnnotation marks the commands, which take a single chart as optional argument
+const chartArgumentAnnotation = "helm-schema/chart-argument"
+
 func newCommand(run func(cmd *cobra.Command, args []string) error) (*cobra.Command, error) {
 	cmd := &cobra.Command{
-		Use:           "helm-schema",
-		Short:         "helm-schema automatically generates a jsonschema file for helm charts from values files",
-		Version:       version,
+		Use:         "helm-schema [chart]",
+		Short:       "helm-schema automatically generates a jsonschema file for helm charts from values files",
+		Version:     version,
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := loadConfigFile(); err != nil {
+				return usageError{err}
+			}
+			if err := loadRuleSeverities(); err != nil {
+				return usageError{err}
+			}
+			configureLogging()
+			if err := validateErrorOn(); err != nil {
+				return err
+			}
+			if dir := viper.GetString("pprof"); dir != "" {
+				stop, err := startProfiling(dir)
+				if err != nil {
+					return fmt.Errorf("could not start profiling: %w", err)
+				}
+				stopProfiling = stop
+			}
+			if _, ok := cmd.Annotations[chartArgumentAnnotation]; !ok {
+				args = nil
+			}
+			if err := selectChart(args); err != nil {
+				return usageError{err}
+			}
+			return nil
+		},
 		RunE:          run,
 		SilenceUsage:  true,
 		SilenceErrors: true,
@@ -46,10 +173,28 @@ func newCommand(run func(cmd *cobra.Command, args []string) error) (*cobra.Comma
 		"level of logs that should printed, one of (%s)",
 		strings.Join(possibleLogLevels(), ", "),
 	)
+	cmd.PersistentFlags().
+		String("config", "", "config file containing default values for all flags (default: .helm-schema.yaml if present)")
 	cmd.PersistentFlags().
 		StringP("chart-search-root", "c", ".", "directory to search recursively within for charts")
 	cmd.PersistentFlags().
-		BoolP("dry-run", "d", false, "don't actually create files just print to stdout passed")
+		Int("max-search-depth", 0, "only search for charts at most this number of directories below the chart search root (0 means unlimited)")
+	cmd.PersistentFlags().
+		StringSlice("prune-dirs", []string{".git", "node_modules", ".terraform"}, "names of the directories, which are never searched for charts")
+	cmd.PersistentFlags().
+		Bool("follow-symlinks", false, "also search the symlinked directories for charts (e.g. shared charts symlinked into umbrella charts)")
+	cmd.PersistentFlags().
+		StringArray("chart", []string{}, "process this chart (directory or Chart.yaml) instead of searching for charts, can be repeated")
+	cmd.PersistentFlags().
+		String("charts-file", "", "process the charts listed in this file (one directory or Chart.yaml per line) instead of searching for charts")
+	cmd.PersistentFlags().
+		String("values-file", "", "generate the jsonschema of this values file only, without a Chart.yaml and without searching for charts")
+	cmd.PersistentFlags().
+		Bool("stdin", false, "read the values from stdin and print the generated jsonschema to stdout, without accessing the filesystem")
+	cmd.PersistentFlags().
+		Bool("stdout", false, "only print the generated jsonschemas to stdout, without writing any files or printing other output")
+	cmd.PersistentFlags().
+		BoolP("dry-run", "d", false, "don't write any files, print a diff of the changes of the schemas and the other generated files instead")
 	cmd.PersistentFlags().
 		BoolP("append-newline", "a", false, "append newline to generated jsonschema at the end of the file")
 	cmd.PersistentFlags().
@@ -60,19 +205,187 @@ func newCommand(run func(cmd *cobra.Command, args []string) error) (*cobra.Comma
 		BoolP("helm-docs-compatibility-mode", "p", false, "parse and use helm-docs comments")
 	cmd.PersistentFlags().
 		BoolP("dont-strip-helm-docs-prefix", "x", false, "disable the removal of the helm-docs prefix (--)")
+	cmd.PersistentFlags().
+		Bool("bitnami-compatibility-mode", false, "parse and use the @param comments of the bitnami readme-generator-for-helm")
 	cmd.PersistentFlags().
 		BoolP("no-dependencies", "n", false, "don't analyze dependencies")
 	cmd.PersistentFlags().
 		String("dependencies", "", "Comma-separated list of dependencies to process")
+	cmd.PersistentFlags().
+		String("dependency-schemas", schema.DependencySchemasGenerated, "schemas of the dependencies merged into their parents, one of ("+strings.Join(schema.DependencySchemasModes, ", ")+"), published prefers the values.schema.json shipped with a dependency")
+	cmd.PersistentFlags().
+		String("dependency-merge", schema.DependencyMergeDependencyWins, "how values of dependencies redeclared by their parents are merged, one of ("+strings.Join(schema.DependencyMergeStrategies, ", ")+")")
+	cmd.PersistentFlags().
+		Int("max-depth", 0, "maximum levels of dependencies merged into the schemas, the deeper ones allow any values (default: 0, which means unlimited)")
+	cmd.PersistentFlags().
+		StringSlice("keep-required", []string{}, "dependencies (names or aliases), whose required properties are kept in the schemas of their parents")
+	cmd.PersistentFlags().
+		StringSlice("condition-type", []string{"boolean"}, "type of the properties patched into dependencies for their conditions, if the values of the dependencies don't declare one")
+	cmd.PersistentFlags().
+		String("condition-description", schema.DefaultConditionDescription, "go template of the description of the properties patched into dependencies for their conditions, if the values of the dependencies don't describe them (e.g. Enables {{ .Chart.Name }}, if {{ .Condition }} is true)")
+	cmd.PersistentFlags().
+		Bool("fail-on-circular", false, "fail on circular or missing dependencies instead of warning and processing the charts in no particular order")
+	cmd.PersistentFlags().
+		StringSlice("exclude-values", []string{}, "dotted paths of values, which are excluded from the schemas, * matches within a key (e.g. internal.*)")
+	cmd.PersistentFlags().
+		String("validations-annotation", schema.KubernetesValidationsAnnotation, "custom annotation the CEL rules of the validations annotations are written to")
+	cmd.PersistentFlags().
+		Bool("detect-sensitive", false, "treat values with names like password, secret or token as sensitive and don't write their defaults to the schemas")
+	cmd.PersistentFlags().
+		Bool("self-check", false, "validate the values file of every chart against its generated schema and don't write schemas rejecting them")
+	cmd.PersistentFlags().
+		String("policy-file", "", "yaml file with the rules every generated jsonschema must follow (built-in rules, required keywords and policy commands), schemas violating them aren't written")
+	cmd.PersistentFlags().
+		Float64("min-description-coverage", 0, "fail if less than this percentage of the values of a chart have descriptions (0 disables it)")
+	cmd.PersistentFlags().
+		Float64("min-type-coverage", 0, "fail if less than this percentage of the values of a chart have types (0 disables it)")
+	cmd.PersistentFlags().
+		Float64("min-title-coverage", 0, "fail if less than this percentage of the values of a chart have titles (0 disables it)")
 	cmd.PersistentFlags().
 		BoolP("add-schema-reference", "r", false, "add reference to schema in values.yaml if not found")
 	cmd.PersistentFlags().StringP("log-level", "l", "info", logLevelUsage)
 	cmd.PersistentFlags().
-		StringSliceP("value-files", "f", []string{"values.yaml"}, "filenames to check for chart values")
+		String("log-format", logFormatText, "format of the logs, one of (text, json), the chart and its path are fields of the logs about a chart")
+	cmd.PersistentFlags().
+		String("log-file", "", "file the logs are appended to in addition to stderr (e.g. to audit long runs)")
 	cmd.PersistentFlags().
-		StringP("output-file", "o", "values.schema.json", "jsonschema file path relative to each chart directory to which jsonschema will be written")
+		String("error-on", errorOnError, "which problems fail the run, one of (error, warning, never), warnings-only runs exit with 2")
+	cmd.PersistentFlags().
+		Bool("fail-fast", false, "stop at the first chart with errors instead of processing all charts")
+	cmd.PersistentFlags().
+		Int("max-errors", 0, "stop after this number of charts with errors (0 processes all charts)")
+	cmd.PersistentFlags().
+		Bool("no-progress", false, "don't show the number of processed charts on stderr, it's only shown if stderr is a terminal")
+	cmd.PersistentFlags().
+		Bool("timings", false, "print the durations of reading, parsing, inferring, merging dependencies, post-processing and writing of every chart to stderr")
+	cmd.PersistentFlags().
+		String("timings-file", "", "write the durations of the phases and the coverage of the values of every chart as json report to this file")
+	cmd.PersistentFlags().
+		String("pprof", "", "write a cpu profile (cpu.pprof) and a heap profile (heap.pprof) of the run to this directory")
+	cmd.PersistentFlags().
+		StringSliceP("value-files", "f", []string{"values.yaml"}, "filenames to check for chart values, the first existing one is used, the annotation helm-schema/values of Chart.yaml overrides them")
+	cmd.PersistentFlags().
+		StringP("output-file", "o", "values.schema.json", "jsonschema file path relative to each chart directory to which jsonschema will be written (supports go templates, e.g. {{ .Chart.Name }}.schema.json, default: values.schema.cue for cue, values.openapi.json for openapi)")
+	cmd.PersistentFlags().
+		String("output-dir", "", "write all jsonschemas below this directory instead of the chart directories")
+	cmd.PersistentFlags().
+		String("output-layout", "mirror", "layout of the jsonschemas in the output directory, one of (mirror, flat)")
 	cmd.PersistentFlags().
 		StringSliceP("skip-auto-generation", "k", []string{}, "comma separated list of fields to skip from being created by default (possible: title, description, required, default, additionalProperties)")
+	cmd.PersistentFlags().
+		String("required-mode", schema.RequiredModeAll, "which values are required, one of ("+strings.Join(schema.RequiredModes, ", ")+"), the annotation helm-schema/required-mode of Chart.yaml overrides it")
+	cmd.PersistentFlags().
+		String("cache-file", "", "file to store content hashes in, so unchanged charts are skipped on subsequent runs (e.g. .helm-schema-cache)")
+	cmd.PersistentFlags().
+		String("catalog-file", "", "write an index of all generated jsonschemas to this file (e.g. catalog.json)")
+	cmd.PersistentFlags().
+		String("catalog-format", "schemastore", "format of the catalog file, one of (schemastore, vscode)")
+	cmd.PersistentFlags().
+		String("changed-since", "", "only generate the schemas of charts which changed since this git ref (and the charts depending on them)")
+	cmd.PersistentFlags().
+		String("property-order", "alpha", "order of the properties in the generated jsonschema, one of (alpha, source)")
+	cmd.PersistentFlags().
+		String("defs-file", schema.SharedDefsFile, "file with the definitions the annotations of all charts can reference as #/$defs/<name>")
+	cmd.PersistentFlags().
+		String("defs-mode", schema.DefsModeBundle, "how the referenced shared definitions are added to the schemas, one of (bundle, inline)")
+	cmd.PersistentFlags().
+		Bool("dedupe", false, "move subschemas occurring more than once to the $defs of the schema and reference them with $ref")
+	cmd.PersistentFlags().
+		Int("indent", 2, "number of spaces the json of the generated schemas is indented with")
+	cmd.PersistentFlags().
+		Bool("compact", false, "write the json of the generated schemas without whitespace (overrides --indent)")
+	cmd.PersistentFlags().
+		Bool("flatten", false, "replace all $refs by the schemas they point to, so the schemas are self-contained")
+	cmd.PersistentFlags().
+		Bool("flatten-remote", false, "also fetch the remote documents (http and https) referenced by $refs with --flatten")
+	cmd.PersistentFlags().
+		String("ca-file", "", "path to a PEM bundle of certificates trusted in addition to the ones of the system for all network operations")
+	cmd.PersistentFlags().
+		Bool("insecure-skip-tls-verify", false, "skip the verification of the certificates of all servers")
+	cmd.PersistentFlags().
+		String("repository-config", util.HelmRepositoryConfig(), "path to helm's repositories file, whose credentials and certificates are used for the urls of the repositories")
+	cmd.PersistentFlags().
+		StringToString("bearer-token", map[string]string{}, "bearer tokens sent to hosts, which aren't authenticated by a repository (e.g. charts.example.com=token)")
+	cmd.PersistentFlags().
+		Duration("fetch-timeout", httpclient.DefaultTimeout, "timeout of every attempt of a request to a repository, registry or remote document")
+	cmd.PersistentFlags().
+		Int("fetch-retries", 3, "number of retries of requests failing with a network error or a transient status (429, 5xx)")
+	cmd.PersistentFlags().
+		Int("fetch-concurrency", 0, "maximum number of concurrent requests to every host (default: 0, which means unlimited)")
+	cmd.PersistentFlags().
+		Bool("offline", false, "forbid network access, remote $refs are read from the local store (populated by helm-schema cache warm)")
+	cmd.PersistentFlags().
+		String("store-dir", util.HelmCachePath("helm-schema", "store"), "directory of the local store of the charts of remote dependencies and the remote documents")
+	cmd.PersistentFlags().
+		String("lang", "", "language of the titles and descriptions in the generated jsonschema (e.g. de), by default all translations are written to x-i18n")
+	cmd.PersistentFlags().
+		String("description-format", "markdown", "format of the descriptions in the generated jsonschema, one of (markdown, plain)")
+	cmd.PersistentFlags().
+		Bool("emit-questions", false, "additionally write a rancher questions.yaml to every chart directory")
+	cmd.PersistentFlags().
+		Bool("emit-ui-schema", false, "additionally write a react-jsonschema-form uiSchema of the x-ui-* annotations next to every jsonschema (e.g. values.uischema.json)")
+	cmd.PersistentFlags().
+		Bool("emit-yaml", false, "additionally write the jsonschema as yaml with the origins of the properties as comments next to every jsonschema (e.g. values.schema.yaml)")
+	cmd.PersistentFlags().
+		Bool("emit-typescript", false, "additionally write a typescript definition of the values next to every jsonschema (e.g. values.d.ts)")
+	cmd.PersistentFlags().
+		Bool("emit-crd", false, "additionally write a CustomResourceDefinition validating the values in spec.values next to every jsonschema (e.g. values.crd.yaml)")
+	cmd.PersistentFlags().
+		String("crd-group", "", "API group of the CRDs written with --emit-crd (can be overridden with the helm-schema/crd-group chart annotation)")
+	cmd.PersistentFlags().
+		String("crd-kind", "", "kind of the CRDs written with --emit-crd (default: the chart name, can be overridden with the helm-schema/crd-kind chart annotation)")
+	cmd.PersistentFlags().
+		String("crd-version", "v1alpha1", "version of the CRDs written with --emit-crd (can be overridden with the helm-schema/crd-version chart annotation)")
+	cmd.PersistentFlags().
+		Bool("emit-go", false, "additionally write go structs of the values next to every jsonschema (e.g. values.go)")
+	cmd.PersistentFlags().
+		String("go-package", "values", "package name of the go structs written with --emit-go")
+	cmd.PersistentFlags().
+		String("format", schema.OutputFormatJSON, "format of the generated schemas, one of ("+strings.Join(schema.OutputFormats, ", ")+")")
+	cmd.PersistentFlags().
+		String("schema-uri", "", "$schema of the generated jsonschemas (default: http://json-schema.org/draft-07/schema#)")
+	cmd.PersistentFlags().
+		String("schema-id", "", "go template of the $id of every generated jsonschema (e.g. https://example.org/{{ .Chart.Name }}/{{ .Chart.Version }}.json)")
+	cmd.PersistentFlags().
+		String("post-process-cmd", "", "shell command every generated jsonschema is piped through (e.g. jq '.required = []'), the chart metadata is passed as HELM_SCHEMA_CHART_* environment variables")
+	cmd.PersistentFlags().
+		Bool("add-chart-metadata", false, "add the name, version and appVersion of the chart as x-helm-chart to the jsonschema")
+	cmd.PersistentFlags().
+		Bool("add-generated-by", false, "add the version of helm-schema and the sha256 of the Chart.yaml and values file as x-generated-by to the jsonschema")
+	cmd.PersistentFlags().
+		Bool("add-x-order", false, "add the position of every property in the values file as x-order annotation")
+	cmd.PersistentFlags().
+		IntP("workers", "w", 0, "number of charts processed in parallel (default: 0, which means twice the number of CPUs)")
+
+	cmd.AddCommand(newGenerateCommand())
+	cmd.AddCommand(newValidateCommand())
+	cmd.AddCommand(newCheckCommand())
+	cmd.AddCommand(newSnapshotCommand())
+	cmd.AddCommand(newPublishCommand())
+	cmd.AddCommand(newDocsCommand())
+	cmd.AddCommand(newMigrateCommand())
+	cmd.AddCommand(newAnnotateCommand())
+	cmd.AddCommand(newWizardCommand())
+	cmd.AddCommand(newServeCommand())
+	cmd.AddCommand(newLSPCommand())
+	cmd.AddCommand(newDiffCommand())
+	cmd.AddCommand(newChangelogCommand())
+	cmd.AddCommand(newListCommand())
+	cmd.AddCommand(newExplainCommand())
+	cmd.AddCommand(newLintCommand())
+	cmd.AddCommand(newLintChartFileCommand())
+	cmd.AddCommand(newConsistencyCommand())
+	cmd.AddCommand(newStatsCommand())
+	cmd.AddCommand(newTestCommand())
+	cmd.AddCommand(newSampleCommand())
+	cmd.AddCommand(newFuzzCommand())
+	cmd.AddCommand(newCacheCommand())
+	cmd.AddCommand(newHelmfileCommand())
+	cmd.AddCommand(newFluxCommand())
+	cmd.AddCommand(newArgoCDCommand())
+	cmd.AddCommand(newKustomizeCommand())
+	cmd.AddCommand(newTerraformCommand())
+	wrapUsageErrors(cmd)
 
 	viper.AutomaticEnv()
 	viper.SetEnvPrefix("HELM_SCHEMA")
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+// consistencyFinding is an inconsistency with the severity of its rule
+type consistencyFinding struct {
+	schema.Inconsistency
+	Severity string `json:"severity"`
+}
+
+func newConsistencyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "consistency",
+		Args:  cobra.NoArgs,
+		Short: "compare the values shared by the charts",
+		Long: `Generates the jsonschemas of all charts (without writing them) and compares the values with the same
+path in several charts (e.g. image.pullPolicy). Values with incompatible types are reported as
+inconsistent-type, values with different enums as inconsistent-enum, both as warnings by default. The values of
+the dependencies of a chart are compared in their own charts.`,
+		RunE:          consistency,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("report", reportFormatText, "format of the report, one of (text, json)")
+
+	return cmd
+}
+
+func consistency(cmd *cobra.Command, _ []string) error {
+	report, _ := cmd.Flags().GetString("report")
+	if report != reportFormatText && report != reportFormatJSON {
+		return usageErrorf("unsupported report format %s, use %s or %s", report, reportFormatText, reportFormatJSON)
+	}
+
+	// compare the charts which could be generated, even if others failed
+	results, runErr := run(false)
+	foundErrors := runErr != nil
+
+	inconsistencies, shared, err := schema.Inconsistencies(results)
+	if err != nil {
+		return fmt.Errorf("could not read the ignore comments: %w", err)
+	}
+	findings := []consistencyFinding{}
+	foundInconsistencies := false
+	for _, inconsistency := range inconsistencies {
+		severity := ruleSeverity(inconsistency.Rule)
+		switch severity {
+		case severityOff:
+			continue
+		case severityError:
+			foundInconsistencies = true
+		}
+		findings = append(findings, consistencyFinding{Inconsistency: inconsistency, Severity: severity})
+	}
+	log.Infof("%d of %d values shared by %d charts are inconsistent", len(findings), shared, len(results))
+
+	if err := writeConsistencyReport(os.Stdout, report, findings); err != nil {
+		return err
+	}
+	if foundErrors || foundInconsistencies {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+func writeConsistencyReport(w io.Writer, format string, findings []consistencyFinding) error {
+	if format == reportFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(findings)
+	}
+
+	for _, finding := range findings {
+		fmt.Fprintf(w, "%s (%s %s):\n", finding.Path, finding.Severity, finding.Rule)
+		for _, chart := range finding.Charts {
+			conventions := []string{}
+			if len(chart.Types) > 0 {
+				conventions = append(conventions, strings.Join(chart.Types, ", "))
+			}
+			if len(chart.Enum) > 0 {
+				conventions = append(conventions, "["+strings.Join(chart.Enum, ", ")+"]")
+			}
+			convention := strings.Join(conventions, " ")
+			if convention == "" {
+				convention = "no type"
+			}
+			fmt.Fprintf(w, "  %s (%s): %s\n", chart.Chart, chart.ChartPath, convention)
+		}
+	}
+	return nil
+}
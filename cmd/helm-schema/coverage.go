@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+// coverageThresholds are the minimum percentages of the leaf values of every chart with descriptions, types and
+// titles, 0 disables a threshold
+type coverageThresholds struct {
+	Description float64
+	Type        float64
+	Title       float64
+}
+
+// readCoverageThresholds returns the thresholds of the flags
+func readCoverageThresholds() (coverageThresholds, error) {
+	thresholds := coverageThresholds{
+		Description: viper.GetFloat64("min-description-coverage"),
+		Type:        viper.GetFloat64("min-type-coverage"),
+		Title:       viper.GetFloat64("min-title-coverage"),
+	}
+	for _, threshold := range []float64{thresholds.Description, thresholds.Type, thresholds.Title} {
+		if threshold < 0 || threshold > 100 {
+			return thresholds, fmt.Errorf("the minimum coverage must be a percentage between 0 and 100, got %g", threshold)
+		}
+	}
+	return thresholds, nil
+}
+
+// enabled returns whether any threshold is set
+func (t coverageThresholds) enabled() bool {
+	return t.Description > 0 || t.Type > 0 || t.Title > 0
+}
+
+// unmetThreshold is a threshold the coverage doesn't reach, with the ID of its rule
+type unmetThreshold struct {
+	rule    string
+	message string
+}
+
+// unmet returns the thresholds which the coverage doesn't reach
+func (t coverageThresholds) unmet(coverage schema.Coverage) []unmetThreshold {
+	unmet := []unmetThreshold{}
+	for _, threshold := range []struct {
+		rule    string
+		name    string
+		min     float64
+		percent float64
+	}{
+		{ruleDescriptionCoverage, "descriptions", t.Description, coverage.Description},
+		{ruleTypeCoverage, "types", t.Type, coverage.Type},
+		{ruleTitleCoverage, "titles", t.Title, coverage.Title},
+	} {
+		if threshold.percent < threshold.min {
+			unmet = append(unmet, unmetThreshold{threshold.rule, fmt.Sprintf("%.1f%% of the %d values have %s, at least %g%% are required", threshold.percent, coverage.Values, threshold.name, threshold.min)})
+		}
+	}
+	return unmet
+}
+
+// resultCoverage returns the coverage of the values of the chart of the result. The values of its dependencies
+// are covered by their own charts and the global values are shared by all charts, so both aren't counted.
+func resultCoverage(result *schema.Result) schema.Coverage {
+	ignored := []string{"global"}
+	if result.Chart != nil {
+		for _, dep := range result.Chart.Dependencies {
+			ignored = append(ignored, schema.DependencyKey(dep))
+		}
+	}
+	return result.Schema.Coverage(ignored...)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/store"
+)
+
+// dependencyResolver finds the processed charts of dependencies and limits the levels of
+// dependencies merged into a schema to maxDepth (0 means unlimited)
+type dependencyResolver struct {
+	maxDepth int
+	// store contains the charts of remote dependencies, which aren't in the charts directories
+	store  *store.Store
+	byName map[string]*schema.Result
+	// byDir contains the charts by their charts directory and name (e.g. app/charts|redis)
+	byDir map[string]*schema.Result
+}
+
+func newDependencyResolver(maxDepth int, chartStore *store.Store) *dependencyResolver {
+	return &dependencyResolver{
+		maxDepth: maxDepth,
+		store:    chartStore,
+		byName:   make(map[string]*schema.Result),
+		byDir:    make(map[string]*schema.Result),
+	}
+}
+
+// add registers the processed chart, so the charts depending on it can find it
+func (r *dependencyResolver) add(result *schema.Result) {
+	r.byName[result.Chart.Name] = result
+	chartsDir := filepath.Dir(filepath.Dir(result.ChartPath))
+	r.byDir[chartsDir+"|"+result.Chart.Name] = result
+}
+
+// lookup returns the chart of the dependency. The one in the charts directory of the parent is preferred,
+// so dependencies of dependencies with the same name (e.g. different versions of a library) don't collide.
+func (r *dependencyResolver) lookup(parent *schema.Result, dep *chart.Dependency) (*schema.Result, bool) {
+	chartsDir := filepath.Join(filepath.Dir(parent.ChartPath), "charts")
+	if result, ok := r.byDir[chartsDir+"|"+dep.Name]; ok {
+		return result, true
+	}
+	result, ok := r.byName[dep.Name]
+	return result, ok
+}
+
+// packaged returns the packaged chart of the dependency, which isn't among the processed charts, see readPackagedDependency
+func (r *dependencyResolver) packaged(parent *schema.Result, dep *chart.Dependency) (*schema.PackagedDependency, error) {
+	return readPackagedDependency(filepath.Dir(parent.ChartPath), dep, r.store)
+}
+
+// truncate replaces the schemas of the dependencies of the dependency, which are deeper than maxDepth in the
+// schema of its parent, by schemas allowing any values. The levels are limited, so circular dependencies end.
+func (r *dependencyResolver) truncate(depSchema *schema.Schema, dependency *schema.Result) {
+	if r.maxDepth > 0 {
+		r.truncateLevels(depSchema, dependency, r.maxDepth-1)
+	}
+}
+
+func (r *dependencyResolver) truncateLevels(s *schema.Schema, result *schema.Result, levels int) {
+	for _, dep := range result.Chart.Dependencies {
+		key := schema.DependencyKey(dep)
+		property, ok := s.Properties[key]
+		if !ok {
+			continue
+		}
+		if levels > 0 {
+			if dependency, ok := r.lookup(result, dep); ok {
+				r.truncateLevels(property, dependency, levels-1)
+			}
+			continue
+		}
+		s.Properties[key] = &schema.Schema{
+			Type:              []string{"object"},
+			Title:             property.Title,
+			Description:       property.Description,
+			CustomAnnotations: property.CustomAnnotations,
+		}
+	}
+}
+
+// readPackagedDependency returns the archive of the dependency in the charts directory of the chart in chartDir
+// or, if it has a remote repository, the one in the local store. It returns nil, if there's none shipping a schema.
+func readPackagedDependency(chartDir string, dep *chart.Dependency, chartStore *store.Store) (*schema.PackagedDependency, error) {
+	packaged, err := schema.ReadPackagedDependency(chartDir, dep)
+	if err != nil || packaged != nil || chartStore == nil || !store.IsRemote(dep) {
+		return packaged, err
+	}
+	archive, err := chartStore.FindChart(dep)
+	if err != nil || archive == "" {
+		return nil, err
+	}
+	return schema.ReadPackagedChart(archive)
+}
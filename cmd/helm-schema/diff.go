@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+const (
+	reportFormatText = "text"
+	reportFormatJSON = "json"
+)
+
+func newDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff [old.schema.json new.schema.json]",
+		Short: "show the changes between jsonschemas and fail on breaking changes",
+		Long: `Compares two jsonschema files or, with --against, the generated jsonschemas with the ones committed at a git ref.
+The changes are classified as breaking (e.g. a removed property, a narrowed type or a newly required property),
+additive or modified (e.g. a new default). The command fails if there are breaking changes.`,
+		Args:          cobra.MaximumNArgs(2),
+		RunE:          diff,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("against", "", "git ref to compare the generated jsonschemas with (e.g. origin/main)")
+	cmd.Flags().String("report", reportFormatText, "format of the report, one of (text, json)")
+
+	return cmd
+}
+
+// schemaDiff contains the changes of the schema of a chart
+type schemaDiff struct {
+	Chart   string          `json:"chart,omitempty"`
+	File    string          `json:"file"`
+	Changes []schema.Change `json:"changes"`
+}
+
+func diff(cmd *cobra.Command, args []string) error {
+	against, _ := cmd.Flags().GetString("against")
+	report, _ := cmd.Flags().GetString("report")
+
+	if report != reportFormatText && report != reportFormatJSON {
+		return fmt.Errorf("unsupported report format %s, use %s or %s", report, reportFormatText, reportFormatJSON)
+	}
+
+	var diffs []schemaDiff
+	var err error
+	switch {
+	case against != "" && len(args) == 0:
+		diffs, err = diffAgainst(against)
+	case against == "" && len(args) == 2:
+		diffs, err = diffFiles(args[0], args[1])
+	default:
+		return errors.New("either two jsonschema files or --against is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := writeDiffReport(os.Stdout, report, diffs); err != nil {
+		return err
+	}
+	for _, d := range diffs {
+		if schema.HasBreakingChanges(d.Changes) {
+			return errors.New("breaking changes were found")
+		}
+	}
+	return nil
+}
+
+// diffFiles compares two jsonschema files
+func diffFiles(oldFile, newFile string) ([]schemaDiff, error) {
+	oldSchema, err := readSchemaFile(oldFile)
+	if err != nil {
+		return nil, err
+	}
+	newSchema, err := readSchemaFile(newFile)
+	if err != nil {
+		return nil, err
+	}
+	return []schemaDiff{{File: newFile, Changes: schema.Diff(oldSchema, newSchema)}}, nil
+}
+
+// diffAgainst compares the generated schemas with the schema files at the git ref
+func diffAgainst(ref string) ([]schemaDiff, error) {
+	if outputFormat := viper.GetString("format"); outputFormat != schema.OutputFormatJSON {
+		return nil, fmt.Errorf("--against is only supported for the %s format", schema.OutputFormatJSON)
+	}
+
+	results, err := run(false)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := []schemaDiff{}
+	for _, result := range results {
+		content, exists, err := util.GitFileAt(filepath.Dir(result.OutputPath), ref, filepath.Base(result.OutputPath))
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			chartLog(result).Infof("The schema %s of chart %s didn't exist at %s", result.OutputPath, result.Chart.Name, ref)
+			continue
+		}
+		oldSchema, err := schema.ReadSchemaFile(content)
+		if err != nil {
+			return nil, fmt.Errorf("could not read schema %s at %s: %w", result.OutputPath, ref, err)
+		}
+
+		// compare the schema like it would be written
+		newContent, err := result.Schema.ToJson()
+		if err != nil {
+			return nil, err
+		}
+		newSchema, err := schema.ReadSchemaFile(newContent)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, schemaDiff{Chart: result.Chart.Name, File: result.OutputPath, Changes: schema.Diff(oldSchema, newSchema)})
+	}
+	return diffs, nil
+}
+
+func readSchemaFile(path string) (*schema.Schema, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := schema.ReadSchemaFile(content)
+	if err != nil {
+		return nil, fmt.Errorf("could not read schema %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func writeDiffReport(w io.Writer, format string, diffs []schemaDiff) error {
+	if format == reportFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diffs)
+	}
+
+	for _, d := range diffs {
+		if len(d.Changes) == 0 {
+			continue
+		}
+		if d.Chart != "" {
+			fmt.Fprintf(w, "%s (%s):\n", d.Chart, d.File)
+		} else {
+			fmt.Fprintf(w, "%s:\n", d.File)
+		}
+		for _, change := range d.Changes {
+			fmt.Fprintf(w, "  %-9s %s: %s%s\n", change.Kind, change.Path, change.Message, changeValues(change))
+		}
+	}
+	return nil
+}
+
+// changeValues returns the old and new value of the change, if they are set
+func changeValues(change schema.Change) string {
+	format := func(value interface{}) string {
+		valueJSON, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(valueJSON)
+	}
+	switch {
+	case change.Old != nil && change.New != nil:
+		return fmt.Sprintf(" (%s -> %s)", format(change.Old), format(change.New))
+	case change.Old != nil:
+		return fmt.Sprintf(" (was %s)", format(change.Old))
+	case change.New != nil:
+		return fmt.Sprintf(" (%s)", format(change.New))
+	}
+	return ""
+}
+
+// diffContextLines is the number of unchanged lines around the changes in the diffs of a dry-run
+const diffContextLines = 3
+
+// fileDiff returns the unified diff of the existing file and its new content, it's empty if the file
+// wouldn't change
+func fileDiff(path string, content []byte) (string, error) {
+	existing, err := os.ReadFile(path)
+	missing := errors.Is(err, os.ErrNotExist)
+	if err != nil && !missing {
+		return "", err
+	}
+	return util.UnifiedDiff(path, path, existing, content, diffContextLines, missing), nil
+}
+
+// The ansi escape sequences of the colored diffs
+const (
+	ansiBold  = "\033[1m"
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+	ansiCyan  = "\033[36m"
+	ansiReset = "\033[0m"
+)
+
+// colorizeDiff colors the removed lines of the unified diff red and the added ones green, if color is set
+func colorizeDiff(diff string, color bool) string {
+	if !color {
+		return diff
+	}
+	var colored strings.Builder
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		content := strings.TrimSuffix(line, "\n")
+		var ansi string
+		switch {
+		case content == "":
+		case strings.HasPrefix(content, "--- "), strings.HasPrefix(content, "+++ "):
+			ansi = ansiBold
+		case strings.HasPrefix(content, "@@"):
+			ansi = ansiCyan
+		case content[0] == '-':
+			ansi = ansiRed
+		case content[0] == '+':
+			ansi = ansiGreen
+		}
+		if ansi == "" {
+			colored.WriteString(line)
+			continue
+		}
+		colored.WriteString(ansi + content + ansiReset + line[len(content):])
+	}
+	return colored.String()
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+func newDocsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "docs [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "generate markdown or html documentation of the values from the jsonschemas",
+		Long: `Generates the jsonschemas and renders the documentation of every chart's values as markdown or html.
+With --inject, the markdown documentation replaces everything between the markers
+` + schema.MarkdownBeginMarker + ` and ` + schema.MarkdownEndMarker + ` in the docs file.
+With --site-dir, the html pages of all charts and an index page are written to one directory.`,
+		RunE:          docs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("format", schema.MarkdownFormatTable, "format of the documentation, one of (table, tree, html)")
+	cmd.Flags().String("docs-file", "values.md", "go template of the docs file path relative to each chart directory (default: values.html for html)")
+	cmd.Flags().Bool("inject", false, "inject the documentation between the markers of the existing docs file (e.g. README.md)")
+	cmd.Flags().String("site-dir", "", "write the html pages of all charts and an index page to this directory")
+
+	return cmd
+}
+
+func docs(cmd *cobra.Command, _ []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	docsFileTemplate, _ := cmd.Flags().GetString("docs-file")
+	inject, _ := cmd.Flags().GetBool("inject")
+	siteDir, _ := cmd.Flags().GetString("site-dir")
+	dryRun := viper.GetBool("dry-run")
+	chartSearchRoot := viper.GetString("chart-search-root")
+
+	if format != schema.MarkdownFormatTable && format != schema.MarkdownFormatTree && format != schema.HTMLFormat {
+		return fmt.Errorf("unsupported docs format %s, use %s, %s or %s", format, schema.MarkdownFormatTable, schema.MarkdownFormatTree, schema.HTMLFormat)
+	}
+	if format == schema.HTMLFormat {
+		if inject {
+			return errors.New("--inject is only supported for markdown")
+		}
+		if !cmd.Flags().Changed("docs-file") {
+			docsFileTemplate = "values.html"
+		}
+	} else if siteDir != "" {
+		return errors.New("--site-dir is only supported for html")
+	}
+
+	// document the charts which could be generated, even if others failed
+	results, err := run(false)
+	foundErrors := err != nil
+	indexEntries := []schema.HTMLIndexEntry{}
+
+	for _, result := range results {
+		var docsPath string
+		if siteDir != "" {
+			// mirror the chart directories, so charts with the same name don't collide
+			relChartDir, err := filepath.Rel(chartSearchRoot, filepath.Dir(result.ChartPath))
+			if err != nil {
+				foundErrors = true
+				chartLog(result).Error(err)
+				continue
+			}
+			docsPath = filepath.Join(siteDir, relChartDir, "values.html")
+			indexEntries = append(indexEntries, schema.HTMLIndexEntry{
+				Name:        result.Chart.Name,
+				Version:     result.Chart.Version,
+				Description: result.Chart.Description,
+				Path:        filepath.ToSlash(filepath.Join(relChartDir, "values.html")),
+			})
+		} else {
+			docsFile, err := util.RenderTemplate("docs file", docsFileTemplate, result)
+			if err != nil {
+				foundErrors = true
+				chartLog(result).Errorf("Could not create docs file name for chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+				continue
+			}
+			docsPath = filepath.Join(filepath.Dir(result.ChartPath), docsFile)
+		}
+
+		content, err := docsDocument(result, format, docsPath, inject)
+		if err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not create docs of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+			continue
+		}
+
+		if dryRun {
+			chartLog(result).Infof("Printing docs for %s", docsPath)
+			fmt.Println(string(content))
+			continue
+		}
+
+		if err := writeDocs(docsPath, content); err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not write docs %s: %s", docsPath, err)
+		}
+	}
+
+	if siteDir != "" {
+		indexPath := filepath.Join(siteDir, "index.html")
+		index, err := schema.HTMLIndex("Helm chart values", indexEntries)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			log.Infof("Printing docs for %s", indexPath)
+			fmt.Println(string(index))
+		} else if err := writeDocs(indexPath, index); err != nil {
+			foundErrors = true
+			log.Errorf("Could not write docs %s: %s", indexPath, err)
+		}
+	}
+
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+func writeDocs(docsPath string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(docsPath), 0755); err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(docsPath, content, 0644)
+}
+
+// docsDocument returns the new content of the docs file of the result
+func docsDocument(result *schema.Result, format, docsPath string, inject bool) ([]byte, error) {
+	if format == schema.HTMLFormat {
+		return result.Schema.ToHTML(result.Chart.Name, result.Chart.Description)
+	}
+
+	doc, err := result.Schema.ToMarkdown(format)
+	if err != nil {
+		return nil, err
+	}
+
+	if inject {
+		content, err := os.ReadFile(docsPath)
+		if err != nil {
+			return nil, err
+		}
+		return schema.InjectMarkdown(content, doc)
+	}
+
+	header := "# " + result.Chart.Name + "\n\n"
+	if result.Chart.Description != "" {
+		header += result.Chart.Description + "\n\n"
+	}
+	header += "## Values\n\n"
+
+	return append([]byte(header), doc...), nil
+}
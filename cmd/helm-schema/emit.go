@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+// emittedFile is an additional file generated from the schema of a chart
+type emittedFile struct {
+	Path    string
+	Content []byte
+}
+
+// emitOptions describes which additional files are generated from the schemas
+type emitOptions struct {
+	TypeScript bool
+	Go         bool
+	GoPackage  string
+	CRD        bool
+	CRDOptions schema.CRDOptions
+	Questions  bool
+	UISchema   bool
+	YAML       bool
+}
+
+// emittedFiles returns the additional files generated from the schema of the result.
+// They are written next to the schema and named like it (e.g. values.schema.json -> values.d.ts),
+// except questions.yaml, which is written to the chart directory.
+func emittedFiles(result *schema.Result, opts emitOptions) ([]emittedFile, error) {
+	files := []emittedFile{}
+	basePath := emittedBasePath(result.OutputPath)
+
+	if opts.TypeScript {
+		content, err := result.Schema.ToTypeScript(schema.TypeName(result.Chart.Name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, emittedFile{Path: basePath + ".d.ts", Content: content})
+	}
+
+	if opts.Go {
+		content, err := result.Schema.ToGo(opts.GoPackage, schema.TypeName(result.Chart.Name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, emittedFile{Path: basePath + ".go", Content: content})
+	}
+
+	if opts.CRD {
+		crdOptions, err := result.CRDOptions(opts.CRDOptions)
+		if err != nil {
+			return nil, err
+		}
+		content, err := result.Schema.ToCRD(crdOptions)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, emittedFile{Path: basePath + ".crd.yaml", Content: content})
+	}
+
+	if opts.UISchema {
+		content, err := result.Schema.ToUISchema()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, emittedFile{Path: basePath + ".uischema.json", Content: content})
+	}
+
+	if opts.YAML {
+		content, err := result.Schema.ToYAML(filepath.Dir(result.OutputPath))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, emittedFile{Path: basePath + ".schema.yaml", Content: content})
+	}
+
+	if opts.Questions {
+		content, err := result.Schema.ToQuestions()
+		if err != nil {
+			return nil, err
+		}
+		// rancher only reads the questions from the chart directory
+		files = append(files, emittedFile{Path: filepath.Join(filepath.Dir(result.ChartPath), "questions.yaml"), Content: content})
+	}
+
+	return files, nil
+}
+
+// emittedBasePath strips the extensions of the schema file
+func emittedBasePath(outputPath string) string {
+	dir, file := filepath.Split(outputPath)
+	file = strings.TrimSuffix(strings.TrimSuffix(file, ".json"), ".yaml")
+	file = strings.TrimSuffix(file, ".schema")
+	return filepath.Join(dir, file)
+}
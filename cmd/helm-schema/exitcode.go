@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// The exit codes of helm-schema
+const (
+	exitOK = 0
+	// exitErrors means that charts had errors (e.g. invalid values) or other operations failed
+	exitErrors = 1
+	// exitWarnings means that only warnings were found (with --error-on warning) or schemas are outdated
+	exitWarnings = 2
+	// exitUsage means that the flags, arguments or the config file are invalid
+	exitUsage = 3
+)
+
+// The values of --error-on
+const (
+	errorOnError   = "error"
+	errorOnWarning = "warning"
+	errorOnNever   = "never"
+)
+
+// usageError is an error caused by invalid flags, arguments or configuration
+type usageError struct {
+	error
+}
+
+func (e usageError) Unwrap() error {
+	return e.error
+}
+
+// usageErrorf returns a usage error formatted like fmt.Errorf
+func usageErrorf(format string, a ...interface{}) error {
+	return usageError{fmt.Errorf(format, a...)}
+}
+
+// errOutdated is returned, if the only problem are outdated or missing schemas
+var errOutdated = errors.New("some schemas are outdated")
+
+// warningCounter counts the logged warnings
+type warningCounter struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (c *warningCounter) Levels() []log.Level {
+	return []log.Level{log.WarnLevel}
+}
+
+func (c *warningCounter) Fire(*log.Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count++
+	return nil
+}
+
+func (c *warningCounter) Count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+// warnings counts the warnings of the run for --error-on warning
+var warnings = &warningCounter{}
+
+// validateErrorOn returns an error, if --error-on isn't supported
+func validateErrorOn() error {
+	switch errorOn := viper.GetString("error-on"); errorOn {
+	case errorOnError, errorOnWarning, errorOnNever:
+		return nil
+	default:
+		return usageErrorf("unsupported --error-on %s, use %s, %s or %s", errorOn, errorOnError, errorOnWarning, errorOnNever)
+	}
+}
+
+// exitCode returns the exit code of the error of the command and the warnings, as configured by --error-on.
+// Usage errors always fail, as the charts weren't processed at all.
+func exitCode(err error) int {
+	var usageErr usageError
+	if errors.As(err, &usageErr) {
+		return exitUsage
+	}
+	errorOn := viper.GetString("error-on")
+	switch {
+	case errorOn == errorOnNever:
+		return exitOK
+	case errors.Is(err, errOutdated):
+		return exitWarnings
+	case err != nil:
+		return exitErrors
+	case errorOn == errorOnWarning && warnings.Count() > 0:
+		return exitWarnings
+	}
+	return exitOK
+}
+
+// wrapUsageErrors marks the errors of parsing the flags and validating the arguments of the command and
+// its subcommands as usage errors
+func wrapUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return usageError{err}
+	})
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			if err := args(cmd, a); err != nil {
+				return usageError{err}
+			}
+			return nil
+		}
+	}
+	for _, subCommand := range cmd.Commands() {
+		wrapUsageErrors(subCommand)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestExitCode(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Reset()
+		warnings.count = 0
+	})
+	usageErr := usageErrorf("unknown flag")
+	genericErr := errors.New("some errors were found")
+
+	tests := []struct {
+		errorOn  string
+		err      error
+		warnings int
+		code     int
+	}{
+		{errorOnError, nil, 0, exitOK},
+		{errorOnError, nil, 1, exitOK},
+		{errorOnError, genericErr, 0, exitErrors},
+		{errorOnError, errOutdated, 0, exitWarnings},
+		{errorOnError, fmt.Errorf("wrapped: %w", errOutdated), 0, exitWarnings},
+		{errorOnError, usageErr, 0, exitUsage},
+		{errorOnWarning, nil, 0, exitOK},
+		{errorOnWarning, nil, 2, exitWarnings},
+		{errorOnWarning, genericErr, 1, exitErrors},
+		{errorOnWarning, errOutdated, 0, exitWarnings},
+		{errorOnWarning, usageErr, 0, exitUsage},
+		{errorOnNever, nil, 1, exitOK},
+		{errorOnNever, genericErr, 0, exitOK},
+		{errorOnNever, errOutdated, 0, exitOK},
+		// usage errors always fail
+		{errorOnNever, usageErr, 0, exitUsage},
+		{errorOnNever, fmt.Errorf("wrapped: %w", usageErr), 0, exitUsage},
+	}
+	for _, test := range tests {
+		viper.Set("error-on", test.errorOn)
+		warnings.count = test.warnings
+		assert.Equal(t, exitCode(test.err), test.code, fmt.Sprintf("--error-on %s, %v, %d warnings", test.errorOn, test.err, test.warnings))
+	}
+}
+
+func TestWrapUsageErrors(t *testing.T) {
+	runErr := errors.New("some errors were found")
+	newTestCommand := func() *cobra.Command {
+		cmd := &cobra.Command{Use: "root", RunE: func(*cobra.Command, []string) error { return nil }}
+		cmd.AddCommand(&cobra.Command{
+			Use:  "sub",
+			Args: cobra.MaximumNArgs(1),
+			RunE: func(*cobra.Command, []string) error { return runErr },
+		})
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		wrapUsageErrors(cmd)
+		return cmd
+	}
+
+	tests := []struct {
+		args  []string
+		usage bool
+	}{
+		{args: []string{"--unknown"}, usage: true},
+		{args: []string{"sub", "--unknown"}, usage: true},
+		{args: []string{"sub", "a", "b"}, usage: true},
+		// the errors of running the command aren't usage errors
+		{args: []string{"sub", "a"}},
+	}
+	for _, test := range tests {
+		cmd := newTestCommand()
+		cmd.SetArgs(test.args)
+		err := cmd.Execute()
+		if err == nil {
+			t.Fatalf("Expected an error for the arguments %v", test.args)
+		}
+		var usageErr usageError
+		assert.Equal(t, errors.As(err, &usageErr), test.usage, fmt.Sprint(test.args))
+		if !test.usage {
+			assert.Equal(t, err, runErr)
+		}
+	}
+
+	// the subcommands of helm-schema are wrapped
+	err := executeCommand(t, t.TempDir(), "snapshot", "a", "b")
+	assert.Equal(t, exitCode(err), exitUsage)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+func newExplainCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain <property> [chart]",
+		Short: "show where the keywords of a property of the generated jsonschemas come from",
+		Long: `Shows for every keyword of the property (a dotted path like ingress.annotations) in the generated jsonschemas,
+whether it comes from an annotation or comment in a values file, the sidecar annotations file, the schema patch file,
+a dependency or the condition of a parent chart, or whether it was inferred from the value or generated.`,
+		Args:          cobra.RangeArgs(1, 2),
+		RunE:          explain,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("format", reportFormatText, "output format, one of (text, json)")
+
+	return cmd
+}
+
+// explanation contains the origins of the keywords of a property in the schema of a chart
+type explanation struct {
+	Chart     string `json:"chart"`
+	ChartPath string `json:"chartPath"`
+	Property  string `json:"property"`
+	// Via contains the dependencies the property was merged from, starting at the chart
+	Via     []string        `json:"via,omitempty"`
+	Origins []schema.Origin `json:"origins"`
+}
+
+func explain(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format != reportFormatText && format != reportFormatJSON {
+		return fmt.Errorf("unsupported format %s, use %s or %s", format, reportFormatText, reportFormatJSON)
+	}
+	if err := selectChart(args[1:]); err != nil {
+		return err
+	}
+	path := strings.Split(args[0], ".")
+
+	results, err := run(false)
+	if err != nil {
+		return err
+	}
+	chartNameToResult := make(map[string]*schema.Result)
+	for _, result := range results {
+		chartNameToResult[result.Chart.Name] = result
+	}
+
+	explanations := []explanation{}
+	for _, result := range results {
+		e, found, err := explainProperty(result, path, results, chartNameToResult)
+		if err != nil {
+			return err
+		}
+		if found {
+			explanations = append(explanations, *e)
+		}
+	}
+	if len(explanations) == 0 {
+		return fmt.Errorf("the property %s doesn't exist in any of the generated jsonschemas", args[0])
+	}
+	return writeExplanations(os.Stdout, format, explanations)
+}
+
+// explainProperty follows the path through the dependencies of the chart to the chart, whose values
+// define the property, and explains the keywords of the property in the schema of the chart
+func explainProperty(result *schema.Result, path []string, results []*schema.Result, chartNameToResult map[string]*schema.Result) (*explanation, bool, error) {
+	property := &result.Schema
+	for _, segment := range path {
+		if property = property.Properties[segment]; property == nil {
+			return nil, false, nil
+		}
+	}
+	keywords, err := property.Keywords()
+	if err != nil {
+		return nil, false, err
+	}
+	e := &explanation{Chart: result.Chart.Name, ChartPath: result.ChartPath, Property: strings.Join(path, ".")}
+
+	// the dependencies are merged into the root of the schema of their parent
+	current := result
+	rel := []string{}
+	for _, segment := range path {
+		if len(rel) == 0 && !viper.GetBool("no-dependencies") {
+			if dependency := dependencyResult(current, segment, chartNameToResult); dependency != nil {
+				via := dependency.Chart.Name
+				if segment != dependency.Chart.Name {
+					via = fmt.Sprintf("%s (alias %s)", dependency.Chart.Name, segment)
+				}
+				e.Via = append(e.Via, fmt.Sprintf("%s (%s)", via, dependency.ChartPath))
+				current = dependency
+				continue
+			}
+		}
+		rel = append(rel, segment)
+	}
+
+	if len(rel) == 0 {
+		// the property is the schema of a dependency
+		for _, keyword := range keywords {
+			e.Origins = append(e.Origins, schema.Origin{Keyword: keyword, Source: schema.OriginDependency, File: current.ChartPath})
+		}
+		return e, true, nil
+	}
+
+	content, err := os.ReadFile(current.ValuesPath)
+	if err != nil {
+		return nil, false, err
+	}
+	origins, found, err := schema.ExplainKeywords(current.ValuesPath, content, rel, keywords, viper.GetBool("uncomment"))
+	if err != nil {
+		return nil, false, err
+	}
+	if !found {
+		parent := conditionParent(current, rel, results)
+		if parent == nil {
+			return nil, false, fmt.Errorf("could not find %s in the values of chart %s (%s)", strings.Join(rel, "."), current.Chart.Name, current.ValuesPath)
+		}
+		origins = nil
+		for _, keyword := range keywords {
+			origins = append(origins, schema.Origin{Keyword: keyword, Source: schema.OriginCondition, File: parent.ChartPath})
+		}
+	}
+	e.Origins = origins
+	return e, true, nil
+}
+
+// dependencyResult returns the result of the dependency of the chart with the name or alias
+func dependencyResult(result *schema.Result, name string, chartNameToResult map[string]*schema.Result) *schema.Result {
+	selectedDependencies := viper.GetStringSlice("dependencies")
+	for _, dep := range result.Chart.Dependencies {
+		if dep.Name == "" || (dep.Alias != name && (dep.Alias != "" || dep.Name != name)) {
+			continue
+		}
+		if len(selectedDependencies) > 0 && !slices.Contains(selectedDependencies, dep.Name) {
+			continue
+		}
+		return chartNameToResult[dep.Name]
+	}
+	return nil
+}
+
+// conditionParent returns the chart, whose dependency condition patched the path into the schema of the chart
+func conditionParent(result *schema.Result, path []string, results []*schema.Result) *schema.Result {
+	for _, parent := range results {
+		for _, dep := range parent.Chart.Dependencies {
+			if dep.Condition == "" {
+				continue
+			}
+			keys := strings.Split(dep.Condition, ".")
+			if keys[0] == result.Chart.Name && len(path) < len(keys) && slices.Equal(keys[1:len(path)+1], path) {
+				return parent
+			}
+		}
+	}
+	return nil
+}
+
+func writeExplanations(w io.Writer, format string, explanations []explanation) error {
+	if format == reportFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(explanations)
+	}
+
+	for i, e := range explanations {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s in chart %s (%s):\n", e.Property, e.Chart, e.ChartPath)
+		for _, via := range e.Via {
+			fmt.Fprintf(w, "  via dependency %s\n", via)
+		}
+		for _, origin := range e.Origins {
+			location := origin.File
+			if origin.Line > 0 {
+				location = fmt.Sprintf("%s:%d", origin.File, origin.Line)
+			}
+			fmt.Fprintf(w, "  %-22s %-10s %s\n", origin.Keyword, origin.Source, location)
+		}
+	}
+	return nil
+}
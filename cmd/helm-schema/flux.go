@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// The api groups of the flux objects
+const (
+	fluxHelmGroup   = "helm.toolkit.fluxcd.io"
+	fluxSourceGroup = "source.toolkit.fluxcd.io"
+)
+
+// fluxDefaultValuesKey is the key of a ConfigMap or Secret of valuesFrom containing the values by default
+const fluxDefaultValuesKey = "values.yaml"
+
+type fluxHelmReleaseSpec struct {
+	Chart *struct {
+		Spec fluxChartSpec `yaml:"spec"`
+	} `yaml:"chart"`
+	ChartRef   *fluxReference        `yaml:"chartRef"`
+	Values     yaml.Node             `yaml:"values"`
+	ValuesFrom []fluxValuesReference `yaml:"valuesFrom"`
+}
+
+// fluxChartSpec is the chart template of a HelmRelease or the spec of a HelmChart
+type fluxChartSpec struct {
+	Chart     string        `yaml:"chart"`
+	Version   string        `yaml:"version"`
+	SourceRef fluxReference `yaml:"sourceRef"`
+}
+
+type fluxReference struct {
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+type fluxValuesReference struct {
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+	ValuesKey  string `yaml:"valuesKey"`
+	TargetPath string `yaml:"targetPath"`
+	Optional   bool   `yaml:"optional"`
+}
+
+// fluxRepositorySpec is the spec of a HelmRepository or an OCIRepository
+type fluxRepositorySpec struct {
+	URL  string `yaml:"url"`
+	Type string `yaml:"type"`
+	Ref  struct {
+		Tag    string `yaml:"tag"`
+		SemVer string `yaml:"semver"`
+		Digest string `yaml:"digest"`
+	} `yaml:"ref"`
+}
+
+func newFluxCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "flux [manifests...]",
+		Short: "validate the values of flux HelmReleases against the jsonschemas of their charts",
+		Long: `Reads the flux HelmReleases of the manifests (files or directories, the current directory by default,
+- reads stdin) and validates their values against the schemas of their charts. The values of valuesFrom
+(ConfigMaps and Secrets of the manifests) and spec.values are merged in this order into the values of the
+chart like flux does. The charts of GitRepositories and Buckets are local charts below --source-root, the
+ones of HelmRepositories and OCIRepositories are downloaded into the local store.`,
+		RunE:          validateFlux,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("source-root", ".", "directory of the GitRepository and Bucket sources, the paths of their charts are relative to it")
+	addReleaseFlags(cmd)
+
+	return cmd
+}
+
+func validateFlux(cmd *cobra.Command, args []string) error {
+	sourceRoot, _ := cmd.Flags().GetString("source-root")
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	manifests, err := readManifests(args)
+	if err != nil {
+		return err
+	}
+
+	releases := []*release{}
+	foundErrors := false
+	for _, m := range manifests {
+		if m.Kind != "HelmRelease" || m.group() != fluxHelmGroup {
+			continue
+		}
+		r, err := fluxRelease(m, manifests, sourceRoot)
+		if err != nil {
+			foundErrors = true
+			log.Errorf("Invalid %s (%s): %s", m, m.Path, err)
+			continue
+		}
+		releases = append(releases, r)
+	}
+	if len(releases) == 0 && !foundErrors {
+		log.Warnf("No HelmReleases found in %s", strings.Join(args, ", "))
+		return nil
+	}
+
+	if err := validateReleases(cmd, releases, false); err != nil {
+		return err
+	}
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// fluxRelease returns the release of the HelmRelease with the values of valuesFrom and spec.values
+func fluxRelease(m *manifest, manifests []*manifest, sourceRoot string) (*release, error) {
+	var spec fluxHelmReleaseSpec
+	if err := m.Spec.Decode(&spec); err != nil {
+		return nil, err
+	}
+	chartRef, err := fluxChart(m, &spec, manifests, sourceRoot)
+	if err != nil {
+		return nil, err
+	}
+	r := &release{Name: strings.TrimPrefix(m.String(), m.Kind+"/"), Source: m.Path, Chart: chartRef}
+
+	for _, ref := range spec.ValuesFrom {
+		values, err := fluxValuesFrom(m, ref, manifests)
+		if err != nil {
+			return nil, err
+		}
+		if values != nil {
+			r.Values = append(r.Values, *values)
+		}
+	}
+	if spec.Values.Kind != 0 {
+		content, err := yaml.Marshal(&spec.Values)
+		if err != nil {
+			return nil, err
+		}
+		r.Values = append(r.Values, releaseValues{Source: "spec.values", Content: content})
+	}
+	return r, nil
+}
+
+// fluxChart returns the chart of the HelmRelease, given by its chart template or a reference to an
+// OCIRepository or a HelmChart
+func fluxChart(m *manifest, spec *fluxHelmReleaseSpec, manifests []*manifest, sourceRoot string) (releaseChart, error) {
+	namespace := m.Metadata.Namespace
+	if spec.ChartRef != nil {
+		if spec.ChartRef.Namespace != "" {
+			namespace = spec.ChartRef.Namespace
+		}
+		source := findManifest(manifests, spec.ChartRef.Kind, namespace, spec.ChartRef.Name)
+		if source == nil || source.group() != fluxSourceGroup {
+			return releaseChart{}, fmt.Errorf("the %s %s of chartRef isn't in the manifests", spec.ChartRef.Kind, spec.ChartRef.Name)
+		}
+		switch spec.ChartRef.Kind {
+		case "OCIRepository":
+			var repositorySpec fluxRepositorySpec
+			if err := source.Spec.Decode(&repositorySpec); err != nil {
+				return releaseChart{}, err
+			}
+			if repositorySpec.Ref.Digest != "" {
+				return releaseChart{}, fmt.Errorf("the digest of %s isn't supported, use a tag or a semver range", source)
+			}
+			repository, name, found := splitChartReference(repositorySpec.URL)
+			if !strings.HasPrefix(repositorySpec.URL, "oci://") || !found {
+				return releaseChart{}, fmt.Errorf("invalid url %s of %s, use oci://registry/repository/chart", repositorySpec.URL, source)
+			}
+			version := repositorySpec.Ref.Tag
+			if repositorySpec.Ref.SemVer != "" {
+				version = repositorySpec.Ref.SemVer
+			}
+			return releaseChart{Repository: repository, Name: name, Version: version}, nil
+		case "HelmChart":
+			var chartSpec fluxChartSpec
+			if err := source.Spec.Decode(&chartSpec); err != nil {
+				return releaseChart{}, err
+			}
+			return fluxChartSource(source, chartSpec, manifests, sourceRoot)
+		}
+		return releaseChart{}, fmt.Errorf("unsupported kind %s of chartRef, use OCIRepository or HelmChart", spec.ChartRef.Kind)
+	}
+	if spec.Chart == nil {
+		return releaseChart{}, errors.New("the HelmRelease has neither a chart nor a chartRef")
+	}
+	return fluxChartSource(m, spec.Chart.Spec, manifests, sourceRoot)
+}
+
+// fluxChartSource returns the chart of the chart template of the manifest (a HelmRelease or a HelmChart)
+func fluxChartSource(m *manifest, chartSpec fluxChartSpec, manifests []*manifest, sourceRoot string) (releaseChart, error) {
+	if chartSpec.Chart == "" {
+		return releaseChart{}, errors.New("the chart template has no chart")
+	}
+	if chartSpec.SourceRef.Kind == "GitRepository" || chartSpec.SourceRef.Kind == "Bucket" {
+		return releaseChart{Path: filepath.Join(sourceRoot, filepath.FromSlash(chartSpec.Chart))}, nil
+	}
+	if chartSpec.SourceRef.Kind != "HelmRepository" {
+		return releaseChart{}, fmt.Errorf("unsupported kind %s of the source of the chart, use HelmRepository, GitRepository or Bucket", chartSpec.SourceRef.Kind)
+	}
+
+	namespace := m.Metadata.Namespace
+	if chartSpec.SourceRef.Namespace != "" {
+		namespace = chartSpec.SourceRef.Namespace
+	}
+	source := findManifest(manifests, chartSpec.SourceRef.Kind, namespace, chartSpec.SourceRef.Name)
+	if source == nil || source.group() != fluxSourceGroup {
+		return releaseChart{}, fmt.Errorf("the HelmRepository %s of the chart isn't in the manifests", chartSpec.SourceRef.Name)
+	}
+	var repositorySpec fluxRepositorySpec
+	if err := source.Spec.Decode(&repositorySpec); err != nil {
+		return releaseChart{}, err
+	}
+	repository := repositorySpec.URL
+	if repositorySpec.Type == "oci" && !strings.HasPrefix(repository, "oci://") {
+		repository = "oci://" + repository
+	}
+	return releaseChart{Repository: repository, Name: chartSpec.Chart, Version: chartSpec.Version}, nil
+}
+
+// fluxValuesFrom returns the values of the ConfigMap or Secret of a reference of valuesFrom. It returns nil
+// for missing optional references.
+func fluxValuesFrom(m *manifest, ref fluxValuesReference, manifests []*manifest) (*releaseValues, error) {
+	if ref.Kind != "ConfigMap" && ref.Kind != "Secret" {
+		return nil, fmt.Errorf("unsupported kind %s of valuesFrom, use ConfigMap or Secret", ref.Kind)
+	}
+	valuesKey := ref.ValuesKey
+	if valuesKey == "" {
+		valuesKey = fluxDefaultValuesKey
+	}
+	source := findManifest(manifests, ref.Kind, m.Metadata.Namespace, ref.Name)
+	if source == nil {
+		if ref.Optional {
+			log.Debugf("Skipping the missing optional %s %s of valuesFrom of %s", ref.Kind, ref.Name, m)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("the %s %s of valuesFrom isn't in the manifests", ref.Kind, ref.Name)
+	}
+	value, ok, err := source.value(valuesKey)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if ref.Optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("the %s %s of valuesFrom has no key %s", ref.Kind, ref.Name, valuesKey)
+	}
+
+	values := releaseValues{Source: fmt.Sprintf("%s/%s (%s)", ref.Kind, ref.Name, valuesKey), Content: []byte(value)}
+	if ref.TargetPath != "" {
+		// the value is set at the target path like with helm's --set
+		targeted := map[string]interface{}{}
+		setValue(targeted, splitSetName(ref.TargetPath), scalarValue(value))
+		if values.Content, err = yaml.Marshal(targeted); err != nil {
+			return nil, err
+		}
+	}
+	return &values, nil
+}
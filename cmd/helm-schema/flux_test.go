@@ -0,0 +1,107 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestFluxRelease(t *testing.T) {
+	manifests, err := readManifests([]string{filepath.Join("testdata", "flux")})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		chart  releaseChart
+		values []releaseValues
+		err    bool
+	}{
+		{
+			name:   "git",
+			chart:  releaseChart{Path: filepath.Join("sources", "charts", "app")},
+			values: []releaseValues{{Source: "spec.values", Content: []byte("replicas: 1\n")}},
+		},
+		{
+			name:  "bucket",
+			chart: releaseChart{Path: filepath.Join("sources", "charts", "app")},
+		},
+		{
+			name:  "helm-repository",
+			chart: releaseChart{Repository: "https://charts.bitnami.com/bitnami", Name: "redis", Version: "18.x"},
+		},
+		{
+			name:  "oci-helm-repository",
+			chart: releaseChart{Repository: "oci://ghcr.io/example/charts", Name: "app", Version: "1.0.0"},
+		},
+		{
+			name: "missing-helm-repository",
+			err:  true,
+		},
+		{
+			name:  "oci-repository",
+			chart: releaseChart{Repository: "oci://ghcr.io/stefanprodan/charts", Name: "podinfo", Version: ">=6.0.0"},
+		},
+		{
+			name: "oci-repository-digest",
+			err:  true,
+		},
+		{
+			// the OCIRepository isn't one of flux
+			name: "oci-repository-foreign-group",
+			err:  true,
+		},
+		{
+			name:  "helm-chart",
+			chart: releaseChart{Repository: "https://charts.bitnami.com/bitnami", Name: "redis", Version: "18.x"},
+		},
+		{
+			name: "no-chart",
+			err:  true,
+		},
+		{
+			name:  "values-from",
+			chart: releaseChart{Path: filepath.Join("sources", "charts", "app")},
+			values: []releaseValues{
+				{Source: "ConfigMap/defaults (values.yaml)", Content: []byte("replicas: 2\n")},
+				{Source: "ConfigMap/defaults (custom.yaml)", Content: []byte("image:\n  tag: v1\n")},
+				{Source: "ConfigMap/defaults (replicas)", Content: []byte("deployment:\n    replicas: 3\n")},
+				{Source: "Secret/credentials (values.yaml)", Content: []byte("auth:\n  enabled: true\n")},
+				{Source: "Secret/credentials (password)", Content: []byte("auth:\n    password: secret\n")},
+				{Source: "spec.values", Content: []byte("replicas: 4\n")},
+			},
+		},
+		{
+			name: "missing-values-from",
+			err:  true,
+		},
+		{
+			name: "missing-values-key",
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := findManifest(manifests, "HelmRelease", "apps", test.name)
+			if m == nil {
+				t.Fatalf("The HelmRelease %s isn't in the testdata", test.name)
+			}
+			r, err := fluxRelease(m, manifests, "sources")
+			if test.err {
+				if err == nil {
+					t.Fatalf("Expected an error for the HelmRelease %s", test.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+			}
+			assert.Equal(t, r.Name, "apps/"+test.name)
+			assert.Equal(t, r.Source, filepath.Join("testdata", "flux", "releases.yaml"))
+			assert.Equal(t, r.Chart, test.chart)
+			assert.Equal(t, r.Values, test.values)
+		})
+	}
+}
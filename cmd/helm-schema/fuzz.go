@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// fuzzAttempts is the number of attempts to generate valid values for a document
+const fuzzAttempts = 50
+
+func newFuzzCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "fuzz [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "generate random values files, which are valid against the jsonschema of the chart",
+		Long: `Generates the jsonschema of the chart in the chart search root (without writing it) and random values
+files, which are valid against it. The values respect the types, enums, patterns, formats and bounds of the schema
+and contain legal, but unusual values (e.g. strings with quotes or unicode), which break templates not quoting
+them. The documents are printed to stdout or written to the output directory as fuzz-<n>-values.yaml, e.g. to be
+rendered with helm template in CI. The seed is logged, so a run can be reproduced with --seed.`,
+		RunE:          fuzz,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().Int("count", 10, "number of values files to generate")
+	cmd.Flags().Uint64("seed", 0, "seed of the random values (default: a random seed)")
+	cmd.Flags().String("fuzz-dir", "", "write the values files to this directory instead of stdout")
+
+	return cmd
+}
+
+func fuzz(cmd *cobra.Command, _ []string) error {
+	count, _ := cmd.Flags().GetInt("count")
+	seed, _ := cmd.Flags().GetUint64("seed")
+	fuzzDir, _ := cmd.Flags().GetString("fuzz-dir")
+	if count < 1 {
+		return fmt.Errorf("the count must be positive, got %d", count)
+	}
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+
+	results, runErr := run(false)
+	rootResult, err := chartSearchRootResult(results, runErr)
+	if err != nil {
+		return err
+	}
+	schemaURL, err := filepath.Abs(rootResult.OutputPath)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Generating %d values files for chart %s with seed %d", count, rootResult.Chart.Name, seed)
+	r := rand.New(rand.NewPCG(seed, seed))
+	foundErrors := runErr != nil
+	for i := 1; i <= count; i++ {
+		content, err := rootResult.Schema.RandomValidValues(schemaURL, r, fuzzAttempts)
+		if err != nil {
+			foundErrors = true
+			log.Errorf("Could not generate values file %d: %s", i, err)
+			continue
+		}
+		if fuzzDir == "" {
+			fmt.Printf("---\n%s", content)
+			continue
+		}
+		if err := os.MkdirAll(fuzzDir, 0755); err != nil {
+			return err
+		}
+		if err := util.WriteFileAtomic(filepath.Join(fuzzDir, fmt.Sprintf("fuzz-%d-values.yaml", i)), content, 0644); err != nil {
+			return err
+		}
+	}
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
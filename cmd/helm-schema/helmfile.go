@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// defaultHelmfile is the helmfile read without an argument
+const defaultHelmfile = "helmfile.yaml"
+
+// helmfileState contains the parts of a helmfile, which are needed to validate the values of its releases
+type helmfileState struct {
+	Repositories []helmfileRepository `yaml:"repositories"`
+	Releases     []helmfileRelease    `yaml:"releases"`
+}
+
+type helmfileRepository struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	OCI  bool   `yaml:"oci"`
+}
+
+type helmfileRelease struct {
+	Name      string `yaml:"name"`
+	Chart     string `yaml:"chart"`
+	Version   string `yaml:"version"`
+	Installed *bool  `yaml:"installed"`
+	// Values contains the paths of values files and inline values
+	Values []yaml.Node     `yaml:"values"`
+	Set    []helmfileValue `yaml:"set"`
+}
+
+// helmfileValue is a value set with the dotted name of its key, like helm's --set
+type helmfileValue struct {
+	Name   string        `yaml:"name"`
+	Value  interface{}   `yaml:"value"`
+	Values []interface{} `yaml:"values"`
+}
+
+func newHelmfileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "helmfile [helmfile]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "validate the values of the releases of a helmfile against the jsonschemas of their charts",
+		Long: `Reads the releases of the helmfile (helmfile.yaml by default, - reads stdin) and validates their values
+merged into the values of their charts against the schemas of the charts. The schemas of local charts are
+generated, remote charts (of the repositories of the helmfile or OCI registries) are downloaded into the
+local store and their shipped schemas are used, or generated from their values if they don't ship one.
+Templates aren't rendered, pass the output of helmfile build on stdin for templated helmfiles.`,
+		RunE:          validateHelmfile,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().Bool("generate", false, "write the jsonschemas of the local charts of the releases like generate")
+	addReleaseFlags(cmd)
+
+	return cmd
+}
+
+func validateHelmfile(cmd *cobra.Command, args []string) error {
+	helmfilePath := defaultHelmfile
+	if len(args) > 0 {
+		helmfilePath = args[0]
+	}
+	writeSchemas, _ := cmd.Flags().GetBool("generate")
+
+	var content []byte
+	var err error
+	baseDir := filepath.Dir(helmfilePath)
+	if helmfilePath == "-" {
+		content, err = util.ReadFileAndFixNewline(os.Stdin)
+		baseDir = "."
+	} else {
+		content, err = os.ReadFile(helmfilePath)
+	}
+	if err != nil {
+		return err
+	}
+
+	releases, err := readHelmfile(helmfilePath, baseDir, content)
+	if err != nil {
+		return err
+	}
+	if len(releases) == 0 {
+		log.Warnf("No releases found in %s", helmfilePath)
+		return nil
+	}
+	return validateReleases(cmd, releases, writeSchemas)
+}
+
+// readHelmfile returns the installed releases of all documents of the helmfile. Relative paths of charts and
+// values files are relative to the base directory.
+func readHelmfile(helmfilePath, baseDir string, content []byte) ([]*release, error) {
+	state := helmfileState{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc helmfileState
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s, templated helmfiles must be rendered with helmfile build first: %w", helmfilePath, err)
+		}
+		state.Repositories = append(state.Repositories, doc.Repositories...)
+		state.Releases = append(state.Releases, doc.Releases...)
+	}
+
+	releases := []*release{}
+	for _, helmfileRelease := range state.Releases {
+		if helmfileRelease.Installed != nil && !*helmfileRelease.Installed {
+			log.Debugf("Skipping release %s, which isn't installed", helmfileRelease.Name)
+			continue
+		}
+		r, err := helmfileRelease.release(helmfilePath, baseDir, state.Repositories)
+		if err != nil {
+			return nil, fmt.Errorf("invalid release %s in %s: %w", helmfileRelease.Name, helmfilePath, err)
+		}
+		releases = append(releases, r)
+	}
+	return releases, nil
+}
+
+// release returns the release with its chart and its values
+func (h helmfileRelease) release(helmfilePath, baseDir string, repositories []helmfileRepository) (*release, error) {
+	chartRef, err := h.chart(baseDir, repositories)
+	if err != nil {
+		return nil, err
+	}
+	r := &release{Name: h.Name, Source: helmfilePath, Chart: chartRef}
+
+	for i, node := range h.Values {
+		if node.Kind == yaml.ScalarNode {
+			valuesPath := node.Value
+			if strings.HasSuffix(valuesPath, ".gotmpl") || strings.Contains(valuesPath, "{{") {
+				log.Warnf("Skipping the values %s of release %s, templates aren't rendered", valuesPath, h.Name)
+				continue
+			}
+			if !filepath.IsAbs(valuesPath) {
+				valuesPath = filepath.Join(baseDir, valuesPath)
+			}
+			content, err := os.ReadFile(valuesPath)
+			if err != nil {
+				return nil, err
+			}
+			r.Values = append(r.Values, releaseValues{Source: valuesPath, Content: content})
+			continue
+		}
+		content, err := yaml.Marshal(&node)
+		if err != nil {
+			return nil, err
+		}
+		r.Values = append(r.Values, releaseValues{Source: fmt.Sprintf("inline values %d", i+1), Content: content})
+	}
+
+	if len(h.Set) > 0 {
+		values := map[string]interface{}{}
+		for _, set := range h.Set {
+			if set.Name == "" {
+				return nil, errors.New("a value of set has no name")
+			}
+			value := set.Value
+			if set.Values != nil {
+				value = set.Values
+			}
+			setValue(values, splitSetName(set.Name), value)
+		}
+		content, err := yaml.Marshal(values)
+		if err != nil {
+			return nil, err
+		}
+		r.Values = append(r.Values, releaseValues{Source: "set", Content: content})
+	}
+	return r, nil
+}
+
+// chart returns the chart of the release: a local chart directory, a chart of one of the repositories
+// (repository/chart) or a chart in an OCI registry (oci://registry/repository/chart)
+func (h helmfileRelease) chart(baseDir string, repositories []helmfileRepository) (releaseChart, error) {
+	if h.Chart == "" {
+		return releaseChart{}, errors.New("the release has no chart")
+	}
+	if strings.HasPrefix(h.Chart, "oci://") {
+		repository, name, found := splitChartReference(h.Chart)
+		if !found {
+			return releaseChart{}, fmt.Errorf("the chart %s has no registry and repository (oci://registry/repository/chart)", h.Chart)
+		}
+		return releaseChart{Repository: repository, Name: name, Version: h.Version}, nil
+	}
+
+	localPath := h.Chart
+	if !filepath.IsAbs(localPath) {
+		localPath = filepath.Join(baseDir, localPath)
+	}
+	isLocal := strings.HasPrefix(h.Chart, "./") || strings.HasPrefix(h.Chart, "../") || filepath.IsAbs(h.Chart)
+	if info, err := os.Stat(localPath); isLocal || (err == nil && info.IsDir()) {
+		return releaseChart{Path: localPath}, nil
+	}
+
+	repositoryName, name, found := strings.Cut(h.Chart, "/")
+	if !found {
+		return releaseChart{}, fmt.Errorf("the chart %s is neither a local chart nor a chart of a repository (repository/chart)", h.Chart)
+	}
+	for _, repository := range repositories {
+		if repository.Name != repositoryName {
+			continue
+		}
+		repositoryURL := repository.URL
+		if repository.OCI && !strings.HasPrefix(repositoryURL, "oci://") {
+			repositoryURL = "oci://" + repositoryURL
+		}
+		return releaseChart{Repository: repositoryURL, Name: name, Version: h.Version}, nil
+	}
+	return releaseChart{}, fmt.Errorf("the repository %s of the chart %s isn't declared in the repositories", repositoryName, h.Chart)
+}
+
+// splitChartReference splits the reference of a chart in an OCI registry (e.g. oci://ghcr.io/org/charts/app)
+// into its repository and the name of the chart
+func splitChartReference(ref string) (string, string, bool) {
+	index := strings.LastIndex(strings.TrimSuffix(ref, "/"), "/")
+	if index <= len("oci://") {
+		return "", "", false
+	}
+	return ref[:index], strings.TrimSuffix(ref[index+1:], "/"), true
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// kustomizationFiles are the names of the kustomization files
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// kustomizeDefaultChartHome is the directory of the local charts relative to the kustomization by default
+const kustomizeDefaultChartHome = "charts"
+
+// The strategies of valuesMerge merging valuesInline with valuesFile
+const (
+	kustomizeValuesOverride = "override"
+	kustomizeValuesMerge    = "merge"
+	kustomizeValuesReplace  = "replace"
+)
+
+type kustomization struct {
+	HelmGlobals struct {
+		ChartHome string `yaml:"chartHome"`
+	} `yaml:"helmGlobals"`
+	HelmCharts []kustomizeHelmChart `yaml:"helmCharts"`
+}
+
+type kustomizeHelmChart struct {
+	Name                  string    `yaml:"name"`
+	Repo                  string    `yaml:"repo"`
+	Version               string    `yaml:"version"`
+	ReleaseName           string    `yaml:"releaseName"`
+	ValuesFile            string    `yaml:"valuesFile"`
+	ValuesInline          yaml.Node `yaml:"valuesInline"`
+	ValuesMerge           string    `yaml:"valuesMerge"`
+	AdditionalValuesFiles []string  `yaml:"additionalValuesFiles"`
+}
+
+func newKustomizeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kustomize [kustomizations...]",
+		Short: "validate the values of the helmCharts of kustomizations against the jsonschemas of their charts",
+		Long: `Reads the helmCharts generators of the kustomization files (files or directories searched for them, the
+current directory by default) and validates their values against the schemas of their charts. The valuesFile,
+valuesInline (merged with it by valuesMerge) and additionalValuesFiles are merged in this order into the values
+of the chart like kustomize does. Charts in the chart home of the kustomization are local charts, the others
+are downloaded from their repo into the local store.`,
+		RunE:          validateKustomize,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	addReleaseFlags(cmd)
+
+	return cmd
+}
+
+func validateKustomize(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	files := []string{}
+	for _, path := range args {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		found, err := findFiles(path, func(name string) bool {
+			return slices.Contains(kustomizationFiles, name)
+		})
+		if err != nil {
+			return err
+		}
+		files = append(files, found...)
+	}
+
+	releases := []*release{}
+	for _, file := range files {
+		kustomizationReleases, err := readKustomization(file)
+		if err != nil {
+			return err
+		}
+		releases = append(releases, kustomizationReleases...)
+	}
+	if len(releases) == 0 {
+		log.Warnf("No helmCharts found in the kustomizations of %s", strings.Join(args, ", "))
+		return nil
+	}
+
+	return validateReleases(cmd, releases, false)
+}
+
+// readKustomization returns the releases of the helmCharts of the kustomization file
+func readKustomization(path string) ([]*release, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var k kustomization
+	if err := yaml.Unmarshal(content, &k); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	chartHome := k.HelmGlobals.ChartHome
+	if chartHome == "" {
+		chartHome = kustomizeDefaultChartHome
+	}
+	if !filepath.IsAbs(chartHome) {
+		chartHome = filepath.Join(dir, chartHome)
+	}
+
+	releases := []*release{}
+	for _, helmChart := range k.HelmCharts {
+		r, err := helmChart.release(path, dir, chartHome)
+		if err != nil {
+			return nil, fmt.Errorf("invalid helm chart %s in %s: %w", helmChart.Name, path, err)
+		}
+		releases = append(releases, r)
+	}
+	return releases, nil
+}
+
+// release returns the release of the helm chart with its values
+func (h kustomizeHelmChart) release(path, dir, chartHome string) (*release, error) {
+	if h.Name == "" {
+		return nil, errors.New("the helm chart has no name")
+	}
+	name := h.ReleaseName
+	if name == "" {
+		name = h.Name
+	}
+	r := &release{Name: name, Source: path}
+
+	// kustomize prefers the charts in the chart home, the pulled ones are stored in name-version/name
+	chartDirs := []string{filepath.Join(chartHome, h.Name)}
+	if h.Version != "" {
+		chartDirs = append(chartDirs, filepath.Join(chartHome, h.Name+"-"+h.Version, h.Name))
+	}
+	for _, chartDir := range chartDirs {
+		if _, err := os.Stat(filepath.Join(chartDir, "Chart.yaml")); err == nil {
+			r.Chart = releaseChart{Path: chartDir}
+			break
+		}
+	}
+	if r.Chart.Path == "" {
+		if h.Repo == "" {
+			return nil, fmt.Errorf("the chart isn't in the chart home %s and has no repo", chartHome)
+		}
+		r.Chart = releaseChart{Repository: h.Repo, Name: h.Name, Version: h.Version}
+	}
+
+	var fileValues, inlineValues *releaseValues
+	if h.ValuesFile != "" {
+		valuesPath := h.ValuesFile
+		if !filepath.IsAbs(valuesPath) {
+			valuesPath = filepath.Join(dir, valuesPath)
+		}
+		content, err := os.ReadFile(valuesPath)
+		if err != nil {
+			return nil, err
+		}
+		fileValues = &releaseValues{Source: valuesPath, Content: content}
+	}
+	if h.ValuesInline.Kind != 0 {
+		content, err := yaml.Marshal(&h.ValuesInline)
+		if err != nil {
+			return nil, err
+		}
+		inlineValues = &releaseValues{Source: "valuesInline", Content: content}
+	}
+
+	layers := []*releaseValues{}
+	switch h.ValuesMerge {
+	case "", kustomizeValuesOverride:
+		layers = append(layers, fileValues, inlineValues)
+	case kustomizeValuesMerge:
+		// the values of the file are kept, only the missing ones are added from the inline values
+		layers = append(layers, inlineValues, fileValues)
+	case kustomizeValuesReplace:
+		if inlineValues == nil {
+			inlineValues = fileValues
+		}
+		layers = append(layers, inlineValues)
+	default:
+		return nil, fmt.Errorf("unsupported valuesMerge %s, use %s, %s or %s", h.ValuesMerge, kustomizeValuesOverride, kustomizeValuesMerge, kustomizeValuesReplace)
+	}
+	for _, layer := range layers {
+		if layer != nil {
+			r.Values = append(r.Values, *layer)
+		}
+	}
+
+	for _, additionalFile := range h.AdditionalValuesFiles {
+		if !filepath.IsAbs(additionalFile) {
+			additionalFile = filepath.Join(dir, additionalFile)
+		}
+		content, err := os.ReadFile(additionalFile)
+		if err != nil {
+			return nil, err
+		}
+		r.Values = append(r.Values, releaseValues{Source: additionalFile, Content: content})
+	}
+	return r, nil
+}
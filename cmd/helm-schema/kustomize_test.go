@@ -0,0 +1,85 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestReadKustomization(t *testing.T) {
+	base := filepath.Join("testdata", "kustomize", "base")
+	kustomization := filepath.Join(base, "kustomization.yaml")
+	values := releaseValues{Source: filepath.Join(base, "values.yaml"), Content: []byte("replicas: 1\n")}
+	inlineValues := releaseValues{Source: "valuesInline", Content: []byte("replicas: 2\n")}
+	chartHome := filepath.Join("testdata", "kustomize", "chart-home")
+
+	tests := []struct {
+		name     string
+		releases []*release
+		err      bool
+	}{
+		{
+			name: "base",
+			releases: []*release{
+				{
+					// the values file is overridden by the inline values
+					Name:   "frontend",
+					Source: kustomization,
+					Chart:  releaseChart{Path: filepath.Join(base, "charts", "web")},
+					Values: []releaseValues{
+						values,
+						inlineValues,
+						{Source: filepath.Join(base, "values-prod.yaml"), Content: []byte("ingress:\n  enabled: true\n")},
+					},
+				},
+				{
+					// the pulled chart of the version in the chart home, the inline values only add missing ones
+					Name:   "redis",
+					Source: kustomization,
+					Chart:  releaseChart{Path: filepath.Join(base, "charts", "redis-18.0.0", "redis")},
+					Values: []releaseValues{inlineValues, values},
+				},
+				{
+					Name:   "postgresql",
+					Source: kustomization,
+					Chart:  releaseChart{Repository: "https://charts.bitnami.com/bitnami", Name: "postgresql", Version: "15.x"},
+					Values: []releaseValues{inlineValues},
+				},
+				{
+					// without inline values, the values file isn't replaced
+					Name:   "nginx",
+					Source: kustomization,
+					Chart:  releaseChart{Repository: "oci://registry-1.docker.io/bitnamicharts", Name: "nginx"},
+					Values: []releaseValues{values},
+				},
+			},
+		},
+		{
+			name: "chart-home",
+			releases: []*release{{
+				Name:   "web",
+				Source: filepath.Join(chartHome, "kustomization.yaml"),
+				Chart:  releaseChart{Path: filepath.Join(chartHome, "vendor", "web")},
+			}},
+		},
+		{name: "no-repo", err: true},
+		{name: "invalid-merge", err: true},
+		{name: "missing-values-file", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			releases, err := readKustomization(filepath.Join("testdata", "kustomize", test.name, "kustomization.yaml"))
+			if test.err {
+				if err == nil {
+					t.Fatalf("Expected an error for the kustomization %s", test.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+			}
+			assert.Equal(t, releases, test.releases)
+		})
+	}
+}
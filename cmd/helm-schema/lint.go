@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/templates"
+)
+
+// lintResult contains the findings of a chart
+type lintResult struct {
+	Chart    string        `json:"chart"`
+	File     string        `json:"file"`
+	Findings []lintFinding `json:"findings"`
+}
+
+// lintFinding is a finding with the severity of its rule
+type lintFinding struct {
+	templates.Finding
+	Severity string `json:"severity"`
+}
+
+func newLintCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "lint [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "compare the values used in the templates with the values files",
+		Long: `Parses the templates of the charts for references of .Values and compares them with the values files.
+Values used in the templates, which aren't defined in the values file, never reach the generated jsonschema and
+are reported as errors. Values defined in the values file, which aren't used by any template, are reported as
+warnings. The values of the dependencies and global values aren't reported as unused.
+
+The types of the values are inferred from their usage (if, range, toYaml, arithmetic) and compared with the
+generated jsonschema. Types which don't fit the usage are reported as errors, values without a type are
+reported with the inferred type.`,
+		RunE:          lint,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("report", reportFormatText, "format of the report, one of (text, json)")
+	cmd.Flags().Bool("fail-on-unused", false, "fail if values are defined, but not used")
+
+	return cmd
+}
+
+func lint(cmd *cobra.Command, _ []string) error {
+	report, _ := cmd.Flags().GetString("report")
+	failOnUnused, _ := cmd.Flags().GetBool("fail-on-unused")
+	if report != reportFormatText && report != reportFormatJSON {
+		return fmt.Errorf("unsupported report format %s, use %s or %s", report, reportFormatText, reportFormatJSON)
+	}
+
+	var valueFileNames []string
+	if err := viper.UnmarshalKey("value-files", &valueFileNames); err != nil {
+		return err
+	}
+	opts, err := newBufferOptions()
+	if err != nil {
+		return err
+	}
+
+	results, foundErrors := discoverCharts()
+	lintResults := []lintResult{}
+	foundFindings := false
+	for _, result := range results {
+		if filepath.Base(result.ChartPath) != "Chart.yaml" {
+			chartLog(result).Debugf("Skipping %s, the templates of a values file without chart aren't known", result.ChartPath)
+			continue
+		}
+		chartDir := filepath.Dir(result.ChartPath)
+		valuesPath := findValuesFile(chartDir, valueFileNames)
+		if valuesPath == "" {
+			chartLog(result).Debugf("Skipping %s, there is no values file", result.ChartPath)
+			continue
+		}
+
+		findings, err := lintChart(opts, chartDir, valuesPath, result)
+		if err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not lint %s: %s", result.ChartPath, err)
+			continue
+		}
+		lintFindings := []lintFinding{}
+		for _, finding := range findings {
+			severity := ruleSeverity(finding.Kind)
+			if finding.Kind == templates.FindingUnused && failOnUnused {
+				severity = severityError
+			}
+			switch severity {
+			case severityOff:
+				continue
+			case severityError:
+				foundFindings = true
+			}
+			lintFindings = append(lintFindings, lintFinding{Finding: finding, Severity: severity})
+		}
+		lintResults = append(lintResults, lintResult{Chart: result.Chart.Name, File: valuesPath, Findings: lintFindings})
+	}
+
+	if err := writeLintReport(os.Stdout, report, lintResults); err != nil {
+		return err
+	}
+	if foundErrors || foundFindings {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// lintChart compares the references in the templates of the chart with its values file and the schema generated from it
+func lintChart(opts *bufferOptions, chartDir, valuesPath string, result *schema.Result) (findings []templates.Finding, err error) {
+	content, err := os.ReadFile(valuesPath)
+	if err != nil {
+		return nil, err
+	}
+	references, err := templates.ChartReferences(chartDir)
+	if err != nil {
+		return nil, err
+	}
+	// the values of the dependencies are used by their templates
+	ignored := []string{}
+	for _, dep := range result.Chart.Dependencies {
+		if dep.Alias != "" {
+			ignored = append(ignored, dep.Alias)
+		} else {
+			ignored = append(ignored, dep.Name)
+		}
+	}
+	if findings, err = templates.Analyze(valuesPath, content, references, ignored); err != nil {
+		return nil, err
+	}
+	valuesSchema, err := opts.generateValues(valuesPath, content)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, templates.CheckTypes(valuesSchema, references)...)
+
+	// the findings acknowledged with ignore comments in the values file aren't reported
+	suppressions, err := schema.ReadSuppressions(content)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(findings, func(finding templates.Finding) bool {
+		return suppressions.Suppressed(finding.Path, finding.Kind)
+	}), nil
+}
+
+func writeLintReport(w io.Writer, format string, results []lintResult) error {
+	if format == reportFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	for _, result := range results {
+		if len(result.Findings) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%s):\n", result.Chart, result.File)
+		for _, finding := range result.Findings {
+			fmt.Fprintf(w, "  %-7s %-9s %s:%d: %s\n", finding.Severity, finding.Kind, finding.File, finding.Line, finding.Message)
+		}
+	}
+	return nil
+}
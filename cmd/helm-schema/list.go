@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+const (
+	listFormatText = "text"
+	listFormatDot  = "dot"
+	listFormatJSON = "json"
+)
+
+func newListCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "list [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "list the charts, their dependencies and the processing order",
+		Long: `Lists the discovered charts, their dependencies (with aliases and conditions) and the order
+the charts are processed in. Dependencies which aren't satisfied by one of the found charts are marked as missing.
+No schemas are generated.`,
+		RunE:          list,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("format", listFormatText, "output format, one of (text, dot, json)")
+
+	return cmd
+}
+
+func list(cmd *cobra.Command, _ []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	if format != listFormatText && format != listFormatDot && format != listFormatJSON {
+		return fmt.Errorf("unsupported list format %s, use %s, %s or %s", format, listFormatText, listFormatDot, listFormatJSON)
+	}
+
+	results, foundErrors := discoverCharts()
+	graph, err := schema.NewDependencyGraph(results)
+	if err != nil {
+		if _, ok := err.(*schema.CircularError); !ok {
+			log.Warnf("Could not sort charts: %s", err)
+		} else if reportRule(log.NewEntry(log.StandardLogger()), ruleCircularDependencies, "Could not sort charts: %s", err) {
+			foundErrors = true
+		}
+	}
+
+	if err := writeGraph(os.Stdout, format, graph); err != nil {
+		return err
+	}
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// discoverCharts reads the Chart.yaml files of the charts, which would be processed by a run
+func discoverCharts() ([]*schema.Result, bool) {
+	queue := make(chan string)
+	errs := make(chan error)
+	done := make(chan struct{})
+	foundErrors := false
+	go func() {
+		for err := range errs {
+			foundErrors = true
+			log.Error(err)
+		}
+		close(done)
+	}()
+
+	queueCharts(queue, errs)
+
+	results := []*schema.Result{}
+	for chartPath := range queue {
+		result := &schema.Result{ChartPath: chartPath}
+		if filepath.Base(chartPath) != "Chart.yaml" {
+			absDir, err := filepath.Abs(filepath.Dir(chartPath))
+			if err != nil {
+				errs <- err
+				continue
+			}
+			result.Chart = &chart.ChartFile{Name: filepath.Base(absDir)}
+		} else {
+			chartFile, err := readChartFile(chartPath)
+			if err != nil {
+				errs <- fmt.Errorf("could not read %s: %w", chartPath, err)
+				continue
+			}
+			result.Chart = chartFile
+		}
+		results = append(results, result)
+	}
+	close(errs)
+	<-done
+
+	slices.SortFunc(results, func(a, b *schema.Result) int {
+		return strings.Compare(a.ChartPath, b.ChartPath)
+	})
+	return results, foundErrors
+}
+
+// queueCharts starts queuing the paths of the selected charts (or the values file without chart), the
+// charts found below the chart search root by default. The queue is closed after the last one.
+func queueCharts(queue chan<- string, errs chan<- error) {
+	noDeps := viper.GetBool("no-dependencies")
+	switch chartDir, valuesFile := viper.GetString("selected-chart"), viper.GetString("values-file"); {
+	case valuesFile != "":
+		go func() {
+			queue <- valuesFile
+			close(queue)
+		}()
+	case len(viper.GetStringSlice("selected-charts")) > 0:
+		go queueFiles(viper.GetStringSlice("selected-charts"), queue)
+	case chartDir != "":
+		go searchChart(chartDir, noDeps, queue, errs)
+	default:
+		go searchFiles(viper.GetString("chart-search-root"), "Chart.yaml", queue, errs)
+	}
+}
+
+func writeGraph(w io.Writer, format string, graph *schema.DependencyGraph) error {
+	switch format {
+	case listFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(graph)
+	case listFormatDot:
+		_, err := io.WriteString(w, graph.Dot())
+		return err
+	}
+
+	names := make(map[string]string)
+	fmt.Fprintln(w, "Charts:")
+	for _, c := range graph.Charts {
+		names[c.Path] = strings.TrimSpace(c.Name + " " + c.Version)
+		fmt.Fprintf(w, "  %s (%s)\n", names[c.Path], c.Path)
+	}
+	if len(graph.Edges) > 0 {
+		fmt.Fprintln(w, "\nDependencies:")
+	}
+	for _, edge := range graph.Edges {
+		var details []string
+		if edge.Alias != "" {
+			details = append(details, "alias: "+edge.Alias)
+		}
+		if edge.Condition != "" {
+			details = append(details, "condition: "+edge.Condition)
+		}
+		if edge.To == "" {
+			details = append(details, "missing")
+		}
+		line := fmt.Sprintf("  %s -> %s %s", names[edge.From], edge.Name, edge.Version)
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, ", ") + ")"
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintln(w, "\nOrder:")
+	for i, path := range graph.Order {
+		fmt.Fprintf(w, "  %d. %s\n", i+1, names[path])
+	}
+	if graph.Circular {
+		fmt.Fprintln(w, "\nThe last charts are in no particular order, because of a circular or missing dependency")
+	} else if len(graph.Order) < len(graph.Charts) {
+		fmt.Fprintln(w, "\nThe order is incomplete, because the charts couldn't be sorted")
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+	"github.com/ojsef39/helm-schema/pkg/lsp"
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+func newLSPCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lsp",
+		Short: "start a language server for values files",
+		Long: `Starts a language server communicating over stdin and stdout, which offers completion, hover docs and
+diagnostics in values files. The schema is generated from the edited document and merged with the schemas
+of the dependencies in the charts directory, so the feedback is live, before a schema file is written.`,
+		RunE:          serveLSP,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+}
+
+func serveLSP(_ *cobra.Command, _ []string) error {
+	var valueFileNames []string
+
+	opts, err := newBufferOptions()
+	if err != nil {
+		return err
+	}
+	// the schemas are only used for json validation
+	opts.Format = schema.OutputFormatJSON
+	if err := viper.UnmarshalKey("value-files", &valueFileNames); err != nil {
+		return err
+	}
+	noDeps := viper.GetBool("no-dependencies")
+
+	// the logs are written to stderr, stdout is used by the protocol
+	generate := func(valuesPath string, content []byte) (*schema.Schema, error) {
+		result, err := opts.generate(valuesPath, content)
+		if err != nil {
+			return nil, err
+		}
+		if !noDeps {
+			addDependencySchemas(opts, valueFileNames, &result.Schema, filepath.Dir(valuesPath), nil)
+		}
+		return &result.Schema, nil
+	}
+	return lsp.NewServer(generate, version).Serve(os.Stdin, os.Stdout)
+}
+
+// addDependencySchemas adds the schemas of the dependencies in the charts directory of the
+// chart to its schema, like the schemas of the dependencies are merged when generating them.
+// The ancestors are the directories of the charts depending on the chart, which end circles
+// (e.g. of symlinks) and limit the depth to --max-depth.
+func addDependencySchemas(opts *bufferOptions, valueFileNames []string, s *schema.Schema, chartDir string, ancestors []string) {
+	chartFile, err := readChartFile(filepath.Join(chartDir, "Chart.yaml"))
+	if err != nil {
+		// a values file without a chart
+		log.Debugf("Not adding dependencies to the schema of %s: %s", chartDir, err)
+		return
+	}
+	if realDir, err := filepath.EvalSymlinks(chartDir); err == nil {
+		chartDir = realDir
+	}
+	if slices.Contains(ancestors, chartDir) {
+		log.Debugf("Not adding the dependencies of %s again, it depends on itself", chartDir)
+		return
+	}
+	ancestors = append(slices.Clone(ancestors), chartDir)
+	truncated := opts.MaxDepth > 0 && len(ancestors) > opts.MaxDepth
+
+	dependencyDirs := map[string]string{}
+	chartFiles, _ := filepath.Glob(filepath.Join(chartDir, "charts", "*", "Chart.yaml"))
+	for _, dependencyChartPath := range chartFiles {
+		if dependencyChart, err := readChartFile(dependencyChartPath); err == nil {
+			dependencyDirs[dependencyChart.Name] = filepath.Dir(dependencyChartPath)
+		}
+	}
+
+	for _, dep := range chartFile.Dependencies {
+		var depSchema *schema.Schema
+		var description string
+		if truncated {
+			// the dependencies beyond the maximum depth allow any values
+			depSchema = &schema.Schema{}
+		} else if dependencyDir, ok := dependencyDirs[dep.Name]; ok {
+			depSchema, description, err = dependencySchema(opts, valueFileNames, dependencyDir, ancestors)
+			if err != nil {
+				log.Warnf("Could not generate the schema of dependency %s: %s", dep.Name, err)
+				continue
+			}
+		} else if packaged, err := readPackagedDependency(chartDir, dep, opts.Store); err != nil {
+			log.Warnf("Could not read the packaged dependency %s: %s", dep.Name, err)
+			continue
+		} else if packaged != nil {
+			depSchema, description = packaged.Schema, packaged.Chart.Description
+		} else {
+			continue
+		}
+		depSchema, err = schema.DependencySchema(dep, depSchema, description, schema.KeepsRequired(chartFile, dep, opts.KeepRequired))
+		if err != nil {
+			log.Warnf("Could not add dependency %s: %s", dep.Name, err)
+			continue
+		}
+		conflicts, err := s.MergeDependency(schema.DependencyKey(dep), depSchema, opts.DependencyMerge)
+		if err != nil {
+			log.Warnf("Could not add dependency %s: %s", dep.Name, err)
+			continue
+		}
+		for _, conflict := range conflicts {
+			log.Debugf("Conflicting values of dependency %s: %s", dep.Name, conflict)
+		}
+	}
+}
+
+// dependencySchema returns the schema of the chart in the directory including its dependencies
+func dependencySchema(opts *bufferOptions, valueFileNames []string, chartDir string, ancestors []string) (*schema.Schema, string, error) {
+	chartPath := filepath.Join(chartDir, "Chart.yaml")
+	chartFile, err := readChartFile(chartPath)
+	if err != nil {
+		return nil, "", err
+	}
+	if opts.DependencySchemas == schema.DependencySchemasPublished {
+		published, _, err := schema.ReadPublishedSchema(chartPath)
+		if err != nil {
+			return nil, "", err
+		}
+		if published != nil {
+			return published, chartFile.Description, nil
+		}
+	}
+	chartValueFileNames := schema.ValueFileNames(chartFile, valueFileNames)
+	for _, valueFileName := range chartValueFileNames {
+		valuesPath := filepath.Join(chartDir, valueFileName)
+		content, err := os.ReadFile(valuesPath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		result, err := opts.generate(valuesPath, content)
+		if err != nil {
+			return nil, "", err
+		}
+		addDependencySchemas(opts, valueFileNames, &result.Schema, chartDir, ancestors)
+		return &result.Schema, chartFile.Description, nil
+	}
+	return nil, "", errors.New("no values file found, looked for " + strings.Join(chartValueFileNames, ", "))
+}
+
+func readChartFile(chartPath string) (*chart.ChartFile, error) {
+	chartFile, err := os.Open(chartPath)
+	if err != nil {
+		return nil, err
+	}
+	defer chartFile.Close()
+
+	parsed, err := chart.ReadChart(chartFile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parsed.ReadRequirements(filepath.Dir(chartPath)); err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
@@ -1,29 +1,59 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"go/token"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/text/language"
 
 	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/util"
 )
 
 func searchFiles(startPath, fileName string, queue chan<- string, errs chan<- error) {
 	defer close(queue)
-	err := filepath.Walk(startPath, func(path string, info os.FileInfo, err error) error {
+	walkFiles(startPath, fileName, viper.GetInt("max-search-depth"), queue, errs)
+}
+
+// walkFiles queues the files with the name below the start path, which are at most maxDepth directories
+// deep (0 means unlimited). The directories of --prune-dirs are skipped and with --follow-symlinks the
+// symlinked directories are searched as well.
+func walkFiles(startPath, fileName string, maxDepth int, queue chan<- string, errs chan<- error) {
+	pruneDirs := viper.GetStringSlice("prune-dirs")
+	err := util.Walk(startPath, viper.GetBool("follow-symlinks"), func(path string, info os.FileInfo, err error) error {
+		var loopErr *util.SymlinkLoopError
+		if errors.As(err, &loopErr) {
+			log.Warnf("Not following symlink %s, it points to its parent directory %s", loopErr.Path, loopErr.Target)
+			return nil
+		}
 		if err != nil {
 			errs <- err
 			return nil
 		}
 
+		if info.IsDir() && path != startPath {
+			if slices.Contains(pruneDirs, info.Name()) {
+				return filepath.SkipDir
+			}
+			if relPath, err := filepath.Rel(startPath, path); err == nil && maxDepth > 0 &&
+				len(strings.Split(relPath, string(filepath.Separator))) > maxDepth {
+				return filepath.SkipDir
+			}
+		}
 		if !info.IsDir() && info.Name() == fileName {
 			queue <- path
 		}
@@ -35,33 +65,336 @@ func searchFiles(startPath, fileName string, queue chan<- string, errs chan<- er
 	}
 }
 
+// searchChart queues the Chart.yaml of the given chart and, unless noDeps is set,
+// the ones of the dependencies in its charts directory
+func searchChart(chartDir string, noDeps bool, queue chan<- string, errs chan<- error) {
+	defer close(queue)
+	queue <- filepath.Join(chartDir, "Chart.yaml")
+	if noDeps {
+		return
+	}
+	dependenciesDir := filepath.Join(chartDir, "charts")
+	if _, err := os.Stat(dependenciesDir); err == nil {
+		walkFiles(dependenciesDir, "Chart.yaml", 0, queue, errs)
+	}
+}
+
+// selectChart restricts the run to the chart given as argument (a chart directory or
+// its Chart.yaml) or to the file given with --values-file, instead of searching below
+// the chart search root. The chart search root is set to the directory of the selection.
+func selectChart(args []string) error {
+	valuesFile := viper.GetString("values-file")
+	charts, err := chartList(viper.GetStringSlice("chart"), viper.GetString("charts-file"))
+	if err != nil {
+		return err
+	}
+	if len(charts) > 0 {
+		if len(args) > 0 || valuesFile != "" {
+			return errors.New("--chart and --charts-file can't be used together with a chart argument or --values-file")
+		}
+		viper.Set("selected-charts", charts)
+		return nil
+	}
+	if len(args) == 0 {
+		if valuesFile != "" {
+			if _, err := os.Stat(valuesFile); err != nil {
+				return err
+			}
+			viper.Set("chart-search-root", filepath.Dir(valuesFile))
+		}
+		return nil
+	}
+	if valuesFile != "" {
+		return errors.New("a chart and --values-file can't be used together")
+	}
+
+	chartDir := args[0]
+	info, err := os.Stat(chartDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if info.Name() != "Chart.yaml" {
+			return fmt.Errorf("%s is neither a chart directory nor a Chart.yaml, use --values-file for values files", chartDir)
+		}
+		chartDir = filepath.Dir(chartDir)
+	}
+	if _, err := os.Stat(filepath.Join(chartDir, "Chart.yaml")); err != nil {
+		return fmt.Errorf("no Chart.yaml found in %s", chartDir)
+	}
+	viper.Set("selected-chart", chartDir)
+	viper.Set("chart-search-root", chartDir)
+	return nil
+}
+
+// chartList returns the Chart.yaml files of the charts given with --chart and in the charts file, which
+// contains a chart directory or Chart.yaml per line (empty lines and lines starting with # are ignored)
+func chartList(charts []string, chartsFile string) ([]string, error) {
+	if chartsFile != "" {
+		content, err := os.ReadFile(chartsFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read charts file %s: %w", chartsFile, err)
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				charts = append(charts, line)
+			}
+		}
+	}
+
+	chartPaths := []string{}
+	seen := make(map[string]bool)
+	for _, chartPath := range charts {
+		if filepath.Base(chartPath) != "Chart.yaml" {
+			chartPath = filepath.Join(chartPath, "Chart.yaml")
+		}
+		chartPath = filepath.Clean(chartPath)
+		if _, err := os.Stat(chartPath); err != nil {
+			return nil, fmt.Errorf("no Chart.yaml found for chart %s: %w", filepath.Dir(chartPath), err)
+		}
+		if !seen[chartPath] {
+			seen[chartPath] = true
+			chartPaths = append(chartPaths, chartPath)
+		}
+	}
+	return chartPaths, nil
+}
+
+// queueFiles queues the files and closes the queue
+func queueFiles(paths []string, queue chan<- string) {
+	defer close(queue)
+	for _, path := range paths {
+		queue <- path
+	}
+}
+
 func exec(cmd *cobra.Command, _ []string) error {
-	configureLogging()
+	if viper.GetBool("stdin") {
+		return generateFromStdin(os.Stdin, os.Stdout)
+	}
+
+	start := time.Now()
+	results, err := run(true)
+	elapsed := time.Since(start)
+
+	if viper.GetBool("timings") {
+		if timingsErr := writeTimingsTable(os.Stderr, results, elapsed); timingsErr != nil {
+			return timingsErr
+		}
+	}
+	if timingsFile := viper.GetString("timings-file"); timingsFile != "" {
+		if timingsErr := writeTimingsFile(timingsFile, results, elapsed); timingsErr != nil {
+			log.Errorf("Could not write timings %s: %s", timingsFile, timingsErr)
+			return timingsErr
+		}
+	}
+
+	if catalogFile := viper.GetString("catalog-file"); catalogFile != "" && !viper.GetBool("dry-run") && !viper.GetBool("stdout") {
+		if catalogErr := writeCatalog(catalogFile, viper.GetString("catalog-format"), results); catalogErr != nil {
+			log.Errorf("Could not write catalog %s: %s", catalogFile, catalogErr)
+			return catalogErr
+		}
+	}
 
+	return err
+}
+
+func newGenerateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:           "generate [chart]",
+		Short:         "generate the jsonschemas (the same as running helm-schema without a command)",
+		Args:          cobra.MaximumNArgs(1),
+		Annotations:   map[string]string{chartArgumentAnnotation: "true"},
+		RunE:          exec,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+}
+
+// writeCatalog writes an index of all generated schemas to the catalog file
+func writeCatalog(catalogFile, format string, results []*schema.Result) error {
+	if format != schema.CatalogFormatSchemastore && format != schema.CatalogFormatVSCode {
+		return fmt.Errorf("unsupported catalog format %s, use %s or %s", format, schema.CatalogFormatSchemastore, schema.CatalogFormatVSCode)
+	}
+
+	catalog, err := schema.NewCatalog(results, filepath.Dir(catalogFile))
+	if err != nil {
+		return err
+	}
+	catalogJSON, err := catalog.ToJson(format)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(catalogFile, append(catalogJSON, '\n'), 0644)
+}
+
+// run generates the schemas of all charts and returns the results which were generated
+// without errors. If writeSchemas is true, the schemas are printed (dry-run) or written.
+func run(writeSchemas bool) ([]*schema.Result, error) {
 	var skipAutoGeneration, valueFileNames []string
 
 	chartSearchRoot := viper.GetString("chart-search-root")
-	dryRun := viper.GetBool("dry-run")
+	chartDir := viper.GetString("selected-chart")
+	selectedCharts := viper.GetStringSlice("selected-charts")
+	valuesFile := viper.GetString("values-file")
+	// --stdout is a dry-run which only prints the schemas
+	printOnly := viper.GetBool("stdout")
+	dryRun := viper.GetBool("dry-run") || printOnly
 	noDeps := viper.GetBool("no-dependencies")
+	failOnCircular := viper.GetBool("fail-on-circular")
+	selfCheck := viper.GetBool("self-check")
+	var policy *schema.Policy
+	if policyFile := viper.GetString("policy-file"); policyFile != "" {
+		var err error
+		if policy, err = schema.ReadPolicy(policyFile); err != nil {
+			return nil, usageError{err}
+		}
+	}
+	thresholds, err := readCoverageThresholds()
+	if err != nil {
+		return nil, usageError{err}
+	}
+	detectSensitive := viper.GetBool("detect-sensitive")
+	keepRequiredDependencies := viper.GetStringSlice("keep-required")
+	maxDepth := viper.GetInt("max-depth")
+	if maxDepth < 0 {
+		return nil, usageErrorf("the maximum depth of dependencies must not be negative, got %d", maxDepth)
+	}
+	if maxSearchDepth := viper.GetInt("max-search-depth"); maxSearchDepth < 0 {
+		return nil, usageErrorf("the maximum search depth must not be negative, got %d", maxSearchDepth)
+	}
+	dependencySchemas := viper.GetString("dependency-schemas")
+	dependencyMerge := viper.GetString("dependency-merge")
+	if !slices.Contains(schema.DependencyMergeStrategies, dependencyMerge) {
+		return nil, usageErrorf("unsupported dependency merge strategy %s, use one of %s", dependencyMerge, strings.Join(schema.DependencyMergeStrategies, ", "))
+	}
+	if !slices.Contains(schema.DependencySchemasModes, dependencySchemas) {
+		return nil, usageErrorf("unsupported dependency schemas %s, use one of %s", dependencySchemas, strings.Join(schema.DependencySchemasModes, ", "))
+	}
+	excludeValues := viper.GetStringSlice("exclude-values")
+	if err := schema.ValidateExcludePatterns(excludeValues); err != nil {
+		return nil, usageError{err}
+	}
+	validationsAnnotation := viper.GetString("validations-annotation")
+	if !strings.HasPrefix(validationsAnnotation, schema.CustomAnnotationPrefix) {
+		return nil, usageErrorf("the annotation of the CEL rules must start with %s, got %s", schema.CustomAnnotationPrefix, validationsAnnotation)
+	}
+	descriptionFormat := viper.GetString("description-format")
+	lang := viper.GetString("lang")
+	defsMode := viper.GetString("defs-mode")
+	dedupe := viper.GetBool("dedupe")
+	flatten := viper.GetBool("flatten")
+	flattenClient, err := remoteClient(viper.GetBool("flatten-remote"))
+	if err != nil {
+		return nil, usageError{err}
+	}
+	if valuesFile != "" {
+		// a bare values file has no dependencies
+		noDeps = true
+	}
 	addSchemaReference := viper.GetBool("add-schema-reference")
 	keepFullComment := viper.GetBool("keep-full-comment")
 	helmDocsCompatibilityMode := viper.GetBool("helm-docs-compatibility-mode")
 	uncomment := viper.GetBool("uncomment")
 	outFile := viper.GetString("output-file")
+	outDir := viper.GetString("output-dir")
+	outLayout := viper.GetString("output-layout")
 	dontRemoveHelmDocsPrefix := viper.GetBool("dont-strip-helm-docs-prefix")
+	bitnamiCompatibilityMode := viper.GetBool("bitnami-compatibility-mode")
 	appendNewline := viper.GetBool("append-newline")
 	dependencies := viper.GetString("dependencies")
+	cacheFile := viper.GetString("cache-file")
+	changedSince := viper.GetString("changed-since")
+	propertyOrder := viper.GetString("property-order")
+	addOrderHint := viper.GetBool("add-x-order")
+	schemaId := viper.GetString("schema-id")
+	embedChartMetadata := viper.GetBool("add-chart-metadata")
+	embedGeneratedBy := viper.GetBool("add-generated-by")
+	schemaURI := viper.GetString("schema-uri")
+	postProcessCmd := viper.GetString("post-process-cmd")
+	conditionPatch := schema.ConditionPatch{
+		Type:        viper.GetStringSlice("condition-type"),
+		Description: viper.GetString("condition-description"),
+	}
+	outputFormat := viper.GetString("format")
+	if !slices.Contains(schema.OutputFormats, outputFormat) {
+		return nil, usageErrorf("unsupported output format %s, use one of %s", outputFormat, strings.Join(schema.OutputFormats, ", "))
+	}
+	if !viper.IsSet("output-file") {
+		outFile = schema.DefaultOutputFile(outputFormat)
+	}
+	emit := emitOptions{
+		TypeScript: viper.GetBool("emit-typescript"),
+		Go:         viper.GetBool("emit-go"),
+		GoPackage:  viper.GetString("go-package"),
+		CRD:        viper.GetBool("emit-crd"),
+		CRDOptions: schema.CRDOptions{
+			Group:   viper.GetString("crd-group"),
+			Kind:    viper.GetString("crd-kind"),
+			Version: viper.GetString("crd-version"),
+		},
+		Questions: viper.GetBool("emit-questions"),
+		UISchema:  viper.GetBool("emit-ui-schema"),
+		YAML:      viper.GetBool("emit-yaml"),
+	}
+	if emit.Go && !token.IsIdentifier(emit.GoPackage) {
+		return nil, usageErrorf("invalid go package name %s", emit.GoPackage)
+	}
+	rootKeywords, err := configSection("schema-keywords")
+	if err != nil {
+		return nil, usageError{err}
+	}
 	if err := viper.UnmarshalKey("value-files", &valueFileNames); err != nil {
-		return err
+		return nil, usageError{err}
 	}
 	if err := viper.UnmarshalKey("skip-auto-generation", &skipAutoGeneration); err != nil {
-		return err
+		return nil, usageError{err}
+	}
+	workersCount, err := getWorkersCount(viper.GetInt("workers"))
+	if err != nil {
+		return nil, usageError{err}
+	}
+	maxErrors, err := getMaxErrors(viper.GetBool("fail-fast"), viper.GetInt("max-errors"))
+	if err != nil {
+		return nil, usageError{err}
 	}
-	workersCount := runtime.NumCPU() * 2
 
 	skipConfig, err := schema.NewSkipAutoGenerationConfig(skipAutoGeneration)
 	if err != nil {
-		return err
+		return nil, usageError{err}
+	}
+	skipConfig.RequiredMode = viper.GetString("required-mode")
+	if err := schema.ValidateRequiredMode(skipConfig.RequiredMode); err != nil {
+		return nil, usageError{err}
+	}
+
+	outputConfig, err := schema.NewOutputConfig(outFile, outDir, outLayout, chartSearchRoot)
+	if err != nil {
+		return nil, usageError{err}
+	}
+
+	if propertyOrder != schema.PropertyOrderAlpha && propertyOrder != schema.PropertyOrderSource {
+		return nil, usageErrorf("unsupported property order %s, use %s or %s", propertyOrder, schema.PropertyOrderAlpha, schema.PropertyOrderSource)
+	}
+	if descriptionFormat != schema.DescriptionFormatMarkdown && descriptionFormat != schema.DescriptionFormatPlain {
+		return nil, usageErrorf("unsupported description format %s, use %s or %s", descriptionFormat, schema.DescriptionFormatMarkdown, schema.DescriptionFormatPlain)
+	}
+	if _, err := language.Parse(lang); lang != "" && err != nil {
+		return nil, usageErrorf("invalid language %s: %w", lang, err)
+	}
+	if defsMode != schema.DefsModeBundle && defsMode != schema.DefsModeInline {
+		return nil, usageErrorf("unsupported defs mode %s, use %s or %s", defsMode, schema.DefsModeBundle, schema.DefsModeInline)
+	}
+	indent, err := jsonIndent()
+	if err != nil {
+		return nil, usageError{err}
+	}
+	if dedupe && flatten {
+		return nil, usageErrorf("--dedupe and --flatten can't be combined")
+	}
+	sharedDefs, err := schema.ReadSharedDefs(viper.GetString("defs-file"))
+	if err != nil {
+		return nil, usageError{err}
 	}
 
 	// Parse dependencies
@@ -73,25 +406,65 @@ func exec(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
+	var cache *schema.Cache
+	if cacheFile != "" {
+		cacheOptions, err := cacheOptionsHash()
+		if err != nil {
+			return nil, err
+		}
+		cache, err = schema.LoadCache(cacheFile, cacheOptions)
+		if err != nil {
+			return nil, fmt.Errorf("could not read cache file %s: %w", cacheFile, err)
+		}
+	}
+
 	// 1. Start a producer that searches Chart.yaml and values.yaml files
-	queue := make(chan string)
-	resultsChan := make(chan schema.Result)
+	// The channels are bounded by the number of workers, so the producer
+	// can't run too far ahead of the workers on huge repositories
+	queue := make(chan string, workersCount)
+	resultsChan := make(chan schema.Result, workersCount)
 	results := []*schema.Result{}
 	errs := make(chan error)
-	done := make(chan struct{})
 
-	go searchFiles(chartSearchRoot, "Chart.yaml", queue, errs)
+	switch {
+	case valuesFile != "":
+		queue <- valuesFile
+		close(queue)
+	case len(selectedCharts) > 0:
+		// only the listed charts are processed, without searching any directories
+		go queueFiles(selectedCharts, queue)
+	case chartDir != "":
+		go searchChart(chartDir, noDeps, queue, errs)
+	default:
+		go searchFiles(chartSearchRoot, "Chart.yaml", queue, errs)
+	}
+
+	// The workers stop taking charts from the queue, once too many charts failed
+	workerQueue := make(chan string)
+	stop := make(chan struct{})
+	go func() {
+		defer close(workerQueue)
+		for chartPath := range queue {
+			select {
+			case <-stop:
+				// the rest of the queue is drained, so the producer doesn't block
+				for range queue {
+				}
+				return
+			case workerQueue <- chartPath:
+			}
+		}
+	}()
 
 	// 2. Start workers and every worker does:
 	wg := sync.WaitGroup{}
+	wg.Add(workersCount)
 	go func() {
 		wg.Wait()
-		done <- struct{}{}
+		close(resultsChan)
 	}()
 
 	for i := 0; i < workersCount; i++ {
-		wg.Add(1)
-
 		go func() {
 			defer wg.Done()
 			schema.Worker(
@@ -101,37 +474,94 @@ func exec(cmd *cobra.Command, _ []string) error {
 				keepFullComment,
 				helmDocsCompatibilityMode,
 				dontRemoveHelmDocsPrefix,
+				bitnamiCompatibilityMode,
 				valueFileNames,
 				skipConfig,
-				outFile,
-				queue,
+				outputConfig,
+				cache,
+				workerQueue,
 				resultsChan,
 			)
 		}()
 	}
 
+	var chartProgress *progress
+	if writeSchemas {
+		chartProgress = newProgress(dryRun)
+	}
+	defer chartProgress.Finish()
+	chartProgress.Start("Reading charts", 0)
+
+	// the errors of unchanged charts are ignored with --changed-since, so they are only counted below
+	failedCount := 0
 loop:
 	for {
 		select {
 		case err := <-errs:
 			log.Error(err)
-		case res := <-resultsChan:
+		case res, ok := <-resultsChan:
+			if !ok {
+				break loop
+			}
+			if maxErrors > 0 && failedCount >= maxErrors {
+				// the charts, which were in progress when the workers were stopped
+				continue
+			}
 			results = append(results, &res)
-		case <-done:
-			break loop
+			chartProgress.Step(filepath.Dir(res.ChartPath))
+			if len(res.Errors) > 0 && changedSince == "" {
+				failedCount++
+				if failedCount == maxErrors {
+					close(stop)
+				}
+			}
+		}
+	}
+
+	// the workers finish in random order, so sort the results to get reproducible runs
+	slices.SortFunc(results, func(a, b *schema.Result) int {
+		return strings.Compare(a.ChartPath, b.ChartPath)
+	})
+
+	if maxErrors > 0 && failedCount >= maxErrors {
+		chartProgress.Finish()
+		for _, result := range results {
+			if len(result.Errors) > 0 {
+				logResultErrors(result)
+			}
 		}
+		return nil, fmt.Errorf("stopped after %d charts with errors", failedCount)
 	}
 
+	// both charts fail, instead of one overwriting the schema of the other
+	for _, collision := range schema.FindOutputCollisions(results) {
+		collision.Result.Errors = append(collision.Result.Errors, collision)
+		other := schema.OutputCollision{Path: collision.Path, Result: collision.Other, Other: collision.Result}
+		collision.Other.Errors = append(collision.Other.Errors, other)
+	}
+
+	// only output the charts which changed since the given ref (and their parents)
+	var affectedCharts map[string]bool
+	if changedSince != "" {
+		affectedCharts, err = chartsChangedSince(changedSince, chartSearchRoot, results)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("%d of %d charts changed since %s", len(affectedCharts), len(results), changedSince)
+	}
+
+	resolver := newDependencyResolver(maxDepth, localStore())
+
 	// sort results with topology sort (only if we're checking the dependencies)
 	if !noDeps {
 		// sort results with topology sort
-		results, err = schema.TopoSort(results)
+		results, err = schema.TopoSort(results, resolver.packaged)
 		if err != nil {
-			if _, ok := err.(*schema.CircularError); !ok {
+			if _, ok := err.(*schema.CircularError); !ok || failOnCircular {
 				log.Errorf("Error while sorting results: %s", err)
-				return err
-			} else {
-				log.Warnf("Could not sort results: %s", err)
+				return nil, err
+			} else if reportRule(log.NewEntry(log.StandardLogger()), ruleCircularDependencies, "Could not sort results: %s", err) {
+				return nil, err
 			}
 		}
 	}
@@ -155,125 +585,608 @@ loop:
 		}
 	}
 
-	chartNameToResult := make(map[string]*schema.Result)
-	foundErrors := false
+	generated := []*schema.Result{}
+	outputHashes := make(map[string]string)
+	// the charts with errors, the skipped charts and the number of generated schemas for the summary
+	failed := make(map[string]bool)
+	processed, skipped, generatedCount := 0, 0, 0
+	// the charts, whose files would change in a dry-run
+	changedCharts := []string{}
+	colorDiffs := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
 
 	// process results
+	chartProgress.Start("Generating schemas", len(results))
 	for _, result := range results {
+		if maxErrors > 0 && len(failed) >= maxErrors {
+			break
+		}
+		processed++
+		chartProgress.Step(filepath.Dir(result.ChartPath))
+		// Charts which didn't change are still processed, because their
+		// schemas are needed by the changed charts depending on them
+		unchanged := affectedCharts != nil && !affectedCharts[result.ChartPath]
+
 		// Error handling
+		if len(result.Errors) > 0 && unchanged {
+			chartLog(result).Debugf("Ignoring %d errors of unchanged chart %s", len(result.Errors), result.ChartPath)
+			skipped++
+			continue
+		}
 		if len(result.Errors) > 0 {
-			foundErrors = true
-			if result.Chart != nil {
-				log.Errorf(
-					"Found %d errors while processing the chart %s (%s)",
-					len(result.Errors),
-					result.Chart.Name,
-					result.ChartPath,
-				)
-			} else {
-				log.Errorf("Found %d errors while processing the chart %s", len(result.Errors), result.ChartPath)
-			}
-			for _, err := range result.Errors {
-				log.Error(err)
+			failed[result.ChartPath] = true
+			logResultErrors(result)
+			if cache != nil {
+				cache.Delete(result.ChartPath)
 			}
 			continue
 		}
 
-		log.Debugf("Processing result for chart: %s (%s)", result.Chart.Name, result.ChartPath)
-		if !noDeps {
+		chartLog(result).Debugf("Processing result for chart: %s (%s)", result.Chart.Name, result.ChartPath)
+
+		// The schema shipped with a dependency is used instead of the generated one, so it's never written
+		published := false
+		if dependencySchemas == schema.DependencySchemasPublished {
+			publishedSchema, content, err := schema.ReadPublishedSchema(result.ChartPath)
+			if err != nil {
+				failed[result.ChartPath] = true
+				chartLog(result).Errorf("Could not read the published schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+				continue
+			}
+			if publishedSchema != nil {
+				chartLog(result).Debugf("Using the published schema of chart %s (%s)", result.Chart.Name, result.ChartPath)
+				result.Schema = *publishedSchema
+				result.InputHash = schema.Hash([]byte(result.InputHash), content)
+				result.Cached = false
+				published = true
+			}
+		}
+
+		// Check if the schema of the last run can be reused
+		upToDate := false
+		if cache != nil {
+			outputHashes[result.ChartPath] = outputHash(result, noDeps, conditionsToPatch, resolver, outputHashes)
+			entry, _ := cache.Get(result.ChartPath)
+			if result.Cached {
+				if entry.OutputHash == outputHashes[result.ChartPath] {
+					chartLog(result).Debugf("Schema of chart %s (%s) is up to date", result.Chart.Name, result.ChartPath)
+					upToDate = true
+				} else {
+					// one of the dependencies changed
+					if err := regenerateSchema(result, uncomment, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, bitnamiCompatibilityMode, skipConfig); err != nil {
+						failed[result.ChartPath] = true
+						chartLog(result).Errorf("Could not generate schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+						cache.Delete(result.ChartPath)
+						continue
+					}
+				}
+			}
+		}
+
+		mergeStart := time.Now()
+		if !noDeps && !upToDate {
 			// Patch condition into schema if needed
 			if patch, ok := conditionsToPatch[result.Chart.Name]; ok {
-				schemaToPatch := &result.Schema
-				lastIndex := len(patch) - 1
-				for i, key := range patch {
-					if alreadyPresentSchema, ok := schemaToPatch.Properties[key]; !ok {
-						log.Debugf(
-							"Patching conditional field \"%s\" into schema of chart %s",
-							key,
-							result.Chart.Name,
-						)
-						if i == lastIndex {
-							schemaToPatch.Properties[key] = &schema.Schema{
-								Type:        []string{"boolean"},
-								Title:       key,
-								Description: "Conditional property used in parent chart",
-							}
-						} else {
-							schemaToPatch.Properties[key] = &schema.Schema{Type: []string{"object"}, Title: key}
-							schemaToPatch = schemaToPatch.Properties[key]
-						}
-					} else {
-						schemaToPatch = alreadyPresentSchema
-					}
+				condition := strings.Join(append([]string{result.Chart.Name}, patch...), ".")
+				chartLog(result).Debugf("Patching conditional field \"%s\" into schema of chart %s", condition, result.Chart.Name)
+				if err := result.PatchCondition(condition, patch, conditionPatch); err != nil {
+					failed[result.ChartPath] = true
+					chartLog(result).Errorf("Could not patch the condition %s into the schema of chart %s (%s): %s", condition, result.Chart.Name, result.ChartPath, err)
+					continue
 				}
 			}
 
+			// a schema missing a dependency, which couldn't be merged, must neither be written nor cached
+			mergeFailed := false
 			for _, dep := range result.Chart.Dependencies {
 				if dep.Name != "" {
 					if len(selectedDependencies) > 0 && !contains(selectedDependencies, dep.Name) {
 						continue
 					}
-					if dependencyResult, ok := chartNameToResult[dep.Name]; ok {
-						log.Debugf(
+					var dependencySchema *schema.Schema
+					var description string
+					dependencyResult, found := resolver.lookup(result, dep)
+					if found {
+						chartLog(result).Debugf(
 							"Found chart of dependency %s (%s)",
 							dependencyResult.Chart.Name,
 							dependencyResult.ChartPath,
 						)
-						depSchema := schema.Schema{
-							Type:        []string{"object"},
-							Title:       dep.Name,
-							Description: dependencyResult.Chart.Description,
-							Properties:  dependencyResult.Schema.Properties,
-						}
-						// you don't NEED to overwrite the values
-						// so every required check will be disabled
-						depSchema.DisableRequiredProperties()
-
-						if dep.Alias != "" {
-							result.Schema.Properties[dep.Alias] = &depSchema
-						} else {
-							result.Schema.Properties[dep.Name] = &depSchema
-						}
-
+						dependencySchema, description = &dependencyResult.Schema, dependencyResult.Chart.Description
+					} else if packaged, err := resolver.packaged(result, dep); err != nil {
+						mergeFailed = true
+						chartLog(result).Errorf("Could not read the packaged dependency %s of chart %s (%s): %s", dep.Name, result.Chart.Name, result.ChartPath, err)
+						break
+					} else if packaged != nil {
+						chartLog(result).Debugf("Found the schema of dependency %s in %s", dep.Name, packaged.Path)
+						dependencySchema, description = packaged.Schema, packaged.Chart.Description
 					} else {
-						log.Warnf("Dependency (%s->%s) specified but no schema found. If you want to create jsonschemas for external dependencies, you need to run helm dependency build & untar the charts.", result.Chart.Name, dep.Name)
+						reportRule(chartLog(result), ruleMissingDependencySchema, "Dependency (%s->%s) specified but no schema found. If you want to create jsonschemas for external dependencies, you need to run helm dependency build & untar the charts or populate the local store with helm-schema cache warm.", result.Chart.Name, dep.Name)
+						continue
+					}
+					keepRequired := schema.KeepsRequired(result.Chart, dep, keepRequiredDependencies)
+					depSchema, err := schema.DependencySchema(dep, dependencySchema, description, keepRequired)
+					if err != nil {
+						mergeFailed = true
+						chartLog(result).Errorf("Could not add dependency %s to the schema of chart %s (%s): %s", dep.Name, result.Chart.Name, result.ChartPath, err)
+						break
+					}
+					if found {
+						resolver.truncate(depSchema, dependencyResult)
+					}
+					conflicts, err := result.Schema.MergeDependency(schema.DependencyKey(dep), depSchema, dependencyMerge)
+					if err != nil {
+						mergeFailed = true
+						chartLog(result).Errorf("Could not add dependency %s to the schema of chart %s (%s): %s", dep.Name, result.Chart.Name, result.ChartPath, err)
+						break
 					}
+					for _, conflict := range conflicts {
+						if reportRule(chartLog(result), ruleDependencyConflict, "Conflicting values of dependency %s in chart %s (%s): %s", dep.Name, result.Chart.Name, result.ChartPath, conflict) {
+							failed[result.ChartPath] = true
+						}
+					}
+					result.Schema.AddDefs(dependencySchema.Defs)
 				} else {
-					log.Warnf("Dependency without name found (checkout %s).", result.ChartPath)
+					if reportRule(chartLog(result), ruleUnnamedDependency, "Dependency without name found (checkout %s).", result.ChartPath) {
+						failed[result.ChartPath] = true
+					}
 				}
 			}
-			chartNameToResult[result.Chart.Name] = result
+			if mergeFailed {
+				failed[result.ChartPath] = true
+				if cache != nil {
+					cache.Delete(result.ChartPath)
+				}
+				continue
+			}
+		}
+		result.Timings.Merge = time.Since(mergeStart)
+
+		// The patch file applies to the merged schema, so it can change the values of the dependencies
+		if !upToDate && !published {
+			if err := result.Schema.ApplySchemaPatchFile(filepath.Dir(result.ChartPath)); err != nil {
+				failed[result.ChartPath] = true
+				chartLog(result).Errorf("Could not patch the schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+				if cache != nil {
+					cache.Delete(result.ChartPath)
+				}
+				continue
+			}
+		}
+		if !noDeps {
+			resolver.add(result)
+		}
+
+		if unchanged || published {
+			skipped++
+			continue
+		}
+
+		postProcessStart := time.Now()
+		if !upToDate {
+			if count := result.Schema.ExcludeValues(excludeValues); count > 0 {
+				chartLog(result).Debugf("Excluded %d values of chart %s", count, result.Chart.Name)
+			}
+			result.Schema.RenameValidations(validationsAnnotation)
+			if sharedDefs != nil {
+				if err := result.Schema.ResolveSharedDefs(sharedDefs, defsMode); err != nil {
+					failed[result.ChartPath] = true
+					chartLog(result).Errorf("Could not resolve the shared definitions of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+					if cache != nil {
+						cache.Delete(result.ChartPath)
+					}
+					continue
+				}
+			}
+			if lang != "" {
+				if err := result.Schema.Localize(lang); err != nil {
+					failed[result.ChartPath] = true
+					chartLog(result).Errorf("Could not localize schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+					continue
+				}
+			}
+			result.Schema.FormatDescriptions(descriptionFormat)
+			if count := result.Schema.MaskSensitive(detectSensitive); count > 0 {
+				chartLog(result).Debugf("Masked %d sensitive values of chart %s", count, result.Chart.Name)
+			}
+			result.Schema.ApplyPropertyOrder(propertyOrder, addOrderHint)
+			result.Schema.ApplyRootKeywords(schemaURI, rootKeywords)
+			if dedupe {
+				deduped, count, err := result.Schema.Dedupe()
+				if err != nil {
+					failed[result.ChartPath] = true
+					chartLog(result).Errorf("Could not deduplicate schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+					continue
+				}
+				result.Schema = *deduped
+				chartLog(result).Debugf("Replaced %d duplicated subschemas of chart %s by $refs", count, result.Chart.Name)
+			}
+			if flatten {
+				if err := result.Schema.Flatten(valuesURL(result.ValuesPath), flattenClient); err != nil {
+					failed[result.ChartPath] = true
+					chartLog(result).Errorf("Could not flatten schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+					continue
+				}
+			}
+			if err := result.ApplyChartMetadata(schemaId, embedChartMetadata); err != nil {
+				failed[result.ChartPath] = true
+				chartLog(result).Errorf("Could not add metadata of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+				continue
+			}
+			if embedGeneratedBy {
+				if err := result.ApplyGeneratedBy(version); err != nil {
+					failed[result.ChartPath] = true
+					chartLog(result).Errorf("Could not add the generator of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+					continue
+				}
+			}
+			if postProcessCmd != "" {
+				if err := result.PostProcess(postProcessCmd); err != nil {
+					failed[result.ChartPath] = true
+					chartLog(result).Errorf("Could not post-process schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+					if cache != nil {
+						cache.Delete(result.ChartPath)
+					}
+					continue
+				}
+			}
+		}
+		result.Timings.PostProcess = time.Since(postProcessStart)
+		// a schema rejecting the defaults of its chart is a bug, so it's not written unless the rule is lowered
+		if selfCheck {
+			if err := checkOwnValues(result); err != nil && reportRule(chartLog(result), ruleSelfCheck, "The schema of chart %s (%s) rejects its own values: %s", result.Chart.Name, result.ChartPath, err) {
+				failed[result.ChartPath] = true
+				if cache != nil {
+					cache.Delete(result.ChartPath)
+				}
+				continue
+			}
+		}
+		if err := result.Schema.ValidateMetaschema(); err != nil && reportRule(chartLog(result), ruleInvalidSchema, "The schema of chart %s (%s) is invalid: %s", result.Chart.Name, result.ChartPath, err) {
+			failed[result.ChartPath] = true
+			if cache != nil {
+				cache.Delete(result.ChartPath)
+			}
+			continue
+		}
+		if policy != nil {
+			violations, err := policy.Evaluate(result)
+			if err != nil {
+				failed[result.ChartPath] = true
+				chartLog(result).Errorf("Could not evaluate the policy for the schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+				continue
+			}
+			violating := false
+			for _, violation := range violations {
+				if reportRule(chartLog(result), violationRule(violation), "The schema of chart %s (%s) violates the policy: %s", result.Chart.Name, result.ChartPath, violation) {
+					violating = true
+				}
+			}
+			if violating {
+				failed[result.ChartPath] = true
+				if cache != nil {
+					cache.Delete(result.ChartPath)
+				}
+				continue
+			}
+		}
+		// the schemas are written anyway, the thresholds only ratchet the documentation of the values
+		if thresholds.enabled() {
+			for _, unmet := range thresholds.unmet(resultCoverage(result)) {
+				if reportRule(chartLog(result), unmet.rule, "The schema of chart %s (%s) doesn't reach the minimum coverage: %s", result.Chart.Name, result.ChartPath, unmet.message) {
+					failed[result.ChartPath] = true
+				}
+			}
+		}
+		generated = append(generated, result)
+
+		if !writeSchemas {
+			continue
 		}
 
 		// Print to stdout or write to file
-		jsonStr, err := result.Schema.ToJson()
+		writeStart := time.Now()
+		jsonStr, err := schemaContent(result, outputFormat, indent, appendNewline)
 		if err != nil {
-			log.Error(err)
+			failed[result.ChartPath] = true
+			chartLog(result).Errorf("Could not serialize schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
 			continue
 		}
 
-		if appendNewline {
-			jsonStr = append(jsonStr, '\n')
+		var files []emittedFile
+		if !upToDate && !printOnly {
+			files, err = emittedFiles(result, emit)
+			if err != nil {
+				failed[result.ChartPath] = true
+				chartLog(result).Errorf("Could not generate files from schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+				continue
+			}
 		}
 
-		if dryRun {
-			log.Infof("Printing jsonschema for %s chart (%s)", result.Chart.Name, result.ChartPath)
-			if appendNewline {
+		if dryRun && !printOnly {
+			// the changes of the files are shown as diffs, so they can be reviewed
+			files = append([]emittedFile{{Path: result.OutputPath, Content: jsonStr}}, files...)
+			changed := false
+			for _, file := range files {
+				diff, err := fileDiff(file.Path, file.Content)
+				if err != nil {
+					failed[result.ChartPath] = true
+					chartLog(result).Errorf("Could not compare %s of chart %s (%s): %s", file.Path, result.Chart.Name, result.ChartPath, err)
+					continue
+				}
+				if diff != "" {
+					changed = true
+					fmt.Print(colorizeDiff(diff, colorDiffs))
+				}
+			}
+			if changed {
+				changedCharts = append(changedCharts, result.Chart.Name)
+			}
+		} else if dryRun {
+			if bytes.HasSuffix(jsonStr, []byte("\n")) {
 				fmt.Printf("%s", jsonStr)
 			} else {
 				fmt.Printf("%s\n", jsonStr)
 			}
-		} else {
-			chartBasePath := filepath.Dir(result.ChartPath)
-			if err := os.WriteFile(filepath.Join(chartBasePath, outFile), jsonStr, 0644); err != nil {
-				errs <- err
+			for _, file := range files {
+				chartLog(result).Infof("Printing %s", file.Path)
+				fmt.Printf("%s", file.Content)
+			}
+		} else if !upToDate {
+			if err := os.MkdirAll(filepath.Dir(result.OutputPath), 0755); err != nil {
+				failed[result.ChartPath] = true
+				chartLog(result).Errorf("Could not create directory for schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
 				continue
 			}
+			if err := util.WriteFileAtomic(result.OutputPath, jsonStr, 0644); err != nil {
+				failed[result.ChartPath] = true
+				chartLog(result).Errorf("Could not write schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+				continue
+			}
+			writeFailed := false
+			for _, file := range files {
+				if err := util.WriteFileAtomic(file.Path, file.Content, 0644); err != nil {
+					failed[result.ChartPath] = true
+					writeFailed = true
+					chartLog(result).Errorf("Could not write %s of chart %s (%s): %s", file.Path, result.Chart.Name, result.ChartPath, err)
+				}
+			}
+			if writeFailed {
+				continue
+			}
+			if cache != nil {
+				entry := schema.CacheEntry{
+					InputHash:  result.InputHash,
+					OutputHash: outputHashes[result.ChartPath],
+					SchemaHash: schema.Hash(jsonStr),
+				}
+				// only the json files are read back, the schema of the other formats is stored in the cache
+				if outputFormat != schema.OutputFormatJSON {
+					if entry.Schema, err = result.Schema.ToJsonIndent(""); err != nil {
+						failed[result.ChartPath] = true
+						chartLog(result).Errorf("Could not serialize schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+						continue
+					}
+				}
+				cache.Set(result.ChartPath, entry)
+			}
+		}
+		result.Timings.Write = time.Since(writeStart)
+		if upToDate && !dryRun {
+			skipped++
+		} else if !failed[result.ChartPath] {
+			generatedCount++
+		}
+	}
+
+	if cache != nil && writeSchemas && !dryRun {
+		if err := cache.Save(cacheFile); err != nil {
+			log.Errorf("Could not write cache file %s: %s", cacheFile, err)
+		}
+	}
+
+	chartProgress.Finish()
+	if writeSchemas && dryRun && !printOnly {
+		if len(changedCharts) == 0 {
+			log.Infof("No schemas would change")
+		} else {
+			log.Infof("The schemas of %d charts would change: %s", len(changedCharts), strings.Join(changedCharts, ", "))
+		}
+	}
+	if writeSchemas {
+		log.Infof("Processed %d charts: %d generated, %d skipped, %d with errors", processed, generatedCount, skipped, len(failed))
+	}
+
+	if maxErrors > 0 && len(failed) >= maxErrors && processed < len(results) {
+		return generated, fmt.Errorf("stopped after %d charts with errors", len(failed))
+	}
+	if len(failed) > 0 {
+		return generated, errors.New("some errors were found")
+	}
+	return generated, nil
+}
+
+// valuesURL returns the file url of the values file, which relative $refs are resolved against
+func valuesURL(valuesPath string) string {
+	absPath, err := filepath.Abs(valuesPath)
+	if err != nil {
+		return ""
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(absPath)}).String()
+}
+
+// checkOwnValues validates the values file of the result against its schema
+func checkOwnValues(result *schema.Result) error {
+	content, err := os.ReadFile(result.ValuesPath)
+	if err != nil {
+		return err
+	}
+	schemaURL, err := filepath.Abs(result.OutputPath)
+	if err != nil {
+		return err
+	}
+	if err := result.Schema.ValidateValues(schemaURL, content); err != nil {
+		return valuesViolations(err)
+	}
+	return nil
+}
+
+// valuesViolations returns the violations of the schema in an error returned by ValidateValues as a
+// single error listing them with their json pointers
+func valuesViolations(err error) error {
+	violations := []string{}
+	for _, valuesErr := range schema.ValuesErrors(err) {
+		pointer := valuesErr.Pointer
+		if pointer == "" {
+			pointer = "/"
+		}
+		violations = append(violations, pointer+": "+valuesErr.Message)
+	}
+	return errors.New(strings.Join(violations, ", "))
+}
+
+// jsonIndent returns the indentation of the json output from the flags, empty for compact json
+func jsonIndent() (string, error) {
+	indent := viper.GetInt("indent")
+	if indent < 0 {
+		return "", fmt.Errorf("the indentation must not be negative, got %d", indent)
+	}
+	if viper.GetBool("compact") {
+		return "", nil
+	}
+	return strings.Repeat(" ", indent), nil
+}
+
+// schemaContent returns the content of the schema file of the result
+func schemaContent(result *schema.Result, outputFormat, indent string, appendNewline bool) ([]byte, error) {
+	content, err := result.MarshalIndent(outputFormat, indent)
+	if err != nil {
+		return nil, err
+	}
+	if appendNewline && !bytes.HasSuffix(content, []byte("\n")) {
+		content = append(content, '\n')
+	}
+	return content, nil
+}
+
+// logResultErrors logs the errors of reading and generating the schema of the chart of the result
+func logResultErrors(result *schema.Result) {
+	if result.Chart != nil {
+		chartLog(result).Errorf(
+			"Found %d errors while processing the chart %s (%s)",
+			len(result.Errors),
+			result.Chart.Name,
+			result.ChartPath,
+		)
+	} else {
+		chartLog(result).Errorf("Found %d errors while processing the chart %s", len(result.Errors), result.ChartPath)
+	}
+	for _, err := range result.Errors {
+		chartLog(result).Error(err)
+	}
+}
+
+// getMaxErrors returns the number of charts with errors, after which a run stops (0 means never).
+// failFast stops at the first one.
+func getMaxErrors(failFast bool, maxErrors int) (int, error) {
+	if maxErrors < 0 {
+		return 0, fmt.Errorf("the maximum number of errors must not be negative, got %d", maxErrors)
+	}
+	if failFast {
+		return 1, nil
+	}
+	return maxErrors, nil
+}
+
+// getWorkersCount returns the number of workers to start. If the requested
+// count is 0, it is derived from the number of available CPUs.
+func getWorkersCount(requested int) (int, error) {
+	if requested < 0 {
+		return 0, fmt.Errorf("invalid number of workers: %d", requested)
+	}
+	if requested == 0 {
+		return runtime.NumCPU() * 2, nil
+	}
+	return requested, nil
+}
+
+// cacheOptionsHash returns the hash of all options, which could change the generated schemas
+func cacheOptionsHash() (string, error) {
+	settings := viper.AllSettings()
+	for _, key := range []string{"log-level", "log-format", "log-file", "no-progress", "follow-symlinks", "error-on", "fail-fast", "max-errors", "timings", "timings-file", "pprof", "workers", "dry-run", "cache-file", "chart-search-root", "max-search-depth", "prune-dirs", "config", "chart", "charts-file", "selected-chart", "selected-charts", "values-file", "stdout", "stdin", "fail-on-circular", "self-check", "policy-file", "min-description-coverage", "min-type-coverage", "min-title-coverage", "rules"} {
+		delete(settings, key)
+	}
+	settings["version"] = version
+	// the charts reference the shared definitions, so changing them changes the schemas
+	if content, err := os.ReadFile(viper.GetString("defs-file")); err == nil {
+		settings["defs-file"] = schema.Hash(content)
+	}
+
+	// viper lowercases the keys, use the raw ones instead
+	rootKeywords, err := configSection("schema-keywords")
+	if err != nil {
+		return "", err
+	}
+	settings["schema-keywords"] = rootKeywords
+
+	options, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	return schema.Hash(options), nil
+}
+
+// outputHash returns the hash of everything that makes up the final schema of the
+// given result: its own input, the patched conditions and the resolved dependencies
+func outputHash(
+	result *schema.Result,
+	noDeps bool,
+	conditionsToPatch map[string][]string,
+	resolver *dependencyResolver,
+	outputHashes map[string]string,
+) string {
+	parts := [][]byte{[]byte(result.InputHash)}
+	if !noDeps {
+		parts = append(parts, []byte(strings.Join(conditionsToPatch[result.Chart.Name], ".")))
+		for _, dep := range result.Chart.Dependencies {
+			parts = append(parts, []byte(dep.Name))
+			if dependencyResult, ok := resolver.lookup(result, dep); ok {
+				parts = append(parts, []byte(outputHashes[dependencyResult.ChartPath]))
+			} else if packaged, err := resolver.packaged(result, dep); err == nil && packaged != nil {
+				parts = append(parts, packaged.SchemaContent)
+			}
 		}
 	}
-	if foundErrors {
-		return errors.New("some errors were found")
+	return schema.Hash(parts...)
+}
+
+// regenerateSchema replaces the cached schema of the result with a freshly generated one
+func regenerateSchema(
+	result *schema.Result,
+	uncomment, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, bitnamiCompatibilityMode bool,
+	skipConfig *schema.SkipAutoGenerationConfig,
+) error {
+	valuesFile, err := os.Open(result.ValuesPath)
+	if err != nil {
+		return err
+	}
+	defer valuesFile.Close()
+
+	content, err := util.ReadFileAndFixNewline(valuesFile)
+	if err != nil {
+		return err
 	}
+
+	chartConfig, err := skipConfig.ForChart(result.Chart)
+	if err != nil {
+		return err
+	}
+	valuesSchema, err := schema.GenerateSchema(result.ValuesPath, content, uncomment, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, bitnamiCompatibilityMode, chartConfig)
+	if err != nil {
+		return err
+	}
+
+	result.Schema = *valuesSchema
+	result.Cached = false
 	return nil
 }
 
@@ -294,8 +1207,14 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := command.Execute(); err != nil {
+	log.AddHook(warnings)
+	err = command.Execute()
+	stopProfiling()
+	code := exitCode(err)
+	if err != nil {
 		log.Errorf("Execution error: %s", err)
-		os.Exit(1)
+	} else if code == exitWarnings {
+		log.Errorf("Found %d warnings", warnings.Count())
 	}
+	os.Exit(code)
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+// writeFiles writes the files with their contents below dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+	}
+}
+
+// executeCommand runs helm-schema with the arguments in dir, the config is reset afterwards
+func executeCommand(t *testing.T, dir string, args ...string) error {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	viper.Reset()
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		viper.Reset()
+		ruleSeverities = map[string]string{}
+	})
+
+	cmd, err := newCommand(exec)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	cmd.SetArgs(args)
+	return cmd.Execute()
+}
+
+func TestRunSkipsChartsWithFailedDependencies(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"parent/Chart.yaml":  "apiVersion: v2\nname: parent\nversion: 1.0.0\ndependencies:\n  - name: sub\n    version: 1.0.0\n",
+		"parent/values.yaml": "foo: bar\n",
+		// the packaged dependency can't be read
+		"parent/charts/sub-1.0.0.tgz": "not a chart archive",
+	})
+
+	if err := executeCommand(t, dir, "--cache-file", "cache.json"); err == nil {
+		t.Fatal("Expected an error for the unreadable dependency")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "parent", "values.schema.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no schema for the chart with the failed dependency, but got %v", err)
+	}
+	assertNoCacheEntries(t, filepath.Join(dir, "cache.json"))
+}
+
+// assertNoCacheEntries fails the test if the cache file has entries
+func assertNoCacheEntries(t *testing.T, cacheFile string) {
+	t.Helper()
+	content, err := os.ReadFile(cacheFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	var cache schema.Cache
+	if err := json.Unmarshal(content, &cache); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	for chartPath := range cache.Entries {
+		t.Errorf("Expected no cache entry, but got one for %s", chartPath)
+	}
+}
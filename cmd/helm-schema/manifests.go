@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// manifest is a kubernetes object of a manifest file, its spec is decoded depending on its kind
+type manifest struct {
+	// Path is the file containing the manifest
+	Path       string `yaml:"-"`
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec yaml.Node `yaml:"spec"`
+	// Data and StringData are the ones of ConfigMaps and Secrets
+	Data       yaml.Node `yaml:"data"`
+	StringData yaml.Node `yaml:"stringData"`
+}
+
+// group returns the api group of the manifest (e.g. helm.toolkit.fluxcd.io)
+func (m *manifest) group() string {
+	group, _, _ := strings.Cut(m.APIVersion, "/")
+	return group
+}
+
+// String returns the kind, the namespace and the name of the manifest
+func (m *manifest) String() string {
+	if m.Metadata.Namespace == "" {
+		return m.Kind + "/" + m.Metadata.Name
+	}
+	return m.Kind + "/" + m.Metadata.Namespace + "/" + m.Metadata.Name
+}
+
+// value returns the value of the key of a ConfigMap or Secret, the data of Secrets is base64 encoded
+func (m *manifest) value(key string) (string, bool, error) {
+	var data, stringData map[string]string
+	if m.Data.Kind != 0 {
+		if err := m.Data.Decode(&data); err != nil {
+			return "", false, fmt.Errorf("invalid data of %s: %w", m, err)
+		}
+	}
+	if m.StringData.Kind != 0 {
+		if err := m.StringData.Decode(&stringData); err != nil {
+			return "", false, fmt.Errorf("invalid stringData of %s: %w", m, err)
+		}
+	}
+	if value, ok := stringData[key]; ok {
+		return value, true, nil
+	}
+	value, ok := data[key]
+	if !ok || m.Kind != "Secret" {
+		return value, ok, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", false, fmt.Errorf("the key %s of %s isn't base64 encoded: %w", key, m, err)
+	}
+	return string(decoded), true, nil
+}
+
+// readManifests returns the kubernetes objects of the manifest files, - reads stdin. Directories are searched
+// for yaml files (skipping the directories of --prune-dirs), their files which aren't manifests (e.g.
+// templates of charts) are skipped. The files given explicitly must be manifests.
+func readManifests(paths []string) ([]*manifest, error) {
+	manifests := []*manifest{}
+	for _, path := range paths {
+		if path == "-" {
+			content, err := util.ReadFileAndFixNewline(os.Stdin)
+			if err != nil {
+				return nil, err
+			}
+			parsed, err := parseManifests(path, content)
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, parsed...)
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			parsed, err := parseManifests(path, content)
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, parsed...)
+			continue
+		}
+
+		files, err := findFiles(path, func(name string) bool {
+			return filepath.Ext(name) == ".yaml" || filepath.Ext(name) == ".yml"
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, filePath := range files {
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				return nil, err
+			}
+			parsed, err := parseManifests(filePath, content)
+			if err != nil {
+				log.Debugf("Skipping %s: %s", filePath, err)
+				continue
+			}
+			manifests = append(manifests, parsed...)
+		}
+	}
+	return manifests, nil
+}
+
+// parseManifests returns the kubernetes objects of all documents of the file, documents without a kind
+// are skipped
+func parseManifests(path string, content []byte) ([]*manifest, error) {
+	manifests := []*manifest{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		m := &manifest{Path: path}
+		err := decoder.Decode(m)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", path, err)
+		}
+		if m.Kind != "" {
+			manifests = append(manifests, m)
+		}
+	}
+	return manifests, nil
+}
+
+// findManifest returns the manifest with the kind and name in the namespace. Manifests without a namespace
+// (e.g. the ones of kustomize overlays setting it) are found in all namespaces.
+func findManifest(manifests []*manifest, kind, namespace, name string) *manifest {
+	for _, m := range manifests {
+		if m.Kind == kind && m.Metadata.Name == name && (m.Metadata.Namespace == namespace || m.Metadata.Namespace == "" || namespace == "") {
+			return m
+		}
+	}
+	return nil
+}
+
+// findFiles returns the files below the directory with a matching name, the directories of --prune-dirs
+// are skipped
+func findFiles(dir string, match func(name string) bool) ([]string, error) {
+	pruneDirs := viper.GetStringSlice("prune-dirs")
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && slices.Contains(pruneDirs, entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if match(entry.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+const (
+	migrateFromSchema    = "schema"
+	migrateFromQuestions = "questions"
+)
+
+func newMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "write @schema annotations into the values files from existing jsonschemas or questions",
+		Long: `Reads the existing (hand-written) values.schema.json or rancher questions.yaml of every chart
+and writes the keywords as @schema annotations above the matching keys of the chart's values file.
+Keys which are already annotated are left untouched.`,
+		RunE:          migrate,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("from", migrateFromSchema, "source of the annotations, one of (schema, questions)")
+	cmd.Flags().String("source-file", "values.schema.json", "source file relative to each chart directory (default: questions.yaml for questions)")
+
+	return cmd
+}
+
+func migrate(cmd *cobra.Command, _ []string) error {
+	from, _ := cmd.Flags().GetString("from")
+	sourceFile, _ := cmd.Flags().GetString("source-file")
+	dryRun := viper.GetBool("dry-run")
+	chartSearchRoot := viper.GetString("chart-search-root")
+
+	var valueFileNames []string
+	if err := viper.UnmarshalKey("value-files", &valueFileNames); err != nil {
+		return err
+	}
+
+	switch from {
+	case migrateFromSchema:
+	case migrateFromQuestions:
+		if !cmd.Flags().Changed("source-file") {
+			sourceFile = "questions.yaml"
+		}
+	default:
+		return fmt.Errorf("unsupported source %s, use %s or %s", from, migrateFromSchema, migrateFromQuestions)
+	}
+
+	queue := make(chan string)
+	errs := make(chan error)
+	go searchFiles(chartSearchRoot, "Chart.yaml", queue, errs)
+
+	foundErrors := false
+	for {
+		select {
+		case err := <-errs:
+			foundErrors = true
+			log.Error(err)
+			continue
+		case chartPath, ok := <-queue:
+			if !ok {
+				if foundErrors {
+					return errors.New("some errors were found")
+				}
+				return nil
+			}
+			if err := migrateChart(filepath.Dir(chartPath), from, sourceFile, valueFileNames, dryRun); err != nil {
+				foundErrors = true
+				log.Errorf("Could not migrate chart %s: %s", chartPath, err)
+			}
+		}
+	}
+}
+
+// migrateChart annotates the first existing values file of the chart with the keywords of the source file
+func migrateChart(chartDir, from, sourceFile string, valueFileNames []string, dryRun bool) error {
+	sourcePath := filepath.Join(chartDir, sourceFile)
+	sourceContent, err := os.ReadFile(sourcePath)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Debugf("Skipping %s, there is no %s", chartDir, sourceFile)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var source *schema.Schema
+	if from == migrateFromQuestions {
+		source, err = schema.QuestionsToSchema(sourceContent)
+	} else {
+		source = &schema.Schema{}
+		err = json.Unmarshal(sourceContent, source)
+	}
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", sourcePath, err)
+	}
+
+	valuesPath := findValuesFile(chartDir, valueFileNames)
+	if valuesPath == "" {
+		return fmt.Errorf("no values file found (%s)", strings.Join(valueFileNames, ", "))
+	}
+
+	valuesContent, err := os.ReadFile(valuesPath)
+	if err != nil {
+		return err
+	}
+	annotated, missing, err := schema.AnnotateValues(valuesContent, source)
+	if err != nil {
+		return fmt.Errorf("could not annotate %s: %w", valuesPath, err)
+	}
+	for _, path := range missing {
+		log.Warnf("The property %s of %s doesn't exist in %s and can't be annotated", path, sourcePath, valuesPath)
+	}
+
+	if dryRun {
+		log.Infof("Printing annotated values of %s", valuesPath)
+		fmt.Println(string(annotated))
+		return nil
+	}
+	info, err := os.Stat(valuesPath)
+	if err != nil {
+		return err
+	}
+	if err := util.WriteFileAtomic(valuesPath, annotated, info.Mode().Perm()); err != nil {
+		return err
+	}
+	log.Infof("Annotated %s with the keywords of %s", valuesPath, sourcePath)
+	return nil
+}
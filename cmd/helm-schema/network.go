@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/httpclient"
+	"github.com/ojsef39/helm-schema/pkg/store"
+)
+
+// newHTTPClient returns the client of all network operations, configured by the global network flags.
+// With --offline, it serves the documents of the local store instead of accessing the network.
+func newHTTPClient() (*http.Client, error) {
+	if timeout := viper.GetDuration("fetch-timeout"); timeout <= 0 {
+		return nil, fmt.Errorf("the fetch timeout must be positive, got %s", timeout)
+	}
+	if retries := viper.GetInt("fetch-retries"); retries < 0 {
+		return nil, fmt.Errorf("the number of fetch retries must not be negative, got %d", retries)
+	}
+	if concurrency := viper.GetInt("fetch-concurrency"); concurrency < 0 {
+		return nil, fmt.Errorf("the fetch concurrency must not be negative, got %d", concurrency)
+	}
+	if viper.GetBool("offline") {
+		return &http.Client{Transport: localStore().Transport()}, nil
+	}
+	return httpclient.New(httpclient.Options{
+		CAFile:             viper.GetString("ca-file"),
+		InsecureSkipVerify: viper.GetBool("insecure-skip-tls-verify"),
+		RepositoryConfig:   viper.GetString("repository-config"),
+		BearerTokens:       viper.GetStringMapString("bearer-token"),
+		Timeout:            viper.GetDuration("fetch-timeout"),
+		Retries:            viper.GetInt("fetch-retries"),
+		MaxRequestsPerHost: viper.GetInt("fetch-concurrency"),
+	})
+}
+
+// remoteClient returns the client fetching remote $refs while flattening, there's none if they aren't allowed
+func remoteClient(allowRemote bool) (*http.Client, error) {
+	if !allowRemote {
+		return nil, nil
+	}
+	return newHTTPClient()
+}
+
+// localStore returns the store of the remote dependencies and documents
+func localStore() *store.Store {
+	return store.New(viper.GetString("store-dir"))
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// The progress line is redrawn at most every progressInterval and the name of the current chart
+// is shortened to maxProgressNameLength, so the line doesn't wrap
+const (
+	progressInterval      = 100 * time.Millisecond
+	maxProgressNameLength = 60
+)
+
+// progress shows the number of processed charts and the current chart in a single line on stderr. The
+// logs are written above the line. All methods of a nil progress do nothing.
+type progress struct {
+	mu        sync.Mutex
+	out       io.Writer
+	logOut    io.Writer
+	label     string
+	done      int
+	total     int
+	current   string
+	finished  bool
+	drawn     bool
+	lastDrawn time.Time
+}
+
+// newProgress returns the progress of a run, which is only shown if stderr is a terminal, the logs are
+// text and the schemas aren't printed to stdout
+func newProgress(dryRun bool) *progress {
+	if dryRun || viper.GetBool("no-progress") || viper.GetString("log-format") != logFormatText || !isTerminal(os.Stderr) {
+		return nil
+	}
+	p := &progress{out: os.Stderr, logOut: log.StandardLogger().Out}
+	log.SetOutput(&progressLogWriter{progress: p})
+	return p
+}
+
+// isTerminal returns true, if the file is a terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Start starts a phase with the label, a total of 0 means the number of charts isn't known yet
+func (p *progress) Start(label string, total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.label, p.total, p.done, p.current = label, total, 0, ""
+	p.draw(true)
+}
+
+// Step counts the chart as processed and shows it as the current one
+func (p *progress) Step(chart string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.current = chart
+	p.draw(p.done == p.total)
+}
+
+// Finish removes the progress line and writes the logs directly again
+func (p *progress) Finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return
+	}
+	p.clear()
+	log.SetOutput(p.logOut)
+	p.finished = true
+}
+
+// draw redraws the progress line, unless it was redrawn recently and force isn't set
+func (p *progress) draw(force bool) {
+	if p.finished || (!force && time.Since(p.lastDrawn) < progressInterval) {
+		return
+	}
+	line := fmt.Sprintf("%s: %d", p.label, p.done)
+	if p.total > 0 {
+		line = fmt.Sprintf("%s: %d/%d", p.label, p.done, p.total)
+	}
+	if current := []rune(p.current); len(current) > maxProgressNameLength {
+		line += " ..." + string(current[len(current)-maxProgressNameLength:])
+	} else if len(current) > 0 {
+		line += " " + p.current
+	}
+	fmt.Fprintf(p.out, "\r\033[K%s", line)
+	p.drawn = true
+	p.lastDrawn = time.Now()
+}
+
+// clear removes the progress line
+func (p *progress) clear() {
+	if p.drawn {
+		fmt.Fprint(p.out, "\r\033[K")
+		p.drawn = false
+	}
+}
+
+// progressLogWriter writes the logs above the progress line
+type progressLogWriter struct {
+	progress *progress
+}
+
+func (w *progressLogWriter) Write(b []byte) (int, error) {
+	p := w.progress
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	n, err := p.logOut.Write(b)
+	p.draw(true)
+	return n, err
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/registry"
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+func newPublishCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "publish [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "generate the jsonschemas and push them as OCI artifacts",
+		Long: `Generates the jsonschemas and pushes every schema as OCI artifact to <registry>/<chart name>:<tag>.
+The credentials of helm (helm registry login) and docker are used to authenticate.
+With --sign and --attest, every pushed schema is signed and gets a SLSA provenance attestation (with cosign
+or the given commands).`,
+		RunE:          publish,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("registry", "", "OCI repository prefix the jsonschemas are pushed to (e.g. oci://ghcr.io/org/schemas)")
+	cmd.Flags().String("tag", "{{ .Chart.Version }}", "go template of the tag of every pushed jsonschema")
+	cmd.Flags().String("media-type", registry.SchemaMediaType, "media type of the jsonschema layer")
+	cmd.Flags().String("artifact-type", registry.SchemaArtifactType, "artifact type of the pushed manifests")
+	cmd.Flags().String("registry-config", util.HelmRegistryConfig(), "path to the registry credentials file")
+	cmd.Flags().Bool("plain-http", false, "use plain http instead of https to connect to the registry")
+	cmd.Flags().Bool("sign", false, "sign every pushed jsonschema with cosign")
+	cmd.Flags().String("sign-cmd", "", "shell command signing every pushed jsonschema instead of cosign, the reference is passed as HELM_SCHEMA_REF (implies --sign)")
+	cmd.Flags().Bool("attest", false, "attach a SLSA provenance attestation to every pushed jsonschema with cosign")
+	cmd.Flags().String("attest-cmd", "", "shell command attaching the provenance attestation instead of cosign, the predicate file is passed as HELM_SCHEMA_PROVENANCE (implies --attest)")
+
+	return cmd
+}
+
+func publish(cmd *cobra.Command, _ []string) error {
+	registryURL, _ := cmd.Flags().GetString("registry")
+	tagTemplate, _ := cmd.Flags().GetString("tag")
+	mediaType, _ := cmd.Flags().GetString("media-type")
+	artifactType, _ := cmd.Flags().GetString("artifact-type")
+	registryConfig, _ := cmd.Flags().GetString("registry-config")
+	plainHTTP, _ := cmd.Flags().GetBool("plain-http")
+	signOpts := registry.SignOptions{}
+	signOpts.Sign, _ = cmd.Flags().GetBool("sign")
+	signOpts.SignCommand, _ = cmd.Flags().GetString("sign-cmd")
+	signOpts.Attest, _ = cmd.Flags().GetBool("attest")
+	signOpts.AttestCommand, _ = cmd.Flags().GetString("attest-cmd")
+	signOpts.Sign = signOpts.Sign || signOpts.SignCommand != ""
+	signOpts.Attest = signOpts.Attest || signOpts.AttestCommand != ""
+	dryRun := viper.GetBool("dry-run")
+	appendNewline := viper.GetBool("append-newline")
+
+	if registryURL == "" {
+		return errors.New("the --registry flag is required")
+	}
+	if viper.GetBool("offline") {
+		return errors.New("publish needs network access and can't be used with --offline")
+	}
+	// the tag is rendered after the schemas are generated, so invalid templates are rejected before
+	if _, err := template.New("tag").Parse(tagTemplate); err != nil {
+		return usageErrorf("invalid tag template %s: %w", tagTemplate, err)
+	}
+	indent, err := jsonIndent()
+	if err != nil {
+		return err
+	}
+	results, err := run(false)
+	if err != nil {
+		// don't publish an incomplete set of schemas
+		return err
+	}
+
+	client, err := newHTTPClient()
+	if err != nil {
+		return err
+	}
+	opts := registry.Options{RegistryConfig: registryConfig, PlainHTTP: plainHTTP, Client: client}
+	foundErrors := false
+
+	for _, result := range results {
+		ref, err := publishReference(registryURL, tagTemplate, result)
+		if err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not create reference for chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+			continue
+		}
+
+		jsonStr, err := result.Schema.ToJsonIndent(indent)
+		if err != nil {
+			foundErrors = true
+			chartLog(result).Error(err)
+			continue
+		}
+		if appendNewline {
+			jsonStr = append(jsonStr, '\n')
+		}
+
+		if dryRun {
+			chartLog(result).Infof("Would push jsonschema of chart %s (%s) to %s", result.Chart.Name, result.ChartPath, ref)
+			if signOpts.Sign || signOpts.Attest {
+				chartLog(result).Infof("Would sign or attest jsonschema of chart %s (%s) pushed to %s", result.Chart.Name, result.ChartPath, ref)
+			}
+			continue
+		}
+
+		annotations := map[string]string{
+			ocispec.AnnotationTitle:   result.Chart.Name,
+			ocispec.AnnotationVersion: result.Chart.Version,
+		}
+		if result.Chart.Description != "" {
+			annotations[ocispec.AnnotationDescription] = result.Chart.Description
+		}
+
+		digest, err := registry.Push(context.Background(), ref, registry.Artifact{
+			Content:      jsonStr,
+			FileName:     filepath.Base(result.OutputPath),
+			MediaType:    mediaType,
+			ArtifactType: artifactType,
+			Annotations:  annotations,
+		}, opts)
+		if err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not push jsonschema of chart %s (%s) to %s: %s", result.Chart.Name, result.ChartPath, ref, err)
+			continue
+		}
+		chartLog(result).Infof("Pushed jsonschema of chart %s to %s@%s", result.Chart.Name, ref, digest)
+
+		if !signOpts.Sign && !signOpts.Attest {
+			continue
+		}
+		provenance, err := publishProvenance(result, ref, mediaType, artifactType)
+		if err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not create the provenance of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+			continue
+		}
+		env := []string{
+			"HELM_SCHEMA_CHART_NAME=" + result.Chart.Name,
+			"HELM_SCHEMA_CHART_VERSION=" + result.Chart.Version,
+			"HELM_SCHEMA_CHART_PATH=" + result.ChartPath,
+		}
+		if err := registry.Sign(context.Background(), ref, digest, provenance, signOpts, env); err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not sign jsonschema of chart %s (%s) pushed to %s: %s", result.Chart.Name, result.ChartPath, ref, err)
+			continue
+		}
+		chartLog(result).Infof("Signed jsonschema of chart %s pushed to %s@%s", result.Chart.Name, ref, digest)
+	}
+
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// publishProvenance returns the provenance of the schema of the result pushed to the reference, whose
+// materials are the Chart.yaml and values file of the chart
+func publishProvenance(result *schema.Result, ref, mediaType, artifactType string) (registry.Provenance, error) {
+	provenance := registry.Provenance{
+		Parameters: map[string]string{
+			"chart":        result.Chart.Name,
+			"version":      result.Chart.Version,
+			"reference":    registry.TrimScheme(ref),
+			"mediaType":    mediaType,
+			"artifactType": artifactType,
+		},
+		BuilderVersion: version,
+	}
+	for _, file := range []string{result.ChartPath, result.ValuesPath} {
+		if file == "" {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return provenance, err
+		}
+		provenance.Materials = append(provenance.Materials, registry.Material{URI: "file:" + filepath.ToSlash(file), Content: content})
+	}
+	return provenance, nil
+}
+
+// publishReference returns the reference the schema of the result is pushed to
+func publishReference(registryURL, tagTemplate string, result *schema.Result) (string, error) {
+	tag, err := util.RenderTemplate("tag", tagTemplate, result)
+	if err != nil {
+		return "", err
+	}
+	if tag == "" {
+		return "", errors.New("rendered tag is empty")
+	}
+
+	// semver build metadata isn't allowed in tags, helm replaces it the same way
+	normalizedTag := strings.ReplaceAll(tag, "+", "_")
+
+	return path.Join(registry.TrimScheme(registryURL), result.Chart.Name) + ":" + normalizedTag, nil
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+	"github.com/ojsef39/helm-schema/pkg/registry"
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/store"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// releaseChart is the chart of a release, either a local chart directory or a chart in a repository
+type releaseChart struct {
+	// Path is the directory of a local chart
+	Path string
+	// Repository is the url of the chart repository (http, https or oci://) containing the chart with the name
+	Repository string
+	Name       string
+	// Version is a version or a version constraint, the newest version is used without one
+	Version string
+}
+
+func (c releaseChart) String() string {
+	if c.Path != "" {
+		return c.Path
+	}
+	return strings.TrimSuffix(c.Repository, "/") + "/" + c.Name
+}
+
+// releaseValues are values of a release (e.g. a values file or inline values)
+type releaseValues struct {
+	// Source describes where the values are from, e.g. the path of the values file
+	Source  string
+	Content []byte
+}
+
+// release is a release of a chart with its values (e.g. one of a helmfile), which are merged in their order
+// into the values of the chart like helm does
+type release struct {
+	Name string
+	// Source is the file declaring the release
+	Source string
+	Chart  releaseChart
+	Values []releaseValues
+}
+
+// resolvedChart is the schema and the values of the chart of a release
+type resolvedChart struct {
+	Result *schema.Result
+	Values []byte
+	// SchemaURL is used to resolve the relative references of the schema
+	SchemaURL string
+}
+
+// addReleaseFlags adds the flags of the commands validating the values of releases
+func addReleaseFlags(cmd *cobra.Command) {
+	cmd.Flags().String("registry-config", util.HelmRegistryConfig(), "path to the registry credentials file of the OCI registries of the charts")
+	cmd.Flags().Bool("plain-http", false, "use plain http instead of https to connect to the OCI registries of the charts")
+}
+
+// releaseResolver resolves the charts of releases. The schemas of local charts are generated, the ones of
+// remote charts are read from their archives (or generated from their values, if they don't ship one).
+type releaseResolver struct {
+	// local contains the results of the local charts by their absolute directory
+	local        map[string]*schema.Result
+	store        *store.Store
+	registryOpts registry.Options
+	client       *http.Client
+	bufferOpts   *bufferOptions
+	charts       map[string]*resolvedChart
+}
+
+// validateReleases validates the values of the releases against the schemas of their charts. With
+// writeSchemas, the schemas of the local charts are written like by generate.
+func validateReleases(cmd *cobra.Command, releases []*release, writeSchemas bool) error {
+	registryConfig, _ := cmd.Flags().GetString("registry-config")
+	plainHTTP, _ := cmd.Flags().GetBool("plain-http")
+	resolver := &releaseResolver{
+		local:        make(map[string]*schema.Result),
+		store:        localStore(),
+		registryOpts: registry.Options{RegistryConfig: registryConfig, PlainHTTP: plainHTTP},
+		charts:       make(map[string]*resolvedChart),
+	}
+
+	foundErrors, err := resolver.generateLocalCharts(releases, writeSchemas)
+	if err != nil {
+		return err
+	}
+	for _, r := range releases {
+		resolved, err := resolver.resolve(r.Chart)
+		if err != nil {
+			foundErrors = true
+			log.Errorf("Could not resolve the chart %s of release %s (%s): %s", r.Chart, r.Name, r.Source, err)
+			continue
+		}
+		if err := validateRelease(r, resolved); err != nil {
+			foundErrors = true
+			log.Errorf("Values of release %s (%s) are invalid for chart %s: %s", r.Name, r.Source, resolved.Result.Chart.Name, err)
+			continue
+		}
+		log.Infof("Values of release %s (%s) are valid for chart %s", r.Name, r.Source, resolved.Result.Chart.Name)
+	}
+
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// generateLocalCharts generates the schemas of the local charts of the releases. It returns true, if the
+// schema of a chart couldn't be generated, and the errors of invalid options.
+func (r *releaseResolver) generateLocalCharts(releases []*release, writeSchemas bool) (bool, error) {
+	dirs := []string{}
+	for _, rel := range releases {
+		if rel.Chart.Path != "" {
+			dirs = append(dirs, rel.Chart.Path)
+		}
+	}
+	if len(dirs) == 0 {
+		return false, nil
+	}
+	charts, err := chartList(dirs, "")
+	if err != nil {
+		return false, err
+	}
+	viper.Set("selected-charts", charts)
+	results, err := run(writeSchemas)
+	var optionsErr usageError
+	if errors.As(err, &optionsErr) {
+		return false, err
+	}
+	for _, result := range results {
+		dir, absErr := filepath.Abs(filepath.Dir(result.ChartPath))
+		if absErr != nil {
+			return false, absErr
+		}
+		r.local[dir] = result
+	}
+	return err != nil, nil
+}
+
+// resolve returns the schema and the values of the chart
+func (r *releaseResolver) resolve(c releaseChart) (*resolvedChart, error) {
+	if c.Path != "" {
+		dir, err := filepath.Abs(c.Path)
+		if err != nil {
+			return nil, err
+		}
+		result, ok := r.local[dir]
+		if !ok {
+			return nil, errors.New("its schema couldn't be generated")
+		}
+		values, err := os.ReadFile(result.ValuesPath)
+		if err != nil {
+			return nil, err
+		}
+		schemaURL, err := filepath.Abs(result.OutputPath)
+		if err != nil {
+			return nil, err
+		}
+		return &resolvedChart{Result: result, Values: values, SchemaURL: schemaURL}, nil
+	}
+
+	key := strings.Join([]string{c.Repository, c.Name, c.Version}, "|")
+	if resolved, ok := r.charts[key]; ok {
+		return resolved, nil
+	}
+	archive, err := r.fetch(&chart.Dependency{Name: c.Name, Version: c.Version, Repository: c.Repository})
+	if err != nil {
+		return nil, err
+	}
+	packaged, err := schema.ReadChartArchive(archive)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", archive, err)
+	}
+	if packaged == nil {
+		return nil, fmt.Errorf("%s doesn't contain a chart", archive)
+	}
+
+	result := &schema.Result{ChartPath: archive, Chart: packaged.Chart}
+	if packaged.Schema != nil {
+		result.Schema = *packaged.Schema
+	} else {
+		log.Warnf("Chart %s doesn't ship a %s, generating one from its values", c, schema.PublishedSchemaFile)
+		if r.bufferOpts == nil {
+			if r.bufferOpts, err = newBufferOptions(); err != nil {
+				return nil, err
+			}
+		}
+		generated, err := r.bufferOpts.generate("", packaged.Values)
+		if err != nil {
+			return nil, fmt.Errorf("could not generate the schema of its values: %w", err)
+		}
+		result.Schema = generated.Schema
+	}
+	schemaURL, err := filepath.Abs(archive)
+	if err != nil {
+		return nil, err
+	}
+	resolved := &resolvedChart{Result: result, Values: packaged.Values, SchemaURL: schemaURL}
+	r.charts[key] = resolved
+	return resolved, nil
+}
+
+// fetch returns the archive of the chart of the dependency in the local store, after downloading it from its
+// chart repository or pulling it from its OCI registry. With --offline, the store isn't updated.
+func (r *releaseResolver) fetch(dep *chart.Dependency) (string, error) {
+	if !store.IsRemote(dep) && !store.IsOCI(dep) {
+		return "", fmt.Errorf("unsupported repository %s, use a chart repository (http or https) or an OCI registry (oci://)", dep.Repository)
+	}
+	if viper.GetBool("offline") {
+		archive, err := r.store.FindChart(dep)
+		if err == nil && archive == "" {
+			err = fmt.Errorf("there's no version matching %q in the local store %s", dep.Version, r.store.Dir)
+		}
+		return archive, err
+	}
+
+	if r.client == nil {
+		client, err := newHTTPClient()
+		if err != nil {
+			return "", err
+		}
+		r.client = client
+		r.registryOpts.Client = client
+	}
+	if store.IsRemote(dep) {
+		return r.store.Warm(r.client, dep)
+	}
+	version, content, err := registry.PullChart(context.Background(), strings.TrimSuffix(dep.Repository, "/")+"/"+dep.Name, dep.Version, r.registryOpts)
+	if err != nil {
+		return "", err
+	}
+	return r.store.AddChart(dep, version, content)
+}
+
+// validateRelease validates the values of the release merged into the values of its chart
+func validateRelease(r *release, resolved *resolvedChart) error {
+	merged := resolved.Values
+	for _, values := range r.Values {
+		var err error
+		if merged, err = schema.MergeValues(merged, values.Content); err != nil {
+			return fmt.Errorf("could not merge %s: %w", values.Source, err)
+		}
+	}
+	if err := resolved.Result.Schema.ValidateValues(resolved.SchemaURL, merged); err != nil {
+		return valuesViolations(err)
+	}
+	return nil
+}
+
+// splitSetName splits the dotted path of a value (e.g. the name of a value of helmfile's set) into its keys,
+// dots escaped with a backslash are part of the key (e.g. podAnnotations.prometheus\.io/scrape)
+func splitSetName(name string) []string {
+	keys := []string{}
+	var key strings.Builder
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '\\' && i+1 < len(name) && name[i+1] == '.':
+			key.WriteByte('.')
+			i++
+		case name[i] == '.':
+			keys = append(keys, key.String())
+			key.Reset()
+		default:
+			key.WriteByte(name[i])
+		}
+	}
+	return append(keys, key.String())
+}
+
+// setValue sets the value of the keys in the values, creating the missing mappings
+func setValue(values map[string]interface{}, keys []string, value interface{}) {
+	for _, key := range keys[:len(keys)-1] {
+		nested, ok := values[key].(map[string]interface{})
+		if !ok {
+			nested = map[string]interface{}{}
+			values[key] = nested
+		}
+		values = nested
+	}
+	values[keys[len(keys)-1]] = value
+}
+
+// scalarValue returns the value with the type inferred like by helm's --set (e.g. true is a boolean), values
+// which aren't scalars are kept as strings
+func scalarValue(value string) interface{} {
+	var typed interface{}
+	if err := yaml.Unmarshal([]byte(value), &typed); err != nil {
+		return value
+	}
+	switch typed.(type) {
+	case map[string]interface{}, []interface{}, nil:
+		return value
+	}
+	return typed
+}
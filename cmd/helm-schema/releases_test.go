@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+func TestSplitSetName(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+	}{
+		{"replicas", []string{"replicas"}},
+		{"image.tag", []string{"image", "tag"}},
+		// escaped dots are part of the key
+		{`a\.b.c`, []string{"a.b", "c"}},
+		{`podAnnotations.prometheus\.io/scrape`, []string{"podAnnotations", "prometheus.io/scrape"}},
+		// backslashes not escaping a dot are kept
+		{`path.C:\dir`, []string{"path", `C:\dir`}},
+		{`trailing\`, []string{`trailing\`}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, splitSetName(test.name), test.keys, test.name)
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	values := map[string]interface{}{
+		"image":    map[string]interface{}{"repository": "nginx"},
+		"replicas": 1,
+	}
+	setValue(values, []string{"image", "tag"}, "1.0")
+	setValue(values, []string{"ingress", "tls", "enabled"}, true)
+	// values which aren't mappings are replaced
+	setValue(values, []string{"replicas", "min"}, 2)
+
+	assert.Equal(t, values, map[string]interface{}{
+		"image": map[string]interface{}{"repository": "nginx", "tag": "1.0"},
+		"ingress": map[string]interface{}{
+			"tls": map[string]interface{}{"enabled": true},
+		},
+		"replicas": map[string]interface{}{"min": 2},
+	})
+}
+
+func TestScalarValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected interface{}
+	}{
+		{"true", true},
+		{"false", false},
+		{"2", 2},
+		{"1.5", 1.5},
+		{"nginx", "nginx"},
+		{`"1.0"`, "1.0"},
+		// values which aren't scalars are kept as strings
+		{"", ""},
+		{"null", "null"},
+		{"[a, b]", "[a, b]"},
+		{"{a: b}", "{a: b}"},
+		{"a: b", "a: b"},
+		{"{invalid", "{invalid"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, scalarValue(test.value), test.expected, test.value)
+	}
+}
+
+func TestValidateRelease(t *testing.T) {
+	var chartSchema schema.Schema
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"replicas": {"type": "integer", "minimum": 1},
+			"image": {"type": "string"}
+		}
+	}`), &chartSchema); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	resolved := &resolvedChart{
+		Result: &schema.Result{Schema: chartSchema},
+		Values: []byte("replicas: 1\nimage: nginx\n"),
+	}
+
+	tests := []struct {
+		name   string
+		values []string
+		err    string
+	}{
+		{name: "values of the chart"},
+		{name: "valid values", values: []string{"replicas: 2\n", "image: redis\n"}},
+		// the values are merged in their order
+		{name: "overridden invalid value", values: []string{"replicas: 0\n", "replicas: 3\n"}},
+		{name: "invalid value", values: []string{"replicas: 3\n", "replicas: 0\n"}, err: "/replicas: "},
+		{name: "invalid type", values: []string{"image: [nginx]\n"}, err: "/image: "},
+		{name: "values which aren't a mapping", values: []string{"- replicas\n"}, err: "could not merge values-0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &release{Name: "test"}
+			for i, values := range test.values {
+				r.Values = append(r.Values, releaseValues{Source: fmt.Sprintf("values-%d", i), Content: []byte(values)})
+			}
+			err := validateRelease(r, resolved)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("Expected an error containing %q, but got %v", test.err, err)
+			}
+		})
+	}
+}
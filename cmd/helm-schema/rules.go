@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/templates"
+)
+
+// The severities of rules
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityOff     = "off"
+)
+
+// The IDs of the rules of the findings about charts, which aren't reported by policies or lint
+const (
+	ruleMissingDependencySchema = "missing-dependency-schema"
+	ruleDependencyConflict      = "dependency-conflict"
+	ruleUnnamedDependency       = "unnamed-dependency"
+	ruleCircularDependencies    = "circular-dependencies"
+	ruleSelfCheck               = "self-check"
+	ruleInvalidSchema           = "invalid-schema"
+	rulePolicyCommand           = "policy-command"
+	ruleDescriptionCoverage     = "description-coverage"
+	ruleTypeCoverage            = "type-coverage"
+	ruleTitleCoverage           = "title-coverage"
+	ruleInvalidChartFile        = "invalid-chart-file"
+	ruleInvalidValues           = "invalid-values"
+)
+
+// rule is a check of helm-schema with a stable ID, whose severity can be changed in the rules of the config file
+type rule struct {
+	ID       string
+	Severity string
+}
+
+// rules are all rules with their default severities
+var rules = []rule{
+	{ruleMissingDependencySchema, severityWarning},
+	{ruleDependencyConflict, severityWarning},
+	{ruleUnnamedDependency, severityWarning},
+	{ruleCircularDependencies, severityWarning},
+	{ruleSelfCheck, severityError},
+	{ruleInvalidSchema, severityError},
+	{schema.PolicyRuleClosedObjects, severityError},
+	{schema.PolicyRuleDescriptions, severityError},
+	{schema.PolicyRuleTypes, severityError},
+	{schema.PolicyRuleRequire, severityError},
+	{rulePolicyCommand, severityError},
+	{ruleDescriptionCoverage, severityError},
+	{ruleTypeCoverage, severityError},
+	{ruleTitleCoverage, severityError},
+	{templates.FindingUndefined, severityError},
+	{templates.FindingUnused, severityWarning},
+	{templates.FindingTypeConflict, severityError},
+	{templates.FindingUntyped, severityWarning},
+	{ruleInvalidChartFile, severityError},
+	{ruleInvalidValues, severityError},
+	{schema.InconsistencyType, severityWarning},
+	{schema.InconsistencyEnum, severityWarning},
+}
+
+// ruleSeverities are the severities of the rules changed by the config file
+var ruleSeverities = map[string]string{}
+
+// loadRuleSeverities reads the severities of the rules section of the config file
+func loadRuleSeverities() error {
+	section, err := configSection("rules")
+	if err != nil {
+		return err
+	}
+	ruleSeverities = map[string]string{}
+	for id, value := range section {
+		if !slices.ContainsFunc(rules, func(r rule) bool { return r.ID == id }) {
+			return fmt.Errorf("unknown rule %s in the config file, use one of %s", id, strings.Join(ruleIDs(), ", "))
+		}
+		severity, ok := value.(string)
+		if !ok || (severity != severityError && severity != severityWarning && severity != severityOff) {
+			return fmt.Errorf("unsupported severity %v of rule %s in the config file, use %s, %s or %s", value, id, severityError, severityWarning, severityOff)
+		}
+		ruleSeverities[id] = severity
+	}
+	return nil
+}
+
+// ruleIDs returns the IDs of all rules
+func ruleIDs() []string {
+	ids := []string{}
+	for _, r := range rules {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+// ruleSeverity returns the severity of the rule, the one of the config file overrides the default one
+func ruleSeverity(id string) string {
+	if severity, ok := ruleSeverities[id]; ok {
+		return severity
+	}
+	for _, r := range rules {
+		if r.ID == id {
+			return r.Severity
+		}
+	}
+	return severityError
+}
+
+// reportRule logs the finding of the rule with the ID as field rule at the level of its severity, nothing is
+// logged for rules which are off. It returns true if the severity is error, so the finding fails the run.
+func reportRule(entry *log.Entry, id string, format string, args ...interface{}) bool {
+	entry = entry.WithField("rule", id)
+	switch ruleSeverity(id) {
+	case severityOff:
+		entry.Debugf(format, args...)
+		return false
+	case severityWarning:
+		entry.Warnf(format, args...)
+		return false
+	}
+	entry.Errorf(format, args...)
+	return true
+}
+
+// violationRule returns the ID of the rule of the policy violation, all policy commands share one rule
+func violationRule(violation schema.PolicyViolation) string {
+	if slices.Contains(schema.PolicyRules, violation.Rule) || violation.Rule == schema.PolicyRuleRequire {
+		return violation.Rule
+	}
+	return rulePolicyCommand
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/templates"
+)
+
+// withConfigFile loads the config file with the content for the test
+func withConfigFile(t *testing.T, content string) {
+	t.Helper()
+	configFile := filepath.Join(t.TempDir(), ".helm-schema.yaml")
+	if err := os.WriteFile(configFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	viper.Reset()
+	viper.Set("config", configFile)
+	t.Cleanup(func() {
+		viper.Reset()
+		ruleSeverities = map[string]string{}
+	})
+	if err := loadConfigFile(); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+}
+
+func TestLoadRuleSeverities(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		severities map[string]string
+		err        bool
+	}{
+		{
+			name:       "no rules",
+			config:     "format: json\n",
+			severities: map[string]string{},
+		},
+		{
+			name:       "overrides",
+			config:     "rules:\n  unused: error\n  descriptions: warning\n  missing-dependency-schema: off\n",
+			severities: map[string]string{"unused": "error", "descriptions": "warning", "missing-dependency-schema": "off"},
+		},
+		{
+			name:   "unknown rule",
+			config: "rules:\n  unknown: error\n",
+			err:    true,
+		},
+		{
+			name:   "invalid severity",
+			config: "rules:\n  unused: fatal\n",
+			err:    true,
+		},
+		{
+			name:   "severity which isn't a string",
+			config: "rules:\n  unused: true\n",
+			err:    true,
+		},
+		{
+			name:   "rules which aren't a map",
+			config: "rules: [unused]\n",
+			err:    true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			withConfigFile(t, test.config)
+			err := loadRuleSeverities()
+			if test.err {
+				if err == nil {
+					t.Fatalf("Expected an error for the config %q", test.config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+			}
+			assert.Equal(t, ruleSeverities, test.severities)
+		})
+	}
+}
+
+func TestRuleSeverity(t *testing.T) {
+	withConfigFile(t, "rules:\n  unused: error\n  self-check: off\n")
+	if err := loadRuleSeverities(); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	tests := []struct {
+		id       string
+		severity string
+	}{
+		// the defaults
+		{ruleMissingDependencySchema, severityWarning},
+		{ruleInvalidSchema, severityError},
+		{templates.FindingUntyped, severityWarning},
+		{schema.InconsistencyType, severityWarning},
+		// the overrides of the config file
+		{templates.FindingUnused, severityError},
+		{ruleSelfCheck, severityOff},
+		// unknown rules fail the run
+		{"unknown", severityError},
+	}
+	for _, test := range tests {
+		assert.Equal(t, ruleSeverity(test.id), test.severity, test.id)
+	}
+}
+
+func TestViolationRule(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected string
+	}{
+		{schema.PolicyRuleClosedObjects, schema.PolicyRuleClosedObjects},
+		{schema.PolicyRuleDescriptions, schema.PolicyRuleDescriptions},
+		{schema.PolicyRuleTypes, schema.PolicyRuleTypes},
+		{schema.PolicyRuleRequire, schema.PolicyRuleRequire},
+		// the rules of policy commands share one rule
+		{"./check-labels.sh", rulePolicyCommand},
+		{"", rulePolicyCommand},
+	}
+	for _, test := range tests {
+		assert.Equal(t, violationRule(schema.PolicyViolation{Rule: test.rule}), test.expected, test.rule)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+func newSampleCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:         "sample [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "print a sample values file generated from the jsonschema of the chart",
+		Long: `Generates the jsonschema of the chart in the chart search root (without writing it) and prints a values file
+skeleton to stdout. Properties with a default get the default, required properties without a default get a
+placeholder fitting their type and the other properties are commented out. The sample is validated against the
+schema, violations are logged as warnings.`,
+		RunE:          sample,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+}
+
+func sample(_ *cobra.Command, _ []string) error {
+	results, runErr := run(false)
+	rootResult, err := chartSearchRootResult(results, runErr)
+	if err != nil {
+		return err
+	}
+
+	content, err := rootResult.Schema.ToSample()
+	if err != nil {
+		return err
+	}
+	schemaURL, err := filepath.Abs(rootResult.OutputPath)
+	if err != nil {
+		return err
+	}
+	if err := rootResult.Schema.ValidateValues(schemaURL, content); err != nil {
+		for _, valuesErr := range schema.ValuesErrors(err) {
+			log.Warnf("The sample values are invalid at %s: %s", valuesErr.Pointer, valuesErr.Message)
+		}
+	}
+	if _, err := os.Stdout.Write(content); err != nil {
+		return err
+	}
+	if runErr != nil {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// chartSearchRootResult returns the result of the chart in the chart search root. The error
+// of the run is returned, if the chart couldn't be generated.
+func chartSearchRootResult(results []*schema.Result, runErr error) (*schema.Result, error) {
+	chartSearchRoot := viper.GetString("chart-search-root")
+	for _, result := range results {
+		if filepath.Clean(filepath.Dir(result.ChartPath)) == filepath.Clean(chartSearchRoot) {
+			return result, nil
+		}
+	}
+	if runErr != nil {
+		return nil, runErr
+	}
+	return nil, fmt.Errorf("no chart found in %s", chartSearchRoot)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// requestSchemaURL is the url of the schemas given in requests, used in the validation errors
+const requestSchemaURL = "file:///values.schema.json"
+
+func newServeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "serve the generator over http",
+		Long: `Starts a http server, so the schemas can be generated without starting a process per values file.
+The options are the defaults of every request, format and property-order can be overridden with query parameters.
+
+  POST /generate  generates the jsonschema of the values file in the body
+  POST /validate  validates {"values": "<yaml>"} against {"schema": {...}} or the schema
+                  generated from {"chartValues": "<yaml>"}, responds with {"valid": bool, "error": string}
+  GET  /healthz   responds with 200 if the server is running`,
+		RunE:          serve,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("listen", "localhost:8080", "address the server listens on")
+	cmd.Flags().Int64("max-body-size", 10<<20, "maximum size of a request body in bytes")
+
+	return cmd
+}
+
+func serve(cmd *cobra.Command, _ []string) error {
+	listen, _ := cmd.Flags().GetString("listen")
+	maxBodySize, _ := cmd.Flags().GetInt64("max-body-size")
+
+	opts, err := newBufferOptions()
+	if err != nil {
+		return err
+	}
+	workersCount, err := getWorkersCount(viper.GetInt("workers"))
+	if err != nil {
+		return err
+	}
+
+	srv := &server{opts: opts, maxBodySize: maxBodySize, slots: make(chan struct{}, workersCount)}
+	httpServer := &http.Server{
+		Addr:              listen,
+		Handler:           srv.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Infof("Listening on %s", listen)
+	return httpServer.ListenAndServe()
+}
+
+type server struct {
+	opts        *bufferOptions
+	maxBodySize int64
+	// slots limits the number of schemas generated in parallel to the number of workers
+	slots chan struct{}
+}
+
+// handler returns the handler of the endpoints of the server
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /generate", s.handleGenerate)
+	mux.HandleFunc("POST /validate", s.handleValidate)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+type validateRequest struct {
+	Values      string          `json:"values"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+	ChartValues string          `json:"chartValues,omitempty"`
+}
+
+type validateResponse struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// requestOptions returns the options of the server, overridden by the query parameters of the request
+func (s *server) requestOptions(r *http.Request) (*bufferOptions, error) {
+	opts := *s.opts
+	query := r.URL.Query()
+	if format := query.Get("format"); format != "" {
+		opts.Format = format
+	}
+	if propertyOrder := query.Get("property-order"); propertyOrder != "" {
+		opts.PropertyOrder = propertyOrder
+	}
+	if err := opts.check(); err != nil {
+		return nil, err
+	}
+	return &opts, nil
+}
+
+// generate generates the schema of the values, waiting for a free slot
+func (s *server) generate(opts *bufferOptions, content []byte) (*schema.Result, error) {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	return opts.generate("", content)
+}
+
+func (s *server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	opts, err := s.requestOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	content, err := util.ReadFileAndFixNewline(http.MaxBytesReader(w, r.Body, s.maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.generate(opts, content)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not generate the schema: %s", err), http.StatusUnprocessableEntity)
+		return
+	}
+	schemaContent, err := schemaContent(result, opts.Format, opts.Indent, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch opts.Format {
+	case schema.OutputFormatJSON:
+		w.Header().Set("Content-Type", "application/schema+json")
+	case schema.OutputFormatOpenAPI:
+		w.Header().Set("Content-Type", "application/json")
+	case schema.OutputFormatYAML:
+		w.Header().Set("Content-Type", "application/yaml")
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	_, _ = w.Write(schemaContent)
+}
+
+func (s *server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	var request validateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.maxBodySize)).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if (len(request.Schema) == 0) == (request.ChartValues == "") {
+		http.Error(w, "either schema or chartValues is required", http.StatusBadRequest)
+		return
+	}
+
+	schemaJSON := []byte(request.Schema)
+	if request.ChartValues != "" {
+		opts, err := s.requestOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// the values are validated against the jsonschema, independent of the requested format
+		opts.Format = schema.OutputFormatJSON
+		result, err := s.generate(opts, []byte(request.ChartValues))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not generate the schema: %s", err), http.StatusUnprocessableEntity)
+			return
+		}
+		schemaJSON, err = result.Schema.ToJson()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	response := validateResponse{Valid: true}
+	// the references of the schemas of requests must not read the files of the server
+	if err := schema.ValidateUntrustedValuesJSON(schemaJSON, requestSchemaURL, []byte(request.Values)); err != nil {
+		response = validateResponse{Valid: false, Error: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/spf13/viper"
+)
+
+// newTestServer starts the server with the default options and the maximum body size
+func newTestServer(t *testing.T, maxBodySize int64) *httptest.Server {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	// the flags are bound to viper, so the options have their defaults
+	if _, err := newCommand(exec); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	opts, err := newBufferOptions()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	srv := &server{opts: opts, maxBodySize: maxBodySize, slots: make(chan struct{}, 1)}
+	httpServer := httptest.NewServer(srv.handler())
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+// post sends the body to the path and returns the response with its body
+func post(t *testing.T, httpServer *httptest.Server, path, body string) (*http.Response, string) {
+	t.Helper()
+	response, err := http.Post(httpServer.URL+path, "application/octet-stream", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	return response, string(content)
+}
+
+func TestServeGenerate(t *testing.T) {
+	httpServer := newTestServer(t, 1<<20)
+
+	tests := []struct {
+		path        string
+		body        string
+		status      int
+		contentType string
+		contains    string
+	}{
+		{path: "/generate", body: "port: 80\n", status: http.StatusOK, contentType: "application/schema+json", contains: `"port"`},
+		{path: "/generate?format=json", body: "port: 80\n", status: http.StatusOK, contentType: "application/schema+json", contains: `"type": "integer"`},
+		{path: "/generate?format=yaml", body: "port: 80\n", status: http.StatusOK, contentType: "application/yaml", contains: "type: integer"},
+		{path: "/generate?format=openapi", body: "port: 80\n", status: http.StatusOK, contentType: "application/json", contains: `"port"`},
+		{path: "/generate?format=cue", body: "port: 80\n", status: http.StatusOK, contentType: "text/plain; charset=utf-8", contains: "port"},
+		{path: "/generate?format=xml", body: "port: 80\n", status: http.StatusBadRequest},
+		{path: "/generate?property-order=random", body: "port: 80\n", status: http.StatusBadRequest},
+		{path: "/generate", body: "# @schema\n# type: map\n# @schema\nport: 80\n", status: http.StatusUnprocessableEntity, contains: "could not generate the schema"},
+	}
+	for _, test := range tests {
+		response, body := post(t, httpServer, test.path, test.body)
+		assert.Equal(t, response.StatusCode, test.status, test.path)
+		if test.contentType != "" {
+			assert.Equal(t, response.Header.Get("Content-Type"), test.contentType, test.path)
+		}
+		if !strings.Contains(body, test.contains) {
+			t.Errorf("Expected the response of %s to contain %q, but got %s", test.path, test.contains, body)
+		}
+	}
+
+	response, err := http.Get(httpServer.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	response.Body.Close()
+	assert.Equal(t, response.StatusCode, http.StatusOK)
+}
+
+func TestServeValidate(t *testing.T) {
+	httpServer := newTestServer(t, 1<<20)
+
+	tests := []struct {
+		name    string
+		request string
+		status  int
+		valid   bool
+	}{
+		{
+			name:    "valid values of the schema",
+			request: `{"values": "port: 80", "schema": {"properties": {"port": {"type": "integer"}}}}`,
+			status:  http.StatusOK,
+			valid:   true,
+		},
+		{
+			name:    "invalid values of the schema",
+			request: `{"values": "port: x", "schema": {"properties": {"port": {"type": "integer"}}}}`,
+			status:  http.StatusOK,
+		},
+		{
+			name:    "valid values of the chart values",
+			request: `{"values": "port: 8080", "chartValues": "port: 80"}`,
+			status:  http.StatusOK,
+			valid:   true,
+		},
+		{
+			name:    "invalid values of the chart values",
+			request: `{"values": "port: x", "chartValues": "port: 80"}`,
+			status:  http.StatusOK,
+		},
+		{
+			name:    "invalid chart values",
+			request: `{"values": "port: 80", "chartValues": "# @schema\n# type: map\n# @schema\nport: 80"}`,
+			status:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:    "both schema and chart values",
+			request: `{"values": "port: 80", "schema": {}, "chartValues": "port: 80"}`,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "neither schema nor chart values",
+			request: `{"values": "port: 80"}`,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "invalid json",
+			request: `{"values": `,
+			status:  http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, body := post(t, httpServer, "/validate", test.request)
+			assert.Equal(t, response.StatusCode, test.status)
+			if test.status != http.StatusOK {
+				return
+			}
+			assert.Equal(t, response.Header.Get("Content-Type"), "application/json")
+			var result validateResponse
+			if err := json.Unmarshal([]byte(body), &result); err != nil {
+				t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+			}
+			assert.Equal(t, result.Valid, test.valid)
+			assert.Equal(t, result.Error != "", !test.valid)
+		})
+	}
+}
+
+func TestServeMaxBodySize(t *testing.T) {
+	httpServer := newTestServer(t, 64)
+
+	response, _ := post(t, httpServer, "/generate", "port: 80\n")
+	assert.Equal(t, response.StatusCode, http.StatusOK)
+
+	tooLarge := "values: " + strings.Repeat("x", 100) + "\n"
+	response, _ = post(t, httpServer, "/generate", tooLarge)
+	assert.Equal(t, response.StatusCode, http.StatusBadRequest)
+
+	response, _ = post(t, httpServer, "/validate", `{"values": "`+strings.Repeat("x", 100)+`", "schema": {}}`)
+	assert.Equal(t, response.StatusCode, http.StatusBadRequest)
+}
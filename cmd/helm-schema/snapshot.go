@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// defaultSnapshotDir is the directory the snapshots are stored in by default
+const defaultSnapshotDir = "testdata/snapshots"
+
+func newSnapshotCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "snapshot [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "compare the jsonschemas with golden snapshots",
+		Long: `Generates the jsonschemas (without writing them) and compares them with their snapshots in the snapshot
+directory, independent of the committed schema files. The snapshot of a chart is stored below the directory at
+the path of its schema relative to the current directory. Missing snapshots are written, snapshots differing
+from the generated schemas are shown as diffs and fail the run, unless --update overwrites them. With
+--verify-snapshots, no snapshots are written and missing ones fail the run as well, e.g. in CI.`,
+		RunE:          snapshot,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("snapshot-dir", defaultSnapshotDir, "directory the snapshots of the schemas are stored in")
+	cmd.Flags().Bool("verify-snapshots", false, "only verify the snapshots, fail if one is missing or differs from the generated schema")
+	cmd.Flags().Bool("update", false, "overwrite the snapshots differing from the generated schemas")
+
+	return cmd
+}
+
+func snapshot(cmd *cobra.Command, _ []string) error {
+	snapshotDir, _ := cmd.Flags().GetString("snapshot-dir")
+	verify, _ := cmd.Flags().GetBool("verify-snapshots")
+	update, _ := cmd.Flags().GetBool("update")
+	if verify && update {
+		return usageErrorf("--verify-snapshots and --update can't be combined")
+	}
+	outputFormat := viper.GetString("format")
+	appendNewline := viper.GetBool("append-newline")
+	indent, err := jsonIndent()
+	if err != nil {
+		return err
+	}
+	colorDiffs := isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+
+	// compare the charts which could be generated, even if others failed
+	results, runErr := run(false)
+	foundErrors := runErr != nil
+	foundDrift := false
+
+	for _, result := range results {
+		generated, err := schemaContent(result, outputFormat, indent, appendNewline)
+		if err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not serialize schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+			continue
+		}
+		snapshotFile, err := snapshotPath(snapshotDir, result)
+		if err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not find the snapshot of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+			continue
+		}
+
+		existing, err := os.ReadFile(snapshotFile)
+		missing := errors.Is(err, os.ErrNotExist)
+		switch {
+		case err != nil && !missing:
+			foundErrors = true
+			chartLog(result).Errorf("Could not read the snapshot of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+			continue
+		case !missing && bytes.Equal(existing, generated):
+			chartLog(result).Infof("The schema of chart %s matches its snapshot %s", result.Chart.Name, snapshotFile)
+			continue
+		case missing && verify:
+			foundDrift = true
+			chartLog(result).Errorf("The snapshot %s of chart %s is missing", snapshotFile, result.Chart.Name)
+			continue
+		case !missing && !update:
+			foundDrift = true
+			chartLog(result).Errorf("The schema of chart %s differs from its snapshot %s", result.Chart.Name, snapshotFile)
+			fmt.Print(colorizeDiff(util.UnifiedDiff(snapshotFile, result.OutputPath, existing, generated, diffContextLines, false), colorDiffs))
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(snapshotFile), 0o755); err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not create the directory of the snapshot of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+			continue
+		}
+		if err := util.WriteFileAtomic(snapshotFile, generated, 0o644); err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not write the snapshot of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+			continue
+		}
+		if missing {
+			chartLog(result).Infof("Wrote the snapshot %s of chart %s", snapshotFile, result.Chart.Name)
+		} else {
+			chartLog(result).Infof("Updated the snapshot %s of chart %s", snapshotFile, result.Chart.Name)
+		}
+	}
+
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	if foundDrift {
+		return errors.New("some schemas differ from their snapshots")
+	}
+	return nil
+}
+
+// snapshotPath returns the path of the snapshot of the schema of the result: the path of its schema relative
+// to the current directory below the snapshot directory. Schemas outside of the current directory are stored
+// by the name of their chart.
+func snapshotPath(snapshotDir string, result *schema.Result) (string, error) {
+	absOutput, err := filepath.Abs(result.OutputPath)
+	if err != nil {
+		return "", err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	relOutput, err := filepath.Rel(cwd, absOutput)
+	if err != nil || relOutput == ".." || strings.HasPrefix(relOutput, ".."+string(filepath.Separator)) {
+		relOutput = filepath.Join(result.Chart.Name, filepath.Base(result.OutputPath))
+	}
+	return filepath.Join(snapshotDir, relOutput), nil
+}
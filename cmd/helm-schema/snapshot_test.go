@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+func TestSnapshot(t *testing.T) {
+	const staleSnapshot = "{}\n"
+	snapshotFile := filepath.Join("testdata", "snapshots", "web", "values.schema.json")
+
+	tests := []struct {
+		name string
+		// snapshot is the content of the existing snapshot, none is missing
+		snapshot string
+		args     []string
+		err      bool
+		// written is true, if the snapshot is the generated schema afterwards
+		written bool
+	}{
+		{name: "missing snapshot", written: true},
+		{name: "missing snapshot with --verify-snapshots", args: []string{"--verify-snapshots"}, err: true},
+		{name: "drift", snapshot: staleSnapshot, err: true},
+		{name: "drift with --verify-snapshots", snapshot: staleSnapshot, args: []string{"--verify-snapshots"}, err: true},
+		{name: "drift with --update", snapshot: staleSnapshot, args: []string{"--update"}, written: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"web/Chart.yaml":  "apiVersion: v2\nname: web\nversion: 1.0.0\n",
+				"web/values.yaml": "port: 80\n",
+			})
+			if test.snapshot != "" {
+				writeFiles(t, dir, map[string]string{snapshotFile: test.snapshot})
+			}
+
+			err := executeCommand(t, dir, append([]string{"snapshot"}, test.args...)...)
+			if test.err && err == nil {
+				t.Fatal("Expected an error, but got none")
+			}
+			if !test.err && err != nil {
+				t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(dir, snapshotFile))
+			switch {
+			case test.written:
+				if err != nil {
+					t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+				}
+				if !strings.Contains(string(content), `"port"`) {
+					t.Errorf("Expected the snapshot to be the generated schema, but got %s", content)
+				}
+			case test.snapshot == "":
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("Expected no snapshot, but got %v", err)
+				}
+			default:
+				assert.Equal(t, string(content), test.snapshot)
+			}
+			// the schema files aren't written
+			if _, err := os.Stat(filepath.Join(dir, "web", "values.schema.json")); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Expected no schema file, but got %v", err)
+			}
+		})
+	}
+
+	t.Run("matching snapshot", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"web/Chart.yaml":  "apiVersion: v2\nname: web\nversion: 1.0.0\n",
+			"web/values.yaml": "port: 80\n",
+		})
+		if err := executeCommand(t, dir, "snapshot"); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		if err := executeCommand(t, dir, "snapshot", "--verify-snapshots"); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+	})
+
+	t.Run("--verify-snapshots with --update", func(t *testing.T) {
+		err := executeCommand(t, t.TempDir(), "snapshot", "--verify-snapshots", "--update")
+		var usageErr usageError
+		if !errors.As(err, &usageErr) {
+			t.Fatalf("Expected a usage error, but got %v", err)
+		}
+	})
+}
+
+func TestSnapshotPath(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "charts", "web", "values.schema.json")
+
+	tests := []struct {
+		outputPath string
+		expected   string
+	}{
+		// the path of the schema relative to the current directory
+		{filepath.Join("charts", "web", "values.schema.json"), filepath.Join("snapshots", "charts", "web", "values.schema.json")},
+		{filepath.Join("charts", "web", "schema.yaml"), filepath.Join("snapshots", "charts", "web", "schema.yaml")},
+		// schemas outside of the current directory are stored by the name of their chart
+		{outside, filepath.Join("snapshots", "web", "values.schema.json")},
+		{filepath.Join("..", "web", "values.schema.json"), filepath.Join("snapshots", "web", "values.schema.json")},
+	}
+	for _, test := range tests {
+		path, err := snapshotPath("snapshots", &schema.Result{OutputPath: test.outputPath, Chart: &chart.ChartFile{Name: "web"}})
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		assert.Equal(t, path, test.expected, test.outputPath)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+// chartStats are the statistics of the schema of a chart
+type chartStats struct {
+	Chart     string `json:"chart"`
+	ChartPath string `json:"chartPath"`
+	schema.Stats
+	// Bytes is the size of the schema file
+	Bytes        int               `json:"bytes"`
+	Dependencies []dependencyStats `json:"dependencies"`
+}
+
+// dependencyStats are the statistics of the part of the schema of a chart, which a dependency contributes
+type dependencyStats struct {
+	// Key is the alias or the name of the dependency
+	Key string `json:"key"`
+	schema.Stats
+	// Bytes is the size of the compact json of the schema of the dependency
+	Bytes int `json:"bytes"`
+}
+
+func newStatsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "stats [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "print statistics of the jsonschemas",
+		Long: `Generates the jsonschemas (without writing them) and prints per chart the number of properties, the
+depth of the nested properties, the number of required properties, the typed and untyped leaf values and the
+size of the schema file. The parts of the schemas contributed by the dependencies are broken down below their
+charts. The sizes of the dependencies are the ones of their compact json.`,
+		RunE:          stats,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("report", reportFormatText, "format of the report, one of (text, json)")
+
+	return cmd
+}
+
+func stats(cmd *cobra.Command, _ []string) error {
+	report, _ := cmd.Flags().GetString("report")
+	if report != reportFormatText && report != reportFormatJSON {
+		return usageErrorf("unsupported report format %s, use %s or %s", report, reportFormatText, reportFormatJSON)
+	}
+	outputFormat := viper.GetString("format")
+	appendNewline := viper.GetBool("append-newline")
+	indent, err := jsonIndent()
+	if err != nil {
+		return err
+	}
+
+	// report the charts which could be generated, even if others failed
+	results, runErr := run(false)
+	foundErrors := runErr != nil
+
+	allStats := []chartStats{}
+	for _, result := range results {
+		content, err := schemaContent(result, outputFormat, indent, appendNewline)
+		if err != nil {
+			foundErrors = true
+			chartLog(result).Errorf("Could not serialize schema of chart %s (%s): %s", result.Chart.Name, result.ChartPath, err)
+			continue
+		}
+		resultStats := chartStats{
+			Chart:        result.Chart.Name,
+			ChartPath:    result.ChartPath,
+			Stats:        result.Schema.Stats(),
+			Bytes:        len(content),
+			Dependencies: []dependencyStats{},
+		}
+		for _, dep := range result.Chart.Dependencies {
+			key := schema.DependencyKey(dep)
+			depSchema, ok := result.Schema.Properties[key]
+			if !ok {
+				continue
+			}
+			depContent, err := json.Marshal(depSchema)
+			if err != nil {
+				foundErrors = true
+				chartLog(result).Errorf("Could not serialize the schema of dependency %s of chart %s (%s): %s", key, result.Chart.Name, result.ChartPath, err)
+				continue
+			}
+			resultStats.Dependencies = append(resultStats.Dependencies, dependencyStats{Key: key, Stats: depSchema.Stats(), Bytes: len(depContent)})
+		}
+		allStats = append(allStats, resultStats)
+	}
+
+	if err := writeStatsReport(os.Stdout, report, allStats); err != nil {
+		return err
+	}
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// writeStatsReport writes the statistics as json or as table with the dependencies below their charts
+func writeStatsReport(w io.Writer, format string, allStats []chartStats) error {
+	if format == reportFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(allStats)
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CHART\tPROPERTIES\tDEPTH\tREQUIRED\tTYPED\tUNTYPED\tBYTES")
+	row := func(name string, s schema.Stats, bytes string) {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n", name, s.Properties, s.Depth, s.Required, s.Typed, s.Untyped, bytes)
+	}
+	for _, chart := range allStats {
+		row(chart.Chart, chart.Stats, fmt.Sprint(chart.Bytes))
+		for _, dep := range chart.Dependencies {
+			share := 0.0
+			if chart.Bytes > 0 {
+				share = float64(dep.Bytes) * 100 / float64(chart.Bytes)
+			}
+			row("  "+dep.Key, dep.Stats, fmt.Sprintf("%d (%.1f%%)", dep.Bytes, share))
+		}
+	}
+	return table.Flush()
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+	"golang.org/x/text/language"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/store"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// stdinChartName is the name of the chart the values read from stdin belong to
+const stdinChartName = "chart"
+
+// bufferOptions are the options used to generate the schemas of values which aren't
+// read from a chart directory (from stdin, the body of a request or an editor buffer)
+type bufferOptions struct {
+	Uncomment                 bool
+	KeepFullComment           bool
+	HelmDocsCompatibilityMode bool
+	DontRemoveHelmDocsPrefix  bool
+	BitnamiCompatibilityMode  bool
+	SkipConfig                *schema.SkipAutoGenerationConfig
+	PropertyOrder             string
+	AddOrderHint              bool
+	SchemaURI                 string
+	SchemaId                  string
+	RootKeywords              map[string]interface{}
+	PostProcessCmd            string
+	Format                    string
+	DetectSensitive           bool
+	ExcludeValues             []string
+	KeepRequired              []string
+	DependencySchemas         string
+	DependencyMerge           string
+	MaxDepth                  int
+	DescriptionFormat         string
+	Lang                      string
+	SharedDefs                map[string]*schema.Schema
+	DefsMode                  string
+	Dedupe                    bool
+	Flatten                   bool
+	// Store contains the charts of remote dependencies and the remote documents for --offline
+	Store *store.Store
+	// FlattenClient fetches the remote documents while flattening, there's none if they aren't allowed
+	FlattenClient *http.Client
+	Indent        string
+}
+
+// newBufferOptions reads the options from the flags and the config file
+func newBufferOptions() (*bufferOptions, error) {
+	var skipAutoGeneration []string
+
+	rootKeywords, err := configSection("schema-keywords")
+	if err != nil {
+		return nil, err
+	}
+	if err := viper.UnmarshalKey("skip-auto-generation", &skipAutoGeneration); err != nil {
+		return nil, err
+	}
+	skipConfig, err := schema.NewSkipAutoGenerationConfig(skipAutoGeneration)
+	if err != nil {
+		return nil, err
+	}
+	skipConfig.RequiredMode = viper.GetString("required-mode")
+	if err := schema.ValidateRequiredMode(skipConfig.RequiredMode); err != nil {
+		return nil, err
+	}
+	sharedDefs, err := schema.ReadSharedDefs(viper.GetString("defs-file"))
+	if err != nil {
+		return nil, err
+	}
+
+	indent, err := jsonIndent()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &bufferOptions{
+		Uncomment:                 viper.GetBool("uncomment"),
+		KeepFullComment:           viper.GetBool("keep-full-comment"),
+		HelmDocsCompatibilityMode: viper.GetBool("helm-docs-compatibility-mode"),
+		DontRemoveHelmDocsPrefix:  viper.GetBool("dont-strip-helm-docs-prefix"),
+		BitnamiCompatibilityMode:  viper.GetBool("bitnami-compatibility-mode"),
+		SkipConfig:                skipConfig,
+		PropertyOrder:             viper.GetString("property-order"),
+		AddOrderHint:              viper.GetBool("add-x-order"),
+		SchemaURI:                 viper.GetString("schema-uri"),
+		SchemaId:                  viper.GetString("schema-id"),
+		RootKeywords:              rootKeywords,
+		PostProcessCmd:            viper.GetString("post-process-cmd"),
+		Format:                    viper.GetString("format"),
+		DetectSensitive:           viper.GetBool("detect-sensitive"),
+		ExcludeValues:             viper.GetStringSlice("exclude-values"),
+		KeepRequired:              viper.GetStringSlice("keep-required"),
+		DependencySchemas:         viper.GetString("dependency-schemas"),
+		DependencyMerge:           viper.GetString("dependency-merge"),
+		MaxDepth:                  viper.GetInt("max-depth"),
+		DescriptionFormat:         viper.GetString("description-format"),
+		Lang:                      viper.GetString("lang"),
+		SharedDefs:                sharedDefs,
+		DefsMode:                  viper.GetString("defs-mode"),
+		Dedupe:                    viper.GetBool("dedupe"),
+		Flatten:                   viper.GetBool("flatten"),
+		Indent:                    indent,
+	}
+	if err := opts.check(); err != nil {
+		return nil, err
+	}
+	opts.Store = localStore()
+	if opts.FlattenClient, err = remoteClient(viper.GetBool("flatten-remote")); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// check returns an error if one of the options has an unsupported value
+func (o *bufferOptions) check() error {
+	if !slices.Contains(schema.OutputFormats, o.Format) {
+		return fmt.Errorf("unsupported output format %s, use one of %s", o.Format, strings.Join(schema.OutputFormats, ", "))
+	}
+	if o.PropertyOrder != schema.PropertyOrderAlpha && o.PropertyOrder != schema.PropertyOrderSource {
+		return fmt.Errorf("unsupported property order %s, use %s or %s", o.PropertyOrder, schema.PropertyOrderAlpha, schema.PropertyOrderSource)
+	}
+	if o.DescriptionFormat != schema.DescriptionFormatMarkdown && o.DescriptionFormat != schema.DescriptionFormatPlain {
+		return fmt.Errorf("unsupported description format %s, use %s or %s", o.DescriptionFormat, schema.DescriptionFormatMarkdown, schema.DescriptionFormatPlain)
+	}
+	if err := schema.ValidateExcludePatterns(o.ExcludeValues); err != nil {
+		return err
+	}
+	if !slices.Contains(schema.DependencySchemasModes, o.DependencySchemas) {
+		return fmt.Errorf("unsupported dependency schemas %s, use one of %s", o.DependencySchemas, strings.Join(schema.DependencySchemasModes, ", "))
+	}
+	if o.MaxDepth < 0 {
+		return fmt.Errorf("the maximum depth of dependencies must not be negative, got %d", o.MaxDepth)
+	}
+	if !slices.Contains(schema.DependencyMergeStrategies, o.DependencyMerge) {
+		return fmt.Errorf("unsupported dependency merge strategy %s, use one of %s", o.DependencyMerge, strings.Join(schema.DependencyMergeStrategies, ", "))
+	}
+	if _, err := language.Parse(o.Lang); o.Lang != "" && err != nil {
+		return fmt.Errorf("invalid language %s: %w", o.Lang, err)
+	}
+	if o.Dedupe && o.Flatten {
+		return errors.New("--dedupe and --flatten can't be combined")
+	}
+	if o.DefsMode != schema.DefsModeBundle && o.DefsMode != schema.DefsModeInline {
+		return fmt.Errorf("unsupported defs mode %s, use %s or %s", o.DefsMode, schema.DefsModeBundle, schema.DefsModeInline)
+	}
+	return nil
+}
+
+// generate returns the result with the schema of the values. Without a values path, nothing
+// is read from the filesystem, so the sidecar annotations, the schema patch file and refs
+// relative to the values file aren't supported.
+func (o *bufferOptions) generate(valuesPath string, content []byte) (*schema.Result, error) {
+	valuesSchema, err := o.generateValues(valuesPath, content)
+	if err != nil {
+		return nil, err
+	}
+	if valuesPath != "" {
+		if err := valuesSchema.ApplySchemaPatchFile(filepath.Dir(valuesPath)); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &schema.Result{
+		Chart:  &chart.ChartFile{Name: stdinChartName},
+		Schema: *valuesSchema,
+	}
+	result.Schema.ExcludeValues(o.ExcludeValues)
+	if o.SharedDefs != nil {
+		if err := result.Schema.ResolveSharedDefs(o.SharedDefs, o.DefsMode); err != nil {
+			return nil, err
+		}
+	}
+	if o.Lang != "" {
+		if err := result.Schema.Localize(o.Lang); err != nil {
+			return nil, err
+		}
+	}
+	result.Schema.FormatDescriptions(o.DescriptionFormat)
+	result.Schema.MaskSensitive(o.DetectSensitive)
+	result.Schema.ApplyPropertyOrder(o.PropertyOrder, o.AddOrderHint)
+	result.Schema.ApplyRootKeywords(o.SchemaURI, o.RootKeywords)
+	if o.Dedupe {
+		deduped, _, err := result.Schema.Dedupe()
+		if err != nil {
+			return nil, err
+		}
+		result.Schema = *deduped
+	}
+	if o.Flatten {
+		base := ""
+		if valuesPath != "" {
+			base = valuesURL(valuesPath)
+		}
+		if err := result.Schema.Flatten(base, o.FlattenClient); err != nil {
+			return nil, err
+		}
+	}
+	if err := result.ApplyChartMetadata(o.SchemaId, false); err != nil {
+		return nil, err
+	}
+	if o.PostProcessCmd != "" {
+		if err := result.PostProcess(o.PostProcessCmd); err != nil {
+			return nil, err
+		}
+	}
+	if err := result.Schema.ValidateMetaschema(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// generateValues returns the schema generated from the values only, without applying the schema patch file
+func (o *bufferOptions) generateValues(valuesPath string, content []byte) (*schema.Schema, error) {
+	return schema.GenerateSchema(
+		valuesPath,
+		content,
+		o.Uncomment,
+		o.KeepFullComment,
+		o.HelmDocsCompatibilityMode,
+		o.DontRemoveHelmDocsPrefix,
+		o.BitnamiCompatibilityMode,
+		o.SkipConfig,
+	)
+}
+
+// generateFromStdin generates the schema of the values read from stdin and writes it to stdout
+func generateFromStdin(stdin io.Reader, stdout io.Writer) error {
+	if viper.GetString("selected-chart") != "" || len(viper.GetStringSlice("selected-charts")) > 0 || viper.GetString("values-file") != "" {
+		return errors.New("--stdin can't be used together with a chart or --values-file")
+	}
+	opts, err := newBufferOptions()
+	if err != nil {
+		return err
+	}
+
+	content, err := util.ReadFileAndFixNewline(stdin)
+	if err != nil {
+		return err
+	}
+	result, err := opts.generate("", content)
+	if err != nil {
+		return err
+	}
+
+	schemaJSON, err := schemaContent(result, opts.Format, opts.Indent, true)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(schemaJSON)
+	return err
+}
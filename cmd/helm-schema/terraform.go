@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// terraformHelmRelease is the resource type of the releases of the terraform helm provider
+const terraformHelmRelease = "helm_release"
+
+// terraformShow is the json output of terraform show -json of a plan or of the state
+type terraformShow struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Type    string `json:"type"`
+		Change  struct {
+			Actions []string                `json:"actions"`
+			After   *terraformReleaseValues `json:"after"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+	Values *struct {
+		RootModule terraformModule `json:"root_module"`
+	} `json:"values"`
+}
+
+type terraformModule struct {
+	Resources []struct {
+		Address string                  `json:"address"`
+		Type    string                  `json:"type"`
+		Values  *terraformReleaseValues `json:"values"`
+	} `json:"resources"`
+	ChildModules []terraformModule `json:"child_modules"`
+}
+
+// terraformReleaseValues are the arguments of a helm_release
+type terraformReleaseValues struct {
+	Chart        string         `json:"chart"`
+	Repository   string         `json:"repository"`
+	Version      string         `json:"version"`
+	Values       []*string      `json:"values"`
+	Set          []terraformSet `json:"set"`
+	SetList      []terraformSet `json:"set_list"`
+	SetSensitive []terraformSet `json:"set_sensitive"`
+}
+
+// terraformSet is a value of set, set_list or set_sensitive, its type string keeps the value a string
+type terraformSet struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+	Type  string      `json:"type"`
+}
+
+func newTerraformCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "terraform [plan.json]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "validate the values of terraform helm_releases against the jsonschemas of their charts",
+		Long: `Reads the helm_release resources of the json output of terraform show -json (of a plan or the state,
+stdin without an argument) and validates their rendered values against the schemas of their charts. The values,
+set, set_list and set_sensitive arguments are merged in this order into the values of the chart like the helm
+provider does. Local charts are relative to --source-root, the charts of repositories and OCI registries are
+downloaded into the local store.`,
+		RunE:          validateTerraform,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("source-root", ".", "directory of the terraform configuration, the paths of local charts are relative to it")
+	addReleaseFlags(cmd)
+
+	return cmd
+}
+
+func validateTerraform(cmd *cobra.Command, args []string) error {
+	sourceRoot, _ := cmd.Flags().GetString("source-root")
+	planPath := "-"
+	if len(args) > 0 {
+		planPath = args[0]
+	}
+
+	var content []byte
+	var err error
+	if planPath == "-" {
+		content, err = util.ReadFileAndFixNewline(os.Stdin)
+	} else {
+		content, err = os.ReadFile(planPath)
+	}
+	if err != nil {
+		return err
+	}
+	releases, err := readTerraformReleases(planPath, content, sourceRoot)
+	if err != nil {
+		return err
+	}
+	if len(releases) == 0 {
+		log.Warnf("No helm_release resources found in %s", planPath)
+		return nil
+	}
+	return validateReleases(cmd, releases, false)
+}
+
+// readTerraformReleases returns the releases of the helm_release resources, which aren't deleted by the plan.
+// Without resource changes (e.g. for the state), the resources of all modules are used.
+func readTerraformReleases(planPath string, content []byte, sourceRoot string) ([]*release, error) {
+	var show terraformShow
+	if err := json.Unmarshal(content, &show); err != nil {
+		return nil, fmt.Errorf("could not parse %s, use the output of terraform show -json: %w", planPath, err)
+	}
+
+	resources := map[string]*terraformReleaseValues{}
+	addresses := []string{}
+	for _, change := range show.ResourceChanges {
+		if change.Type != terraformHelmRelease || change.Change.After == nil || slices.Equal(change.Change.Actions, []string{"delete"}) {
+			continue
+		}
+		resources[change.Address] = change.Change.After
+		addresses = append(addresses, change.Address)
+	}
+	if len(show.ResourceChanges) == 0 && show.Values != nil {
+		modules := []terraformModule{show.Values.RootModule}
+		for len(modules) > 0 {
+			module := modules[0]
+			modules = append(modules[1:], module.ChildModules...)
+			for _, resource := range module.Resources {
+				if resource.Type == terraformHelmRelease && resource.Values != nil {
+					resources[resource.Address] = resource.Values
+					addresses = append(addresses, resource.Address)
+				}
+			}
+		}
+	}
+
+	releases := []*release{}
+	for _, address := range addresses {
+		r, err := resources[address].release(address, planPath, sourceRoot)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in %s: %w", address, planPath, err)
+		}
+		releases = append(releases, r)
+	}
+	return releases, nil
+}
+
+// release returns the release of the helm_release with its values
+func (t *terraformReleaseValues) release(address, planPath, sourceRoot string) (*release, error) {
+	r := &release{Name: address, Source: planPath}
+	switch {
+	case t.Chart == "":
+		return nil, errors.New("the helm_release has no chart, its value is unknown before applying")
+	case strings.HasPrefix(t.Chart, "oci://"):
+		repository, name, found := splitChartReference(t.Chart)
+		if !found {
+			return nil, fmt.Errorf("the chart %s has no registry and repository (oci://registry/repository/chart)", t.Chart)
+		}
+		r.Chart = releaseChart{Repository: repository, Name: name, Version: t.Version}
+	case t.Repository != "":
+		r.Chart = releaseChart{Repository: t.Repository, Name: t.Chart, Version: t.Version}
+	default:
+		chartPath := filepath.FromSlash(t.Chart)
+		if !filepath.IsAbs(chartPath) {
+			chartPath = filepath.Join(sourceRoot, chartPath)
+		}
+		r.Chart = releaseChart{Path: chartPath}
+	}
+
+	for i, values := range t.Values {
+		if values == nil {
+			log.Warnf("Skipping the values %d of %s, they're unknown before applying", i+1, address)
+			continue
+		}
+		r.Values = append(r.Values, releaseValues{Source: fmt.Sprintf("values %d", i+1), Content: []byte(*values)})
+	}
+
+	for _, argument := range []struct {
+		name   string
+		values []terraformSet
+	}{{"set", t.Set}, {"set_list", t.SetList}, {"set_sensitive", t.SetSensitive}} {
+		if len(argument.values) == 0 {
+			continue
+		}
+		values := map[string]interface{}{}
+		for _, set := range argument.values {
+			if set.Name == "" {
+				return nil, fmt.Errorf("a value of %s has no name", argument.name)
+			}
+			if set.Value == nil {
+				log.Warnf("Skipping %s of %s, its value is unknown before applying", set.Name, address)
+				continue
+			}
+			value := set.Value
+			if stringValue, ok := value.(string); ok && set.Type != "string" {
+				value = scalarValue(stringValue)
+			}
+			setValue(values, splitSetName(set.Name), value)
+		}
+		content, err := yaml.Marshal(values)
+		if err != nil {
+			return nil, err
+		}
+		r.Values = append(r.Values, releaseValues{Source: argument.name, Content: content})
+	}
+	return r, nil
+}
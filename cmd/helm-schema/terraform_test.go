@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestReadTerraformReleases(t *testing.T) {
+	tests := []struct {
+		file     string
+		releases []*release
+		err      bool
+	}{
+		{
+			file: "plan.json",
+			releases: []*release{
+				{
+					Name:  "helm_release.web",
+					Chart: releaseChart{Path: filepath.Join("root", "charts", "web")},
+					Values: []releaseValues{
+						// the unknown values are skipped
+						{Source: "values 1", Content: []byte("replicas: 2\n")},
+						{Source: "set", Content: []byte("image:\n    tag: \"1.0\"\npodAnnotations:\n    prometheus.io/scrape: true\nreplicas: 3\n")},
+						{Source: "set_list", Content: []byte("hosts:\n    - a.example.com\n    - b.example.com\n")},
+						{Source: "set_sensitive", Content: []byte("auth:\n    password: secret\n")},
+					},
+				},
+				{
+					Name:  "helm_release.redis",
+					Chart: releaseChart{Repository: "https://charts.bitnami.com/bitnami", Name: "redis", Version: "18.0.0"},
+				},
+				{
+					// replaced releases aren't deleted
+					Name:  "module.db.helm_release.postgresql",
+					Chart: releaseChart{Repository: "oci://registry-1.docker.io/bitnamicharts", Name: "postgresql", Version: "15.x"},
+				},
+			},
+		},
+		{
+			// the state has no resource changes, the resources of all modules are used
+			file: "state.json",
+			releases: []*release{
+				{
+					Name:  "helm_release.web",
+					Chart: releaseChart{Path: filepath.FromSlash("/charts/web")},
+				},
+				{
+					Name:  "module.db.helm_release.postgresql",
+					Chart: releaseChart{Repository: "https://charts.bitnami.com/bitnami", Name: "postgresql", Version: "15.0.0"},
+				},
+			},
+		},
+		{file: "unknown-chart.json", err: true},
+		{file: "invalid-oci-chart.json", err: true},
+		{file: "unnamed-set.json", err: true},
+		{file: "invalid.json", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			planPath := filepath.Join("testdata", "terraform", test.file)
+			content, err := os.ReadFile(planPath)
+			if err != nil {
+				t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+			}
+			releases, err := readTerraformReleases(planPath, content, "root")
+			if test.err {
+				if err == nil {
+					t.Fatalf("Expected an error for %s", test.file)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+			}
+			for _, r := range test.releases {
+				r.Source = planPath
+			}
+			assert.Equal(t, releases, test.releases)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+// defaultFixturesGlob matches the values files helm's chart-testing installs the chart with
+const defaultFixturesGlob = "ci/*-values.yaml"
+
+// fixtureResult is the validation result of a fixture
+type fixtureResult struct {
+	Chart   string
+	Fixture string
+	Errors  []schema.ValuesError
+}
+
+func newTestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "test [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "validate the fixture values files of the charts against the generated jsonschemas",
+		Long: `Generates the jsonschemas (without writing them) and validates every fixture of a chart against its schema.
+The fixtures are the values files matching the glob relative to the chart directory (by default ci/*-values.yaml,
+the ones chart-testing installs the chart with). Like helm, the fixtures are merged into the values file of the
+chart before they are validated. The failures are reported per fixture.`,
+		RunE:          testFixtures,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().String("fixtures", defaultFixturesGlob, "glob of the fixture values files relative to the chart directory")
+
+	return cmd
+}
+
+func testFixtures(cmd *cobra.Command, _ []string) error {
+	fixturesGlob, _ := cmd.Flags().GetString("fixtures")
+	if _, err := filepath.Match(fixturesGlob, ""); err != nil {
+		return fmt.Errorf("invalid fixtures glob %s: %w", fixturesGlob, err)
+	}
+
+	// test the charts which could be generated, even if others failed
+	results, runErr := run(false)
+	foundErrors := runErr != nil
+
+	fixtureResults := []fixtureResult{}
+	for _, result := range results {
+		if filepath.Base(result.ChartPath) != "Chart.yaml" {
+			continue
+		}
+		fixtures, err := filepath.Glob(filepath.Join(filepath.Dir(result.ChartPath), fixturesGlob))
+		if err != nil {
+			return err
+		}
+		if len(fixtures) == 0 {
+			chartLog(result).Debugf("Skipping %s, there are no fixtures matching %s", result.ChartPath, fixturesGlob)
+			continue
+		}
+		slices.Sort(fixtures)
+		for _, fixture := range fixtures {
+			fixtureResult, err := testFixture(result, fixture)
+			if err != nil {
+				foundErrors = true
+				chartLog(result).Errorf("Could not test fixture %s of chart %s: %s", fixture, result.Chart.Name, err)
+				continue
+			}
+			fixtureResults = append(fixtureResults, fixtureResult)
+		}
+	}
+
+	if writeFixtureResults(os.Stdout, fixtureResults) {
+		foundErrors = true
+	}
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// testFixture validates the fixture merged into the values of the chart against the schema of the chart
+func testFixture(result *schema.Result, fixture string) (fixtureResult, error) {
+	fixtureResult := fixtureResult{Chart: result.Chart.Name, Fixture: fixture}
+	values, err := os.ReadFile(result.ValuesPath)
+	if err != nil {
+		return fixtureResult, err
+	}
+	override, err := os.ReadFile(fixture)
+	if err != nil {
+		return fixtureResult, err
+	}
+	merged, err := schema.MergeValues(values, override)
+	if err != nil {
+		return fixtureResult, fmt.Errorf("could not merge it into %s: %w", result.ValuesPath, err)
+	}
+	schemaURL, err := filepath.Abs(result.OutputPath)
+	if err != nil {
+		return fixtureResult, err
+	}
+	if err := result.Schema.ValidateValues(schemaURL, merged); err != nil {
+		fixtureResult.Errors = schema.ValuesErrors(err)
+	}
+	return fixtureResult, nil
+}
+
+// writeFixtureResults writes the results and a summary and returns true, if a fixture failed
+func writeFixtureResults(w io.Writer, results []fixtureResult) bool {
+	failed := 0
+	for _, result := range results {
+		if len(result.Errors) == 0 {
+			fmt.Fprintf(w, "PASS %s (%s)\n", result.Fixture, result.Chart)
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "FAIL %s (%s)\n", result.Fixture, result.Chart)
+		for _, valuesErr := range result.Errors {
+			pointer := valuesErr.Pointer
+			if pointer == "" {
+				pointer = "/"
+			}
+			fmt.Fprintf(w, "  %s: %s\n", pointer, valuesErr.Message)
+		}
+	}
+	fmt.Fprintf(w, "%d fixtures, %d failed\n", len(results), failed)
+	return failed > 0
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+// chartTimings are the durations of the phases of a chart in the timings report, in milliseconds, and the
+// coverage of its values
+type chartTimings struct {
+	Chart       string          `json:"chart"`
+	ChartPath   string          `json:"chartPath"`
+	Cached      bool            `json:"cached"`
+	Read        float64         `json:"readMs"`
+	Parse       float64         `json:"parseMs"`
+	Infer       float64         `json:"inferMs"`
+	Merge       float64         `json:"mergeMs"`
+	PostProcess float64         `json:"postProcessMs"`
+	Write       float64         `json:"writeMs"`
+	Total       float64         `json:"totalMs"`
+	Coverage    schema.Coverage `json:"coverage"`
+}
+
+// timingsReport is the json report written to --timings-file
+type timingsReport struct {
+	Charts []chartTimings `json:"charts"`
+	// Elapsed is the wall clock time of the whole run, the charts are processed in parallel
+	Elapsed float64 `json:"elapsedMs"`
+}
+
+// milliseconds returns the duration in milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// sortByTotal returns the results ordered by their total duration, the slowest first
+func sortByTotal(results []*schema.Result) []*schema.Result {
+	sorted := slices.Clone(results)
+	slices.SortStableFunc(sorted, func(a, b *schema.Result) int {
+		return cmp.Compare(b.Timings.Total(), a.Timings.Total())
+	})
+	return sorted
+}
+
+// newTimingsReport returns the report of the durations of the results
+func newTimingsReport(results []*schema.Result, elapsed time.Duration) timingsReport {
+	report := timingsReport{Charts: []chartTimings{}, Elapsed: milliseconds(elapsed)}
+	for _, result := range sortByTotal(results) {
+		t := result.Timings
+		report.Charts = append(report.Charts, chartTimings{
+			Chart:       result.Chart.Name,
+			ChartPath:   result.ChartPath,
+			Cached:      result.Cached,
+			Read:        milliseconds(t.Read),
+			Parse:       milliseconds(t.Parse),
+			Infer:       milliseconds(t.Infer),
+			Merge:       milliseconds(t.Merge),
+			PostProcess: milliseconds(t.PostProcess),
+			Write:       milliseconds(t.Write),
+			Total:       milliseconds(t.Total()),
+			Coverage:    resultCoverage(result),
+		})
+	}
+	return report
+}
+
+// writeTimingsFile writes the json report of the durations of the results
+func writeTimingsFile(path string, results []*schema.Result, elapsed time.Duration) error {
+	content, err := json.MarshalIndent(newTimingsReport(results, elapsed), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
+
+// writeTimingsTable writes the durations of the results as table, the slowest chart first
+func writeTimingsTable(w io.Writer, results []*schema.Result, elapsed time.Duration) error {
+	format := func(d time.Duration) string {
+		return d.Round(10 * time.Microsecond).String()
+	}
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CHART\tREAD\tPARSE\tINFER\tMERGE\tPOST-PROCESS\tWRITE\tTOTAL")
+	for _, result := range sortByTotal(results) {
+		t := result.Timings
+		name := result.Chart.Name
+		if result.Cached {
+			name += " (cached)"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, format(t.Read), format(t.Parse), format(t.Infer),
+			format(t.Merge), format(t.PostProcess), format(t.Write), format(t.Total()))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d charts in %s\n", len(results), format(elapsed))
+	return err
+}
+
+// stopProfiling stops the profiling started by --pprof, it does nothing without it
+var stopProfiling = func() {}
+
+// startProfiling writes a cpu profile to the directory until the returned function is called, which
+// also writes a heap profile
+func startProfiling(dir string) (func(), error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	cpuFile, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(cpuFile); err != nil {
+		cpuFile.Close()
+		return nil, err
+	}
+	return func() {
+		pprof.StopCPUProfile()
+		if err := cpuFile.Close(); err != nil {
+			log.Errorf("Could not write cpu profile %s: %s", cpuFile.Name(), err)
+		}
+
+		heapPath := filepath.Join(dir, "heap.pprof")
+		heapFile, err := os.Create(heapPath)
+		if err != nil {
+			log.Errorf("Could not write heap profile %s: %s", heapPath, err)
+			return
+		}
+		defer heapFile.Close()
+		// the heap profile shows the live objects of the last garbage collection
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(heapFile); err != nil {
+			log.Errorf("Could not write heap profile %s: %s", heapPath, err)
+		}
+	}, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+func newValidateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "validate [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "validate the values files against the generated jsonschemas",
+		Long: `Generates the jsonschemas (without writing them) and validates the values file of every chart against its schema.
+Additional values files (e.g. the ones of an environment) given with --values are validated
+against the schema of the chart in the chart search root.`,
+		RunE:          validate,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.Flags().StringSlice("values", []string{}, "additional values files validated against the schema of the chart in the chart search root")
+
+	return cmd
+}
+
+func validate(cmd *cobra.Command, _ []string) error {
+	extraValueFiles, _ := cmd.Flags().GetStringSlice("values")
+	chartSearchRoot := viper.GetString("chart-search-root")
+
+	// validate the charts which could be generated, even if others failed
+	results, runErr := run(false)
+	foundErrors := runErr != nil
+
+	var rootResult *schema.Result
+	for _, result := range results {
+		if filepath.Clean(filepath.Dir(result.ChartPath)) == filepath.Clean(chartSearchRoot) {
+			rootResult = result
+		}
+		if validateValuesFile(result, result.ValuesPath) {
+			foundErrors = true
+		}
+	}
+
+	if len(extraValueFiles) > 0 {
+		if rootResult == nil {
+			return fmt.Errorf("no chart found in %s to validate the values files against", chartSearchRoot)
+		}
+		for _, valuesPath := range extraValueFiles {
+			if validateValuesFile(rootResult, valuesPath) {
+				foundErrors = true
+			}
+		}
+	}
+
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// validateValuesFile validates the values file against the schema of the result. It returns true if the
+// values file couldn't be validated or is invalid with the severity error.
+func validateValuesFile(result *schema.Result, valuesPath string) bool {
+	content, err := os.ReadFile(valuesPath)
+	if err != nil {
+		chartLog(result).Errorf("Could not read values file %s of chart %s: %s", valuesPath, result.Chart.Name, err)
+		return true
+	}
+	schemaURL, err := filepath.Abs(result.OutputPath)
+	if err != nil {
+		chartLog(result).Errorf("Could not validate values file %s of chart %s: %s", valuesPath, result.Chart.Name, err)
+		return true
+	}
+	if err := result.Schema.ValidateValues(schemaURL, content); err != nil {
+		return reportRule(chartLog(result), ruleInvalidValues, "Values file %s of chart %s is invalid: %s", valuesPath, result.Chart.Name, err)
+	}
+	chartLog(result).Infof("Values file %s of chart %s is valid", valuesPath, result.Chart.Name)
+	return false
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// wizardQuit is the answer to stop asking and write the answers given so far
+const wizardQuit = "q"
+
+// errWizardQuit is returned by the prompt, if the author wants to stop
+var errWizardQuit = errors.New("quit")
+
+func newWizardCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:         "wizard [chart]",
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{chartArgumentAnnotation: "true"},
+		Short:       "interactively add descriptions and types to the values files",
+		Long: `Walks through the values without a description or without a type and asks for them. The descriptions are
+written as comments and the types as @schema annotations above the keys of the values files. Leave an answer empty
+to skip it and enter q to write the answers given so far and quit.`,
+		RunE:          wizard,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+}
+
+func wizard(_ *cobra.Command, _ []string) error {
+	dryRun := viper.GetBool("dry-run")
+
+	var valueFileNames []string
+	if err := viper.UnmarshalKey("value-files", &valueFileNames); err != nil {
+		return err
+	}
+	opts, err := newBufferOptions()
+	if err != nil {
+		return err
+	}
+
+	input := bufio.NewReader(os.Stdin)
+	valuesPaths, foundErrors := discoverValuesFiles(valueFileNames)
+	for _, valuesPath := range valuesPaths {
+		quit, err := runWizard(opts, valuesPath, input, os.Stderr, dryRun)
+		if err != nil {
+			foundErrors = true
+			log.Errorf("Could not annotate %s: %s", valuesPath, err)
+		}
+		if quit {
+			break
+		}
+	}
+	if foundErrors {
+		return errors.New("some errors were found")
+	}
+	return nil
+}
+
+// runWizard asks for the descriptions and types of the undocumented values of the values file
+// and writes the answers into it. It returns true, if the author wants to quit.
+func runWizard(opts *bufferOptions, valuesPath string, input *bufio.Reader, output io.Writer, dryRun bool) (quit bool, err error) {
+	content, err := os.ReadFile(valuesPath)
+	if err != nil {
+		return false, err
+	}
+	valuesSchema, err := opts.generateValues(valuesPath, content)
+	if err != nil {
+		return false, err
+	}
+	values, err := schema.UndocumentedValues(content, valuesSchema)
+	if err != nil {
+		return false, err
+	}
+	if len(values) == 0 {
+		log.Infof("All values of %s have a description and a type", valuesPath)
+		return false, nil
+	}
+
+	fmt.Fprintf(output, "%s: %d values without a description or type (leave empty to skip, %s to save and quit)\n", valuesPath, len(values), wizardQuit)
+	annotations := []schema.ValueAnnotation{}
+	for _, value := range values {
+		if value.Value != "" {
+			fmt.Fprintf(output, "\n%s = %s (%s:%d)\n", value.Path, value.Value, valuesPath, value.Line)
+		} else {
+			fmt.Fprintf(output, "\n%s (%s:%d)\n", value.Path, valuesPath, value.Line)
+		}
+
+		annotation := schema.ValueAnnotation{Value: value}
+		if value.MissingDescription {
+			if annotation.Description, err = prompt(input, output, "  description: "); err != nil {
+				break
+			}
+		}
+		if value.MissingType {
+			for {
+				var answer string
+				annotation.Type = nil
+				if answer, err = prompt(input, output, "  type (e.g. string or string,null): "); err != nil {
+					break
+				}
+				for _, t := range strings.Split(answer, ",") {
+					if t = strings.TrimSpace(t); t != "" {
+						annotation.Type = append(annotation.Type, t)
+					}
+				}
+				if annotation.Type.Validate() != nil {
+					fmt.Fprintln(output, "  unsupported type, use string, integer, number, boolean, object, array or null")
+					continue
+				}
+				break
+			}
+		}
+		// the description is kept, if the author quits at the type
+		if annotation.Description != "" || len(annotation.Type) > 0 {
+			annotations = append(annotations, annotation)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err != nil && !errors.Is(err, errWizardQuit) {
+		return false, err
+	}
+	quit = err != nil
+	if len(annotations) == 0 {
+		return quit, nil
+	}
+
+	annotated, err := schema.AddValueAnnotations(content, annotations)
+	if err != nil {
+		return quit, err
+	}
+	if dryRun {
+		log.Infof("Printing annotated values of %s", valuesPath)
+		fmt.Println(string(annotated))
+		return quit, nil
+	}
+	info, err := os.Stat(valuesPath)
+	if err != nil {
+		return quit, err
+	}
+	if err := util.WriteFileAtomic(valuesPath, annotated, info.Mode().Perm()); err != nil {
+		return quit, err
+	}
+	log.Infof("Annotated %d values of %s", len(annotations), valuesPath)
+	return quit, nil
+}
+
+// prompt reads a line of input. The end of the input is handled like quitting.
+func prompt(input *bufio.Reader, output io.Writer, question string) (string, error) {
+	fmt.Fprint(output, question)
+	line, err := input.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	answer := strings.TrimSpace(line)
+	if answer == wizardQuit || (errors.Is(err, io.EOF) && answer == "") {
+		fmt.Fprintln(output)
+		return "", errWizardQuit
+	}
+	return answer, nil
+}
@@ -2,6 +2,8 @@ package chart
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -30,3 +32,43 @@ func TestReadChartFile(t *testing.T) {
 		t.Errorf("Expected Dependency name was test, but got %v", c.Dependencies[0].Name)
 	}
 }
+
+func TestReadRequirements(t *testing.T) {
+	dir := t.TempDir()
+	lock := []byte("dependencies:\n- name: locked\n  version: 1.0.0\n")
+	if err := os.WriteFile(filepath.Join(dir, "requirements.lock"), lock, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := ChartFile{APIVersion: APIVersionV1}
+	content, err := c.ReadRequirements(dir)
+	if err != nil {
+		t.Errorf("Error while reading requirements: %v", err)
+	}
+	if string(content) != string(lock) {
+		t.Errorf("Expected the content of requirements.lock, but got %q", content)
+	}
+	if len(c.Dependencies) != 1 || c.Dependencies[0].Name != "locked" {
+		t.Errorf("Expected the dependency of requirements.lock, but got %v", c.Dependencies)
+	}
+
+	// the requirements.yaml takes precedence over the lock file
+	requirements := []byte("dependencies:\n- name: db\n  version: ~1.0\n  condition: db.enabled\n  alias: database\n")
+	if err := os.WriteFile(filepath.Join(dir, "requirements.yaml"), requirements, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c = ChartFile{APIVersion: APIVersionV1}
+	if _, err := c.ReadRequirements(dir); err != nil {
+		t.Errorf("Error while reading requirements: %v", err)
+	}
+	if len(c.Dependencies) != 1 || c.Dependencies[0].Alias != "database" || c.Dependencies[0].Condition != "db.enabled" {
+		t.Errorf("Expected the dependency of requirements.yaml, but got %v", c.Dependencies)
+	}
+
+	// charts with api version v2 define their dependencies in Chart.yaml
+	c = ChartFile{APIVersion: "v2"}
+	content, err = c.ReadRequirements(dir)
+	if err != nil || content != nil || len(c.Dependencies) != 0 {
+		t.Errorf("Expected the requirements of a v2 chart to be ignored, but got %v (%v)", c.Dependencies, err)
+	}
+}
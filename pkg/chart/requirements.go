@@ -0,0 +1,58 @@
+package chart
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ojsef39/helm-schema/pkg/util"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// APIVersionV1 is the api version of the charts of Helm 2, which define their dependencies in a requirements file
+const APIVersionV1 = "v1"
+
+// APIVersionV2 is the api version of the charts of Helm 3, which define their dependencies in the Chart.yaml
+const APIVersionV2 = "v2"
+
+// RequirementsFiles are the files containing the dependencies of a chart with api version v1, the
+// requirements.lock is only read if there's no requirements.yaml
+var RequirementsFiles = []string{"requirements.yaml", "requirements.lock"}
+
+// requirements are the contents of a requirements file
+type requirements struct {
+	Dependencies []*Dependency `yaml:"dependencies"`
+}
+
+// ReadRequirements adds the dependencies of the requirements file in the chart directory to the chart,
+// if it has the api version v1 and doesn't define dependencies in its Chart.yaml. It returns the content
+// of the requirements file, or nil if it wasn't read.
+func (c *ChartFile) ReadRequirements(chartDir string) ([]byte, error) {
+	if c.APIVersion != APIVersionV1 || len(c.Dependencies) > 0 {
+		return nil, nil
+	}
+	for _, name := range RequirementsFiles {
+		path := filepath.Join(chartDir, name)
+		file, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		content, err := util.ReadFileAndFixNewline(file)
+		if err != nil {
+			return nil, err
+		}
+		var parsed requirements
+		if err := yaml.Unmarshal(content, &parsed); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", path, err)
+		}
+		c.Dependencies = parsed.Dependencies
+		return content, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,188 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// DefaultTimeout is the timeout of every attempt of a request, if the options don't set one
+const DefaultTimeout = 30 * time.Second
+
+// Options configures the http client of all network operations
+type Options struct {
+	// CAFile contains PEM certificates, which are trusted in addition to the ones of the system
+	CAFile string
+	// InsecureSkipVerify disables the verification of the certificates of all servers
+	InsecureSkipVerify bool
+	// RepositoryConfig is the path of helm's repositories.yaml, the credentials and certificates
+	// of a repository are used for the requests to its url
+	RepositoryConfig string
+	// BearerTokens are the tokens sent to the hosts (e.g. charts.example.com), which aren't
+	// authenticated by a repository
+	BearerTokens map[string]string
+	// Timeout is the timeout of every attempt of a request
+	Timeout time.Duration
+	// Retries is the number of retries of requests failing with a network error or a transient status
+	// (429 and 5xx), with an exponential backoff between the attempts
+	Retries int
+	// MaxRequestsPerHost limits the concurrent requests to every host (0 means unlimited)
+	MaxRequestsPerHost int
+}
+
+// repository is an entry of helm's repositories.yaml
+type repository struct {
+	Name                  string `yaml:"name"`
+	URL                   string `yaml:"url"`
+	Username              string `yaml:"username"`
+	Password              string `yaml:"password"`
+	CertFile              string `yaml:"certFile"`
+	KeyFile               string `yaml:"keyFile"`
+	CAFile                string `yaml:"caFile"`
+	InsecureSkipTLSVerify bool   `yaml:"insecure_skip_tls_verify"`
+}
+
+// repositoryTransport sends the requests to the url of a repository
+type repositoryTransport struct {
+	repository
+	url       *url.URL
+	transport http.RoundTripper
+}
+
+// transport authenticates the requests by the repositories and bearer tokens
+type transport struct {
+	base         http.RoundTripper
+	repositories []*repositoryTransport
+	tokens       map[string]string
+}
+
+// New returns the http client configured by the options, it uses the proxies of the
+// environment (HTTPS_PROXY, HTTP_PROXY and NO_PROXY)
+func New(opts Options) (*http.Client, error) {
+	base, err := newTransport(opts.CAFile, "", "", opts.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	t := &transport{base: base, tokens: opts.BearerTokens}
+
+	repositories, err := readRepositories(opts.RepositoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	for _, repo := range repositories {
+		repoURL, err := url.Parse(strings.TrimSuffix(repo.URL, "/"))
+		if err != nil || repoURL.Host == "" {
+			continue
+		}
+		repoTransport, err := newTransport(opts.CAFile, repo.CertFile, repo.KeyFile, opts.InsecureSkipVerify || repo.InsecureSkipTLSVerify, repo.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificates of repository %s: %w", repo.Name, err)
+		}
+		t.repositories = append(t.repositories, &repositoryTransport{repository: repo, url: repoURL, transport: repoTransport})
+	}
+	// the most specific url matches first
+	slices.SortStableFunc(t.repositories, func(a, b *repositoryTransport) int {
+		return len(b.url.Path) - len(a.url.Path)
+	})
+
+	var roundTripper http.RoundTripper = t
+	if opts.MaxRequestsPerHost > 0 {
+		roundTripper = &hostLimiter{base: roundTripper, limit: opts.MaxRequestsPerHost, hosts: make(map[string]chan struct{})}
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	return &http.Client{Transport: &retryTransport{base: roundTripper, retries: opts.Retries, timeout: timeout}}, nil
+}
+
+// RoundTrip sends the request with the credentials of the repository of its url or the bearer token of its host
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, repo := range t.repositories {
+		if !repo.matches(req.URL) {
+			continue
+		}
+		if repo.Username != "" && req.Header.Get("Authorization") == "" {
+			req = req.Clone(req.Context())
+			req.SetBasicAuth(repo.Username, repo.Password)
+		}
+		return repo.transport.RoundTrip(req)
+	}
+	if token, ok := t.tokens[req.URL.Hostname()]; ok && req.Header.Get("Authorization") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// matches returns true, if the url belongs to the repository
+func (r *repositoryTransport) matches(u *url.URL) bool {
+	if u.Scheme != r.url.Scheme || u.Host != r.url.Host {
+		return false
+	}
+	return r.url.Path == "" || u.Path == r.url.Path || strings.HasPrefix(u.Path, r.url.Path+"/")
+}
+
+// newTransport returns a transport trusting the certificates of the ca files in addition to the
+// ones of the system, which authenticates with the client certificate if there's one
+func newTransport(caFile, certFile, keyFile string, insecureSkipVerify bool, moreCAFiles ...string) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	t.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecureSkipVerify} //nolint:gosec // it's an explicit option
+
+	caFiles := slices.DeleteFunc(append([]string{caFile}, moreCAFiles...), func(file string) bool { return file == "" })
+	if len(caFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, file := range caFiles {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if !pool.AppendCertsFromPEM(content) {
+				return nil, fmt.Errorf("no certificates found in %s", file)
+			}
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		t.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+	return t, nil
+}
+
+// readRepositories reads the repositories of helm's repositories.yaml, which doesn't have to exist
+func readRepositories(path string) ([]repository, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Repositories []repository `yaml:"repositories"`
+	}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	return config.Repositories, nil
+}
@@ -0,0 +1,205 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repositoryConfig := filepath.Join(dir, "repositories.yaml")
+	repositories := fmt.Sprintf("repositories:\n  - name: private\n    url: %s/private/\n    username: user\n    password: secret\n", server.URL)
+	if err := os.WriteFile(repositoryConfig, []byte(repositories), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		opts          Options
+		path          string
+		authorization string
+		fails         bool
+	}{
+		{
+			// the certificate of the server isn't trusted
+			opts:  Options{},
+			path:  "/",
+			fails: true,
+		},
+		{
+			opts: Options{InsecureSkipVerify: true},
+			path: "/",
+		},
+		{
+			opts:          Options{CAFile: caFile, RepositoryConfig: repositoryConfig},
+			path:          "/private/index.yaml",
+			authorization: "Basic dXNlcjpzZWNyZXQ=",
+		},
+		{
+			opts: Options{CAFile: caFile, RepositoryConfig: repositoryConfig},
+			path: "/privateer/index.yaml",
+		},
+		{
+			opts:          Options{CAFile: caFile, RepositoryConfig: repositoryConfig, BearerTokens: map[string]string{"127.0.0.1": "token"}},
+			path:          "/public/index.yaml",
+			authorization: "Bearer token",
+		},
+		{
+			opts: Options{CAFile: caFile, RepositoryConfig: filepath.Join(dir, "missing.yaml")},
+			path: "/",
+		},
+	}
+	for _, test := range tests {
+		client, err := New(test.opts)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		response, err := client.Get(server.URL + test.path)
+		if test.fails {
+			if err == nil {
+				t.Errorf("Was expecting an error for %s", test.path)
+				response.Body.Close()
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Wasn't expecting an error, but got this: %v", err)
+			continue
+		}
+		body, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			t.Errorf("Wasn't expecting an error, but got this: %v", err)
+		}
+		if string(body) != test.authorization {
+			t.Errorf("Was expecting authorization %q for %s, but got %q", test.authorization, test.path, body)
+		}
+	}
+}
+
+func TestRetries(t *testing.T) {
+	retryBackoff = time.Millisecond
+	failures := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		failures int
+		retries  int
+		status   int
+	}{
+		{failures: 2, retries: 2, status: http.StatusOK},
+		{failures: 2, retries: 1, status: http.StatusServiceUnavailable},
+		{failures: 1, retries: 0, status: http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		failures = test.failures
+		client, err := New(Options{Retries: test.retries})
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		// the body is sent again with every attempt
+		response, err := client.Post(server.URL, "text/plain", strings.NewReader("body"))
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		if response.StatusCode != test.status {
+			t.Errorf("Was expecting status %d with %d retries, but got %d", test.status, test.retries, response.StatusCode)
+		}
+		if test.status == http.StatusOK && string(body) != "body" {
+			t.Errorf("Was expecting the body to be sent again, but got %q", body)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	defer func(backoff time.Duration) { retryBackoff = backoff }(retryBackoff)
+	retryBackoff = 500 * time.Millisecond
+
+	tests := []struct {
+		attempt  int
+		header   string
+		expected time.Duration
+	}{
+		{attempt: 0, expected: 500 * time.Millisecond},
+		{attempt: 2, expected: 2 * time.Second},
+		{attempt: 10, expected: maxRetryBackoff},
+		// the shift would overflow
+		{attempt: 40, expected: maxRetryBackoff},
+		{attempt: 100, expected: maxRetryBackoff},
+		{attempt: 0, header: "3", expected: 3 * time.Second},
+		{attempt: 0, header: "3600", expected: maxRetryBackoff},
+	}
+	for _, test := range tests {
+		response := &http.Response{Header: http.Header{}}
+		if test.header != "" {
+			response.Header.Set("Retry-After", test.header)
+		}
+		if delay := backoff(test.attempt, response); delay != test.expected {
+			t.Errorf("Was expecting a delay of %s for attempt %d, but got %s", test.expected, test.attempt, delay)
+		}
+	}
+}
+
+func TestMaxRequestsPerHost(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	client, err := New(Options{MaxRequestsPerHost: 2})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if response, err := client.Get(server.URL); err == nil {
+				response.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	if maxInFlight > 2 {
+		t.Errorf("Was expecting at most 2 concurrent requests, but got %d", maxInFlight)
+	}
+}
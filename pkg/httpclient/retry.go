@@ -0,0 +1,156 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The delays between the attempts of a request double from retryBackoff up to maxRetryBackoff
+var (
+	retryBackoff    = 500 * time.Millisecond
+	maxRetryBackoff = 10 * time.Second
+)
+
+// retryTransport retries requests failing with a network error or a transient status, every attempt
+// has its own timeout
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	timeout time.Duration
+}
+
+// RoundTrip sends the request until it succeeds or the retries are exhausted
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && hasBody(req) {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		response, err := t.attempt(attemptReq)
+		if attempt >= t.retries || !retryable(req, response, err) {
+			return response, err
+		}
+		delay := backoff(attempt, response)
+		if response != nil {
+			// the connection can be reused, if the body is read
+			_, _ = io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// attempt sends the request once, the timeout ends when the body of the response is closed
+func (t *retryTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	response, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	response.Body = &releasingBody{ReadCloser: response.Body, release: cancel}
+	return response, nil
+}
+
+// retryable returns true, if the request failed with a network error or a status, which might be transient
+// (e.g. an overloaded registry)
+func retryable(req *http.Request, response *http.Response, err error) bool {
+	if req.Context().Err() != nil || (hasBody(req) && req.GetBody == nil) {
+		// canceled requests and bodies, which can't be sent again, aren't retried
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch response.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// hasBody returns true, if the request sends a body
+func hasBody(req *http.Request) bool {
+	return req.Body != nil && req.Body != http.NoBody
+}
+
+// backoff returns the delay before the next attempt, the Retry-After header (in seconds) of the response
+// takes precedence
+func backoff(attempt int, response *http.Response) time.Duration {
+	if response != nil {
+		if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, maxRetryBackoff)
+		}
+	}
+	// the shift overflows for many attempts, the delay reached the maximum long before
+	if attempt > 20 {
+		return maxRetryBackoff
+	}
+	return min(retryBackoff<<attempt, maxRetryBackoff)
+}
+
+// hostLimiter limits the number of concurrent requests to every host
+type hostLimiter struct {
+	base  http.RoundTripper
+	limit int
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+// RoundTrip waits until less than limit requests to the host of the request are in progress, a
+// request is in progress until the body of its response is closed
+func (l *hostLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	l.mu.Lock()
+	slots, ok := l.hosts[req.URL.Host]
+	if !ok {
+		slots = make(chan struct{}, l.limit)
+		l.hosts[req.URL.Host] = slots
+	}
+	l.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	release := func() { <-slots }
+	response, err := l.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	response.Body = &releasingBody{ReadCloser: response.Body, release: release}
+	return response, nil
+}
+
+// releasingBody calls release once, when it's closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
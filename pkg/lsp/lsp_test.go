@@ -0,0 +1,135 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+const testValues = `# @schema
+# minimum: 100
+# @schema
+# -- The port
+port: 80
+image:
+  # @schema
+  # enum: [a, b]
+  # @schema
+  pullPolicy: a
+  tag: "1.0"
+hosts:
+  - name: a
+`
+
+func generateTestSchema(t *testing.T, content string) *schema.Schema {
+	skipConfig, err := schema.NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := schema.GenerateSchema("", []byte(content), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	return s
+}
+
+func labels(items []CompletionItem) []string {
+	result := []string{}
+	for _, item := range items {
+		result = append(result, item.Label)
+	}
+	return result
+}
+
+func TestHoverAt(t *testing.T) {
+	s := generateTestSchema(t, testValues)
+
+	hover := HoverAt(s, []byte(testValues), Position{Line: 4, Character: 1})
+	if hover == nil {
+		t.Fatal("Expected a hover of port")
+	}
+	assert.Equal(t, hover.Contents.Value, "`port`\n\nThe port\n\nDefault: `\"80\"`")
+	assert.Equal(t, *hover.Range, Range{Start: Position{Line: 4}, End: Position{Line: 4, Character: 4}})
+
+	hover = HoverAt(s, []byte(testValues), Position{Line: 9, Character: 4})
+	if hover == nil {
+		t.Fatal("Expected a hover of image.pullPolicy")
+	}
+	assert.Equal(t, hover.Contents.Value, "`image.pullPolicy`\n\nAllowed values: `a`, `b`\n\nDefault: `\"a\"`")
+
+	assert.Equal(t, HoverAt(s, []byte(testValues), Position{Line: 0, Character: 2}) == nil, true)
+}
+
+func TestCompletionsAt(t *testing.T) {
+	s := generateTestSchema(t, testValues)
+
+	content := testValues + "\n"
+	assert.Equal(t, labels(CompletionsAt(s, []byte(content), Position{Line: 13})), []string{"port", "image", "hosts", "global"})
+
+	content = strings.Replace(testValues, `  tag: "1.0"`, "  t", 1)
+	assert.Equal(t, labels(CompletionsAt(s, []byte(content), Position{Line: 10, Character: 3})), []string{"pullPolicy", "tag"})
+
+	content = strings.Replace(testValues, "  pullPolicy: a", "  pullPolicy: ", 1)
+	assert.Equal(t, labels(CompletionsAt(s, []byte(content), Position{Line: 9, Character: 14})), []string{"a", "b"})
+}
+
+func TestValuesDiagnostics(t *testing.T) {
+	s := generateTestSchema(t, testValues)
+
+	diagnostics := ValuesDiagnostics(s, "values.yaml", []byte(testValues))
+	assert.Equal(t, len(diagnostics), 1)
+	assert.Equal(t, diagnostics[0].Range, Range{Start: Position{Line: 4}, End: Position{Line: 4, Character: 4}})
+	assert.Equal(t, diagnostics[0].Message, "/port: minimum: got 80, want 100")
+
+	diagnostic := GenerationDiagnostic(errors.New("yaml: line 3: did not find expected key"))
+	assert.Equal(t, diagnostic.Range.Start.Line, 2)
+}
+
+func TestServe(t *testing.T) {
+	generate := func(_ string, content []byte) (*schema.Schema, error) {
+		return generateTestSchema(t, string(content)), nil
+	}
+
+	var input bytes.Buffer
+	for _, msg := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///chart/values.yaml","text":"port: 80\n"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///chart/values.yaml"},"position":{"line":0,"character":1}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"unknown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+
+	var output bytes.Buffer
+	if err := NewServer(generate, "test").Serve(&input, &output); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	reader := bufio.NewReader(&output)
+	var responses []*message
+	for {
+		msg, err := readMessage(reader)
+		if err != nil {
+			break
+		}
+		responses = append(responses, msg)
+	}
+	assert.Equal(t, len(responses), 4)
+	assert.Equal(t, responses[1].Method, "textDocument/publishDiagnostics")
+
+	var hover Hover
+	if err := json.Unmarshal(responses[2].Result, &hover); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, hover.Contents.Value, "`port` (integer)\n\nDefault: `80`")
+	assert.Equal(t, responses[3].Error.Code, errorMethodNotFound)
+}
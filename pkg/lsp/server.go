@@ -0,0 +1,196 @@
+// Package lsp implements a language server, which offers completion, hover docs and
+// diagnostics in values files based on the schema generated from the edited document.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+// Generator returns the schema of the values file at the given path with the given (unsaved) content
+type Generator func(valuesPath string, content []byte) (*schema.Schema, error)
+
+type document struct {
+	path    string
+	content []byte
+	// schema is the last schema which could be generated, so completion and hover
+	// keep working while the document is invalid
+	schema *schema.Schema
+}
+
+type Server struct {
+	generate Generator
+	version  string
+	writer   io.Writer
+	// writeMutex serializes the messages written to the client
+	writeMutex sync.Mutex
+	documents  map[string]*document
+}
+
+func NewServer(generate Generator, version string) *Server {
+	return &Server{generate: generate, version: version, documents: map[string]*document{}}
+}
+
+// Serve handles the messages of the client until it sends exit or closes the connection
+func (s *Server) Serve(reader io.Reader, writer io.Writer) error {
+	s.writer = writer
+	bufferedReader := bufio.NewReader(reader)
+	for {
+		msg, err := readMessage(bufferedReader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			// notifications don't have a response
+			if err != nil {
+				log.Warnf("Could not handle %s: %s", msg.Method, err)
+			}
+			continue
+		}
+		response := &message{ID: msg.ID}
+		if err != nil {
+			var rpcErr *responseError
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &responseError{Code: errorInvalidParams, Message: err.Error()}
+			}
+			response.Error = rpcErr
+		} else if response.Result, err = json.Marshal(result); err != nil {
+			return err
+		}
+		if err := s.send(response); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+func (s *Server) send(msg *message) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return writeMessage(s.writer, msg)
+}
+
+// handle returns the result of a request or handles a notification
+func (s *Server) handle(msg *message) (interface{}, error) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   textDocumentSyncFull,
+				"hoverProvider":      true,
+				"completionProvider": map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{"name": "helm-schema", "version": s.version},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, []byte(params.TextDocument.Text))
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// the documents are synced completely, so the last change contains the whole content
+		return nil, s.update(params.TextDocument.URI, []byte(params.ContentChanges[len(params.ContentChanges)-1].Text))
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return nil, s.publishDiagnostics(params.TextDocument.URI, []Diagnostic{})
+	case "textDocument/hover":
+		var params positionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.documents[params.TextDocument.URI]
+		if !ok || doc.schema == nil {
+			return nil, nil
+		}
+		return HoverAt(doc.schema, doc.content, params.Position), nil
+	case "textDocument/completion":
+		var params positionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.documents[params.TextDocument.URI]
+		if !ok || doc.schema == nil {
+			return []CompletionItem{}, nil
+		}
+		return CompletionsAt(doc.schema, doc.content, params.Position), nil
+	case "initialized", "textDocument/didSave", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	default:
+		return nil, &responseError{Code: errorMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+// update regenerates the schema of the document and publishes its diagnostics
+func (s *Server) update(uri string, content []byte) error {
+	doc, ok := s.documents[uri]
+	if !ok {
+		path, err := uriToPath(uri)
+		if err != nil {
+			return err
+		}
+		doc = &document{path: path}
+		s.documents[uri] = doc
+	}
+	doc.content = content
+
+	generated, err := s.generate(doc.path, content)
+	if err != nil {
+		return s.publishDiagnostics(uri, []Diagnostic{GenerationDiagnostic(err)})
+	}
+	doc.schema = generated
+	return s.publishDiagnostics(uri, ValuesDiagnostics(generated, doc.path, content))
+}
+
+func (s *Server) publishDiagnostics(uri string, diagnostics []Diagnostic) error {
+	params, err := json.Marshal(publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+	if err != nil {
+		return err
+	}
+	return s.send(&message{Method: "textDocument/publishDiagnostics", Params: params})
+}
+
+// uriToPath returns the path of a file uri
+func uriToPath(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Scheme != "file" {
+		return "", errors.New("only file uris are supported: " + uri)
+	}
+	return filepath.FromSlash(parsed.Path), nil
+}
@@ -0,0 +1,344 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+// itemsPathElement is the path element of the items of a sequence
+const itemsPathElement = "[]"
+
+var yamlErrorLine = regexp.MustCompile(`yaml: line (\d+):`)
+
+// GenerationDiagnostic returns the diagnostic of an error while generating the schema.
+// Yaml errors are reported at their line, all others at the start of the document.
+func GenerationDiagnostic(err error) Diagnostic {
+	line := 0
+	if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+		if parsed, parseErr := strconv.Atoi(match[1]); parseErr == nil && parsed > 0 {
+			line = parsed - 1
+		}
+	}
+	return Diagnostic{
+		Range:    Range{Start: Position{Line: line}, End: Position{Line: line + 1}},
+		Severity: severityError,
+		Source:   "helm-schema",
+		Message:  err.Error(),
+	}
+}
+
+// ValuesDiagnostics validates the values against the schema (e.g. a default violating the
+// minimum of its annotation) and returns a diagnostic at the key of every invalid value
+func ValuesDiagnostics(s *schema.Schema, valuesPath string, content []byte) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	schemaURL, err := filepath.Abs(filepath.Join(filepath.Dir(valuesPath), "values.schema.json"))
+	if err != nil {
+		return append(diagnostics, GenerationDiagnostic(err))
+	}
+	err = s.ValidateValues(schemaURL, content)
+	if err == nil {
+		return diagnostics
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return append(diagnostics, GenerationDiagnostic(err))
+	}
+	for _, valuesErr := range schema.ValuesErrors(err) {
+		message := valuesErr.Message
+		if valuesErr.Pointer != "" {
+			message = fmt.Sprintf("%s: %s", valuesErr.Pointer, message)
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    pointerRange(&root, valuesErr.Pointer),
+			Severity: severityError,
+			Source:   "helm-schema",
+			Message:  message,
+		})
+	}
+	return diagnostics
+}
+
+// pointerRange returns the range of the key of the value at the json pointer
+func pointerRange(root *yaml.Node, pointer string) Range {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	target := node
+	if pointer != "" {
+		for _, part := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			found := false
+			switch node.Kind {
+			case yaml.MappingNode:
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == part {
+						target, node, found = node.Content[i], node.Content[i+1], true
+						break
+					}
+				}
+			case yaml.SequenceNode:
+				if index, err := strconv.Atoi(part); err == nil && index >= 0 && index < len(node.Content) {
+					node, found = node.Content[index], true
+					target = node
+				}
+			}
+			if !found {
+				break
+			}
+		}
+	}
+	return nodeRange(target)
+}
+
+// nodeRange returns the range of a scalar node or the first line of other nodes
+func nodeRange(node *yaml.Node) Range {
+	if node.Line == 0 {
+		return Range{End: Position{Line: 1}}
+	}
+	start := Position{Line: node.Line - 1, Character: node.Column - 1}
+	if node.Kind != yaml.ScalarNode {
+		return Range{Start: start, End: Position{Line: start.Line + 1}}
+	}
+	return Range{Start: start, End: Position{Line: start.Line, Character: start.Character + len(node.Value)}}
+}
+
+// HoverAt returns the documentation of the key at the position or nil if there is no key
+func HoverAt(s *schema.Schema, content []byte, position Position) *Hover {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil || len(root.Content) == 0 {
+		return nil
+	}
+	path, keyNode := keyAt(root.Content[0], position, nil)
+	if keyNode == nil {
+		return nil
+	}
+	keySchema := schemaAt(s, path)
+	if keySchema == nil {
+		return nil
+	}
+	keyRange := nodeRange(keyNode)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: documentation(displayPath(path), keySchema)},
+		Range:    &keyRange,
+	}
+}
+
+// keyAt returns the path and the node of the mapping key at the position
+func keyAt(node *yaml.Node, position Position, path []string) ([]string, *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			keyPath := append(append([]string{}, path...), keyNode.Value)
+			if keyNode.Line-1 == position.Line &&
+				position.Character >= keyNode.Column-1 &&
+				position.Character <= keyNode.Column-1+len(keyNode.Value) {
+				return keyPath, keyNode
+			}
+			if found, foundNode := keyAt(valueNode, position, keyPath); foundNode != nil {
+				return found, foundNode
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if found, foundNode := keyAt(item, position, append(append([]string{}, path...), itemsPathElement)); foundNode != nil {
+				return found, foundNode
+			}
+		}
+	}
+	return nil, nil
+}
+
+// schemaAt returns the schema of the value at the path or nil if the schema has none
+func schemaAt(s *schema.Schema, path []string) *schema.Schema {
+	for _, element := range path {
+		if s == nil {
+			return nil
+		}
+		if element == itemsPathElement {
+			s = s.Items
+			continue
+		}
+		if property, ok := s.Properties[element]; ok {
+			s = property
+			continue
+		}
+		additionalSchema, ok := s.AdditionalProperties.(*schema.Schema)
+		if !ok {
+			return nil
+		}
+		s = additionalSchema
+	}
+	return s
+}
+
+// displayPath returns the path in the dotted notation of helm (e.g. image.tag or hosts[].name)
+func displayPath(path []string) string {
+	var result strings.Builder
+	for i, element := range path {
+		if element != itemsPathElement && i > 0 {
+			result.WriteString(".")
+		}
+		result.WriteString(element)
+	}
+	return result.String()
+}
+
+// documentation returns the markdown documentation of the schema of a key
+func documentation(key string, s *schema.Schema) string {
+	var doc strings.Builder
+	fmt.Fprintf(&doc, "`%s`", key)
+	if len(s.Type) > 0 {
+		fmt.Fprintf(&doc, " (%s)", strings.Join(s.Type, " | "))
+	}
+	if s.Title != "" && s.Title != key && !strings.HasSuffix(key, "."+s.Title) {
+		fmt.Fprintf(&doc, " **%s**", s.Title)
+	}
+	if s.Description != "" {
+		fmt.Fprintf(&doc, "\n\n%s", s.Description)
+	}
+	if s.Deprecated {
+		doc.WriteString("\n\n**Deprecated**")
+	}
+	if len(s.Enum) > 0 {
+		fmt.Fprintf(&doc, "\n\nAllowed values: `%s`", strings.Join(s.Enum, "`, `"))
+	}
+	if s.Default != nil {
+		if defaultJSON, err := json.Marshal(s.Default); err == nil {
+			fmt.Fprintf(&doc, "\n\nDefault: `%s`", defaultJSON)
+		}
+	}
+	return doc.String()
+}
+
+// CompletionsAt returns the keys which can be added at the position or, after the colon
+// of a key, the possible values of the key. It works on the lines of the document, because
+// the document is usually invalid yaml while typing.
+func CompletionsAt(s *schema.Schema, content []byte, position Position) []CompletionItem {
+	lines := strings.Split(string(content), "\n")
+	if position.Line >= len(lines) {
+		return []CompletionItem{}
+	}
+	line := lines[position.Line]
+	if position.Character < len(line) {
+		line = line[:position.Character]
+	}
+
+	path, prefix := linePath(lines, position.Line, line)
+	if key, _, isValue := strings.Cut(prefix, ":"); isValue {
+		return valueCompletions(schemaAt(s, append(path, strings.TrimSpace(key))))
+	}
+	return keyCompletions(schemaAt(s, path))
+}
+
+// linePath returns the path of the mapping the line belongs to and the content of the line
+func linePath(lines []string, lineIndex int, line string) ([]string, string) {
+	var reversedPath []string
+	indent := indentation(line)
+	prefix := strings.TrimSpace(line)
+	for strings.HasPrefix(prefix, "- ") || prefix == "-" {
+		// a new item of a sequence
+		reversedPath = append(reversedPath, itemsPathElement)
+		prefix = strings.TrimSpace(strings.TrimPrefix(prefix, "-"))
+	}
+
+	for i := lineIndex - 1; i >= 0 && indent > 0; i-- {
+		content := strings.TrimSpace(lines[i])
+		if content == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+		lineIndent := indentation(lines[i])
+		if lineIndent >= indent {
+			continue
+		}
+		if strings.HasPrefix(content, "-") {
+			// the line is the start of a sequence item, the keys of its mapping
+			// are indented by the dash
+			itemContent := strings.TrimSpace(strings.TrimPrefix(content, "-"))
+			if key, ok := blockKey(itemContent); ok && indent > lineIndent+2 {
+				reversedPath = append(reversedPath, key)
+			}
+			reversedPath = append(reversedPath, itemsPathElement)
+			indent = lineIndent
+			continue
+		}
+		key, ok := blockKey(content)
+		if !ok {
+			// the line isn't part of a nested block
+			return nil, prefix
+		}
+		reversedPath = append(reversedPath, key)
+		indent = lineIndent
+	}
+
+	path := make([]string, len(reversedPath))
+	for i, element := range reversedPath {
+		path[len(path)-1-i] = element
+	}
+	return path, prefix
+}
+
+// blockKey returns the key of a line starting a nested block (a key without a value)
+func blockKey(content string) (string, bool) {
+	key, rest, ok := strings.Cut(content, ":")
+	rest = strings.TrimSpace(rest)
+	if !ok || (rest != "" && !strings.HasPrefix(rest, "#")) {
+		return "", false
+	}
+	return strings.Trim(strings.TrimSpace(key), `"'`), true
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func keyCompletions(s *schema.Schema) []CompletionItem {
+	items := []CompletionItem{}
+	if s == nil {
+		return items
+	}
+	for _, name := range s.PropertyNames() {
+		property := s.Properties[name]
+		item := CompletionItem{
+			Label:      name,
+			Kind:       completionKindProperty,
+			Detail:     strings.Join(property.Type, " | "),
+			InsertText: name + ": ",
+		}
+		if property.Description != "" {
+			item.Documentation = &MarkupContent{Kind: "markdown", Value: property.Description}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func valueCompletions(s *schema.Schema) []CompletionItem {
+	items := []CompletionItem{}
+	if s == nil {
+		return items
+	}
+	values := s.Enum
+	if len(values) == 0 {
+		for _, t := range s.Type {
+			if t == "boolean" {
+				values = []string{"true", "false"}
+			}
+		}
+	}
+	for _, value := range values {
+		items = append(items, CompletionItem{Label: value, Kind: completionKindValue})
+	}
+	return items
+}
@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// ChartMediaType is the media type of the layer with the archive of a helm chart
+const ChartMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+
+// PullChart pulls the archive of the chart pushed by helm push to the repository (e.g. ghcr.io/org/charts/foo).
+// The version is a version or a constraint (e.g. ~1.2.0), which selects the newest matching tag. It returns
+// the pulled version and the content of the archive.
+func PullChart(ctx context.Context, repository, version string, opts Options) (string, []byte, error) {
+	repo, err := remote.NewRepository(TrimScheme(repository))
+	if err != nil {
+		return "", nil, err
+	}
+	client, err := newClient(opts)
+	if err != nil {
+		return "", nil, err
+	}
+	repo.Client = client
+	repo.PlainHTTP = opts.PlainHTTP
+
+	tag, err := resolveTag(ctx, repo, version)
+	if err != nil {
+		return "", nil, err
+	}
+	_, manifestContent, err := oras.FetchBytes(ctx, repo, tag, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return "", nil, err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestContent, &manifest); err != nil {
+		return "", nil, fmt.Errorf("could not parse the manifest of %s:%s: %w", repository, tag, err)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != ChartMediaType {
+			continue
+		}
+		archive, err := content.FetchAll(ctx, repo, layer)
+		if err != nil {
+			return "", nil, err
+		}
+		// helm replaces the + of build metadata, which isn't allowed in tags, with _
+		return strings.ReplaceAll(tag, "_", "+"), archive, nil
+	}
+	return "", nil, fmt.Errorf("%s:%s isn't a helm chart, it has no layer of type %s", repository, tag, ChartMediaType)
+}
+
+// resolveTag returns the tag of the newest version matching the version constraint, a version is used as it is
+func resolveTag(ctx context.Context, repo *remote.Repository, version string) (string, error) {
+	if _, err := semver.StrictNewVersion(version); err == nil {
+		return strings.ReplaceAll(version, "+", "_"), nil
+	}
+	if version == "" {
+		version = "*"
+	}
+	constraint, err := semver.NewConstraint(version)
+	if err != nil {
+		return "", fmt.Errorf("invalid version %s: %w", version, err)
+	}
+
+	var newest *semver.Version
+	tag := ""
+	err = repo.Tags(ctx, "", func(tags []string) error {
+		for _, candidate := range tags {
+			tagVersion, err := semver.NewVersion(strings.ReplaceAll(candidate, "_", "+"))
+			if err != nil || !constraint.Check(tagVersion) {
+				continue
+			}
+			if newest == nil || tagVersion.GreaterThan(newest) {
+				newest, tag = tagVersion, candidate
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if newest == nil {
+		return "", fmt.Errorf("the repository %s has no version matching %s", repo.Reference.String(), version)
+	}
+	return tag, nil
+}
@@ -0,0 +1,126 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+const (
+	// SchemaMediaType is the default media type of the pushed schema layers
+	SchemaMediaType = "application/schema+json"
+	// SchemaArtifactType is the default artifact type of the pushed manifests
+	SchemaArtifactType = "application/vnd.helm-schema.values.v1+json"
+)
+
+// Options configures the connection to OCI registries
+type Options struct {
+	// RegistryConfig is the path of a docker style credentials file (e.g. the one of helm).
+	// The docker credentials are used as fallback.
+	RegistryConfig string
+	// PlainHTTP uses http instead of https
+	PlainHTTP bool
+	// Client sends the requests (e.g. with proxies, custom certificates and retries), the default client
+	// retrying failed requests is used without one
+	Client *http.Client
+}
+
+// Artifact is a single file, which is pushed as OCI artifact
+type Artifact struct {
+	Content      []byte
+	FileName     string
+	MediaType    string
+	ArtifactType string
+	// Annotations are added to the manifest
+	Annotations map[string]string
+}
+
+// TrimScheme removes the oci:// scheme from the given reference
+func TrimScheme(ref string) string {
+	return strings.TrimPrefix(ref, "oci://")
+}
+
+// Push pushes the artifact to the given reference (e.g. ghcr.io/org/schemas/foo:1.0.0)
+// and returns the digest of the pushed manifest
+func Push(ctx context.Context, ref string, artifact Artifact, opts Options) (string, error) {
+	repo, err := remote.NewRepository(TrimScheme(ref))
+	if err != nil {
+		return "", err
+	}
+	tag := repo.Reference.Reference
+	if tag == "" {
+		return "", fmt.Errorf("reference %s has no tag", ref)
+	}
+
+	client, err := newClient(opts)
+	if err != nil {
+		return "", err
+	}
+	repo.Client = client
+	repo.PlainHTTP = opts.PlainHTTP
+
+	// pack the artifact in memory and copy it to the registry afterwards
+	store := memory.New()
+
+	layer := content.NewDescriptorFromBytes(artifact.MediaType, artifact.Content)
+	layer.Annotations = map[string]string{ocispec.AnnotationTitle: artifact.FileName}
+	if err := store.Push(ctx, layer, bytes.NewReader(artifact.Content)); err != nil {
+		return "", err
+	}
+
+	manifest, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, artifact.ArtifactType, oras.PackManifestOptions{
+		Layers:              []ocispec.Descriptor{layer},
+		ManifestAnnotations: artifact.Annotations,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := store.Tag(ctx, manifest, tag); err != nil {
+		return "", err
+	}
+
+	if _, err := oras.Copy(ctx, store, tag, repo, tag, oras.DefaultCopyOptions); err != nil {
+		return "", err
+	}
+	return manifest.Digest.String(), nil
+}
+
+// newClient creates an authenticating client using the credentials of the registry config
+func newClient(opts Options) (*auth.Client, error) {
+	var stores []credentials.Store
+
+	if opts.RegistryConfig != "" {
+		store, err := credentials.NewStore(opts.RegistryConfig, credentials.StoreOptions{})
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	}
+
+	dockerStore, err := credentials.NewStoreFromDocker(credentials.StoreOptions{})
+	if err != nil {
+		return nil, err
+	}
+	stores = append(stores, dockerStore)
+
+	client := retry.DefaultClient
+	if opts.Client != nil {
+		client = opts.Client
+	}
+	return &auth.Client{
+		Client:     client,
+		Cache:      auth.NewCache(),
+		Credential: credentials.Credential(credentials.NewStoreWithFallbacks(stores[0], stores[1:]...)),
+	}, nil
+}
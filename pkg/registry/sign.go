@@ -0,0 +1,147 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// The in-toto predicate type of the provenance attestations and the build type of publishing schemas
+const (
+	ProvenancePredicateType = "https://slsa.dev/provenance/v1"
+	ProvenanceBuildType     = "https://github.com/ojsef39/helm-schema/publish@v1"
+	provenanceBuilderID     = "https://github.com/ojsef39/helm-schema"
+)
+
+// SignOptions configures the signing and attesting of pushed artifacts. Without commands, cosign signs
+// the artifacts and attaches the attestations (keyless or with the key configured for cosign).
+type SignOptions struct {
+	Sign bool
+	// SignCommand is the shell command signing an artifact, instead of cosign
+	SignCommand string
+	Attest      bool
+	// AttestCommand is the shell command attaching a provenance attestation to an artifact, instead of cosign
+	AttestCommand string
+}
+
+// Material is an input of a published artifact (e.g. the values file of the chart)
+type Material struct {
+	URI     string
+	Content []byte
+}
+
+// Provenance describes how a published artifact was built, as SLSA provenance predicate
+type Provenance struct {
+	// Parameters are the parameters of the build (e.g. the chart and the reference)
+	Parameters map[string]string
+	Materials  []Material
+	// BuilderVersion is the version of helm-schema
+	BuilderVersion string
+}
+
+// resourceDescriptor is an in-toto resource descriptor
+type resourceDescriptor struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate returns the SLSA provenance predicate. It contains no timestamps, so the same inputs always
+// result in the same predicate.
+func (p Provenance) Predicate() ([]byte, error) {
+	dependencies := []resourceDescriptor{}
+	for _, material := range p.Materials {
+		sum := sha256.Sum256(material.Content)
+		dependencies = append(dependencies, resourceDescriptor{URI: material.URI, Digest: map[string]string{"sha256": hex.EncodeToString(sum[:])}})
+	}
+	predicate := map[string]interface{}{
+		"buildDefinition": map[string]interface{}{
+			"buildType":            ProvenanceBuildType,
+			"externalParameters":   p.Parameters,
+			"resolvedDependencies": dependencies,
+		},
+		"runDetails": map[string]interface{}{
+			"builder": map[string]interface{}{
+				"id":      provenanceBuilderID,
+				"version": map[string]string{"helm-schema": p.BuilderVersion},
+			},
+		},
+	}
+	return json.MarshalIndent(predicate, "", "  ")
+}
+
+// DigestReference returns the reference of the pushed manifest by its digest (e.g. ghcr.io/org/schemas/foo@sha256:...)
+func DigestReference(ref, digest string) string {
+	repository := TrimScheme(ref)
+	if slash, colon := strings.LastIndex(repository, "/"), strings.LastIndex(repository, ":"); colon > slash {
+		repository = repository[:colon]
+	}
+	return repository + "@" + digest
+}
+
+// Sign signs the pushed manifest with the digest and attaches the provenance attestation, as configured by the
+// options. The reference, digest and the path of the provenance predicate are passed to the commands in the
+// environment (HELM_SCHEMA_REF, HELM_SCHEMA_DIGEST and HELM_SCHEMA_PROVENANCE) in addition to env.
+func Sign(ctx context.Context, ref, digest string, provenance Provenance, opts SignOptions, env []string) error {
+	digestRef := DigestReference(ref, digest)
+	env = append(env, "HELM_SCHEMA_REF="+digestRef, "HELM_SCHEMA_TAG="+TrimScheme(ref), "HELM_SCHEMA_DIGEST="+digest)
+
+	if opts.Sign {
+		if err := runCommand(ctx, "sign", opts.SignCommand, []string{"sign", "--yes", digestRef}, env); err != nil {
+			return err
+		}
+	}
+	if !opts.Attest {
+		return nil
+	}
+
+	predicate, err := provenance.Predicate()
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp("", "helm-schema-provenance-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(predicate); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return runCommand(ctx, "attest", opts.AttestCommand,
+		[]string{"attest", "--yes", "--type", "slsaprovenance1", "--predicate", file.Name(), digestRef},
+		append(env, "HELM_SCHEMA_PROVENANCE="+file.Name()))
+}
+
+// runCommand runs the shell command or, if it's empty, cosign with the arguments
+func runCommand(ctx context.Context, name, command string, cosignArgs, env []string) error {
+	var cmd *exec.Cmd
+	switch {
+	case command == "":
+		cmd = exec.CommandContext(ctx, "cosign", cosignArgs...)
+	case runtime.GOOS == "windows":
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	default:
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("%s command failed: %w: %s", name, err, message)
+		}
+		return fmt.Errorf("%s command failed: %w", name, err)
+	}
+	return nil
+}
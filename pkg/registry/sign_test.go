@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDigestReference(t *testing.T) {
+	tests := []struct {
+		ref      string
+		expected string
+	}{
+		{ref: "oci://ghcr.io/org/schemas/foo:1.0.0", expected: "ghcr.io/org/schemas/foo@sha256:abc"},
+		{ref: "localhost:5000/foo:1.0.0", expected: "localhost:5000/foo@sha256:abc"},
+		{ref: "localhost:5000/foo", expected: "localhost:5000/foo@sha256:abc"},
+	}
+	for _, test := range tests {
+		if actual := DigestReference(test.ref, "sha256:abc"); actual != test.expected {
+			t.Errorf("Was expecting %s for %s, but got %s", test.expected, test.ref, actual)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands use sh")
+	}
+	dir := t.TempDir()
+	provenance := Provenance{
+		Parameters:     map[string]string{"chart": "foo"},
+		Materials:      []Material{{URI: "file:foo/values.yaml", Content: []byte("foo: bar\n")}},
+		BuilderVersion: "1.0.0",
+	}
+	opts := SignOptions{
+		Sign:          true,
+		SignCommand:   `echo "$HELM_SCHEMA_REF $HELM_SCHEMA_CHART_NAME" > ` + filepath.Join(dir, "signed"),
+		Attest:        true,
+		AttestCommand: `cp "$HELM_SCHEMA_PROVENANCE" ` + filepath.Join(dir, "provenance.json"),
+	}
+	if err := Sign(context.Background(), "ghcr.io/org/foo:1.0.0", "sha256:abc", provenance, opts, []string{"HELM_SCHEMA_CHART_NAME=foo"}); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	signed, err := os.ReadFile(filepath.Join(dir, "signed"))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if string(signed) != "ghcr.io/org/foo@sha256:abc foo\n" {
+		t.Errorf("Was expecting the digest reference to be signed, but got %s", signed)
+	}
+	attested, err := os.ReadFile(filepath.Join(dir, "provenance.json"))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	predicate, err := provenance.Predicate()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if string(attested) != string(predicate) || !strings.Contains(string(predicate), `"sha256": "`) {
+		t.Errorf("Was expecting the provenance predicate to be attested, but got %s", attested)
+	}
+
+	opts.SignCommand = "exit 1"
+	if err := Sign(context.Background(), "ghcr.io/org/foo:1.0.0", "sha256:abc", provenance, opts, nil); err == nil {
+		t.Errorf("Was expecting an error for a failing sign command")
+	}
+}
@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// bitnamiParam matches the @param comments of the bitnami readme-generator-for-helm
+// (https://github.com/bitnami/readme-generator-for-helm), e.g. ## @param image.tag [string] Image tag
+var bitnamiParam = regexp.MustCompile(`^#+\s*@param\s+(\S+)(?:\s+\[([^\]]*)\])?\s*(.*)$`)
+
+// BitnamiParam is the description and the modifiers of a single @param comment
+type BitnamiParam struct {
+	Description string
+	Modifiers   []string
+}
+
+// ParseBitnamiParams returns the @param comments of all nodes, keyed by the dotted path of the value
+func ParseBitnamiParams(node *yaml.Node) map[string]BitnamiParam {
+	params := make(map[string]BitnamiParam)
+	collectBitnamiParams(node, params)
+	return params
+}
+
+func collectBitnamiParams(node *yaml.Node, params map[string]BitnamiParam) {
+	for _, comment := range []string{node.HeadComment, node.LineComment, node.FootComment} {
+		for _, line := range strings.Split(comment, "\n") {
+			match := bitnamiParam.FindStringSubmatch(strings.TrimSpace(line))
+			if match == nil {
+				continue
+			}
+			param := BitnamiParam{Description: strings.TrimSpace(match[3])}
+			for _, modifier := range strings.Split(match[2], ",") {
+				if modifier = strings.TrimSpace(modifier); modifier != "" {
+					param.Modifiers = append(param.Modifiers, modifier)
+				}
+			}
+			params[match[1]] = param
+		}
+	}
+	for _, child := range node.Content {
+		collectBitnamiParams(child, params)
+	}
+}
+
+// ApplyBitnamiParams sets the descriptions and types of the @param comments in the values.
+// Keywords of @schema annotations take precedence over the @param comments.
+func ApplyBitnamiParams(node *yaml.Node, s *Schema) {
+	params := ParseBitnamiParams(node)
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		node = node.Content[0]
+	}
+	applyBitnamiParams(node, s, "", params)
+
+	for path := range params {
+		log.Debugf("The @param %s doesn't match any value", path)
+	}
+}
+
+func applyBitnamiParams(node *yaml.Node, s *Schema, prefix string, params map[string]BitnamiParam) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if valueNode.Kind == yaml.AliasNode {
+			valueNode = valueNode.Alias
+		}
+		property := s.Properties[keyNode.Value]
+		if property == nil {
+			continue
+		}
+
+		path := keyNode.Value
+		if prefix != "" {
+			path = prefix + "." + keyNode.Value
+		}
+		// the annotations were already validated while generating the schema
+		annotation, _, _ := GetSchemaFromComment(keyNode.HeadComment)
+		if annotation.Description == "" {
+			property.Description = bitnamiDescription(property.Description)
+		}
+		if param, ok := params[path]; ok {
+			delete(params, path)
+			property.applyBitnamiParam(param, annotation)
+		}
+
+		applyBitnamiParams(valueNode, property, path, params)
+	}
+}
+
+func (s *Schema) applyBitnamiParam(param BitnamiParam, annotation Schema) {
+	if param.Description != "" && annotation.Description == "" {
+		s.Description = param.Description
+	}
+	if !annotation.Type.IsEmpty() {
+		return
+	}
+	for _, modifier := range param.Modifiers {
+		switch modifier {
+		case "array", "object", "string":
+			s.Type = StringOrArrayOfString{modifier}
+		}
+	}
+	// the default modifier only changes the documented default, it's ignored
+	if slices.Contains(param.Modifiers, "nullable") && !s.Type.IsEmpty() && !slices.Contains(s.Type, "null") {
+		s.Type = append(s.Type, "null")
+	}
+}
+
+// bitnamiDescription removes the remaining comment prefixes of the ## comments and the empty lines
+func bitnamiDescription(description string) string {
+	lines := []string{}
+	for _, line := range strings.Split(description, "\n") {
+		if line = strings.TrimSpace(strings.TrimLeft(line, CommentPrefix)); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
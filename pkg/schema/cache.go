@@ -0,0 +1,110 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// CacheEntry contains the hashes of a previously generated schema
+type CacheEntry struct {
+	// InputHash is the hash of the Chart.yaml and values file
+	InputHash string `json:"inputHash"`
+	// OutputHash additionally covers the resolved dependencies and patched conditions
+	OutputHash string `json:"outputHash"`
+	// SchemaHash is the hash of the written schema file
+	SchemaHash string `json:"schemaHash"`
+	// Schema is the jsonschema of the formats, whose files can't be read back as jsonschema (e.g. cue)
+	Schema json.RawMessage `json:"schema,omitempty"`
+}
+
+// Cache maps chart paths to the hashes of their last generation
+type Cache struct {
+	// Options is the hash of all options which influence the generated schemas.
+	// If the options change, the whole cache is invalid.
+	Options string                `json:"options"`
+	Entries map[string]CacheEntry `json:"entries"`
+
+	mu sync.Mutex
+}
+
+// LoadCache reads the cache from the given file. If the file doesn't exist or the
+// cache was created with different options, an empty cache is returned.
+func LoadCache(path, options string) (*Cache, error) {
+	cache := &Cache{Options: options, Entries: make(map[string]CacheEntry)}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cache, nil
+		}
+		return nil, err
+	}
+
+	var stored Cache
+	if err := json.Unmarshal(content, &stored); err != nil {
+		return nil, err
+	}
+
+	if stored.Options == options && stored.Entries != nil {
+		cache.Entries = stored.Entries
+	}
+
+	return cache, nil
+}
+
+// Save writes the cache to the given file
+func (c *Cache) Save(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, content, 0644)
+}
+
+// Get returns the entry of the given chart
+func (c *Cache) Get(chartPath string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.Entries[chartPath]
+	return entry, ok
+}
+
+// Set updates the entry of the given chart
+func (c *Cache) Set(chartPath string, entry CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Entries[chartPath] = entry
+}
+
+// Delete removes the entry of the given chart
+func (c *Cache) Delete(chartPath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.Entries, chartPath)
+}
+
+// Hash returns the hex encoded sha256 sum of the given parts
+func Hash(parts ...[]byte) string {
+	h := sha256.New()
+	var size [8]byte
+	for _, part := range parts {
+		// write the length first, so the parts can't be shifted into each other
+		binary.BigEndian.PutUint64(size[:], uint64(len(part)))
+		h.Write(size[:])
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
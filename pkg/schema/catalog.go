@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// CatalogFormatSchemastore creates a catalog like the one of https://www.schemastore.org
+	CatalogFormatSchemastore = "schemastore"
+	// CatalogFormatVSCode creates a mapping of schemas to file globs, like the yaml.schemas
+	// setting of the VS Code YAML extension
+	CatalogFormatVSCode = "vscode"
+
+	catalogSchema = "https://json.schemastore.org/schema-catalog.json"
+)
+
+// CatalogEntry describes the schema of a single chart
+type CatalogEntry struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	FileMatch   []string          `json:"fileMatch"`
+	URL         string            `json:"url"`
+	Versions    map[string]string `json:"versions,omitempty"`
+}
+
+// Catalog is an index of all generated schemas
+type Catalog struct {
+	Schema  string         `json:"$schema"`
+	Version float64        `json:"version"`
+	Schemas []CatalogEntry `json:"schemas"`
+}
+
+// NewCatalog creates a catalog of the given results. All paths are relative to catalogDir.
+func NewCatalog(results []*Result, catalogDir string) (*Catalog, error) {
+	catalog := &Catalog{Schema: catalogSchema, Version: 1, Schemas: []CatalogEntry{}}
+
+	for _, result := range results {
+		schemaURL, err := catalogPath(catalogDir, result.OutputPath)
+		if err != nil {
+			return nil, err
+		}
+		valuesPath, err := catalogPath(catalogDir, result.ValuesPath)
+		if err != nil {
+			return nil, err
+		}
+
+		// urls are relative references, file matches are globs relative to the catalog
+		schemaURL = "./" + schemaURL
+		entry := CatalogEntry{
+			Name:        result.Chart.Name,
+			Description: result.Chart.Description,
+			FileMatch:   []string{valuesPath},
+			URL:         schemaURL,
+		}
+		if result.Chart.Version != "" {
+			entry.Versions = map[string]string{result.Chart.Version: schemaURL}
+		}
+		catalog.Schemas = append(catalog.Schemas, entry)
+	}
+
+	slices.SortFunc(catalog.Schemas, func(a, b CatalogEntry) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.URL, b.URL)
+	})
+
+	return catalog, nil
+}
+
+// ToJson converts the catalog to json in the given format
+func (c *Catalog) ToJson(format string) ([]byte, error) {
+	if format == CatalogFormatVSCode {
+		associations := make(map[string][]string)
+		for _, entry := range c.Schemas {
+			associations[entry.URL] = append(associations[entry.URL], entry.FileMatch...)
+		}
+		return json.MarshalIndent(associations, "", "  ")
+	}
+	return json.MarshalIndent(c, "", "  ")
+}
+
+// catalogPath returns the path relative to the catalog directory
+func catalogPath(catalogDir, path string) (string, error) {
+	absCatalogDir, err := filepath.Abs(catalogDir)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	relPath, err := filepath.Rel(absCatalogDir, absPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(relPath), nil
+}
@@ -0,0 +1,83 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationsAnnotation is the key of the annotation with the CEL rules of the value (e.g. cross-field rules)
+const ValidationsAnnotation = "validations"
+
+// KubernetesValidationsAnnotation is the custom annotation the CEL rules are written to by default, it's the
+// one of CustomResourceDefinitions
+const KubernetesValidationsAnnotation = CustomAnnotationPrefix + "kubernetes-validations"
+
+// validationFields are the fields of a rule of x-kubernetes-validations
+var validationFields = []string{"rule", "message", "messageExpression", "reason", "fieldPath", "optionalOldSelf"}
+
+// validationReasons are the reasons of failing rules supported by kubernetes
+var validationReasons = []string{"FieldValueInvalid", "FieldValueForbidden", "FieldValueRequired", "FieldValueDuplicate"}
+
+// validationRules returns the rules of the validations annotation, which is a rule, a mapping with the rule and
+// its message (see validationFields) or a list of both
+func validationRules(node *yaml.Node) ([]interface{}, error) {
+	items := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		items = node.Content
+	}
+
+	rules := []interface{}{}
+	for _, item := range items {
+		if item.Kind == yaml.ScalarNode {
+			if item.Value == "" {
+				return nil, fmt.Errorf("the %s annotation contains an empty rule", ValidationsAnnotation)
+			}
+			rules = append(rules, map[string]interface{}{"rule": item.Value})
+			continue
+		}
+
+		var rule map[string]interface{}
+		if err := item.Decode(&rule); err != nil {
+			return nil, fmt.Errorf("the %s annotation must contain rules or mappings: %w", ValidationsAnnotation, err)
+		}
+		for field, value := range rule {
+			valid := false
+			switch field {
+			case "rule", "message", "messageExpression", "fieldPath":
+				_, valid = value.(string)
+			case "reason":
+				reason, ok := value.(string)
+				valid = ok && slices.Contains(validationReasons, reason)
+			case "optionalOldSelf":
+				_, valid = value.(bool)
+			default:
+				return nil, fmt.Errorf("unsupported field %s of a rule of the %s annotation, use one of %s", field, ValidationsAnnotation, strings.Join(validationFields, ", "))
+			}
+			if !valid {
+				return nil, fmt.Errorf("invalid value of the field %s of a rule of the %s annotation: %v", field, ValidationsAnnotation, value)
+			}
+		}
+		if rule["rule"] == nil || rule["rule"] == "" {
+			return nil, fmt.Errorf("a rule of the %s annotation has no rule", ValidationsAnnotation)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// RenameValidations moves the CEL rules of the validations annotations of the schema and all of its subschemas
+// from x-kubernetes-validations to the custom annotation (e.g. for CEL-aware admission tooling)
+func (s *Schema) RenameValidations(annotation string) {
+	if annotation == KubernetesValidationsAnnotation {
+		return
+	}
+	s.Walk(func(subSchema *Schema) {
+		if rules, ok := subSchema.CustomAnnotations[KubernetesValidationsAnnotation]; ok {
+			delete(subSchema.CustomAnnotations, KubernetesValidationsAnnotation)
+			subSchema.CustomAnnotations[annotation] = rules
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Changelog returns a markdown changelog fragment of the changes of the values of a chart,
+// grouped into added, removed and changed values
+func Changelog(title string, changes []Change) string {
+	var added, removed, changed []string
+	for _, change := range changes {
+		switch change.Message {
+		case messagePropertyAdded, messageRequiredPropertyAdded:
+			entry := fmt.Sprintf("- `%s`", change.Path)
+			if change.New != nil {
+				entry += fmt.Sprintf(" (default: `%s`)", changelogValue(change.New))
+			}
+			added = append(added, breakingEntry(change, entry))
+		case messagePropertyRemoved, messageAllowedPropertyRemoved:
+			entry := fmt.Sprintf("- `%s`", change.Path)
+			if change.Old != nil {
+				entry += fmt.Sprintf(" (default was: `%s`)", changelogValue(change.Old))
+			}
+			removed = append(removed, breakingEntry(change, entry))
+		case messageDescriptionChanged:
+			// not relevant for the users of the chart
+		default:
+			entry := fmt.Sprintf("- `%s`: %s", change.Path, change.Message)
+			switch {
+			case change.Old != nil && change.New != nil:
+				entry += fmt.Sprintf(" from `%s` to `%s`", changelogValue(change.Old), changelogValue(change.New))
+			case change.Old != nil:
+				entry += fmt.Sprintf(" (was `%s`)", changelogValue(change.Old))
+			case change.New != nil:
+				entry += fmt.Sprintf(" (`%s`)", changelogValue(change.New))
+			}
+			changed = append(changed, breakingEntry(change, entry))
+		}
+	}
+	if len(added)+len(removed)+len(changed) == 0 {
+		return ""
+	}
+
+	var changelog strings.Builder
+	fmt.Fprintf(&changelog, "## %s\n", title)
+	for _, section := range []struct {
+		title   string
+		entries []string
+	}{
+		{"Added", added},
+		{"Removed", removed},
+		{"Changed", changed},
+	} {
+		if len(section.entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(&changelog, "\n### %s\n\n%s\n", section.title, strings.Join(section.entries, "\n"))
+	}
+	return changelog.String()
+}
+
+// breakingEntry marks the entry of a breaking change
+func breakingEntry(change Change, entry string) string {
+	if change.Kind == ChangeBreaking {
+		return strings.Replace(entry, "- ", "- **Breaking:** ", 1)
+	}
+	return entry
+}
+
+func changelogValue(value interface{}) string {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(valueJSON)
+}
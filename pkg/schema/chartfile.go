@@ -0,0 +1,178 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+)
+
+// chartFileSchemaURL is the location the schema of Chart.yaml files is compiled at
+const chartFileSchemaURL = "file:///Chart.schema.json"
+
+// chartVersionPattern matches the versions helm accepts for charts, semantic versions with an optional v and
+// optional minor and patch versions
+const chartVersionPattern = `^v?(0|[1-9]\d*)(\.(0|[1-9]\d*)){0,2}(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`
+
+// chartAliasPattern matches the aliases helm accepts for dependencies
+const chartAliasPattern = `^[a-zA-Z0-9_-]+$`
+
+// ChartFileSchema returns the jsonschema of Chart.yaml files with the apiVersion (v2, if it's empty). The type
+// and the dependencies of a chart were added with apiVersion v2, charts with apiVersion v1 declare their
+// dependencies in requirements.yaml. Unknown keys are rejected, they're mostly misspelled ones, which helm ignores.
+func ChartFileSchema(apiVersion string) (*Schema, error) {
+	switch apiVersion {
+	case "":
+		apiVersion = chart.APIVersionV2
+	case chart.APIVersionV1, chart.APIVersionV2:
+	default:
+		return nil, fmt.Errorf("unsupported apiVersion %s, use %s or %s", apiVersion, chart.APIVersionV1, chart.APIVersionV2)
+	}
+
+	stringSchema := func(description string) *Schema {
+		return &Schema{Type: []string{"string"}, Description: description}
+	}
+	stringsSchema := func(description string) *Schema {
+		return &Schema{Type: []string{"array"}, Items: &Schema{Type: []string{"string"}}, Description: description}
+	}
+
+	minNameLength := 1
+
+	maintainer := &Schema{
+		Type:                 []string{"object"},
+		AdditionalProperties: false,
+		Required:             NewBoolOrArrayOfString([]string{"name"}, false),
+		Properties: map[string]*Schema{
+			"name":  stringSchema("name of the maintainer"),
+			"email": stringSchema("email of the maintainer"),
+			"url":   stringSchema("URL of the maintainer"),
+		},
+		PropertyOrder: []string{"name", "email", "url"},
+	}
+
+	importValue := &Schema{
+		AnyOf: []*Schema{
+			{Type: []string{"string"}},
+			{
+				Type:                 []string{"object"},
+				AdditionalProperties: false,
+				Required:             NewBoolOrArrayOfString([]string{"child", "parent"}, false),
+				Properties: map[string]*Schema{
+					"child":  {Type: []string{"string"}},
+					"parent": {Type: []string{"string"}},
+				},
+				PropertyOrder: []string{"child", "parent"},
+			},
+		},
+	}
+	dependency := &Schema{
+		Type:                 []string{"object"},
+		AdditionalProperties: false,
+		Required:             NewBoolOrArrayOfString([]string{"name"}, false),
+		Properties: map[string]*Schema{
+			"name":       stringSchema("name of the chart"),
+			"version":    stringSchema("version or version range of the chart"),
+			"repository": stringSchema("URL of the repository of the chart, @alias of a repository or file:// of a local chart"),
+			"condition":  stringSchema("values paths separated by commas, the first existing one enables or disables the chart"),
+			"tags":       stringsSchema("tags grouping charts, which are enabled or disabled together"),
+			"enabled":    {Type: []string{"boolean"}, Description: "whether the chart is enabled"},
+			"import-values": {
+				Type:        []string{"array"},
+				Items:       importValue,
+				Description: "values of the chart imported into the values of its parent",
+			},
+			"alias": {Type: []string{"string"}, Pattern: chartAliasPattern, Description: "name the chart is used with"},
+		},
+		PropertyOrder: []string{"name", "version", "repository", "condition", "tags", "enabled", "import-values", "alias"},
+	}
+
+	chartFileSchema := &Schema{
+		Schema:               "http://json-schema.org/draft-07/schema#",
+		Title:                "Chart.yaml",
+		Description:          "The metadata of a helm chart",
+		Type:                 []string{"object"},
+		AdditionalProperties: false,
+		Required:             NewBoolOrArrayOfString([]string{"apiVersion", "name", "version"}, false),
+		Properties: map[string]*Schema{
+			"apiVersion": {
+				Type:        []string{"string"},
+				Enum:        []string{apiVersion},
+				Description: "API version of the chart, v2 for charts of helm 3",
+			},
+			"name": {Type: []string{"string"}, MinLength: &minNameLength, Description: "name of the chart"},
+			"version": {
+				Type:        []string{"string"},
+				Pattern:     chartVersionPattern,
+				Description: "semantic version of the chart, numbers (e.g. 1.10) must be quoted to keep their digits",
+			},
+			"kubeVersion": stringSchema("semantic version range of the supported Kubernetes versions"),
+			"description": stringSchema("description of the chart"),
+			"type": {
+				Type:        []string{"string"},
+				Enum:        []string{"application", "library"},
+				Description: "type of the chart",
+			},
+			"keywords": stringsSchema("keywords of the chart"),
+			"home":     stringSchema("URL of the home page of the chart"),
+			"sources":  stringsSchema("URLs of the source code of the chart"),
+			"dependencies": {
+				Type:        []string{"array"},
+				Items:       dependency,
+				Description: "charts the chart depends on",
+			},
+			"maintainers": {Type: []string{"array"}, Items: maintainer, Description: "maintainers of the chart"},
+			"icon":        stringSchema("URL of an SVG or PNG image used as icon of the chart"),
+			"appVersion": {
+				Type:        []string{"string", "number"},
+				Description: "version of the application of the chart",
+			},
+			"deprecated": {Type: []string{"boolean"}, Description: "whether the chart is deprecated"},
+			"annotations": {
+				Type:                 []string{"object"},
+				AdditionalProperties: &Schema{Type: []string{"string"}},
+				Description:          "annotations of the chart, their values are strings",
+			},
+		},
+		PropertyOrder: []string{
+			"apiVersion", "name", "version", "kubeVersion", "description", "type", "keywords", "home", "sources",
+			"dependencies", "maintainers", "icon", "appVersion", "deprecated", "annotations",
+		},
+	}
+	if apiVersion == chart.APIVersionV1 {
+		delete(chartFileSchema.Properties, "type")
+		delete(chartFileSchema.Properties, "dependencies")
+		chartFileSchema.PropertyOrder = slices.DeleteFunc(chartFileSchema.PropertyOrder, func(key string) bool {
+			return key == "type" || key == "dependencies"
+		})
+	}
+	return chartFileSchema, nil
+}
+
+// ValidateChartFile validates the content of a Chart.yaml file against the ChartFileSchema of its apiVersion.
+// Chart files without a supported apiVersion are validated against the one of v2, which reports it.
+func ValidateChartFile(content []byte) error {
+	var header struct {
+		APIVersion interface{} `yaml:"apiVersion"`
+	}
+	if err := yaml.Unmarshal(content, &header); err != nil {
+		return err
+	}
+	apiVersion := chart.APIVersionV2
+	if header.APIVersion == chart.APIVersionV1 {
+		apiVersion = chart.APIVersionV1
+	}
+	chartFileSchema, err := ChartFileSchema(apiVersion)
+	if err != nil {
+		return err
+	}
+	if header.APIVersion != apiVersion {
+		chartFileSchema.Properties["apiVersion"].Enum = []string{chart.APIVersionV1, chart.APIVersionV2}
+	}
+	schemaJSON, err := chartFileSchema.ToJson()
+	if err != nil {
+		return err
+	}
+	return ValidateValuesJSON(schemaJSON, chartFileSchemaURL, content)
+}
@@ -0,0 +1,61 @@
+package schema
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// OutputCollision is a chart, whose schema would be written to the same file as the one of another chart
+type OutputCollision struct {
+	// Path is the output file with all symlinks resolved
+	Path   string
+	Result *Result
+	// Other is the chart writing the file first
+	Other *Result
+}
+
+func (c OutputCollision) Error() string {
+	return fmt.Sprintf("the schema would be written to %s, which is the output file of chart %s (%s) as well",
+		c.Path, c.Other.Chart.Name, c.Other.ChartPath)
+}
+
+// FindOutputCollisions returns the results, which target the same output file as an earlier result (e.g. because
+// of the output file template or symlinks). A chart found several times through symlinks to its directory
+// isn't a collision, as it always generates the same schema.
+func FindOutputCollisions(results []*Result) []OutputCollision {
+	collisions := []OutputCollision{}
+	writers := make(map[string]*Result)
+	for _, result := range results {
+		if result.OutputPath == "" || result.Chart == nil {
+			continue
+		}
+		path := resolvePath(result.OutputPath)
+		other, ok := writers[path]
+		if !ok {
+			writers[path] = result
+			continue
+		}
+		if resolvePath(filepath.Dir(result.ChartPath)) == resolvePath(filepath.Dir(other.ChartPath)) {
+			continue
+		}
+		collisions = append(collisions, OutputCollision{Path: path, Result: result, Other: other})
+	}
+	return collisions
+}
+
+// resolvePath returns the absolute path with all symlinks resolved, the file and its directories don't
+// have to exist yet
+func resolvePath(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		return resolved
+	}
+	dir := filepath.Dir(absPath)
+	if dir == absPath {
+		return absPath
+	}
+	return filepath.Join(resolvePath(dir), filepath.Base(absPath))
+}
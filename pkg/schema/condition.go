@@ -0,0 +1,65 @@
+package schema
+
+import (
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// DefaultConditionDescription is the description of the properties patched into dependencies for their conditions
+const DefaultConditionDescription = "Conditional property used in parent chart"
+
+// ConditionPatch configures the property patched into the schema of a dependency for its condition in the parent
+// chart (e.g. enabled of the condition subchart.enabled)
+type ConditionPatch struct {
+	// Type is the type of the property, if the values of the dependency don't declare it
+	Type []string
+	// Description is the go template of the description, if the values of the dependency don't describe the
+	// property. It's rendered with the result of the dependency and the condition (e.g. {{ .Condition }}).
+	Description string
+}
+
+// conditionTemplateData is the data the description of a patched condition is rendered with
+type conditionTemplateData struct {
+	*Result
+	Condition string
+}
+
+// PatchCondition adds the property of the condition with the path keys (below the key of the dependency) to
+// the schema of the result. Existing properties are merged: their types and descriptions (e.g. of helm-docs
+// comments) are kept and only the missing ones are added.
+func (r *Result) PatchCondition(condition string, keys []string, patch ConditionPatch) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	description, err := util.RenderTemplate("condition description", patch.Description, conditionTemplateData{Result: r, Condition: condition})
+	if err != nil {
+		return err
+	}
+
+	s := &r.Schema
+	for i, key := range keys {
+		property, ok := s.Properties[key]
+		if !ok {
+			property = &Schema{Title: key}
+			s.SetProperty(key, property)
+		}
+		if i < len(keys)-1 {
+			if untyped(property) {
+				property.Type = []string{"object"}
+			}
+			s = property
+			continue
+		}
+		if untyped(property) {
+			property.Type = patch.Type
+		}
+		if property.Description == "" {
+			property.Description = description
+		}
+	}
+	return nil
+}
+
+// untyped returns true, if the schema neither has a type nor gets one from a $ref or a composition
+func untyped(s *Schema) bool {
+	return len(s.Type) == 0 && s.Ref == "" && len(s.AnyOf) == 0 && len(s.AllOf) == 0 && len(s.OneOf) == 0
+}
@@ -0,0 +1,160 @@
+package schema
+
+import (
+	"slices"
+	"sort"
+)
+
+// The rules of the inconsistencies of values shared by several charts
+const (
+	// InconsistencyType is reported for values with incompatible types in the charts
+	InconsistencyType = "inconsistent-type"
+	// InconsistencyEnum is reported for values with different enums in the charts
+	InconsistencyEnum = "inconsistent-enum"
+)
+
+// ValueConvention is the schema of a value in one chart
+type ValueConvention struct {
+	Chart     string   `json:"chart"`
+	ChartPath string   `json:"chartPath"`
+	Types     []string `json:"types,omitempty"`
+	Enum      []string `json:"enum,omitempty"`
+}
+
+// Inconsistency is a value (e.g. image.pullPolicy), which has incompatible types or different enums in the charts
+type Inconsistency struct {
+	// Path of the value in the dotted notation of helm
+	Path string `json:"path"`
+	// Rule is InconsistencyType or InconsistencyEnum
+	Rule string `json:"rule"`
+	// Charts are the conventions of all charts with the value
+	Charts []ValueConvention `json:"charts"`
+}
+
+// Inconsistencies compares the values shared by the charts of the results and returns the ones with incompatible
+// types or different enums, ordered by their paths. The values of the dependencies of a chart are compared in
+// their own charts, lists are compared without their items, and the values suppressed by ignore comments of a
+// chart (see IgnoreCommentPrefix) aren't compared for it. The second return value is the number of values
+// shared by several charts.
+func Inconsistencies(results []*Result) ([]Inconsistency, int, error) {
+	conventions := map[string][]ValueConvention{}
+	for _, result := range results {
+		suppressions, err := result.Suppressions()
+		if err != nil {
+			return nil, 0, err
+		}
+		name := ""
+		ignored := []string{}
+		if result.Chart != nil {
+			name = result.Chart.Name
+			for _, dep := range result.Chart.Dependencies {
+				ignored = append(ignored, DependencyKey(dep))
+			}
+		}
+		for _, key := range result.Schema.PropertyNames() {
+			if !slices.Contains(ignored, key) {
+				collectConventions(result.Schema.Properties[key], key, ValueConvention{Chart: name, ChartPath: result.ChartPath}, suppressions, conventions)
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(conventions))
+	for path, charts := range conventions {
+		if len(charts) > 1 {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	inconsistencies := []Inconsistency{}
+	for _, path := range paths {
+		charts := conventions[path]
+		if !compatibleTypes(charts) {
+			inconsistencies = append(inconsistencies, Inconsistency{Path: path, Rule: InconsistencyType, Charts: charts})
+		} else if !equalEnums(charts) {
+			inconsistencies = append(inconsistencies, Inconsistency{Path: path, Rule: InconsistencyEnum, Charts: charts})
+		}
+	}
+	return inconsistencies, len(paths), nil
+}
+
+// collectConventions adds the convention of the value with the path and of the values below it
+func collectConventions(s *Schema, path string, chart ValueConvention, suppressions Suppressions, conventions map[string][]ValueConvention) {
+	if s == nil || s.Ref != "" {
+		return
+	}
+	if !suppressions.Suppressed(path, InconsistencyType) || !suppressions.Suppressed(path, InconsistencyEnum) {
+		convention := chart
+		if !suppressions.Suppressed(path, InconsistencyType) {
+			convention.Types = conventionTypes(s)
+		}
+		if !suppressions.Suppressed(path, InconsistencyEnum) {
+			convention.Enum = slices.Clone(s.Enum)
+			sort.Strings(convention.Enum)
+		}
+		conventions[path] = append(conventions[path], convention)
+	}
+	for _, name := range s.PropertyNames() {
+		collectConventions(s.Properties[name], path+"."+name, chart, suppressions, conventions)
+	}
+}
+
+// conventionTypes returns the types of the value without null, which only unsets it. The types of compositions
+// are used for values without a type.
+func conventionTypes(s *Schema) []string {
+	types := slices.Clone([]string(s.Type))
+	if len(types) == 0 {
+		for _, compositions := range [][]*Schema{s.AnyOf, s.OneOf} {
+			for _, composition := range compositions {
+				if composition != nil {
+					types = append(types, composition.Type...)
+				}
+			}
+		}
+	}
+	types = slices.DeleteFunc(types, func(t string) bool { return t == "null" })
+	sort.Strings(types)
+	return slices.Compact(types)
+}
+
+// compatibleTypes returns true if every two conventions with types share one, integers are numbers
+func compatibleTypes(charts []ValueConvention) bool {
+	for i, a := range charts {
+		for _, b := range charts[i+1:] {
+			if len(a.Types) == 0 || len(b.Types) == 0 {
+				continue
+			}
+			if !slices.ContainsFunc(a.Types, func(t string) bool {
+				return slices.ContainsFunc(b.Types, func(u string) bool { return compatibleType(t, u) })
+			}) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compatibleType returns true if values of both types can be the same
+func compatibleType(a, b string) bool {
+	if a == b {
+		return true
+	}
+	numbers := []string{"integer", "number"}
+	return slices.Contains(numbers, a) && slices.Contains(numbers, b)
+}
+
+// equalEnums returns true if the conventions with enums all have the same one
+func equalEnums(charts []ValueConvention) bool {
+	var enum []string
+	for _, chart := range charts {
+		if len(chart.Enum) == 0 {
+			continue
+		}
+		if enum == nil {
+			enum = chart.Enum
+		} else if !slices.Equal(enum, chart.Enum) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package schema
+
+import "slices"
+
+// Coverage counts the leaf values of a schema (the values without properties) with descriptions, types and
+// titles
+type Coverage struct {
+	Values      int     `json:"values"`
+	Described   int     `json:"described"`
+	Typed       int     `json:"typed"`
+	WithTitles  int     `json:"withTitles"`
+	Description float64 `json:"descriptionPercent"`
+	Type        float64 `json:"typePercent"`
+	Title       float64 `json:"titlePercent"`
+}
+
+// Coverage returns the coverage of the leaf values of the schema. The properties of objects are counted instead
+// of them, lists are single values and references (e.g. to shared definitions) aren't counted. The top-level
+// properties with the ignored names (e.g. the ones of the dependencies, which are covered by their own charts)
+// are skipped.
+func (s *Schema) Coverage(ignored ...string) Coverage {
+	var coverage Coverage
+	for _, name := range s.PropertyNames() {
+		if !slices.Contains(ignored, name) {
+			s.Properties[name].addCoverage(&coverage)
+		}
+	}
+	coverage.Description = coveragePercent(coverage.Described, coverage.Values)
+	coverage.Type = coveragePercent(coverage.Typed, coverage.Values)
+	coverage.Title = coveragePercent(coverage.WithTitles, coverage.Values)
+	return coverage
+}
+
+// addCoverage counts the leaf values of the property
+func (s *Schema) addCoverage(coverage *Coverage) {
+	switch {
+	case s == nil || s.Ref != "":
+		return
+	case len(s.Properties) > 0:
+		for _, name := range s.PropertyNames() {
+			s.Properties[name].addCoverage(coverage)
+		}
+		return
+	}
+
+	coverage.Values++
+	if s.Description != "" {
+		coverage.Described++
+	}
+	if len(s.Type) > 0 || len(s.AnyOf) > 0 || len(s.OneOf) > 0 || len(s.AllOf) > 0 {
+		coverage.Typed++
+	}
+	if s.Title != "" {
+		coverage.WithTitles++
+	}
+}
+
+// coveragePercent returns the percentage of the count of the values, schemas without values are covered
+func coveragePercent(count, values int) float64 {
+	if values == 0 {
+		return 100
+	}
+	return float64(count) * 100 / float64(values)
+}
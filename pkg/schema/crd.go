@@ -0,0 +1,210 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ChartAnnotationCRDGroup, ChartAnnotationCRDKind and ChartAnnotationCRDVersion can
+	// be used in the annotations of Chart.yaml to override the CRD options
+	ChartAnnotationCRDGroup   = "helm-schema/crd-group"
+	ChartAnnotationCRDKind    = "helm-schema/crd-kind"
+	ChartAnnotationCRDVersion = "helm-schema/crd-version"
+)
+
+var crdKind = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// crdAnnotations are the custom annotations supported by kubernetes in CRD schemas
+var crdAnnotations = []string{"nullable", "example", "enum", "exclusiveMinimum", "exclusiveMaximum"}
+
+// CRDOptions describes the CustomResourceDefinition wrapping the values schema
+type CRDOptions struct {
+	Group   string
+	Kind    string
+	Version string
+}
+
+// CRDOptions returns the CRD options of the chart. The annotations of Chart.yaml take
+// precedence over the given defaults. The kind defaults to the chart name.
+func (r *Result) CRDOptions(defaults CRDOptions) (CRDOptions, error) {
+	opts := defaults
+	if group, ok := r.Chart.Annotations[ChartAnnotationCRDGroup]; ok {
+		opts.Group = group
+	}
+	if kind, ok := r.Chart.Annotations[ChartAnnotationCRDKind]; ok {
+		opts.Kind = kind
+	}
+	if version, ok := r.Chart.Annotations[ChartAnnotationCRDVersion]; ok {
+		opts.Version = version
+	}
+	if opts.Kind == "" {
+		opts.Kind = pascalCase(r.Chart.Name)
+	}
+
+	if opts.Group == "" {
+		return opts, errors.New("the group of the CRD is missing (use --crd-group or the annotation " + ChartAnnotationCRDGroup + ")")
+	}
+	if !crdKind.MatchString(opts.Kind) {
+		return opts, fmt.Errorf("invalid kind of the CRD: %s", opts.Kind)
+	}
+	if opts.Version == "" {
+		return opts, errors.New("the version of the CRD is missing")
+	}
+	return opts, nil
+}
+
+// ToCRD wraps the schema into a CustomResourceDefinition, which validates
+// the values in spec.values of the custom resources.
+func (s *Schema) ToCRD(opts CRDOptions) ([]byte, error) {
+	valuesSchema, err := s.openAPICopy()
+	if err != nil {
+		return nil, err
+	}
+	valuesSchema.Walk(toStructural)
+
+	valuesJSON, err := json.Marshal(valuesSchema)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(valuesJSON, &values); err != nil {
+		return nil, err
+	}
+
+	plural := pluralize(strings.ToLower(opts.Kind))
+	crd := map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata": map[string]interface{}{
+			"name": plural + "." + opts.Group,
+		},
+		"spec": map[string]interface{}{
+			"group": opts.Group,
+			"names": map[string]interface{}{
+				"kind":     opts.Kind,
+				"listKind": opts.Kind + "List",
+				"plural":   plural,
+				"singular": strings.ToLower(opts.Kind),
+			},
+			"scope": "Namespaced",
+			"versions": []interface{}{
+				map[string]interface{}{
+					"name":    opts.Version,
+					"served":  true,
+					"storage": true,
+					"schema": map[string]interface{}{
+						"openAPIV3Schema": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"spec": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"values": values,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(crd); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toStructural converts a single OpenAPI schema into a structural schema
+// (https://kubernetes.io/docs/tasks/extend-kubernetes/custom-resources/custom-resource-definitions/#specifying-a-structural-schema)
+func toStructural(s *Schema) {
+	annotations := make(map[string]interface{})
+	for key, value := range s.CustomAnnotations {
+		if strings.HasPrefix(key, "x-kubernetes-") || slices.Contains(crdAnnotations, key) {
+			annotations[key] = value
+		}
+	}
+	s.CustomAnnotations = annotations
+	if len(s.Required.Strings) == 0 {
+		annotations["required"] = omittedKeyword{}
+	}
+	s.Ref = ""
+	s.Deprecated = false
+	s.ReadOnly = false
+	s.WriteOnly = false
+
+	// every node needs exactly one type, everything else can't be pruned
+	if len(s.Type) != 1 {
+		if len(s.AnyOf) == 2 && s.anyOfTypes("integer", "string") {
+			annotations["x-kubernetes-int-or-string"] = true
+		} else {
+			annotations["x-kubernetes-preserve-unknown-fields"] = true
+		}
+		s.Type = nil
+		s.AnyOf = nil
+		s.OneOf = nil
+		s.AllOf = nil
+		s.Not = nil
+		s.Items = nil
+		s.Properties = nil
+		s.AdditionalProperties = nil
+		s.Required = BoolOrArrayOfString{}
+		annotations["required"] = omittedKeyword{}
+		return
+	}
+
+	if s.Type[0] != "object" {
+		return
+	}
+
+	// additionalProperties can neither be false nor used together with properties
+	additionalSchema, hasAdditionalSchema := s.AdditionalProperties.(*Schema)
+	allowsAdditional := s.AdditionalProperties == nil || s.AdditionalProperties == true
+	s.AdditionalProperties = nil
+	switch {
+	case len(s.Properties) == 0 && hasAdditionalSchema:
+		s.AdditionalProperties = additionalSchema
+	case len(s.Properties) == 0 || allowsAdditional || hasAdditionalSchema:
+		annotations["x-kubernetes-preserve-unknown-fields"] = true
+	}
+}
+
+// anyOfTypes returns true if the anyOf branches have exactly the given types
+func (s *Schema) anyOfTypes(types ...string) bool {
+	anyOfTypes := []string{}
+	for _, subSchema := range s.AnyOf {
+		if len(subSchema.Type) != 1 {
+			return false
+		}
+		anyOfTypes = append(anyOfTypes, subSchema.Type[0])
+	}
+	slices.Sort(anyOfTypes)
+	return slices.Equal(anyOfTypes, types)
+}
+
+// pluralize returns the english plural of a lowercase kind
+func pluralize(kind string) string {
+	switch {
+	case strings.HasSuffix(kind, "s"), strings.HasSuffix(kind, "x"), strings.HasSuffix(kind, "ch"), strings.HasSuffix(kind, "sh"):
+		return kind + "es"
+	case strings.HasSuffix(kind, "y") && len(kind) > 1 && !strings.ContainsRune("aeiou", rune(kind[len(kind)-2])):
+		return kind[:len(kind)-1] + "ies"
+	default:
+		return kind + "s"
+	}
+}
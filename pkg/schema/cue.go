@@ -0,0 +1,252 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var cueIdentifier = regexp.MustCompile(`^[A-Za-z$][A-Za-z0-9_$]*$`)
+
+// cueKeywords can't be used as unquoted field names
+var cueKeywords = []string{"null", "true", "false", "if", "for", "in", "let", "import", "package", "div", "mod", "quo", "rem"}
+
+// cueGenerator collects the imports needed by the generated constraints
+type cueGenerator struct {
+	imports map[string]bool
+}
+
+// ToCue converts the schema into a CUE definition with the given name (without #)
+func (s *Schema) ToCue(name string) ([]byte, error) {
+	g := &cueGenerator{imports: make(map[string]bool)}
+	definition := g.cueType(s, "")
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by helm-schema. DO NOT EDIT.\n\n")
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for imp := range g.imports {
+			imports = append(imports, strconv.Quote(imp))
+		}
+		sort.Strings(imports)
+		fmt.Fprintf(&buf, "import (\n\t%s\n)\n\n", strings.Join(imports, "\n\t"))
+	}
+	writeCueDoc(&buf, s.Description, "")
+	fmt.Fprintf(&buf, "#%s: %s\n", name, definition)
+
+	return buf.Bytes(), nil
+}
+
+// cueType returns the CUE expression of the schema. Nested structs are indented with indent.
+func (g *cueGenerator) cueType(s *Schema, indent string) string {
+	if s == nil {
+		return "_"
+	}
+
+	var expr string
+	switch {
+	case s.Const != nil:
+		expr = cueLiteral(s.Const)
+	case len(s.Enum) > 0:
+		literals := make([]string, len(s.Enum))
+		for i, value := range s.Enum {
+			literals[i] = s.cueEnumLiteral(value)
+		}
+		expr = strings.Join(literals, " | ")
+	case len(s.AnyOf) > 0:
+		expr = g.cueSubSchemas(s.AnyOf, " | ", indent)
+	case len(s.OneOf) > 0:
+		expr = g.cueSubSchemas(s.OneOf, " | ", indent)
+	case len(s.Type) > 0:
+		types := make([]string, len(s.Type))
+		for i, schemaType := range s.Type {
+			types[i] = g.cuePrimitive(s, schemaType, indent)
+		}
+		expr = strings.Join(types, " | ")
+	default:
+		expr = "_"
+	}
+
+	if len(s.AllOf) > 0 {
+		conjunction := g.cueSubSchemas(s.AllOf, " & ", indent)
+		if expr == "_" {
+			expr = conjunction
+		} else {
+			expr = "(" + expr + ") & " + conjunction
+		}
+	}
+
+	if defaultLiteral, ok := cueDefault(s.Default); ok {
+		expr = "*" + defaultLiteral + " | " + expr
+	}
+	return expr
+}
+
+func (g *cueGenerator) cueSubSchemas(subSchemas []*Schema, separator, indent string) string {
+	exprs := make([]string, len(subSchemas))
+	for i, subSchema := range subSchemas {
+		exprs[i] = g.cueType(subSchema, indent)
+		if strings.Contains(exprs[i], " ") && !strings.HasPrefix(exprs[i], "{") {
+			exprs[i] = "(" + exprs[i] + ")"
+		}
+	}
+	return strings.Join(exprs, separator)
+}
+
+func (g *cueGenerator) cuePrimitive(s *Schema, schemaType, indent string) string {
+	constraints := []string{}
+
+	switch schemaType {
+	case "string":
+		constraints = append(constraints, "string")
+		if s.Pattern != "" {
+			constraints = append(constraints, "=~"+cueLiteral(s.Pattern))
+		}
+		if s.MinLength != nil {
+			g.imports["strings"] = true
+			constraints = append(constraints, fmt.Sprintf("strings.MinRunes(%d)", *s.MinLength))
+		}
+		if s.MaxLength != nil {
+			g.imports["strings"] = true
+			constraints = append(constraints, fmt.Sprintf("strings.MaxRunes(%d)", *s.MaxLength))
+		}
+	case "integer", "number":
+		if schemaType == "integer" {
+			constraints = append(constraints, "int")
+		} else {
+			constraints = append(constraints, "number")
+		}
+		if s.Minimum != nil {
+			constraints = append(constraints, fmt.Sprintf(">=%d", *s.Minimum))
+		}
+		if s.ExclusiveMinimum != nil {
+			constraints = append(constraints, fmt.Sprintf(">%d", *s.ExclusiveMinimum))
+		}
+		if s.Maximum != nil {
+			constraints = append(constraints, fmt.Sprintf("<=%d", *s.Maximum))
+		}
+		if s.ExclusiveMaximum != nil {
+			constraints = append(constraints, fmt.Sprintf("<%d", *s.ExclusiveMaximum))
+		}
+	case "boolean":
+		constraints = append(constraints, "bool")
+	case "null":
+		constraints = append(constraints, "null")
+	case "array":
+		constraints = append(constraints, "[..."+g.cueType(s.Items, indent)+"]")
+		if s.MinItems != nil {
+			g.imports["list"] = true
+			constraints = append(constraints, fmt.Sprintf("list.MinItems(%d)", *s.MinItems))
+		}
+		if s.MaxItems != nil {
+			g.imports["list"] = true
+			constraints = append(constraints, fmt.Sprintf("list.MaxItems(%d)", *s.MaxItems))
+		}
+	case "object":
+		constraints = append(constraints, g.cueStruct(s, indent))
+	default:
+		constraints = append(constraints, "_")
+	}
+
+	expr := strings.Join(constraints, " & ")
+	if len(s.Type) > 1 && len(constraints) > 1 {
+		return "(" + expr + ")"
+	}
+	return expr
+}
+
+// cueStruct returns the struct of the object schema. Definitions are closed
+// in CUE, so additional properties need to be allowed explicitly.
+func (g *cueGenerator) cueStruct(s *Schema, indent string) string {
+	additionalType := ""
+	switch additionalProperties := s.AdditionalProperties.(type) {
+	case *Schema:
+		additionalType = g.cueType(additionalProperties, indent+"\t")
+	case bool:
+		if additionalProperties {
+			additionalType = "_"
+		}
+	case nil:
+		additionalType = "_"
+	}
+
+	if len(s.Properties) == 0 {
+		switch additionalType {
+		case "":
+			return "close({})"
+		case "_":
+			return "{...}"
+		default:
+			return "{[string]: " + additionalType + "}"
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	for _, name := range s.PropertyNames() {
+		property := s.Properties[name]
+		if property == nil {
+			continue
+		}
+		writeCueDoc(&buf, property.Description, indent+"\t")
+
+		label := name
+		if !cueIdentifier.MatchString(name) || slices.Contains(cueKeywords, name) {
+			label = cueLiteral(name)
+		}
+		optional := "?"
+		if property.Required.Bool || slices.Contains(s.Required.Strings, name) {
+			optional = ""
+		}
+		fmt.Fprintf(&buf, "%s\t%s%s: %s\n", indent, label, optional, g.cueType(property, indent+"\t"))
+	}
+	if additionalType != "" {
+		fmt.Fprintf(&buf, "%s\t...\n", indent)
+	}
+	buf.WriteString(indent + "}")
+	return buf.String()
+}
+
+func cueLiteral(value interface{}) string {
+	literal, err := json.Marshal(value)
+	if err != nil {
+		return "_"
+	}
+	return string(literal)
+}
+
+// cueDefault returns the default of scalar values
+func cueDefault(value interface{}) (string, bool) {
+	switch value.(type) {
+	case string, bool, int, int64, float64, uint64, json.Number:
+		return cueLiteral(value), true
+	default:
+		return "", false
+	}
+}
+
+// cueEnumLiteral returns the literal of the enum value. The enum values are
+// stored as strings, so they are only quoted if the schema allows strings.
+func (s *Schema) cueEnumLiteral(value string) string {
+	if value == "null" {
+		return "null"
+	}
+	if !slices.Contains(s.Type, "string") && json.Valid([]byte(value)) {
+		return value
+	}
+	return cueLiteral(value)
+}
+
+func writeCueDoc(buf *bytes.Buffer, description, indent string) {
+	if description == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSpace(description), "\n") {
+		fmt.Fprintf(buf, "%s// %s\n", indent, strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,144 @@
+package schema
+
+import (
+	"cmp"
+	"encoding/json"
+	"regexp"
+	"slices"
+)
+
+// defNameInvalidChars matches the characters, which aren't used in the names of deduplicated definitions
+var defNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// duplicate are the occurrences of structurally identical subschemas
+type duplicate struct {
+	name        string
+	hash        string
+	size        int
+	occurrences []*Schema
+}
+
+// Dedupe returns a copy of the schema, in which the subschemas occurring more than once are moved to
+// its $defs and replaced by $refs. The schema itself isn't changed, because its subschemas are shared
+// with the schemas of the charts depending on it. Only subschemas containing other subschemas (e.g. objects with properties) are
+// considered, their titles and descriptions must be identical as well. The definitions are named
+// after the key of the first occurrence and the hash of their content (e.g. image-1a2b3c4d), so the
+// definitions of dependencies merged into their parents can't collide. Larger subschemas are
+// deduplicated first. It also returns the number of replaced subschemas.
+func (s *Schema) Dedupe() (*Schema, int, error) {
+	s, err := s.clone()
+	if err != nil {
+		return nil, 0, err
+	}
+	duplicates := make(map[string]*duplicate)
+	order := []*duplicate{}
+	var collect func(name string, subSchema *Schema, candidate bool)
+	collect = func(name string, subSchema *Schema, candidate bool) {
+		if subSchema == nil || err != nil {
+			return
+		}
+		if candidate && subSchema.Ref == "" && subSchema.hasSubschemas() {
+			var content []byte
+			if content, err = json.Marshal(subSchema); err != nil {
+				return
+			}
+			hash := Hash(content)
+			if _, ok := duplicates[hash]; !ok {
+				duplicates[hash] = &duplicate{name: name, hash: hash, size: len(content)}
+				order = append(order, duplicates[hash])
+			}
+			duplicates[hash].occurrences = append(duplicates[hash].occurrences, subSchema)
+		}
+		subSchema.forEachSubschema(name, func(childName string, child *Schema) {
+			collect(childName, child, true)
+		})
+	}
+	collect("", s, false)
+	for _, name := range sortedKeys(s.Defs) {
+		// the definitions themselves are kept, only their content is deduplicated
+		collect(name, s.Defs[name], false)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the larger subschemas contain the smaller ones, so they are replaced first
+	slices.SortStableFunc(order, func(a, b *duplicate) int {
+		return cmp.Compare(b.size, a.size)
+	})
+	detached := make(map[*Schema]bool)
+	replaced := 0
+	for _, dup := range order {
+		live := slices.DeleteFunc(dup.occurrences, func(occurrence *Schema) bool {
+			return detached[occurrence]
+		})
+		if len(live) < 2 {
+			continue
+		}
+		name := defNameInvalidChars.ReplaceAllString(dup.name, "-")
+		if name == "" {
+			name = "def"
+		}
+		name += "-" + dup.hash[:8]
+
+		if s.Defs == nil {
+			s.Defs = make(map[string]*Schema)
+		}
+		if _, exists := s.Defs[name]; !exists {
+			def := *live[0]
+			s.Defs[name] = &def
+		}
+		for i, occurrence := range live {
+			// the subschemas of the first occurrence are still part of the definition
+			if i > 0 {
+				occurrence.Walk(func(descendant *Schema) {
+					detached[descendant] = true
+				})
+			}
+			*occurrence = Schema{Ref: defsRefPrefix + name}
+			replaced++
+		}
+	}
+	return s, replaced, nil
+}
+
+// hasSubschemas returns true if the schema contains other schemas
+func (s *Schema) hasSubschemas() bool {
+	_, additionalSchema := s.AdditionalProperties.(*Schema)
+	return len(s.Properties) > 0 || len(s.PatternProperties) > 0 || additionalSchema || s.Items != nil ||
+		len(s.AnyOf) > 0 || len(s.AllOf) > 0 || len(s.OneOf) > 0 || s.Not != nil || s.If != nil || s.Then != nil || s.Else != nil
+}
+
+// forEachSubschema calls fn for the direct subschemas of the schema (without the $defs) with
+// their names, which are the keys of properties and the name of the schema otherwise
+func (s *Schema) forEachSubschema(name string, fn func(string, *Schema)) {
+	for _, property := range s.PropertyNames() {
+		fn(property, s.Properties[property])
+	}
+	for _, pattern := range sortedKeys(s.PatternProperties) {
+		fn(name, s.PatternProperties[pattern])
+	}
+	if additionalProperties, ok := s.AdditionalProperties.(*Schema); ok {
+		fn(name, additionalProperties)
+	}
+	fn(name, s.Items)
+	for _, subSchema := range slices.Concat(s.AnyOf, s.AllOf, s.OneOf) {
+		fn(name, subSchema)
+	}
+	for _, subSchema := range []*Schema{s.Not, s.If, s.Then, s.Else} {
+		fn(name, subSchema)
+	}
+}
+
+// AddDefs adds the definitions, which don't exist in the schema yet (e.g. the ones of a
+// dependency merged into the schema of its parent, whose $refs point to the root)
+func (s *Schema) AddDefs(defs map[string]*Schema) {
+	for name, def := range defs {
+		if s.Defs == nil {
+			s.Defs = make(map[string]*Schema)
+		}
+		if _, exists := s.Defs[name]; !exists {
+			s.Defs[name] = def
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/dadav/go-jsonpointer"
+	"gopkg.in/yaml.v3"
+)
+
+// SharedDefsFile is the repo-level file containing the definitions, which can be referenced
+// from the annotations of every chart as #/$defs/<name>
+const SharedDefsFile = "schema-defs.yaml"
+
+// The ways shared definitions end up in the generated schemas
+const (
+	// DefsModeBundle copies the referenced definitions to the $defs of the generated schema
+	DefsModeBundle = "bundle"
+	// DefsModeInline replaces the references by the definitions
+	DefsModeInline = "inline"
+)
+
+const defsRefPrefix = "#/$defs/"
+
+// ReadSharedDefs returns the definitions of the shared definitions file or nil if there is none
+func ReadSharedDefs(path string) (map[string]*Schema, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defs := make(map[string]*Schema)
+	if err := yaml.Unmarshal(content, &defs); err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", path, err)
+	}
+	for _, name := range sortedKeys(defs) {
+		if defs[name] == nil {
+			return nil, fmt.Errorf("the definition %s of %s is empty", name, path)
+		}
+		if err := defs[name].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid definition %s of %s: %w", name, path, err)
+		}
+	}
+	return defs, nil
+}
+
+// ResolveSharedDefs makes the references to #/$defs/<name> resolvable. Definitions of the schema itself
+// (e.g. added by a patch) take precedence over the shared ones. In bundle mode, the referenced shared
+// definitions (and the ones they reference) are copied to the $defs of the schema, in inline mode the
+// references are replaced by copies of the definitions, which fails for circular references.
+// Titles and descriptions next to a reference are kept when inlining.
+func (s *Schema) ResolveSharedDefs(defs map[string]*Schema, mode string) error {
+	all := make(map[string]*Schema, len(defs)+len(s.Defs))
+	for name, def := range defs {
+		all[name] = def
+	}
+	for name, def := range s.Defs {
+		all[name] = def
+	}
+
+	if mode == DefsModeInline {
+		return s.inlineDefs(all, nil)
+	}
+	for {
+		missing := []string{}
+		s.Walk(func(subSchema *Schema) {
+			name, ok := defsRefName(subSchema.Ref)
+			if _, exists := s.Defs[name]; ok && !exists && !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+		})
+		if len(missing) == 0 {
+			return nil
+		}
+		if s.Defs == nil {
+			s.Defs = make(map[string]*Schema)
+		}
+		for _, name := range missing {
+			def, ok := all[name]
+			if !ok {
+				return fmt.Errorf("the definition %s doesn't exist in the shared definitions", name)
+			}
+			copied, err := def.clone()
+			if err != nil {
+				return err
+			}
+			s.Defs[name] = copied
+		}
+	}
+}
+
+// inlineDefs replaces the references to the definitions in the schema, stack contains
+// the references which are currently inlined
+func (s *Schema) inlineDefs(defs map[string]*Schema, stack []string) error {
+	var err error
+	s.Walk(func(subSchema *Schema) {
+		name, ok := defsRefName(subSchema.Ref)
+		if err != nil || !ok {
+			return
+		}
+		if slices.Contains(stack, subSchema.Ref) {
+			err = fmt.Errorf("the definition %s references itself and can't be inlined, use the %s mode instead", name, DefsModeBundle)
+			return
+		}
+		if _, exists := defs[name]; !exists {
+			err = fmt.Errorf("the definition %s doesn't exist in the shared definitions", name)
+			return
+		}
+		var def *Schema
+		if def, err = lookupDef(defs, subSchema.Ref); err != nil {
+			return
+		}
+		if err = def.inlineDefs(defs, append(stack, subSchema.Ref)); err != nil {
+			return
+		}
+		title, description := subSchema.Title, subSchema.Description
+		*subSchema = *def
+		if title != "" {
+			subSchema.Title = title
+		}
+		if description != "" {
+			subSchema.Description = description
+		}
+	})
+	return err
+}
+
+// lookupDef returns a copy of the schema the reference (e.g. #/$defs/image/properties/tag) points to
+func lookupDef(defs map[string]*Schema, ref string) (*Schema, error) {
+	defsJSON, err := json.Marshal(map[string]interface{}{"$defs": defs})
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := decodeJSON(defsJSON, &doc); err != nil {
+		return nil, err
+	}
+	target, err := jsonpointer.Get(doc, strings.TrimPrefix(ref, "#"))
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %s: %w", ref, err)
+	}
+	targetJSON, err := json.Marshal(target)
+	if err != nil {
+		return nil, err
+	}
+	def := &Schema{}
+	if err := json.Unmarshal(targetJSON, def); err != nil {
+		return nil, fmt.Errorf("could not resolve %s: %w", ref, err)
+	}
+	return def, nil
+}
+
+// defsRefName returns the name of the definition the reference points to
+func defsRefName(ref string) (string, bool) {
+	if !strings.HasPrefix(ref, defsRefPrefix) {
+		return "", false
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(ref, defsRefPrefix), "/")
+	return name, name != ""
+}
+
+// clone returns a deep copy of the schema
+func (s *Schema) clone() (*Schema, error) {
+	schemaJSON, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return parseSchema(schemaJSON)
+}
+
+// parseSchema parses the json schema, the keywords which aren't fields of the Schema struct are kept
+func parseSchema(content []byte) (*Schema, error) {
+	parsed := &Schema{}
+	if err := json.Unmarshal(content, parsed); err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err := decodeJSON(content, &raw); err != nil {
+		return nil, err
+	}
+	parsed.keepUnknownKeywords(raw)
+	return parsed, nil
+}
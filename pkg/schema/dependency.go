@@ -0,0 +1,157 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+)
+
+// The strategies merging the values of a dependency redeclared by its parent with the schema of the dependency
+const (
+	// DependencyMergeDependencyWins prefers the keywords of the dependency
+	DependencyMergeDependencyWins = "dependency-wins"
+	// DependencyMergeParentWins prefers the keywords inferred from the values of the parent
+	DependencyMergeParentWins = "parent-wins"
+	// DependencyMergeUnion prefers the keywords of the dependency, but allows the types of both
+	DependencyMergeUnion = "union"
+)
+
+// DependencyMergeStrategies contains all supported strategies merging redeclared values of dependencies
+var DependencyMergeStrategies = []string{DependencyMergeDependencyWins, DependencyMergeParentWins, DependencyMergeUnion}
+
+// AliasOfAnnotation is the custom annotation of a dependency inlined under an alias, it contains the name of the chart
+const AliasOfAnnotation = "x-helm-alias-of"
+
+// DependencyKey returns the key of the values of the dependency in its parent chart (its alias or name)
+func DependencyKey(dep *chart.Dependency) string {
+	if dep.Alias != "" {
+		return dep.Alias
+	}
+	return dep.Name
+}
+
+// DependencySchema returns the schema of the dependency, which is inlined into the schema of its parent under
+// its key (see DependencyKey). Dependencies with an alias are titled after it and refer to their chart with
+// x-helm-alias-of. The properties are copied, so the same chart can be inlined under several aliases and the
+// schema of the chart itself isn't changed. Unless keepRequired is set, the required properties are removed,
+// because you don't need to overwrite the values of a dependency.
+func DependencySchema(dep *chart.Dependency, s *Schema, description string, keepRequired bool) (*Schema, error) {
+	copied, err := s.clone()
+	if err != nil {
+		return nil, err
+	}
+	if !keepRequired {
+		copied.DisableRequiredProperties()
+	}
+	depSchema := &Schema{
+		Type:        []string{"object"},
+		Title:       DependencyKey(dep),
+		Description: description,
+		Properties:  copied.Properties,
+		// keep the order of the dependency
+		PropertyOrder: copied.PropertyOrder,
+		Required:      copied.Required,
+	}
+	if dep.Alias != "" {
+		depSchema.CustomAnnotations = map[string]interface{}{AliasOfAnnotation: dep.Name}
+	}
+	return depSchema, nil
+}
+
+// MergeDependency adds the schema of the dependency (see DependencySchema) as property key of the schema. The values
+// of a chart can redeclare values of its dependency (e.g. to override subchart.image.tag), their schemas are merged
+// with the schema of the dependency by the strategy: the properties of both are kept, the other keywords of the
+// dependency or the parent take precedence. It returns the conflicts, which are types differing between both and
+// values the dependency doesn't have.
+func (s *Schema) MergeDependency(key string, dependency *Schema, strategy string) ([]string, error) {
+	var conflicts []string
+	if redeclared, ok := s.Properties[key]; ok {
+		var err error
+		if conflicts, err = mergeRedeclared(redeclared, dependency, strategy, key); err != nil {
+			return nil, err
+		}
+	}
+	s.SetProperty(key, dependency)
+	return conflicts, nil
+}
+
+// mergeRedeclared merges the schema of the redeclared values at the path into the schema of the dependency
+func mergeRedeclared(redeclared, dependency *Schema, strategy, path string) ([]string, error) {
+	var conflicts []string
+	if len(redeclared.Type) > 0 && len(dependency.Type) > 0 && !sameTypes(redeclared.Type, dependency.Type) {
+		used := dependency.Type
+		switch strategy {
+		case DependencyMergeParentWins:
+			used = redeclared.Type
+		case DependencyMergeUnion:
+			used = slices.Clone(dependency.Type)
+			for _, t := range redeclared.Type {
+				if !slices.Contains(used, t) {
+					used = append(used, t)
+				}
+			}
+		}
+		conflicts = append(conflicts, fmt.Sprintf(
+			"%s is %s in the values of the chart and %s in the dependency, using %s",
+			path, strings.Join(redeclared.Type, ", "), strings.Join(dependency.Type, ", "), strings.Join(used, ", "),
+		))
+		if strategy == DependencyMergeUnion {
+			dependency.Type = used
+		}
+	}
+	if strategy == DependencyMergeParentWins {
+		if err := dependency.overlayKeywords(redeclared); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range redeclared.PropertyNames() {
+		property := redeclared.Properties[name]
+		if dependencyProperty, ok := dependency.Properties[name]; ok {
+			propertyConflicts, err := mergeRedeclared(property, dependencyProperty, strategy, path+"."+name)
+			if err != nil {
+				return nil, err
+			}
+			conflicts = append(conflicts, propertyConflicts...)
+			continue
+		}
+		if dependency.Type.Matches("object") && len(dependency.Properties) > 0 {
+			conflicts = append(conflicts, fmt.Sprintf("%s.%s isn't a value of the dependency", path, name))
+		}
+		dependency.SetProperty(name, property)
+	}
+	return conflicts, nil
+}
+
+// overlayKeywords replaces the keywords of the schema by the ones of the other schema, except its properties
+// and required properties
+func (s *Schema) overlayKeywords(other *Schema) error {
+	doc, err := toDocument(s)
+	if err != nil {
+		return err
+	}
+	otherDoc, err := toDocument(other)
+	if err != nil {
+		return err
+	}
+	docMap, _ := doc.(map[string]interface{})
+	otherMap, _ := otherDoc.(map[string]interface{})
+	for keyword, value := range otherMap {
+		if keyword != "properties" && keyword != "required" {
+			docMap[keyword] = value
+		}
+	}
+	overlaid, err := decodeSchemaDocument(docMap, s)
+	if err != nil {
+		return err
+	}
+	*s = overlaid
+	return nil
+}
+
+// sameTypes returns true, if both contain the same types
+func sameTypes(a, b StringOrArrayOfString) bool {
+	return len(a) == len(b) && !slices.ContainsFunc(a, func(t string) bool { return !slices.Contains(b, t) })
+}
@@ -0,0 +1,139 @@
+package schema
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/norwoodj/helm-docs/pkg/helm"
+)
+
+// The formats of the descriptions in the generated schema
+const (
+	// DescriptionFormatMarkdown keeps the markdown of the comments (paragraphs, lists and code blocks)
+	DescriptionFormatMarkdown = "markdown"
+	// DescriptionFormatPlain strips the markdown and joins the lines of paragraphs
+	DescriptionFormatPlain = "plain"
+)
+
+var (
+	codeFence   = regexp.MustCompile("^\\s*(```|~~~)")
+	listItem    = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+`)
+	heading     = regexp.MustCompile(`^\s*#{1,6}\s+`)
+	markdownImg = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	// markdownLink keeps the url of the link, if it differs from the text
+	markdownLink       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	markdownInlineCode = regexp.MustCompile("`([^`]+)`")
+	markdownStrong     = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	markdownEmphasis   = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	markdownUnderscore = regexp.MustCompile(`(^|[^\w])_([^_\s][^_]*)_([^\w]|$)`)
+
+	// helmDocsDescriptionStart matches the first line of a helm-docs description (# -- or # key -- )
+	helmDocsDescriptionStart = regexp.MustCompile(`^\s*#\s*(.*)\s+--\s*(.*)$`)
+	helmDocsValueType        = regexp.MustCompile(`^\((.*?)\)\s*(.*)$`)
+	helmDocsTag              = regexp.MustCompile(`^\s*#\s+@(raw|default|notationType|section)\b`)
+)
+
+// NormalizeDescription removes trailing whitespace, consecutive empty lines and the empty lines around the
+// description. Code blocks are kept as they are. With the plain format, the markdown is stripped: the lines
+// of paragraphs are joined, headings, emphasis and links are replaced by their text and the fences of code
+// blocks are removed. List items and the lines of code blocks stay on their own lines.
+func NormalizeDescription(description, format string) string {
+	if description == "" {
+		return ""
+	}
+	plain := format == DescriptionFormatPlain
+	result := []string{}
+	insideCode := false
+	// joinable is true, if the next line of a paragraph can be joined with the last line
+	joinable := false
+	empty := false
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if codeFence.MatchString(line) {
+			insideCode = !insideCode
+			joinable = false
+			if plain {
+				continue
+			}
+		} else if insideCode {
+			if plain {
+				line = "    " + line
+			}
+		} else if strings.TrimSpace(line) == "" {
+			empty = len(result) > 0
+			joinable = false
+			continue
+		} else if plain {
+			if marker := listItem.FindString(line); marker != "" {
+				indent := marker[:len(marker)-len(strings.TrimLeft(marker, " \t"))]
+				line = indent + strings.TrimSpace(marker) + " " + stripMarkdown(strings.TrimPrefix(line, marker))
+			} else if line = stripMarkdown(heading.ReplaceAllString(line, "")); joinable && !empty {
+				result[len(result)-1] += " " + strings.TrimSpace(line)
+				continue
+			}
+			joinable = true
+		}
+		if empty {
+			result = append(result, "")
+			empty = false
+		}
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
+
+func stripMarkdown(line string) string {
+	line = markdownImg.ReplaceAllString(line, "$1")
+	line = markdownLink.ReplaceAllStringFunc(line, func(link string) string {
+		match := markdownLink.FindStringSubmatch(link)
+		if match[1] == match[2] {
+			return match[1]
+		}
+		return match[1] + " (" + match[2] + ")"
+	})
+	line = markdownInlineCode.ReplaceAllString(line, "$1")
+	line = markdownStrong.ReplaceAllString(line, "$1$2")
+	line = markdownEmphasis.ReplaceAllString(line, "$1")
+	return markdownUnderscore.ReplaceAllString(line, "$1$2$3")
+}
+
+// FormatDescriptions normalizes the descriptions of the schema and all of its subschemas, see NormalizeDescription
+func (s *Schema) FormatDescriptions(format string) {
+	s.Walk(func(subSchema *Schema) {
+		subSchema.Description = NormalizeDescription(subSchema.Description, format)
+	})
+}
+
+// helmDocsDescription returns the description of the helm-docs comment with its line breaks. helm-docs
+// itself joins the lines with spaces (unless the description is marked with @raw), which breaks lists and
+// code blocks. Like helm-docs, only the last group of lines starting with # -- is used.
+func helmDocsDescription(commentLines []string) string {
+	start := 0
+	for i, line := range commentLines {
+		if strings.HasPrefix(line, helm.PrefixComment) {
+			start = i
+		}
+	}
+	for ; start < len(commentLines); start++ {
+		if helmDocsDescriptionStart.MatchString(commentLines[start]) {
+			break
+		}
+	}
+	if start == len(commentLines) {
+		return ""
+	}
+
+	first := helmDocsDescriptionStart.FindStringSubmatch(commentLines[start])[2]
+	if match := helmDocsValueType.FindStringSubmatch(first); match != nil && match[1] != "" {
+		first = match[2]
+	}
+	lines := []string{first}
+	for _, line := range commentLines[start+1:] {
+		if _, ok := ignoredRules(line); ok || helmDocsTag.MatchString(line) {
+			continue
+		}
+		line = strings.TrimSpace(line)
+		lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(line, CommentPrefix), " "))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,338 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const (
+	// ChangeBreaking values which were valid before are invalid now
+	ChangeBreaking = "breaking"
+	// ChangeAdditive values which were invalid before are valid now
+	ChangeAdditive = "additive"
+	// ChangeModified doesn't change which values are valid (e.g. a new default or description)
+	ChangeModified = "modified"
+)
+
+// The messages of changes of properties
+const (
+	messagePropertyAdded          = "property was added"
+	messageRequiredPropertyAdded  = "required property was added"
+	messagePropertyRemoved        = "property was removed"
+	messageAllowedPropertyRemoved = "property was removed, but additional properties are allowed"
+	messageDescriptionChanged     = "description was changed"
+)
+
+// Change is a difference between two schemas
+type Change struct {
+	// Path of the value in the dotted notation of helm (e.g. image.tag or hosts[].name)
+	Path    string      `json:"path"`
+	Kind    string      `json:"kind"`
+	Message string      `json:"message"`
+	Old     interface{} `json:"old,omitempty"`
+	New     interface{} `json:"new,omitempty"`
+}
+
+// HasBreakingChanges returns true if one of the changes is breaking
+func HasBreakingChanges(changes []Change) bool {
+	for _, change := range changes {
+		if change.Kind == ChangeBreaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff returns the changes between the old and the new schema, sorted by path
+func Diff(oldSchema, newSchema *Schema) []Change {
+	changes := diffSchemas("", oldSchema, newSchema)
+	slices.SortStableFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return changes
+}
+
+func diffSchemas(path string, oldSchema, newSchema *Schema) []Change {
+	var changes []Change
+	add := func(kind, message string, oldValue, newValue interface{}) {
+		changes = append(changes, Change{Path: displayPath(path), Kind: kind, Message: message, Old: oldValue, New: newValue})
+	}
+
+	// type
+	oldTypes, newTypes := typeSet(oldSchema.Type), typeSet(newSchema.Type)
+	switch {
+	case len(oldTypes) == 0 && len(newTypes) > 0:
+		add(ChangeBreaking, "type was added", nil, []string(newSchema.Type))
+	case len(oldTypes) > 0 && len(newTypes) == 0:
+		add(ChangeAdditive, "type was removed", []string(oldSchema.Type), nil)
+	case !typesContain(newTypes, oldTypes) && typesContain(oldTypes, newTypes):
+		add(ChangeBreaking, "type was narrowed", []string(oldSchema.Type), []string(newSchema.Type))
+	case !typesContain(newTypes, oldTypes):
+		add(ChangeBreaking, "type was changed", []string(oldSchema.Type), []string(newSchema.Type))
+	case !typesContain(oldTypes, newTypes):
+		add(ChangeAdditive, "type was widened", []string(oldSchema.Type), []string(newSchema.Type))
+	}
+
+	// enum and const
+	switch {
+	case len(oldSchema.Enum) == 0 && len(newSchema.Enum) > 0:
+		add(ChangeBreaking, "enum was added", nil, newSchema.Enum)
+	case len(oldSchema.Enum) > 0 && len(newSchema.Enum) == 0:
+		add(ChangeAdditive, "enum was removed", oldSchema.Enum, nil)
+	case len(oldSchema.Enum) > 0:
+		removed, added := sliceDiff(oldSchema.Enum, newSchema.Enum)
+		if len(removed) > 0 {
+			add(ChangeBreaking, "enum values were removed", removed, nil)
+		}
+		if len(added) > 0 {
+			add(ChangeAdditive, "enum values were added", nil, added)
+		}
+	}
+	if !reflect.DeepEqual(normalize(oldSchema.Const), normalize(newSchema.Const)) {
+		if newSchema.Const == nil {
+			add(ChangeAdditive, "const was removed", oldSchema.Const, nil)
+		} else {
+			add(ChangeBreaking, "const was changed", oldSchema.Const, newSchema.Const)
+		}
+	}
+
+	// bounds, a lower bound is tightened if it gets bigger and an upper bound if it gets smaller
+	for _, bound := range []struct {
+		name     string
+		old, new *int
+		lower    bool
+	}{
+		{"minimum", oldSchema.Minimum, newSchema.Minimum, true},
+		{"exclusiveMinimum", oldSchema.ExclusiveMinimum, newSchema.ExclusiveMinimum, true},
+		{"minLength", oldSchema.MinLength, newSchema.MinLength, true},
+		{"minItems", oldSchema.MinItems, newSchema.MinItems, true},
+		{"maximum", oldSchema.Maximum, newSchema.Maximum, false},
+		{"exclusiveMaximum", oldSchema.ExclusiveMaximum, newSchema.ExclusiveMaximum, false},
+		{"maxLength", oldSchema.MaxLength, newSchema.MaxLength, false},
+		{"maxItems", oldSchema.MaxItems, newSchema.MaxItems, false},
+	} {
+		switch {
+		case bound.old == nil && bound.new == nil:
+		case bound.old == nil:
+			add(ChangeBreaking, bound.name+" was added", nil, *bound.new)
+		case bound.new == nil:
+			add(ChangeAdditive, bound.name+" was removed", *bound.old, nil)
+		case *bound.old == *bound.new:
+		case (*bound.new > *bound.old) == bound.lower:
+			add(ChangeBreaking, bound.name+" was tightened", *bound.old, *bound.new)
+		default:
+			add(ChangeAdditive, bound.name+" was loosened", *bound.old, *bound.new)
+		}
+	}
+	for _, keyword := range []struct {
+		name     string
+		old, new string
+	}{
+		{"pattern", oldSchema.Pattern, newSchema.Pattern},
+		{"format", oldSchema.Format, newSchema.Format},
+		{"$ref", oldSchema.Ref, newSchema.Ref},
+	} {
+		switch {
+		case keyword.old == keyword.new:
+		case keyword.new == "":
+			add(ChangeAdditive, keyword.name+" was removed", keyword.old, nil)
+		case keyword.old == "":
+			add(ChangeBreaking, keyword.name+" was added", nil, keyword.new)
+		default:
+			add(ChangeBreaking, keyword.name+" was changed", keyword.old, keyword.new)
+		}
+	}
+
+	// additional properties
+	oldAdditional, newAdditional := allowsAdditionalProperties(oldSchema), allowsAdditionalProperties(newSchema)
+	if oldAdditional && !newAdditional {
+		add(ChangeBreaking, "additional properties are not allowed anymore", nil, nil)
+	} else if !oldAdditional && newAdditional {
+		add(ChangeAdditive, "additional properties are allowed now", nil, nil)
+	}
+
+	// documentation
+	if !reflect.DeepEqual(normalize(oldSchema.Default), normalize(newSchema.Default)) {
+		add(ChangeModified, "default was changed", oldSchema.Default, newSchema.Default)
+	}
+	if oldSchema.Description != newSchema.Description {
+		add(ChangeModified, messageDescriptionChanged, oldSchema.Description, newSchema.Description)
+	}
+	if !oldSchema.Deprecated && newSchema.Deprecated {
+		add(ChangeModified, "was deprecated", nil, nil)
+	}
+
+	// properties
+	for _, name := range sortedPropertyNames(oldSchema, newSchema) {
+		propertyPath := joinPath(path, name)
+		oldProperty, inOld := oldSchema.Properties[name]
+		newProperty, inNew := newSchema.Properties[name]
+		oldRequired := slices.Contains(oldSchema.Required.Strings, name)
+		newRequired := slices.Contains(newSchema.Required.Strings, name)
+
+		switch {
+		case inOld && !inNew:
+			kind, message := ChangeBreaking, messagePropertyRemoved
+			if newAdditional {
+				kind, message = ChangeModified, messageAllowedPropertyRemoved
+			}
+			changes = append(changes, Change{Path: displayPath(propertyPath), Kind: kind, Message: message, Old: oldProperty.Default})
+		case !inOld && inNew:
+			kind, message := ChangeAdditive, messagePropertyAdded
+			if newRequired {
+				kind, message = ChangeBreaking, messageRequiredPropertyAdded
+			}
+			changes = append(changes, Change{Path: displayPath(propertyPath), Kind: kind, Message: message, New: newProperty.Default})
+		default:
+			if !oldRequired && newRequired {
+				changes = append(changes, Change{Path: displayPath(propertyPath), Kind: ChangeBreaking, Message: "property is required now"})
+			} else if oldRequired && !newRequired {
+				changes = append(changes, Change{Path: displayPath(propertyPath), Kind: ChangeAdditive, Message: "property is optional now"})
+			}
+			changes = append(changes, diffSchemas(propertyPath, oldProperty, newProperty)...)
+		}
+	}
+
+	// nested schemas
+	itemsPath := path + "[]"
+	switch {
+	case oldSchema.Items != nil && newSchema.Items != nil:
+		changes = append(changes, diffSchemas(itemsPath, oldSchema.Items, newSchema.Items)...)
+	case oldSchema.Items == nil && newSchema.Items != nil:
+		changes = append(changes, Change{Path: displayPath(itemsPath), Kind: ChangeBreaking, Message: "items schema was added"})
+	case oldSchema.Items != nil && newSchema.Items == nil:
+		changes = append(changes, Change{Path: displayPath(itemsPath), Kind: ChangeAdditive, Message: "items schema was removed"})
+	}
+	oldAdditionalSchema, oldIsSchema := oldSchema.AdditionalProperties.(*Schema)
+	newAdditionalSchema, newIsSchema := newSchema.AdditionalProperties.(*Schema)
+	if oldIsSchema && newIsSchema {
+		changes = append(changes, diffSchemas(joinPath(path, "*"), oldAdditionalSchema, newAdditionalSchema)...)
+	}
+
+	return changes
+}
+
+// ReadSchemaFile parses a jsonschema file for comparing it
+func ReadSchemaFile(content []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, err
+	}
+	// additionalProperties can be a schema as well, which is decoded into a map
+	var decode func(s *Schema)
+	decode = func(s *Schema) {
+		if additional, ok := s.AdditionalProperties.(map[string]interface{}); ok {
+			if additionalJSON, err := json.Marshal(additional); err == nil {
+				var additionalSchema Schema
+				if json.Unmarshal(additionalJSON, &additionalSchema) == nil {
+					s.AdditionalProperties = &additionalSchema
+				}
+			}
+		}
+		for _, property := range s.Properties {
+			decode(property)
+		}
+		if s.Items != nil {
+			decode(s.Items)
+		}
+		if additionalSchema, ok := s.AdditionalProperties.(*Schema); ok {
+			decode(additionalSchema)
+		}
+	}
+	decode(&s)
+	return &s, nil
+}
+
+// allowsAdditionalProperties returns false if additionalProperties is false
+func allowsAdditionalProperties(s *Schema) bool {
+	switch allowed := s.AdditionalProperties.(type) {
+	case bool:
+		return allowed
+	case *bool:
+		return allowed == nil || *allowed
+	}
+	return true
+}
+
+// typeSet returns the types, where integer is part of number
+func typeSet(types StringOrArrayOfString) []string {
+	var set []string
+	for _, t := range types {
+		if t != "" && !slices.Contains(set, t) {
+			set = append(set, t)
+		}
+	}
+	return set
+}
+
+// typesContain returns true if all values of the types in inner are values of the types in outer
+func typesContain(outer, inner []string) bool {
+	for _, t := range inner {
+		if !slices.Contains(outer, t) && !(t == "integer" && slices.Contains(outer, "number")) {
+			return false
+		}
+	}
+	return true
+}
+
+// sliceDiff returns the values which were removed and added
+func sliceDiff(oldValues, newValues []string) (removed, added []string) {
+	for _, value := range oldValues {
+		if !slices.Contains(newValues, value) {
+			removed = append(removed, value)
+		}
+	}
+	for _, value := range newValues {
+		if !slices.Contains(oldValues, value) {
+			added = append(added, value)
+		}
+	}
+	return removed, added
+}
+
+func sortedPropertyNames(schemas ...*Schema) []string {
+	var names []string
+	for _, s := range schemas {
+		for name := range s.Properties {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// normalize converts the value to its json representation, so values read
+// from yaml and json can be compared
+func normalize(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(valueJSON, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// displayPath returns the path or (root) for the root schema
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
@@ -2,6 +2,11 @@ package schema
 
 type CircularError struct {
 	msg string
+	// Cycle contains the names of the charts of the found cycle, the first chart
+	// is repeated at the end (e.g. a, b, c, a). It is empty if a dependency is missing.
+	Cycle []string
+	// ChartPaths contains the Chart.yaml files of the charts in the cycle or with a missing dependency
+	ChartPaths []string
 }
 
 func (e *CircularError) Error() string { return e.msg }
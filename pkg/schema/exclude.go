@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ValidateExcludePatterns returns an error for the first invalid pattern of ExcludeValues
+func ValidateExcludePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(excludePattern(pattern), ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %s: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// ExcludeValues removes the properties annotated with skip: true and the ones, whose dotted paths (e.g.
+// image.tag) match one of the patterns, from the schema. The wildcards of the patterns match within a
+// single key (e.g. internal.* or *.experimental). The values are still part of the values file, so objects
+// rejecting additional properties allow them with a pattern property, which isn't shown by editors.
+// It returns the number of removed properties.
+func (s *Schema) ExcludeValues(patterns []string) int {
+	return s.excludeValues(nil, patterns)
+}
+
+func (s *Schema) excludeValues(parent []string, patterns []string) int {
+	count := 0
+	for _, name := range s.PropertyNames() {
+		property := s.Properties[name]
+		propertyPath := append(slices.Clone(parent), name)
+		if (property != nil && property.Skip) || matchesExcludePattern(propertyPath, patterns) {
+			s.excludeProperty(name)
+			count++
+			continue
+		}
+		if property != nil {
+			count += property.excludeValues(propertyPath, patterns)
+		}
+	}
+	return count
+}
+
+// excludeProperty removes the property from the properties, the required properties and the property order
+func (s *Schema) excludeProperty(name string) {
+	delete(s.Properties, name)
+	s.Required.Strings = slices.DeleteFunc(s.Required.Strings, func(required string) bool {
+		return required == name
+	})
+	s.PropertyOrder = slices.DeleteFunc(s.PropertyOrder, func(property string) bool {
+		return property == name
+	})
+
+	rejectsAdditional := false
+	switch additionalProperties := s.AdditionalProperties.(type) {
+	case *bool:
+		rejectsAdditional = additionalProperties != nil && !*additionalProperties
+	case bool:
+		rejectsAdditional = !additionalProperties
+	}
+	if rejectsAdditional {
+		if s.PatternProperties == nil {
+			s.PatternProperties = make(map[string]*Schema)
+		}
+		s.PatternProperties["^"+regexp.QuoteMeta(name)+"$"] = &Schema{}
+	}
+}
+
+func matchesExcludePattern(propertyPath []string, patterns []string) bool {
+	// the segments are joined with slashes, so the wildcards don't match dots
+	joined := strings.Join(propertyPath, "/")
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(excludePattern(pattern), joined); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func excludePattern(pattern string) string {
+	return strings.ReplaceAll(pattern, ".", "/")
+}
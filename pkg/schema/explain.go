@@ -0,0 +1,243 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The sources of the keywords of a property
+const (
+	// OriginAnnotation is a keyword of the @schema annotation in the values file
+	OriginAnnotation = "annotation"
+	// OriginSidecar is a keyword of the sidecar annotations file
+	OriginSidecar = "sidecar"
+	// OriginComment is a description (or helm-docs keyword) taken from the comment in the values file
+	OriginComment = "comment"
+	// OriginValue is a keyword inferred from the value in the values file
+	OriginValue = "value"
+	// OriginPatch is a keyword of the schema patch file
+	OriginPatch = "patch"
+	// OriginGenerated is a keyword helm-schema adds to every property (e.g. the title)
+	OriginGenerated = "generated"
+	// OriginDependency is a keyword of the schema of a dependency merged into the parent chart
+	OriginDependency = "dependency"
+	// OriginCondition is a keyword of a property patched into a dependency for the condition of the parent chart
+	OriginCondition = "condition"
+)
+
+// Origin describes where a keyword of a property in the final schema comes from
+type Origin struct {
+	Keyword string `json:"keyword"`
+	Source  string `json:"source"`
+	File    string `json:"file,omitempty"`
+	// Line is the 1-based line of the keyword in the file, 0 if unknown
+	Line int `json:"line,omitempty"`
+}
+
+// annotationKey matches the top level keys of the yaml in an @schema block
+var annotationKey = regexp.MustCompile(`^([^\s#:'"-][^:]*):`)
+
+// ExplainKeywords returns the origins of the keywords of the property at the path of the values file.
+// The keywords are the ones of the property in the generated schema, those which can't be attributed
+// to the annotations, sidecar file, patch file or comment are inferred from the value or generated.
+// It returns false if the path doesn't exist in the values.
+func ExplainKeywords(valuesPath string, content []byte, path, keywords []string, uncomment bool) ([]Origin, bool, error) {
+	content, _, err := uncommentValues(content, uncomment)
+	if err != nil {
+		return nil, false, err
+	}
+	values, err := ParseValues(content)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(values.Content) != 1 {
+		return nil, false, nil
+	}
+	keyNode := findKeyNode(values.Content[0], path)
+	if keyNode == nil {
+		return nil, false, nil
+	}
+	valueNode := findValueNode(values.Content[0], path)
+	if valueNode.Kind == yaml.AliasNode {
+		valueNode = valueNode.Alias
+	}
+
+	origins := make(map[string]Origin)
+	commentLine := annotationOrigins(valuesPath, content, keyNode.Line, origins)
+
+	sidecarContent, err := ReadSidecarAnnotations(valuesPath)
+	if err != nil {
+		return nil, false, err
+	}
+	if sidecarContent != nil {
+		sidecarOrigins(filepath.Join(filepath.Dir(valuesPath), SidecarAnnotationsFile), sidecarContent, path, origins)
+	}
+
+	var patchContent []byte
+	if valuesPath != "" {
+		if patchContent, err = ReadSchemaPatch(filepath.Dir(valuesPath)); err != nil {
+			return nil, false, err
+		}
+	}
+	patchAll := false
+	if patchContent != nil {
+		patchAll = patchOrigins(filepath.Join(filepath.Dir(valuesPath), SchemaPatchFile), patchContent, path, origins)
+	}
+
+	result := []Origin{}
+	for _, keyword := range keywords {
+		origin, ok := origins[keyword]
+		switch {
+		case patchAll && !ok:
+			origin = Origin{Source: OriginPatch, File: filepath.Join(filepath.Dir(valuesPath), SchemaPatchFile)}
+		case ok:
+		case keyword == "description" && commentLine > 0:
+			origin = Origin{Source: OriginComment, File: valuesPath, Line: commentLine}
+		case keyword == "type" || keyword == "default" || keyword == "items" ||
+			(keyword == "properties" && valueNode.Kind == yaml.MappingNode):
+			origin = Origin{Source: OriginValue, File: valuesPath, Line: keyNode.Line}
+		default:
+			origin = Origin{Source: OriginGenerated}
+		}
+		origin.Keyword = keyword
+		result = append(result, origin)
+	}
+	return result, true, nil
+}
+
+// findValueNode returns the value node of the path, which must exist
+func findValueNode(node *yaml.Node, path []string) *yaml.Node {
+	for _, segment := range path {
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == segment {
+				node = node.Content[i+1]
+				break
+			}
+		}
+	}
+	return node
+}
+
+// annotationOrigins adds the keywords of the @schema block above the key line and returns
+// the line of the first other comment line, which is used as description (0 if there is none)
+func annotationOrigins(valuesPath string, content []byte, keyLine int, origins map[string]Origin) int {
+	lines := strings.Split(string(content), "\n")
+	// the head comment of the key ends at the first line, which isn't a comment
+	start := keyLine - 1
+	for start > 0 && strings.HasPrefix(strings.TrimSpace(lines[start-1]), CommentPrefix) {
+		start--
+	}
+
+	commentLine := 0
+	insideSchemaBlock := false
+	for i := start; i < keyLine-1; i++ {
+		line := strings.TrimSpace(lines[i])
+		if isSchemaDelimiter(line) {
+			insideSchemaBlock = !insideSchemaBlock
+			continue
+		}
+		if !insideSchemaBlock {
+			if commentLine == 0 && strings.TrimSpace(strings.TrimLeft(line, CommentPrefix)) != "" {
+				commentLine = i + 1
+			}
+			continue
+		}
+		raw := strings.TrimPrefix(strings.TrimPrefix(line, CommentPrefix), " ")
+		if match := annotationKey.FindStringSubmatch(raw); match != nil {
+			origins[strings.TrimSpace(match[1])] = Origin{Source: OriginAnnotation, File: valuesPath, Line: i + 1}
+		}
+	}
+	return commentLine
+}
+
+// sidecarOrigins adds the keywords of the entry of the path in the sidecar file
+func sidecarOrigins(sidecarFile string, content []byte, path []string, origins map[string]Origin) {
+	var sidecar yaml.Node
+	if yaml.Unmarshal(content, &sidecar) != nil || len(sidecar.Content) == 0 || sidecar.Content[0].Kind != yaml.MappingNode {
+		return
+	}
+	entries := sidecar.Content[0]
+	for i := 0; i+1 < len(entries.Content); i += 2 {
+		if !slices.Equal(sidecarPath(entries.Content[i].Value), path) || entries.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		keywords := entries.Content[i+1]
+		for j := 0; j+1 < len(keywords.Content); j += 2 {
+			origins[keywords.Content[j].Value] = Origin{Source: OriginSidecar, File: sidecarFile, Line: keywords.Content[j].Line}
+		}
+	}
+}
+
+// patchOrigins adds the keywords the patch file sets on the property of the path and returns
+// true if the patch replaces the whole property
+func patchOrigins(patchFile string, content []byte, path []string, origins map[string]Origin) bool {
+	pointer := []string{}
+	for _, segment := range path {
+		pointer = append(pointer, "properties", segment)
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var operations []patchOperation
+		if json.Unmarshal(trimmed, &operations) != nil {
+			return false
+		}
+		patchAll := false
+		for _, operation := range operations {
+			if operation.Op != "add" && operation.Op != "replace" && operation.Op != "copy" && operation.Op != "move" {
+				continue
+			}
+			tokens, err := parsePointer(operation.Path)
+			if err != nil {
+				continue
+			}
+			switch {
+			case slices.Equal(tokens, pointer):
+				patchAll = true
+			case len(tokens) > len(pointer) && slices.Equal(tokens[:len(pointer)], pointer):
+				origins[tokens[len(pointer)]] = Origin{Source: OriginPatch, File: patchFile}
+			}
+		}
+		return patchAll
+	}
+
+	var mergePatch interface{}
+	if json.Unmarshal(trimmed, &mergePatch) != nil {
+		return false
+	}
+	for _, token := range pointer {
+		object, ok := mergePatch.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if mergePatch, ok = object[token]; !ok {
+			return false
+		}
+	}
+	if object, ok := mergePatch.(map[string]interface{}); ok {
+		for keyword, value := range object {
+			if value != nil {
+				origins[keyword] = Origin{Source: OriginPatch, File: patchFile}
+			}
+		}
+	}
+	return false
+}
+
+// Keywords returns the keywords of the schema in the order they are written
+func (s *Schema) Keywords() ([]string, error) {
+	schemaJSON, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return jsonObjectKeys(schemaJSON)
+}
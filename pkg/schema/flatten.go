@@ -0,0 +1,166 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/dadav/go-jsonpointer"
+	"gopkg.in/yaml.v3"
+)
+
+type flattener struct {
+	// client fetches the remote documents, they aren't allowed without one
+	client *http.Client
+	// docs are the parsed documents by their url (without fragment)
+	docs map[string]interface{}
+}
+
+// Flatten replaces all $refs of the schema by the schemas they point to, so the schema is self-contained
+// and its $defs aren't needed anymore. References within the schema are resolved against the schema as it
+// was before flattening, relative ones against the base (the url of the file the schema was generated
+// from, e.g. file:///charts/app/values.yaml). Remote documents (http and https) are only fetched if
+// there's a client. Titles and descriptions next to a reference are kept. Circular references can't
+// be flattened and return an error.
+func (s *Schema) Flatten(base string, client *http.Client) error {
+	if base == "" {
+		// refs within the schema are still resolvable
+		base = "file:///" + DefaultOutputFile(OutputFormatJSON)
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return err
+	}
+	baseURL.Fragment = ""
+	root, err := toDocument(s)
+	if err != nil {
+		return err
+	}
+
+	f := &flattener{
+		client: client,
+		docs:   map[string]interface{}{baseURL.String(): root},
+	}
+	s.Defs = nil
+	delete(s.CustomAnnotations, legacyDefsKeyword)
+	return f.flatten(s, baseURL, nil)
+}
+
+// flatten replaces the $refs of the schema, which is part of the document at the base url.
+// The stack contains the references, which are currently flattened.
+func (f *flattener) flatten(s *Schema, base *url.URL, stack []string) error {
+	var err error
+	s.Walk(func(subSchema *Schema) {
+		if err != nil {
+			return
+		}
+		// the definitions are resolved from the documents, so only the referenced ones are kept
+		subSchema.Defs = nil
+		if subSchema.Ref == "" {
+			return
+		}
+		ref, parseErr := url.Parse(subSchema.Ref)
+		if parseErr != nil {
+			err = fmt.Errorf("invalid $ref %s: %w", subSchema.Ref, parseErr)
+			return
+		}
+		target := base.ResolveReference(ref)
+		if slices.Contains(stack, target.String()) {
+			err = fmt.Errorf("the $ref %s is circular and can't be flattened", subSchema.Ref)
+			return
+		}
+
+		var resolved *Schema
+		if resolved, err = f.lookup(target); err != nil {
+			return
+		}
+		docURL := *target
+		docURL.Fragment = ""
+		if err = f.flatten(resolved, &docURL, append(stack, target.String())); err != nil {
+			return
+		}
+		title, description := subSchema.Title, subSchema.Description
+		*subSchema = *resolved
+		if title != "" {
+			subSchema.Title = title
+		}
+		if description != "" {
+			subSchema.Description = description
+		}
+	})
+	return err
+}
+
+// lookup returns the schema the url (with a json pointer as fragment) points to
+func (f *flattener) lookup(target *url.URL) (*Schema, error) {
+	docURL := *target
+	docURL.Fragment = ""
+	doc, ok := f.docs[docURL.String()]
+	if !ok {
+		content, err := f.fetch(&docURL)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", docURL.String(), err)
+		}
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", docURL.String(), err)
+		}
+		f.docs[docURL.String()] = doc
+	}
+
+	if target.Fragment != "" {
+		if !strings.HasPrefix(target.Fragment, "/") {
+			return nil, fmt.Errorf("the $ref %s isn't a json pointer", target.String())
+		}
+		var err error
+		if doc, err = jsonpointer.Get(doc, target.Fragment); err != nil {
+			return nil, fmt.Errorf("could not resolve %s: %w", target.String(), err)
+		}
+	}
+	content, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	resolved := &Schema{}
+	if err := json.Unmarshal(content, resolved); err != nil {
+		return nil, fmt.Errorf("could not resolve %s: %w", target.String(), err)
+	}
+	return resolved, nil
+}
+
+// fetch returns the content of the local file or remote document
+func (f *flattener) fetch(docURL *url.URL) ([]byte, error) {
+	switch docURL.Scheme {
+	case "file":
+		return os.ReadFile(docURL.Path)
+	case "http", "https":
+		if f.client == nil {
+			return nil, fmt.Errorf("fetching remote $refs isn't allowed")
+		}
+		response, err := f.client.Get(docURL.String())
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %s", response.Status)
+		}
+		return io.ReadAll(response.Body)
+	}
+	return nil, fmt.Errorf("unsupported scheme %s", docURL.Scheme)
+}
+
+// toDocument returns the schema as generic json document
+func toDocument(s *Schema) (interface{}, error) {
+	content, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	err = decodeJSON(content, &doc)
+	return doc, err
+}
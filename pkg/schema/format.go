@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+const (
+	// OutputFormatJSON writes the jsonschema
+	OutputFormatJSON = "json"
+	// OutputFormatCue writes a CUE definition of the values
+	OutputFormatCue = "cue"
+	// OutputFormatOpenAPI writes an OpenAPI 3.0 schema object
+	OutputFormatOpenAPI = "openapi"
+	// OutputFormatYAML writes the jsonschema as yaml with comments on the origins of the properties
+	OutputFormatYAML = "yaml"
+)
+
+// DefaultIndent is the indentation of the json output formats
+const DefaultIndent = "  "
+
+// OutputFormats contains all supported output formats
+var OutputFormats = []string{OutputFormatJSON, OutputFormatCue, OutputFormatOpenAPI, OutputFormatYAML}
+
+// DefaultOutputFile returns the default output file name of the format
+func DefaultOutputFile(format string) string {
+	switch format {
+	case OutputFormatCue:
+		return "values.schema.cue"
+	case OutputFormatOpenAPI:
+		return "values.openapi.json"
+	case OutputFormatYAML:
+		return "values.schema.yaml"
+	default:
+		return "values.schema.json"
+	}
+}
+
+// Marshal serializes the schema of the result in the given output format
+func (r *Result) Marshal(format string) ([]byte, error) {
+	return r.MarshalIndent(format, DefaultIndent)
+}
+
+// MarshalIndent serializes the schema of the result in the given output format. The json formats
+// are indented with the indent, an empty indent writes compact json. CUE and yaml are always formatted,
+// the comments of the yaml refer to the values files relative to the output file.
+func (r *Result) MarshalIndent(format, indent string) ([]byte, error) {
+	switch format {
+	case OutputFormatJSON:
+		return r.Schema.ToJsonIndent(indent)
+	case OutputFormatCue:
+		return r.Schema.ToCue(TypeName(r.Chart.Name))
+	case OutputFormatOpenAPI:
+		openAPISchema, err := r.Schema.openAPICopy()
+		if err != nil {
+			return nil, err
+		}
+		return openAPISchema.ToJsonIndent(indent)
+	case OutputFormatYAML:
+		return r.Schema.ToYAML(filepath.Dir(r.OutputPath))
+	default:
+		return nil, fmt.Errorf("unsupported output format %s", format)
+	}
+}
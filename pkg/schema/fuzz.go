@@ -0,0 +1,340 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/netip"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fuzzRunes are used for random strings, they contain characters which need quoting in yaml or
+// escaping in templates, so templates break which don't quote their values
+var fuzzRunes = []rune("abcxyzABCXYZ0189 -_.:/#'\"{}[],&*!|>%@`\\äéß日本🚀")
+
+// fuzzMaxRepeat is the maximum number of additional repetitions of unbounded patterns and items
+const fuzzMaxRepeat = 3
+
+// RandomValues returns random values for the schema, respecting its types, enums, constants, patterns,
+// formats and bounds. Properties which aren't required are left out randomly. Some keywords (e.g. not,
+// if or $ref) aren't considered, so the values aren't always valid, use RandomValidValues to only
+// get valid ones.
+func (s *Schema) RandomValues(r *rand.Rand) interface{} {
+	if s == nil {
+		return randomString(r, nil, nil)
+	}
+	if s.Const != nil {
+		return s.Const
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[r.IntN(len(s.Enum))]
+	}
+	if subSchemas := append(slices.Clone(s.AnyOf), s.OneOf...); len(subSchemas) > 0 {
+		return subSchemas[r.IntN(len(subSchemas))].RandomValues(r)
+	}
+	if s.Ref != "" || len(s.AllOf) > 0 {
+		// the referenced or combined schemas aren't resolved, the default is likely valid
+		return s.Default
+	}
+
+	switch s.randomType(r) {
+	case "null":
+		return nil
+	case "boolean":
+		return r.IntN(2) == 0
+	case "integer":
+		return s.randomInteger(r)
+	case "number":
+		if s.MultipleOf != nil {
+			return s.randomInteger(r)
+		}
+		return s.randomNumber(r)
+	case "array":
+		return s.randomArray(r)
+	case "object":
+		return s.randomObject(r)
+	}
+	return s.randomString(r)
+}
+
+// RandomValidValues returns random values, which are valid against the schema, as yaml. The values
+// are generated up to the given number of attempts, until they are valid.
+func (s *Schema) RandomValidValues(schemaURL string, r *rand.Rand, attempts int) ([]byte, error) {
+	var err error
+	for i := 0; i < attempts; i++ {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(s.RandomValues(r)); err != nil {
+			return nil, err
+		}
+		if err = s.ValidateValues(schemaURL, buf.Bytes()); err == nil {
+			return buf.Bytes(), nil
+		}
+	}
+	return nil, fmt.Errorf("no valid values generated in %d attempts: %w", attempts, err)
+}
+
+// randomType returns one of the types of the schema or the type implied by its keywords
+func (s *Schema) randomType(r *rand.Rand) string {
+	if len(s.Type) > 0 {
+		// null is only picked sometimes, it's the least interesting value
+		if len(s.Type) > 1 && slices.Contains(s.Type, "null") && r.IntN(4) > 0 {
+			types := slices.DeleteFunc(slices.Clone([]string(s.Type)), func(t string) bool { return t == "null" })
+			return types[r.IntN(len(types))]
+		}
+		return s.Type[r.IntN(len(s.Type))]
+	}
+	switch {
+	case len(s.Properties) > 0:
+		return "object"
+	case s.Items != nil:
+		return "array"
+	}
+	switch s.Default.(type) {
+	case bool:
+		return "boolean"
+	case int, int64, uint64, json.Number:
+		return "integer"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "string"
+}
+
+// integerBounds returns the inclusive bounds of the integers of the schema
+func (s *Schema) integerBounds() (int, int) {
+	low, high := -1000, 1000
+	if s.Minimum != nil {
+		low = *s.Minimum
+	}
+	if s.ExclusiveMinimum != nil {
+		low = max(low, *s.ExclusiveMinimum+1)
+	}
+	if s.Maximum != nil {
+		high = *s.Maximum
+	}
+	if s.ExclusiveMaximum != nil {
+		high = min(high, *s.ExclusiveMaximum-1)
+	}
+	if s.Minimum == nil && s.ExclusiveMinimum == nil && high < low {
+		low = high - 1000
+	}
+	if s.Maximum == nil && s.ExclusiveMaximum == nil && high < low {
+		high = low + 1000
+	}
+	return low, high
+}
+
+func (s *Schema) randomInteger(r *rand.Rand) int {
+	low, high := s.integerBounds()
+	if high < low {
+		return low
+	}
+	// the bounds and zero are the most interesting values
+	var value int
+	switch candidates := []int{low, high, 0}; r.IntN(4) {
+	case 0, 1, 2:
+		value = candidates[r.IntN(len(candidates))]
+		if value < low || value > high {
+			value = low
+		}
+	default:
+		value = low + r.IntN(high-low+1)
+	}
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		multiple := *s.MultipleOf
+		value = int(math.Ceil(float64(value)/float64(multiple))) * multiple
+		if value > high {
+			value -= multiple
+		}
+	}
+	return value
+}
+
+func (s *Schema) randomNumber(r *rand.Rand) float64 {
+	low, high := s.integerBounds()
+	if high <= low {
+		return float64(low)
+	}
+	value := float64(low) + r.Float64()*float64(high-low)
+	if s.ExclusiveMinimum != nil && value <= float64(*s.ExclusiveMinimum) {
+		value = float64(*s.ExclusiveMinimum) + 0.5
+	}
+	return value
+}
+
+func (s *Schema) randomArray(r *rand.Rand) []interface{} {
+	low := 0
+	if s.MinItems != nil {
+		low = *s.MinItems
+	}
+	high := low + fuzzMaxRepeat
+	if s.MaxItems != nil {
+		high = min(high, *s.MaxItems)
+	}
+	count := low
+	if high > low {
+		count += r.IntN(high - low + 1)
+	}
+	items := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		items = append(items, s.Items.RandomValues(r))
+	}
+	return items
+}
+
+func (s *Schema) randomObject(r *rand.Rand) map[string]interface{} {
+	object := make(map[string]interface{})
+	for _, name := range s.PropertyNames() {
+		if !slices.Contains(s.Required.Strings, name) && r.IntN(2) == 0 {
+			continue
+		}
+		object[name] = s.Properties[name].RandomValues(r)
+	}
+	return object
+}
+
+func (s *Schema) randomString(r *rand.Rand) string {
+	if s.Pattern != "" {
+		if value, err := randomMatch(r, s.Pattern); err == nil {
+			return value
+		}
+	}
+	if value, ok := randomFormat(r, s.Format); ok {
+		return value
+	}
+	if s.ContentEncoding == "base64" {
+		return base64.StdEncoding.EncodeToString([]byte(randomString(r, nil, nil)))
+	}
+	return randomString(r, s.MinLength, s.MaxLength)
+}
+
+func randomString(r *rand.Rand, minLength, maxLength *int) string {
+	low, high := 0, 12
+	if minLength != nil {
+		low = *minLength
+		high = max(high, low)
+	}
+	if maxLength != nil {
+		high = min(high, *maxLength)
+	}
+	length := low
+	if high > low {
+		length += r.IntN(high - low + 1)
+	}
+	var sb strings.Builder
+	for i := 0; i < length; i++ {
+		sb.WriteRune(fuzzRunes[r.IntN(len(fuzzRunes))])
+	}
+	return sb.String()
+}
+
+// randomFormat returns a random value of the format, if the format is known
+func randomFormat(r *rand.Rand, format string) (string, bool) {
+	at := time.Unix(r.Int64N(4102444800), 0).UTC()
+	switch format {
+	case "date-time":
+		return at.Format(time.RFC3339), true
+	case "date":
+		return at.Format(time.DateOnly), true
+	case "time":
+		return at.Format("15:04:05Z"), true
+	case "email", "idn-email":
+		return fmt.Sprintf("user%d@example.com", r.IntN(1000)), true
+	case "hostname", "idn-hostname":
+		return fmt.Sprintf("host-%d.example.com", r.IntN(1000)), true
+	case "ipv4":
+		return netip.AddrFrom4([4]byte{byte(r.IntN(256)), byte(r.IntN(256)), byte(r.IntN(256)), byte(r.IntN(256))}).String(), true
+	case "ipv6":
+		var addr [16]byte
+		for i := range addr {
+			addr[i] = byte(r.IntN(256))
+		}
+		return netip.AddrFrom16(addr).String(), true
+	case "uri", "iri", "uri-reference", "iri-reference", "url":
+		return fmt.Sprintf("https://example.com/path-%d?query=%d", r.IntN(1000), r.IntN(1000)), true
+	case "uuid":
+		return fmt.Sprintf("%08x-%04x-4%03x-a%03x-%012x", r.Uint32(), r.IntN(1<<16), r.IntN(1<<12), r.IntN(1<<12), r.Int64N(1<<48)), true
+	case "regex":
+		return "^[a-z]+$", true
+	case "duration":
+		return fmt.Sprintf("P%dDT%dH", r.IntN(30), r.IntN(24)), true
+	}
+	return "", false
+}
+
+// randomMatch returns a random string matching the regular expression
+func randomMatch(r *rand.Rand, pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	writeRandomMatch(r, re.Simplify(), &sb)
+	return sb.String(), nil
+}
+
+func writeRandomMatch(r *rand.Rand, re *syntax.Regexp, sb *strings.Builder) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, char := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && r.IntN(2) == 0 {
+				char = unicode.SimpleFold(char)
+			}
+			sb.WriteRune(char)
+		}
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return
+		}
+		pair := r.IntN(len(re.Rune)/2) * 2
+		low, high := re.Rune[pair], re.Rune[pair+1]
+		// prefer the first characters of huge ranges (e.g. of negated classes), they are printable
+		char := low + rune(r.IntN(int(min(high-low+1, 95))))
+		if !utf8.ValidRune(char) {
+			char = low
+		}
+		sb.WriteRune(char)
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		sb.WriteRune(fuzzRunes[r.IntN(len(fuzzRunes))])
+	case syntax.OpCapture:
+		writeRandomMatch(r, re.Sub[0], sb)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			writeRandomMatch(r, sub, sb)
+		}
+	case syntax.OpAlternate:
+		writeRandomMatch(r, re.Sub[r.IntN(len(re.Sub))], sb)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		low, high := 0, fuzzMaxRepeat
+		switch re.Op {
+		case syntax.OpPlus:
+			low, high = 1, 1+fuzzMaxRepeat
+		case syntax.OpQuest:
+			high = 1
+		case syntax.OpRepeat:
+			low, high = re.Min, re.Max
+			if high < 0 {
+				high = low + fuzzMaxRepeat
+			}
+		}
+		for i := low + r.IntN(high-low+1); i > 0; i-- {
+			writeRandomMatch(r, re.Sub[0], sb)
+		}
+	}
+}
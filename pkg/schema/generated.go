@@ -0,0 +1,49 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// GeneratedByAnnotation contains the version of helm-schema and the hashes of the inputs of the schema
+const GeneratedByAnnotation = "x-generated-by"
+
+// generatorName is the name of the tool in the GeneratedByAnnotation
+const generatorName = "helm-schema"
+
+// ApplyGeneratedBy embeds the version of helm-schema and the sha256 of the Chart.yaml and values file of the
+// chart (by their path relative to the chart directory), so consumers can verify which revision of the values
+// a schema was generated from. It contains no timestamps, so the schema stays reproducible.
+func (r *Result) ApplyGeneratedBy(toolVersion string) error {
+	chartDir := filepath.Dir(r.ChartPath)
+	files := []string{r.ValuesPath}
+	if filepath.Base(r.ChartPath) == "Chart.yaml" {
+		// a bare values file has no Chart.yaml
+		files = append([]string{r.ChartPath}, files...)
+	}
+	inputs := map[string]interface{}{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(chartDir, file)
+		if err != nil {
+			name = filepath.Base(file)
+		}
+		sum := sha256.Sum256(content)
+		inputs[filepath.ToSlash(name)] = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	if r.Schema.CustomAnnotations == nil {
+		r.Schema.CustomAnnotations = make(map[string]interface{})
+	}
+	r.Schema.CustomAnnotations[GeneratedByAnnotation] = map[string]interface{}{
+		"name":    generatorName,
+		"version": toolVersion,
+		"inputs":  inputs,
+	}
+	return nil
+}
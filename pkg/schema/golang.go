@@ -0,0 +1,185 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// goGenerator collects the struct definitions of a schema
+type goGenerator struct {
+	buf       bytes.Buffer
+	typeNames map[string]bool
+	// pending contains the nested structs which still need to be written
+	pending []goStruct
+}
+
+type goStruct struct {
+	schema   *Schema
+	typeName string
+}
+
+// ToGo converts the schema into go structs with yaml and json tags.
+// The struct of the root schema is named typeName. Nested objects
+// get their own structs named after their path (e.g. ValuesImage).
+func (s *Schema) ToGo(packageName, typeName string) ([]byte, error) {
+	g := &goGenerator{typeNames: make(map[string]bool)}
+
+	fmt.Fprintf(&g.buf, "// Code generated by helm-schema. DO NOT EDIT.\n\npackage %s\n", packageName)
+	if s.goHasProperties() {
+		g.writeStruct(s, g.uniqueTypeName(typeName))
+	} else {
+		fmt.Fprintf(&g.buf, "\ntype %s %s\n", g.uniqueTypeName(typeName), g.goType(s, typeName, true))
+		g.writePending()
+	}
+
+	formatted, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("could not format generated go code: %w", err)
+	}
+	return formatted, nil
+}
+
+func (s *Schema) goHasProperties() bool {
+	return s.isObject() && len(s.Properties) > 0
+}
+
+func (g *goGenerator) uniqueTypeName(name string) string {
+	uniqueName := name
+	for i := 2; g.typeNames[uniqueName]; i++ {
+		uniqueName = name + strconv.Itoa(i)
+	}
+	g.typeNames[uniqueName] = true
+	return uniqueName
+}
+
+// writeStruct writes the struct of the schema and afterwards the structs of its nested objects
+func (g *goGenerator) writeStruct(s *Schema, typeName string) {
+	fieldNames := make(map[string]bool)
+
+	var fields bytes.Buffer
+	for _, name := range s.PropertyNames() {
+		property := s.Properties[name]
+		if property == nil {
+			continue
+		}
+
+		fieldName := goIdentifier(name)
+		for i := 2; fieldNames[fieldName]; i++ {
+			fieldName = goIdentifier(name) + strconv.Itoa(i)
+		}
+		fieldNames[fieldName] = true
+
+		required := property.Required.Bool || slices.Contains(s.Required.Strings, name)
+
+		var fieldType string
+		if property.goHasProperties() {
+			nestedTypeName := g.uniqueTypeName(typeName + fieldName)
+			g.pending = append(g.pending, goStruct{schema: property, typeName: nestedTypeName})
+			fieldType = nestedTypeName
+			if !required {
+				fieldType = "*" + fieldType
+			}
+		} else {
+			fieldType = g.goType(property, typeName+fieldName, required)
+		}
+
+		writeGoDoc(&fields, property.Description, "\t")
+		omitEmpty := ""
+		if !required {
+			omitEmpty = ",omitempty"
+		}
+		fmt.Fprintf(&fields, "\t%s %s `json:%q yaml:%q`\n", fieldName, fieldType, name+omitEmpty, name+omitEmpty)
+	}
+
+	g.buf.WriteString("\n")
+	writeGoDoc(&g.buf, s.Description, "")
+	fmt.Fprintf(&g.buf, "type %s struct {\n%s}\n", typeName, fields.String())
+
+	g.writePending()
+}
+
+func (g *goGenerator) writePending() {
+	pending := g.pending
+	g.pending = nil
+	for _, nested := range pending {
+		g.writeStruct(nested.schema, nested.typeName)
+	}
+}
+
+// goType returns the go type of the schema. Optional scalars and nullable types are pointers.
+func (g *goGenerator) goType(s *Schema, typeName string, required bool) string {
+	types := []string{}
+	nullable := false
+	for _, schemaType := range s.Type {
+		if schemaType == "null" {
+			nullable = true
+			continue
+		}
+		types = append(types, schemaType)
+	}
+	if len(types) != 1 {
+		return "interface{}"
+	}
+
+	var goType string
+	switch types[0] {
+	case "string":
+		goType = "string"
+	case "integer":
+		goType = "int64"
+	case "number":
+		goType = "float64"
+	case "boolean":
+		goType = "bool"
+	case "array":
+		if s.Items == nil {
+			return "[]interface{}"
+		}
+		if s.Items.goHasProperties() {
+			itemTypeName := g.uniqueTypeName(typeName + "Item")
+			g.pending = append(g.pending, goStruct{schema: s.Items, typeName: itemTypeName})
+			return "[]" + itemTypeName
+		}
+		return "[]" + g.goType(s.Items, typeName+"Item", true)
+	case "object":
+		if additionalProperties, ok := s.AdditionalProperties.(*Schema); ok {
+			if additionalProperties.goHasProperties() {
+				valueTypeName := g.uniqueTypeName(typeName + "Value")
+				g.pending = append(g.pending, goStruct{schema: additionalProperties, typeName: valueTypeName})
+				return "map[string]" + valueTypeName
+			}
+			return "map[string]" + g.goType(additionalProperties, typeName+"Value", true)
+		}
+		return "map[string]interface{}"
+	default:
+		return "interface{}"
+	}
+
+	if nullable || !required {
+		return "*" + goType
+	}
+	return goType
+}
+
+// goIdentifier converts a property name into an exported go identifier
+func goIdentifier(name string) string {
+	identifier := pascalCase(name)
+	if identifier == "" || !unicode.IsLetter(rune(identifier[0])) {
+		identifier = "X" + identifier
+	}
+	return identifier
+}
+
+func writeGoDoc(buf *bytes.Buffer, description, indent string) {
+	if description == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSpace(description), "\n") {
+		fmt.Fprintf(buf, "%s// %s\n", indent, strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// GraphChart is a chart of the dependency graph
+type GraphChart struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
+
+// GraphEdge is a dependency of a chart
+type GraphEdge struct {
+	// From is the path of the chart with the dependency
+	From      string `json:"from"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Alias     string `json:"alias,omitempty"`
+	Condition string `json:"condition,omitempty"`
+	// To is the path of the found chart satisfying the dependency, empty if it is missing
+	To string `json:"to,omitempty"`
+}
+
+// DependencyGraph contains the charts, their dependencies and the order they are processed in
+type DependencyGraph struct {
+	Charts []GraphChart `json:"charts"`
+	Edges  []GraphEdge  `json:"edges"`
+	// Order contains the paths of the charts in processing order
+	Order []string `json:"order"`
+	// Circular is true if there is a circular or missing dependency, the unsorted charts are at the end of the order
+	Circular bool `json:"circular"`
+}
+
+// NewDependencyGraph builds the dependency graph of the charts like TopoSort sees it.
+// If the charts can't be sorted, the graph is returned with the error of TopoSort.
+func NewDependencyGraph(results []*Result) (*DependencyGraph, error) {
+	graph := &DependencyGraph{Charts: []GraphChart{}, Edges: []GraphEdge{}, Order: []string{}}
+	for _, result := range results {
+		graph.Charts = append(graph.Charts, GraphChart{Name: result.Chart.Name, Version: result.Chart.Version, Path: result.ChartPath})
+		for _, dep := range result.Chart.Dependencies {
+			graph.Edges = append(graph.Edges, GraphEdge{
+				From:      result.ChartPath,
+				Name:      dep.Name,
+				Version:   dep.Version,
+				Alias:     dep.Alias,
+				Condition: dep.Condition,
+				To:        satisfyingChart(results, dep.Name, dep.Version),
+			})
+		}
+	}
+
+	sorted, err := TopoSort(results, nil)
+	var circularErr *CircularError
+	graph.Circular = errors.As(err, &circularErr)
+	for _, result := range sorted {
+		graph.Order = append(graph.Order, result.ChartPath)
+	}
+	return graph, err
+}
+
+// satisfyingChart returns the path of the first chart matching the name and version constraint
+func satisfyingChart(results []*Result, name, constraint string) string {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return ""
+	}
+	for _, result := range results {
+		if result.Chart.Name != name {
+			continue
+		}
+		if version, err := semver.NewVersion(result.Chart.Version); err == nil && c.Check(version) {
+			return result.ChartPath
+		}
+	}
+	return ""
+}
+
+// Dot returns the graph in the Graphviz dot language, missing dependencies are drawn dashed
+func (g *DependencyGraph) Dot() string {
+	var dot strings.Builder
+	dot.WriteString("digraph charts {\n")
+	for _, c := range g.Charts {
+		fmt.Fprintf(&dot, "  %q [label=%q];\n", c.Path, c.Name+" "+c.Version)
+	}
+	for _, edge := range g.Edges {
+		label := edge.Name + " " + edge.Version
+		if edge.Alias != "" {
+			label += "\\nalias: " + edge.Alias
+		}
+		if edge.Condition != "" {
+			label += "\\ncondition: " + edge.Condition
+		}
+		to, style := edge.To, ""
+		if to == "" {
+			to = "missing: " + edge.Name + " " + edge.Version
+			style = ", style=dashed"
+			fmt.Fprintf(&dot, "  %q [shape=box, style=dashed];\n", to)
+		}
+		fmt.Fprintf(&dot, "  %q -> %q [label=\"%s\"%s];\n", edge.From, to, strings.ReplaceAll(label, `"`, `\"`), style)
+	}
+	dot.WriteString("}\n")
+	return dot.String()
+}
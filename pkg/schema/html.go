@@ -0,0 +1,128 @@
+package schema
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// HTMLFormat renders the documentation as self-contained html page
+const HTMLFormat = "html"
+
+// HTMLIndexEntry is a link to the documentation page of a chart
+type HTMLIndexEntry struct {
+	Name        string
+	Version     string
+	Description string
+	Path        string
+}
+
+const htmlStyle = `
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 60em; padding: 0 1em; color: #1f2328; }
+input[type=search] { width: 100%; padding: .5em; font-size: 1em; margin-bottom: 1em; box-sizing: border-box; }
+details { margin-left: 1.2em; border-left: 1px solid #d0d7de; padding-left: .6em; }
+details.leaf > summary { list-style: none; }
+summary { cursor: pointer; padding: .2em 0; }
+code { background: #f6f8fa; padding: .1em .3em; border-radius: 4px; }
+.type { color: #6e7781; }
+.required { color: #cf222e; font-size: .85em; }
+.description { margin: .2em 0 .4em; white-space: pre-wrap; }
+.hidden { display: none; }
+`
+
+const htmlSearchScript = `
+const search = document.getElementById("search");
+search.addEventListener("input", () => {
+  const query = search.value.toLowerCase();
+  const entries = Array.from(document.querySelectorAll("[data-search]"));
+  entries.forEach((entry) => entry.classList.add("hidden"));
+  entries.forEach((entry) => {
+    if (!entry.dataset.search.toLowerCase().includes(query)) {
+      return;
+    }
+    entry.classList.remove("hidden");
+    for (let parent = entry.parentElement; parent; parent = parent.parentElement) {
+      if (parent.dataset && parent.dataset.search !== undefined) {
+        parent.classList.remove("hidden");
+        if (query !== "") {
+          parent.open = true;
+        }
+      }
+    }
+  });
+});
+`
+
+var htmlTemplates = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{ .Title }}</title>
+<style>{{ .Style }}</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+{{- with .Description }}
+<p>{{ . }}</p>
+{{- end }}
+<input type="search" id="search" placeholder="Search values" autofocus>
+{{- if .Entries }}
+<ul>
+{{- range .Entries }}
+<li data-search="{{ .Name }} {{ .Description }}"><a href="{{ .Path }}">{{ .Name }}</a> <span class="type">{{ .Version }}</span>{{ with .Description }} &ndash; {{ . }}{{ end }}</li>
+{{- end }}
+</ul>
+{{- end }}
+{{- range .Values }}
+{{ template "value" . }}
+{{- end }}
+<script>{{ .Script }}</script>
+</body>
+</html>
+`))
+
+func init() {
+	template.Must(htmlTemplates.New("value").Parse(`<details id="{{ .Key }}" data-search="{{ .Key }} {{ .Description }}"{{ if not .Children }} class="leaf"{{ end }}>
+<summary><code>{{ .Name }}</code>{{ with .Type }} <span class="type">{{ . }}</span>{{ end }}{{ if .Required }} <span class="required">required</span>{{ end }}</summary>
+{{- with .Description }}
+<p class="description">{{ . }}</p>
+{{- end }}
+{{- with .DefaultJSON }}
+<p>Default: <code>{{ . }}</code></p>
+{{- end }}
+{{- range .Children }}
+{{ template "value" . }}
+{{- end }}
+</details>`))
+}
+
+type htmlPage struct {
+	Title       string
+	Description string
+	Values      []docValue
+	Entries     []HTMLIndexEntry
+	Style       template.CSS
+	Script      template.JS
+}
+
+func renderHTML(page htmlPage) ([]byte, error) {
+	page.Style = template.CSS(htmlStyle)
+	page.Script = template.JS(htmlSearchScript)
+
+	var buf bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&buf, "page", page); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ToHTML renders the documentation of all properties as searchable html page
+// with collapsible nested values
+func (s *Schema) ToHTML(title, description string) ([]byte, error) {
+	return renderHTML(htmlPage{Title: title, Description: description, Values: s.docValues("", 0)})
+}
+
+// HTMLIndex renders a searchable html page linking to the given documentation pages
+func HTMLIndex(title string, entries []HTMLIndexEntry) ([]byte, error) {
+	return renderHTML(htmlPage{Title: title, Entries: entries})
+}
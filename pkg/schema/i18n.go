@@ -0,0 +1,76 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+// I18nAnnotation is the custom annotation containing the translations of the title and description
+// by language (e.g. x-i18n: {de: {description: ...}})
+const I18nAnnotation = CustomAnnotationPrefix + "i18n"
+
+// localizableKeywords are the keywords, which can be translated with a language suffix (e.g. description.de)
+var localizableKeywords = []string{"title", "description"}
+
+// addTranslation adds the translation of the language-suffixed key (e.g. title.fr) to the x-i18n annotation.
+// It returns false, if the key isn't a translation.
+func addTranslation(annotations map[string]interface{}, key string, valueNode *yaml.Node) (bool, error) {
+	keyword, lang, ok := strings.Cut(key, ".")
+	if !ok || !slices.Contains(localizableKeywords, keyword) {
+		return false, nil
+	}
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return true, fmt.Errorf("invalid language of %s: %w", key, err)
+	}
+	if valueNode.Kind != yaml.ScalarNode {
+		return true, fmt.Errorf("%s must be a string", key)
+	}
+
+	translations, ok := annotations[I18nAnnotation].(map[string]interface{})
+	if !ok {
+		translations = make(map[string]interface{})
+		annotations[I18nAnnotation] = translations
+	}
+	translation, ok := translations[tag.String()].(map[string]interface{})
+	if !ok {
+		translation = make(map[string]interface{})
+		translations[tag.String()] = translation
+	}
+	translation[keyword] = valueNode.Value
+	return true, nil
+}
+
+// Localize replaces the titles and descriptions of the schema and all of its subschemas by their
+// translations into the language and removes the x-i18n annotations. If there is no translation
+// into the language (e.g. de-AT), the one into its base language (de) is used. Keywords without
+// a translation are kept.
+func (s *Schema) Localize(lang string) error {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return fmt.Errorf("invalid language %s: %w", lang, err)
+	}
+	base, _ := tag.Base()
+	s.Walk(func(subSchema *Schema) {
+		translations, ok := subSchema.CustomAnnotations[I18nAnnotation].(map[string]interface{})
+		if !ok {
+			return
+		}
+		delete(subSchema.CustomAnnotations, I18nAnnotation)
+		translation, ok := translations[tag.String()].(map[string]interface{})
+		if !ok {
+			translation, _ = translations[base.String()].(map[string]interface{})
+		}
+		if title, ok := translation["title"].(string); ok {
+			subSchema.Title = title
+		}
+		if description, ok := translation["description"].(string); ok {
+			subSchema.Description = description
+		}
+	})
+	return nil
+}
@@ -0,0 +1,177 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// MarkdownFormatTable renders all values as one table with dotted keys
+	MarkdownFormatTable = "table"
+	// MarkdownFormatTree renders the values as nested list
+	MarkdownFormatTree = "tree"
+
+	// MarkdownBeginMarker and MarkdownEndMarker enclose the generated documentation
+	// if it's injected into an existing file
+	MarkdownBeginMarker = "<!-- helm-schema:begin -->"
+	MarkdownEndMarker   = "<!-- helm-schema:end -->"
+)
+
+// docValue is a documented property of the schema
+type docValue struct {
+	Key         string
+	Name        string
+	Depth       int
+	Type        string
+	Default     interface{}
+	Description string
+	Required    bool
+	Children    []docValue
+}
+
+// DefaultJSON returns the default value as json or an empty string if there is none
+func (v docValue) DefaultJSON() string {
+	if v.Default == nil {
+		return ""
+	}
+	defaultJSON, err := json.Marshal(v.Default)
+	if err != nil {
+		return ""
+	}
+	return string(defaultJSON)
+}
+
+// ToMarkdown renders the documentation of all properties in the given format
+func (s *Schema) ToMarkdown(format string) ([]byte, error) {
+	values := flattenDocValues(s.docValues("", 0))
+
+	var buf bytes.Buffer
+	switch format {
+	case MarkdownFormatTable:
+		buf.WriteString("| Key | Type | Default | Required | Description |\n")
+		buf.WriteString("|-----|------|---------|----------|-------------|\n")
+		for _, value := range values {
+			required := ""
+			if value.Required {
+				required = "yes"
+			}
+			fmt.Fprintf(
+				&buf,
+				"| `%s` | %s | %s | %s | %s |\n",
+				value.Key,
+				escapeMarkdownTableCell(value.Type),
+				escapeMarkdownTableCell(markdownCode(value.DefaultJSON())),
+				required,
+				escapeMarkdownTableCell(value.Description),
+			)
+		}
+	case MarkdownFormatTree:
+		for _, value := range values {
+			details := []string{}
+			if value.Type != "" {
+				details = append(details, value.Type)
+			}
+			if value.Required {
+				details = append(details, "required")
+			}
+
+			fmt.Fprintf(&buf, "%s- **%s**", strings.Repeat("  ", value.Depth), value.Name)
+			if len(details) > 0 {
+				fmt.Fprintf(&buf, " (%s)", strings.Join(details, ", "))
+			}
+			if value.Description != "" {
+				fmt.Fprintf(&buf, ": %s", strings.Join(strings.Fields(value.Description), " "))
+			}
+			if value.Default != nil {
+				fmt.Fprintf(&buf, " Default: %s", markdownCode(value.DefaultJSON()))
+			}
+			buf.WriteString("\n")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported markdown format %s, use %s or %s", format, MarkdownFormatTable, MarkdownFormatTree)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// docValues returns the documentation of the properties of the schema (and their nested
+// properties). Properties of array items are prefixed with "[]".
+func (s *Schema) docValues(prefix string, depth int) []docValue {
+	values := []docValue{}
+	for _, name := range s.PropertyNames() {
+		property := s.Properties[name]
+		if property == nil {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		value := docValue{
+			Key:         key,
+			Name:        name,
+			Depth:       depth,
+			Type:        strings.Join(property.Type, ", "),
+			Default:     property.Default,
+			Description: property.Description,
+			Required:    property.Required.Bool || slices.Contains(s.Required.Strings, name),
+			Children:    property.docValues(key, depth+1),
+		}
+		if property.Items != nil {
+			value.Children = append(value.Children, property.Items.docValues(key+"[]", depth+1)...)
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// flattenDocValues returns all values of the tree, parents first
+func flattenDocValues(values []docValue) []docValue {
+	flat := []docValue{}
+	for _, value := range values {
+		flat = append(flat, value)
+		flat = append(flat, flattenDocValues(value.Children)...)
+	}
+	return flat
+}
+
+func markdownCode(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "`" + text + "`"
+}
+
+func escapeMarkdownTableCell(cell string) string {
+	cell = strings.ReplaceAll(cell, "|", "\\|")
+	return strings.ReplaceAll(strings.TrimSpace(cell), "\n", "<br>")
+}
+
+// InjectMarkdown replaces everything between MarkdownBeginMarker and MarkdownEndMarker
+// in content with doc
+func InjectMarkdown(content, doc []byte) ([]byte, error) {
+	begin := bytes.Index(content, []byte(MarkdownBeginMarker))
+	if begin == -1 {
+		return nil, errors.New("begin marker " + MarkdownBeginMarker + " not found")
+	}
+	begin += len(MarkdownBeginMarker)
+
+	end := bytes.Index(content[begin:], []byte(MarkdownEndMarker))
+	if end == -1 {
+		return nil, errors.New("end marker " + MarkdownEndMarker + " not found")
+	}
+	end += begin
+
+	var buf bytes.Buffer
+	buf.Write(content[:begin])
+	buf.WriteString("\n")
+	buf.Write(doc)
+	buf.Write(content[end:])
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,191 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mergeKeyTag is the tag of the << key merging mappings into the mapping containing it
+const mergeKeyTag = "!!merge"
+
+// ParseValues parses the content of a values file into a single yaml document, in which the aliases
+// are replaced by copies of the anchored nodes and the merge keys (<<) by the keys they merge.
+// Helm only reads the first document of a values file, so it contains the defaults. The keys of
+// the following documents, which don't exist in the first one, are added to it (e.g. to document
+// values which aren't set by default). Helm doesn't set them, so they aren't required, unless
+// they're annotated as required. The following documents are merged while decoding, so only the
+// first one is kept in memory.
+func ParseValues(content []byte) (*yaml.Node, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	var values *yaml.Node
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := resolveAliases(&document); err != nil {
+			return nil, err
+		}
+		if values == nil {
+			values = &document
+			continue
+		}
+
+		if len(document.Content) != 1 || document.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		if len(values.Content) != 1 || values.Content[0].Kind != yaml.MappingNode {
+			values.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+		}
+		if err := addOptionalKeys(values.Content[0], document.Content[0]); err != nil {
+			return nil, err
+		}
+	}
+	if values == nil {
+		return &yaml.Node{}, nil
+	}
+	return values, nil
+}
+
+// resolveAliases replaces the aliases and merge keys of the node and its children. The explicit keys of a
+// mapping take precedence over the merged ones and the mappings merged first over the ones merged later.
+func resolveAliases(node *yaml.Node) error {
+	for i, child := range node.Content {
+		if child.Kind == yaml.AliasNode {
+			node.Content[i] = copyNode(child.Alias)
+		}
+		if err := resolveAliases(node.Content[i]); err != nil {
+			return err
+		}
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	keys := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Tag != mergeKeyTag {
+			keys[node.Content[i].Value] = true
+		}
+	}
+	content := []*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if keyNode.Tag != mergeKeyTag {
+			content = append(content, keyNode, valueNode)
+			continue
+		}
+		sources := []*yaml.Node{valueNode}
+		if valueNode.Kind == yaml.SequenceNode {
+			sources = valueNode.Content
+		}
+		for _, source := range sources {
+			if source.Kind != yaml.MappingNode {
+				return fmt.Errorf("line %d: the merge key << must reference a mapping or a list of mappings", keyNode.Line)
+			}
+			for j := 0; j+1 < len(source.Content); j += 2 {
+				if keys[source.Content[j].Value] {
+					continue
+				}
+				keys[source.Content[j].Value] = true
+				content = append(content, copyNode(source.Content[j]), copyNode(source.Content[j+1]))
+			}
+		}
+	}
+	node.Content = content
+	return nil
+}
+
+// copyNode returns a deep copy of the node, so the annotations of aliases can be changed independently
+func copyNode(node *yaml.Node) *yaml.Node {
+	copied := *node
+	copied.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		copied.Content[i] = copyNode(child)
+	}
+	return &copied
+}
+
+// addOptionalKeys adds the keys of the mapping node other, which don't exist in the mapping node base
+func addOptionalKeys(base, other *yaml.Node) error {
+	for i := 0; i+1 < len(other.Content); i += 2 {
+		keyNode, valueNode := other.Content[i], other.Content[i+1]
+		var existing *yaml.Node
+		for j := 0; j+1 < len(base.Content); j += 2 {
+			if base.Content[j].Value == keyNode.Value {
+				existing = base.Content[j+1]
+				break
+			}
+		}
+		if existing != nil {
+			if existing.Kind == yaml.MappingNode && valueNode.Kind == yaml.MappingNode {
+				if err := addOptionalKeys(existing, valueNode); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := markOptional(keyNode, valueNode); err != nil {
+			return err
+		}
+		base.Content = append(base.Content, keyNode, valueNode)
+	}
+	return nil
+}
+
+// markOptionalKeys marks the keys of the mappings at the lines as optional, see markOptional
+func markOptionalKeys(node *yaml.Node, lines []int) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if slices.Contains(lines, node.Content[i].Line) {
+				if err := markOptional(node.Content[i], node.Content[i+1]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, child := range node.Content {
+		if err := markOptionalKeys(child, lines); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markOptional annotates the key, which isn't set by default, with required: false, unless it's
+// annotated as required already
+func markOptional(keyNode, valueNode *yaml.Node) error {
+	annotation, _, err := GetSchemaFromComment(keyNode.HeadComment)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", keyNode.Line, err)
+	}
+	if annotation.Required.Bool || len(annotation.Required.Strings) > 0 {
+		return nil
+	}
+	keywords := map[string]interface{}{"required": false}
+	if !annotation.HasData {
+		// annotated keys don't get an inferred type
+		valueType, err := nodeType(valueNode)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", keyNode.Line, err)
+		}
+		keywords["type"] = valueType[0]
+	}
+	if err := mergeAnnotation(keyNode, keywords); err != nil {
+		return fmt.Errorf("line %d: %w", keyNode.Line, err)
+	}
+	return nil
+}
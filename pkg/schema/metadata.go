@@ -0,0 +1,60 @@
+package schema
+
+import (
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+const (
+	// ChartAnnotationId can be used in the annotations of Chart.yaml to set the $id of the schema
+	ChartAnnotationId = "helm-schema/id"
+
+	// ChartMetadataAnnotation contains the chart metadata in the root schema
+	ChartMetadataAnnotation = "x-helm-chart"
+)
+
+// ApplyRootKeywords overrides the $schema of the root schema (if schemaURI isn't empty)
+// and adds the given keywords to it. The keywords take precedence over the generated ones.
+func (s *Schema) ApplyRootKeywords(schemaURI string, keywords map[string]interface{}) {
+	if schemaURI != "" {
+		s.Schema = schemaURI
+	}
+	for key, value := range keywords {
+		if s.CustomAnnotations == nil {
+			s.CustomAnnotations = make(map[string]interface{})
+		}
+		s.CustomAnnotations[key] = value
+	}
+}
+
+// ApplyChartMetadata sets the $id of the root schema and optionally embeds the metadata of the chart.
+// The idTemplate is a go template rendered with the result. The chart annotation helm-schema/id
+// takes precedence over the idTemplate.
+func (r *Result) ApplyChartMetadata(idTemplate string, embedChartMetadata bool) error {
+	if annotatedId, ok := r.Chart.Annotations[ChartAnnotationId]; ok {
+		idTemplate = annotatedId
+	}
+
+	if idTemplate != "" {
+		id, err := util.RenderTemplate("schema id", idTemplate, r)
+		if err != nil {
+			return err
+		}
+		r.Schema.Id = id
+	}
+
+	if embedChartMetadata {
+		metadata := map[string]interface{}{
+			"name":    r.Chart.Name,
+			"version": r.Chart.Version,
+		}
+		if r.Chart.AppVersion != "" {
+			metadata["appVersion"] = r.Chart.AppVersion
+		}
+		if r.Schema.CustomAnnotations == nil {
+			r.Schema.CustomAnnotations = make(map[string]interface{})
+		}
+		r.Schema.CustomAnnotations[ChartMetadataAnnotation] = metadata
+	}
+
+	return nil
+}
@@ -0,0 +1,353 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AnnotateValues writes the keywords of the given schema as @schema annotations above
+// the matching keys of the values file. Keys which are already annotated are skipped.
+// Besides the annotated content, the paths of all properties which couldn't be found
+// in the values file are returned.
+func AnnotateValues(content []byte, s *Schema) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 {
+		return content, nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("the values file must contain a mapping, found %s", root.Tag)
+	}
+
+	insertions := make(map[int][]string)
+	missing := []string{}
+	if err := annotateMapping(root, s, "", insertions, &missing, annotationBlock); err != nil {
+		return nil, nil, err
+	}
+	return insertAnnotations(content, insertions), missing, nil
+}
+
+// InferredKeywords are the keywords, which can be written by InferAnnotations
+var InferredKeywords = []string{"type", "default", "title"}
+
+// InferAnnotations writes the given keywords of the schema generated from the values as @schema
+// annotations above the keys, which aren't annotated yet. Keys with annotations aren't required
+// by default, so required keys are annotated with required: true and the generated schema stays the same.
+// Running it again doesn't change the values, as all keys are annotated already.
+func InferAnnotations(content []byte, s *Schema, keywords []string) ([]byte, int, error) {
+	for _, keyword := range keywords {
+		if !slices.Contains(InferredKeywords, keyword) {
+			return nil, 0, fmt.Errorf("unsupported keyword %s, use one of %s", keyword, strings.Join(InferredKeywords, ", "))
+		}
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, 0, err
+	}
+	if len(doc.Content) == 0 {
+		return content, 0, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, 0, fmt.Errorf("the values file must contain a mapping, found %s", root.Tag)
+	}
+
+	insertions := make(map[int][]string)
+	block := func(_ string, property *Schema, _, required bool, _ string) ([]string, error) {
+		return inferredAnnotationBlock(property, keywords, required)
+	}
+	// the properties of commented out values (with --uncomment) don't exist in the values
+	if err := annotateMapping(root, s, "", insertions, &[]string{}, block); err != nil {
+		return nil, 0, err
+	}
+	return insertAnnotations(content, insertions), len(insertions), nil
+}
+
+// inferredAnnotationBlock returns the comment lines of the annotation with the keywords of the property.
+// Properties which got keywords from the comments (e.g. helm-docs or sidecar annotations) are skipped.
+func inferredAnnotationBlock(property *Schema, keywords []string, required bool) ([]string, error) {
+	if property.HasData {
+		return nil, nil
+	}
+	block := &yaml.Node{Kind: yaml.MappingNode}
+	add := func(keyword string, value interface{}) error {
+		var valueNode yaml.Node
+		if err := valueNode.Encode(value); err != nil {
+			return err
+		}
+		block.Content = append(block.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: keyword}, &valueNode)
+		return nil
+	}
+	for _, keyword := range keywords {
+		var err error
+		switch {
+		case keyword == "type" && !property.Type.IsEmpty():
+			if len(property.Type) == 1 {
+				err = add(keyword, property.Type[0])
+			} else {
+				err = add(keyword, []string(property.Type))
+			}
+		case keyword == "default" && property.Default != nil:
+			err = add(keyword, property.Default)
+		case keyword == "title" && property.Title != "":
+			err = add(keyword, property.Title)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(block.Content) == 0 {
+		return nil, nil
+	}
+	if required {
+		if err := add("required", true); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(block); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	lines := []string{SchemaPrefix}
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		lines = append(lines, CommentPrefix+" "+line)
+	}
+	return append(lines, SchemaPrefix), nil
+}
+
+// insertAnnotations inserts the annotation lines above the lines of the content
+func insertAnnotations(content []byte, insertions map[int][]string) []byte {
+	var buf bytes.Buffer
+	for i, line := range strings.Split(string(content), "\n") {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		for _, annotation := range insertions[i] {
+			buf.WriteString(annotation + "\n")
+		}
+		buf.WriteString(line)
+	}
+	return buf.Bytes()
+}
+
+// annotationFunc returns the comment lines of the annotation of the property, no lines to skip it
+type annotationFunc func(name string, property *Schema, annotateChildren, required bool, headComment string) ([]string, error)
+
+func annotateMapping(node *yaml.Node, s *Schema, prefix string, insertions map[int][]string, missing *[]string, block annotationFunc) error {
+	found := make(map[string]bool)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if valueNode.Kind == yaml.AliasNode {
+			valueNode = valueNode.Alias
+		}
+		property := s.Properties[keyNode.Value]
+		if property == nil {
+			continue
+		}
+		found[keyNode.Value] = true
+
+		path := keyNode.Value
+		if prefix != "" {
+			path = prefix + "." + keyNode.Value
+		}
+		// the children of flow mappings are on the same line, they can't be annotated
+		annotateChildren := valueNode.Kind == yaml.MappingNode && valueNode.Style&yaml.FlowStyle == 0
+
+		if !strings.Contains(keyNode.HeadComment, SchemaPrefix) {
+			annotation, err := block(
+				keyNode.Value,
+				property,
+				annotateChildren,
+				slices.Contains(s.Required.Strings, keyNode.Value),
+				keyNode.HeadComment,
+			)
+			if err != nil {
+				return fmt.Errorf("could not create annotation of %s: %w", path, err)
+			}
+			indent := strings.Repeat(" ", keyNode.Column-1)
+			for _, line := range annotation {
+				insertions[keyNode.Line-1] = append(insertions[keyNode.Line-1], strings.TrimRight(indent+line, " "))
+			}
+		}
+
+		if annotateChildren {
+			if err := annotateMapping(valueNode, property, path, insertions, missing, block); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, name := range s.PropertyNames() {
+		// global is added to every schema automatically
+		if found[name] || (prefix == "" && name == "global") {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		*missing = append(*missing, name)
+	}
+	return nil
+}
+
+// annotationBlock returns the comment lines of the @schema annotation of the property.
+// If the children of an object are annotated themselves, its properties are left out.
+func annotationBlock(name string, property *Schema, annotateChildren, required bool, headComment string) ([]string, error) {
+	propertyJSON, err := json.Marshal(property)
+	if err != nil {
+		return nil, err
+	}
+	var keywords map[string]interface{}
+	if err := json.Unmarshal(propertyJSON, &keywords); err != nil {
+		return nil, err
+	}
+
+	if annotateChildren {
+		delete(keywords, "properties")
+		delete(keywords, "required")
+		// objects with annotations are closed by default
+		if property.AdditionalProperties == nil {
+			keywords["additionalProperties"] = true
+		}
+	}
+	cleanKeywords(keywords)
+	if keywords["title"] == name {
+		delete(keywords, "title")
+	}
+	if description, ok := keywords["description"].(string); ok && description == commentText(headComment) {
+		delete(keywords, "description")
+	}
+	// keys with annotations aren't required by default
+	if _, ok := keywords["required"]; !ok && required {
+		keywords["required"] = true
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(keywords); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	lines := []string{SchemaPrefix}
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		lines = append(lines, CommentPrefix+" "+line)
+	}
+	return append(lines, SchemaPrefix), nil
+}
+
+// cleanKeywords removes the empty required lists of the keywords and all nested schemas.
+// The type is removed next to enum and const, annotations can't use them together.
+func cleanKeywords(value interface{}) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if required, ok := value["required"].([]interface{}); ok && len(required) == 0 {
+			delete(value, "required")
+		}
+		_, hasEnum := value["enum"]
+		_, hasConst := value["const"]
+		if hasEnum || hasConst {
+			delete(value, "type")
+		}
+		for _, nested := range value {
+			cleanKeywords(nested)
+		}
+	case []interface{}:
+		for _, nested := range value {
+			cleanKeywords(nested)
+		}
+	}
+}
+
+// commentText removes the comment prefixes of the comment
+func commentText(comment string) string {
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(line), CommentPrefix), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// QuestionsToSchema converts a rancher questions.yaml into a schema, so it can be used to annotate the values
+func QuestionsToSchema(content []byte) (*Schema, error) {
+	var questionsFile struct {
+		Questions []Question `yaml:"questions"`
+	}
+	if err := yaml.Unmarshal(content, &questionsFile); err != nil {
+		return nil, err
+	}
+
+	root := NewSchema("object")
+	for _, question := range questionsFile.Questions {
+		addQuestion(root, question)
+	}
+	return root, nil
+}
+
+func addQuestion(root *Schema, question Question) {
+	for _, subquestion := range question.Subquestions {
+		addQuestion(root, subquestion)
+	}
+	if question.Variable == "" {
+		return
+	}
+
+	parent := root
+	keys := strings.Split(question.Variable, ".")
+	for _, key := range keys[:len(keys)-1] {
+		if _, ok := parent.Properties[key]; !ok {
+			parent.SetProperty(key, NewSchema("object"))
+		}
+		parent = parent.Properties[key]
+	}
+
+	property := &Schema{
+		Title:       question.Label,
+		Description: question.Description,
+		Default:     question.Default,
+	}
+	switch question.Type {
+	case "int":
+		property.Type = []string{"integer"}
+		property.Minimum = question.Min
+		property.Maximum = question.Max
+	case "boolean":
+		property.Type = []string{"boolean"}
+	case "enum":
+		for _, option := range question.Options {
+			property.Enum = append(property.Enum, fmt.Sprint(option))
+		}
+	default:
+		// string, multiline, password, hostname and the rancher resource types
+		property.Type = []string{"string"}
+		property.MinLength = question.MinLength
+		property.MaxLength = question.MaxLength
+		if question.Type == "password" {
+			property.WriteOnly = true
+		}
+	}
+
+	key := keys[len(keys)-1]
+	parent.SetProperty(key, property)
+	if question.Required && !slices.Contains(parent.Required.Strings, key) {
+		parent.Required.Strings = append(parent.Required.Strings, key)
+	}
+}
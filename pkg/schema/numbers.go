@@ -0,0 +1,63 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// integerLiteral matches decimal integers, yaml resolves the ones which don't fit into 64 bits as floats
+var integerLiteral = regexp.MustCompile(`^[-+]?[0-9][0-9_]*$`)
+
+// decodeJSON decodes the json like json.Unmarshal, but its numbers are decoded as json.Number.
+// Marshaling the value again keeps the numbers exactly, float64 can't represent all integers of 64 bits.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// normalizeNumbers converts the json numbers of the decoded json value, see numberValue
+func normalizeNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		return numberValue(v.String())
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeNumbers(item)
+		}
+	}
+	return value
+}
+
+// numberValue returns the number of the literal as int (uint64 for larger positive integers) or float64.
+// Integers, which don't even fit into an uint64, are returned as json.Number to keep their digits.
+func numberValue(literal string) interface{} {
+	if integer, ok := parseInteger(literal); ok {
+		return integer
+	}
+	if float, err := strconv.ParseFloat(literal, 64); err == nil {
+		return float
+	}
+	return json.Number(literal)
+}
+
+// parseInteger parses the integer literal of yaml or json (e.g. 42, 0x2a, 0o52 or 1_000)
+func parseInteger(literal string) (interface{}, bool) {
+	if integer, err := strconv.ParseInt(literal, 0, 64); err == nil {
+		return int(integer), true
+	}
+	if integer, err := strconv.ParseUint(strings.TrimPrefix(literal, "+"), 0, 64); err == nil {
+		return integer, true
+	}
+	if integerLiteral.MatchString(literal) {
+		return json.Number(strings.ReplaceAll(strings.TrimPrefix(literal, "+"), "_", "")), true
+	}
+	return nil, false
+}
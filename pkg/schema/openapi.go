@@ -0,0 +1,113 @@
+package schema
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// omittedKeyword can be used as custom annotation to remove a keyword from the marshaled schema
+type omittedKeyword struct{}
+
+// openAPIAnnotations are the custom annotations allowed in OpenAPI 3.0 schema objects
+var openAPIAnnotations = []string{"nullable", "discriminator", "xml", "externalDocs", "example"}
+
+// ToOpenAPI converts the schema into an OpenAPI 3.0 schema object. Type arrays
+// are replaced with nullable or anyOf and keywords unsupported by OpenAPI are removed.
+func (s *Schema) ToOpenAPI() ([]byte, error) {
+	openAPISchema, err := s.openAPICopy()
+	if err != nil {
+		return nil, err
+	}
+	return openAPISchema.ToJson()
+}
+
+// openAPICopy returns a copy of the schema converted to OpenAPI 3.0,
+// the schema itself is still needed as jsonschema
+func (s *Schema) openAPICopy() (*Schema, error) {
+	jsonSchema, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	openAPISchema := &Schema{}
+	if err := json.Unmarshal(jsonSchema, openAPISchema); err != nil {
+		return nil, err
+	}
+	openAPISchema.Walk(toOpenAPI)
+	return openAPISchema, nil
+}
+
+// toOpenAPI converts a single schema (without its subschemas) to OpenAPI 3.0
+func toOpenAPI(s *Schema) {
+	annotations := make(map[string]interface{})
+	for key, value := range s.CustomAnnotations {
+		if strings.HasPrefix(key, "x-") || slices.Contains(openAPIAnnotations, key) {
+			annotations[key] = value
+		}
+	}
+	s.CustomAnnotations = annotations
+
+	s.Schema = ""
+	s.Id = ""
+	s.If = nil
+	s.Then = nil
+	s.Else = nil
+	s.PatternProperties = nil
+
+	types := []string{}
+	for _, schemaType := range s.Type {
+		if schemaType == "null" {
+			annotations["nullable"] = true
+			continue
+		}
+		types = append(types, schemaType)
+	}
+	switch len(types) {
+	case 0:
+		s.Type = nil
+	case 1:
+		s.Type = types
+	default:
+		s.Type = nil
+		for _, schemaType := range types {
+			s.AnyOf = append(s.AnyOf, &Schema{Type: []string{schemaType}})
+		}
+	}
+
+	if s.Const != nil {
+		annotations["enum"] = []interface{}{s.Const}
+		s.Const = nil
+	}
+	if len(s.Examples) > 0 {
+		annotations["example"] = s.Examples[0]
+		s.Examples = nil
+	}
+
+	// base64 encoded strings have the format byte in OpenAPI 3.0
+	if s.ContentEncoding == "base64" && s.Format == "" {
+		s.Format = "byte"
+	}
+	s.ContentEncoding = ""
+	s.ContentMediaType = ""
+
+	// exclusiveMinimum and exclusiveMaximum are booleans in OpenAPI 3.0
+	if s.ExclusiveMinimum != nil {
+		if s.Minimum == nil || *s.ExclusiveMinimum >= *s.Minimum {
+			s.Minimum = s.ExclusiveMinimum
+			annotations["exclusiveMinimum"] = true
+		}
+		s.ExclusiveMinimum = nil
+	}
+	if s.ExclusiveMaximum != nil {
+		if s.Maximum == nil || *s.ExclusiveMaximum <= *s.Maximum {
+			s.Maximum = s.ExclusiveMaximum
+			annotations["exclusiveMaximum"] = true
+		}
+		s.ExclusiveMaximum = nil
+	}
+
+	// required must contain at least one property in OpenAPI 3.0
+	if len(s.Required.Strings) == 0 {
+		annotations["required"] = omittedKeyword{}
+	}
+}
@@ -0,0 +1,400 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SchemaPatchFile is applied to the generated schema of the chart in the same directory.
+// It's either a json patch (RFC 6902) or a json merge patch (RFC 7386).
+const SchemaPatchFile = "values.schema.patch.json"
+
+// patchOperation is a single operation of a json patch
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ReadSchemaPatch returns the content of the patch file in the chart directory or nil if there is none
+func ReadSchemaPatch(chartDir string) ([]byte, error) {
+	content, err := os.ReadFile(filepath.Join(chartDir, SchemaPatchFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return content, err
+}
+
+// ApplySchemaPatchFile applies the patch file in the chart directory to the schema, it does nothing if there is none
+func (s *Schema) ApplySchemaPatchFile(chartDir string) error {
+	patch, err := ReadSchemaPatch(chartDir)
+	if err != nil || patch == nil {
+		return err
+	}
+	if err := s.ApplyPatch(patch); err != nil {
+		return fmt.Errorf("could not apply %s: %w", SchemaPatchFile, err)
+	}
+	return nil
+}
+
+// ApplyPatch applies the json patch (an array of operations) or json
+// merge patch (an object) to the schema. Keywords which helm-schema doesn't
+// know are kept as custom annotations.
+func (s *Schema) ApplyPatch(patch []byte) error {
+	schemaJSON, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := decodeJSON(schemaJSON, &doc); err != nil {
+		return err
+	}
+
+	trimmed := bytes.TrimSpace(patch)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		var operations []patchOperation
+		if err := json.Unmarshal(trimmed, &operations); err != nil {
+			return fmt.Errorf("invalid json patch: %w", err)
+		}
+		for i, operation := range operations {
+			if doc, err = applyPatchOperation(doc, operation); err != nil {
+				return fmt.Errorf("operation %d (%s %s) failed: %w", i, operation.Op, operation.Path, err)
+			}
+		}
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var mergePatch interface{}
+		if err := decodeJSON(trimmed, &mergePatch); err != nil {
+			return fmt.Errorf("invalid json merge patch: %w", err)
+		}
+		doc = applyMergePatch(doc, mergePatch)
+	default:
+		return errors.New("the patch must be a json patch (array) or json merge patch (object)")
+	}
+
+	patched, err := decodeSchemaDocument(doc, s)
+	if err != nil {
+		return fmt.Errorf("the patched schema is invalid: %w", err)
+	}
+	*s = patched
+	return nil
+}
+
+// decodeSchemaDocument converts the decoded json document of a modified copy of the original
+// schema back into a schema. The order of the properties is lost in the maps of the document,
+// so the order of the original schema is used.
+func decodeSchemaDocument(doc interface{}, original *Schema) (Schema, error) {
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		return Schema{}, err
+	}
+	decoded := Schema{}
+	if err := json.Unmarshal(docJSON, &decoded); err != nil {
+		return Schema{}, err
+	}
+	decoded.keepUnknownKeywords(doc)
+	decoded.restorePropertyOrder(original)
+	return decoded, nil
+}
+
+func applyPatchOperation(doc interface{}, operation patchOperation) (interface{}, error) {
+	var value interface{}
+	if operation.Op == "add" || operation.Op == "replace" || operation.Op == "test" {
+		if operation.Value == nil {
+			return nil, errors.New("the value is missing")
+		}
+		if err := decodeJSON(operation.Value, &value); err != nil {
+			return nil, err
+		}
+	}
+
+	switch operation.Op {
+	case "add":
+		return addValue(doc, operation.Path, value)
+	case "remove":
+		doc, _, err := removeValue(doc, operation.Path)
+		return doc, err
+	case "replace":
+		doc, _, err := removeValue(doc, operation.Path)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, operation.Path, value)
+	case "move":
+		doc, moved, err := removeValue(doc, operation.From)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, operation.Path, moved)
+	case "copy":
+		copied, err := getValue(doc, operation.From)
+		if err != nil {
+			return nil, err
+		}
+		return addValue(doc, operation.Path, deepCopy(copied))
+	case "test":
+		actual, err := getValue(doc, operation.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(actual, value) {
+			return nil, fmt.Errorf("the value is %v", actual)
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unsupported operation %s", operation.Op)
+	}
+}
+
+// parsePointer splits the json pointer into its unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid json pointer %s", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func getValue(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		switch container := doc.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("%s doesn't exist", pointer)
+			}
+			doc = value
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(container) {
+				return nil, fmt.Errorf("%s doesn't exist", pointer)
+			}
+			doc = container[index]
+		default:
+			return nil, fmt.Errorf("%s doesn't exist", pointer)
+		}
+	}
+	return doc, nil
+}
+
+// addValue adds the value at the pointer and returns the new document. The parent must exist.
+func addValue(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := getValue(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	token := tokens[len(tokens)-1]
+
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+		return doc, nil
+	case []interface{}:
+		index := len(container)
+		if token != "-" {
+			index, err = strconv.Atoi(token)
+			if err != nil || index < 0 || index > len(container) {
+				return nil, fmt.Errorf("invalid index %s", token)
+			}
+		}
+		return setValue(doc, parentPointer, slices.Insert(slices.Clone(container), index, value))
+	default:
+		return nil, fmt.Errorf("%s is neither an object nor an array", parentPointer)
+	}
+}
+
+// setValue replaces the existing value at the pointer and returns the new document
+func setValue(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parent, err := getValue(doc, pointer[:strings.LastIndex(pointer, "/")])
+	if err != nil {
+		return nil, err
+	}
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[tokens[len(tokens)-1]] = value
+	case []interface{}:
+		index, _ := strconv.Atoi(tokens[len(tokens)-1])
+		container[index] = value
+	}
+	return doc, nil
+}
+
+// removeValue removes the value at the pointer and returns the new document and the removed value
+func removeValue(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+	removed, err := getValue(doc, pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, _ := getValue(doc, parentPointer)
+	token := tokens[len(tokens)-1]
+
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		delete(container, token)
+		return doc, removed, nil
+	case []interface{}:
+		index, _ := strconv.Atoi(token)
+		doc, err = setValue(doc, parentPointer, slices.Delete(slices.Clone(container), index, index+1))
+		return doc, removed, err
+	}
+	return nil, nil, fmt.Errorf("%s doesn't exist", pointer)
+}
+
+// applyMergePatch applies the json merge patch to the document (https://www.rfc-editor.org/rfc/rfc7386)
+func applyMergePatch(doc, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	docObject, ok := doc.(map[string]interface{})
+	if !ok {
+		docObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(docObject, key)
+			continue
+		}
+		docObject[key] = applyMergePatch(docObject[key], value)
+	}
+	return docObject
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for key, nested := range value {
+			copied[key] = deepCopy(nested)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, nested := range value {
+			copied[i] = deepCopy(nested)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// schemaKeywords returns the json names of all keywords of the Schema struct
+func schemaKeywords() []string {
+	keywords := []string{}
+	for _, key := range (Schema{}).getJsonKeys() {
+		if name, _, _ := strings.Cut(key, ","); name != "" && name != "-" {
+			keywords = append(keywords, name)
+		}
+	}
+	return keywords
+}
+
+// keepUnknownKeywords adds the keywords of the raw schema, which aren't
+// fields of the Schema struct, to the custom annotations
+func (s *Schema) keepUnknownKeywords(raw interface{}) {
+	rawSchema, ok := raw.(map[string]interface{})
+	if !ok || s == nil {
+		return
+	}
+	keywords := schemaKeywords()
+	for key, value := range rawSchema {
+		if slices.Contains(keywords, key) {
+			continue
+		}
+		if s.CustomAnnotations == nil {
+			s.CustomAnnotations = make(map[string]interface{})
+		}
+		s.CustomAnnotations[key] = normalizeNumbers(value)
+	}
+
+	for name, property := range s.Properties {
+		property.keepUnknownKeywords(nestedRaw(rawSchema, "properties", name))
+	}
+	for pattern, property := range s.PatternProperties {
+		property.keepUnknownKeywords(nestedRaw(rawSchema, "patternProperties", pattern))
+	}
+	if additionalSchema, ok := s.AdditionalProperties.(*Schema); ok {
+		additionalSchema.keepUnknownKeywords(rawSchema["additionalProperties"])
+	}
+	s.Items.keepUnknownKeywords(rawSchema["items"])
+	s.Not.keepUnknownKeywords(rawSchema["not"])
+	s.If.keepUnknownKeywords(rawSchema["if"])
+	s.Then.keepUnknownKeywords(rawSchema["then"])
+	s.Else.keepUnknownKeywords(rawSchema["else"])
+	for keyword, subSchemas := range map[string][]*Schema{"anyOf": s.AnyOf, "allOf": s.AllOf, "oneOf": s.OneOf} {
+		rawSubSchemas, _ := rawSchema[keyword].([]interface{})
+		for i, subSchema := range subSchemas {
+			if i < len(rawSubSchemas) {
+				subSchema.keepUnknownKeywords(rawSubSchemas[i])
+			}
+		}
+	}
+}
+
+func nestedRaw(raw map[string]interface{}, keyword, name string) interface{} {
+	nested, _ := raw[keyword].(map[string]interface{})
+	return nested[name]
+}
+
+// restorePropertyOrder copies the property order of the original schema, added properties are appended
+func (s *Schema) restorePropertyOrder(original *Schema) {
+	if s == nil || original == nil {
+		return
+	}
+	if len(original.PropertyOrder) > 0 {
+		order := slices.Clone(original.PropertyOrder)
+		for _, name := range s.PropertyOrder {
+			if !slices.Contains(order, name) {
+				order = append(order, name)
+			}
+		}
+		s.PropertyOrder = order
+	}
+	for name, property := range s.Properties {
+		property.restorePropertyOrder(original.Properties[name])
+	}
+	s.Items.restorePropertyOrder(original.Items)
+}
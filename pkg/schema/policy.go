@@ -0,0 +1,189 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The built-in rules of policies
+const (
+	// PolicyRuleClosedObjects requires the objects with properties to reject additional properties
+	PolicyRuleClosedObjects = "closed-objects"
+	// PolicyRuleDescriptions requires a description for every property
+	PolicyRuleDescriptions = "descriptions"
+	// PolicyRuleTypes requires a type (or a $ref or composition) for every property
+	PolicyRuleTypes = "types"
+)
+
+// PolicyRules contains all built-in rules of policies
+var PolicyRules = []string{PolicyRuleClosedObjects, PolicyRuleDescriptions, PolicyRuleTypes}
+
+// PolicyRuleRequire is the rule of the keywords required by Policy.Require
+const PolicyRuleRequire = "require"
+
+// Policy contains the rules every generated schema must follow (e.g. the ones of a platform team)
+type Policy struct {
+	// Rules are built-in rules (see PolicyRules)
+	Rules []string `yaml:"rules"`
+	// Require maps the dotted paths of properties (e.g. image.tag, * matches within a key) to the keywords
+	// they must set (e.g. pattern)
+	Require map[string][]string `yaml:"require"`
+	// Commands are shell commands evaluating the schema on stdin, e.g. with opa or conftest for rego policies.
+	// A failing command is a violation, the lines of its output are the messages.
+	Commands []string `yaml:"commands"`
+}
+
+// PolicyViolation is a violation of a rule of the policy
+type PolicyViolation struct {
+	// Rule is the violated rule, the keyword require or the command
+	Rule string
+	// Path is the dotted path of the property, it's empty for the root and commands
+	Path    string
+	Message string
+}
+
+func (v PolicyViolation) String() string {
+	if v.Path == "" {
+		return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", v.Rule, v.Path, v.Message)
+}
+
+// ReadPolicy reads the policy file
+func ReadPolicy(path string) (*Policy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	policy := &Policy{}
+	if err := decoder.Decode(policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not parse the policy %s: %w", path, err)
+	}
+	for _, rule := range policy.Rules {
+		if !slices.Contains(PolicyRules, rule) {
+			return nil, fmt.Errorf("unknown rule %s in the policy %s, use one of %s", rule, path, strings.Join(PolicyRules, ", "))
+		}
+	}
+	patterns := []string{}
+	for pattern := range policy.Require {
+		patterns = append(patterns, pattern)
+	}
+	if err := ValidateExcludePatterns(patterns); err != nil {
+		return nil, fmt.Errorf("invalid path in the policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Evaluate returns the violations of the policy by the schema of the result, the errors are the
+// ones of commands, which couldn't be run. The violations of properties suppressed by ignore comments
+// in the values file (see IgnoreCommentPrefix) are left out.
+func (p *Policy) Evaluate(r *Result) ([]PolicyViolation, error) {
+	suppressions, err := r.Suppressions()
+	if err != nil {
+		return nil, err
+	}
+	violations := []PolicyViolation{}
+	if err := p.evaluateSchema(&r.Schema, nil, &violations); err != nil {
+		return nil, err
+	}
+	violations = slices.DeleteFunc(violations, func(violation PolicyViolation) bool {
+		return violation.Path != "" && suppressions.Suppressed(violation.Path, violation.Rule)
+	})
+	if len(p.Commands) == 0 {
+		return violations, nil
+	}
+
+	input, err := r.Schema.ToJson()
+	if err != nil {
+		return nil, err
+	}
+	for _, command := range p.Commands {
+		cmd := r.shellCommand(command)
+		cmd.Stdin = bytes.NewReader(input)
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("could not run the policy command %s: %w", command, err)
+		}
+		if err == nil {
+			continue
+		}
+		messages := slices.DeleteFunc(strings.Split(output.String(), "\n"), func(line string) bool {
+			return strings.TrimSpace(line) == ""
+		})
+		if len(messages) == 0 {
+			messages = []string{err.Error()}
+		}
+		for _, message := range messages {
+			violations = append(violations, PolicyViolation{Rule: command, Message: strings.TrimSpace(message)})
+		}
+	}
+	return violations, nil
+}
+
+// evaluateSchema adds the violations of the built-in rules and the required keywords by the schema with
+// the path and its properties
+func (p *Policy) evaluateSchema(s *Schema, propertyPath []string, violations *[]PolicyViolation) error {
+	dotted := strings.Join(propertyPath, ".")
+	if slices.Contains(p.Rules, PolicyRuleClosedObjects) && len(s.Properties) > 0 && allowsAdditionalProperties(s) {
+		*violations = append(*violations, PolicyViolation{Rule: PolicyRuleClosedObjects, Path: dotted, Message: "the object allows additional properties"})
+	}
+	if len(propertyPath) > 0 {
+		if slices.Contains(p.Rules, PolicyRuleDescriptions) && s.Description == "" {
+			*violations = append(*violations, PolicyViolation{Rule: PolicyRuleDescriptions, Path: dotted, Message: "the property has no description"})
+		}
+		if slices.Contains(p.Rules, PolicyRuleTypes) && untyped(s) {
+			*violations = append(*violations, PolicyViolation{Rule: PolicyRuleTypes, Path: dotted, Message: "the property has no type"})
+		}
+		if err := p.evaluateRequired(s, propertyPath, violations); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range s.PropertyNames() {
+		if property := s.Properties[name]; property != nil {
+			if err := p.evaluateSchema(property, append(slices.Clone(propertyPath), name), violations); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// evaluateRequired adds the violations of the keywords, which the property with the path must set
+func (p *Policy) evaluateRequired(s *Schema, propertyPath []string, violations *[]PolicyViolation) error {
+	keywords := []string{}
+	for pattern, required := range p.Require {
+		if matchesExcludePattern(propertyPath, []string{pattern}) {
+			keywords = append(keywords, required...)
+		}
+	}
+	if len(keywords) == 0 {
+		return nil
+	}
+	doc, err := toDocument(s)
+	if err != nil {
+		return err
+	}
+	docMap, _ := doc.(map[string]interface{})
+	slices.Sort(keywords)
+	for _, keyword := range slices.Compact(keywords) {
+		if _, ok := docMap[keyword]; !ok {
+			*violations = append(*violations, PolicyViolation{Rule: PolicyRuleRequire, Path: strings.Join(propertyPath, "."), Message: fmt.Sprintf("the property doesn't set %s", keyword)})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// PostProcess pipes the schema of the result through the shell command. The command reads the
+// schema from stdin and writes the processed schema to stdout. The metadata of the chart is
+// passed in the environment (HELM_SCHEMA_CHART_NAME, HELM_SCHEMA_CHART_VERSION, ...).
+func (r *Result) PostProcess(command string) error {
+	input, err := r.Schema.ToJson()
+	if err != nil {
+		return err
+	}
+
+	cmd := r.shellCommand(command)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("post-process command failed: %w: %s", err, message)
+		}
+		return fmt.Errorf("post-process command failed: %w", err)
+	}
+
+	var doc interface{}
+	if err := decodeJSON(stdout.Bytes(), &doc); err != nil {
+		return fmt.Errorf("post-process command didn't print a jsonschema: %w", err)
+	}
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return errors.New("post-process command didn't print a jsonschema object")
+	}
+	processed, err := decodeSchemaDocument(doc, &r.Schema)
+	if err != nil {
+		return fmt.Errorf("post-process command printed an invalid jsonschema: %w", err)
+	}
+	r.Schema = processed
+	return nil
+}
+
+// shellCommand returns the shell command with the metadata of the chart in its environment
+func (r *Result) shellCommand(command string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(),
+		"HELM_SCHEMA_CHART_NAME="+r.Chart.Name,
+		"HELM_SCHEMA_CHART_VERSION="+r.Chart.Version,
+		"HELM_SCHEMA_CHART_APP_VERSION="+r.Chart.AppVersion,
+		"HELM_SCHEMA_CHART_PATH="+r.ChartPath,
+		"HELM_SCHEMA_VALUES_PATH="+r.ValuesPath,
+		"HELM_SCHEMA_OUTPUT_PATH="+r.OutputPath,
+	)
+	return cmd
+}
@@ -0,0 +1,208 @@
+package schema
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+)
+
+// The sources of the schemas of dependencies, which are merged into the schemas of their parents
+const (
+	// DependencySchemasGenerated generates the schemas of dependencies from their values files
+	DependencySchemasGenerated = "generated"
+	// DependencySchemasPublished prefers the schemas shipped with dependencies
+	DependencySchemasPublished = "published"
+)
+
+// DependencySchemasModes contains all supported sources of the schemas of dependencies
+var DependencySchemasModes = []string{DependencySchemasGenerated, DependencySchemasPublished}
+
+// legacyDefsKeyword contains the definitions of schemas of drafts older than 2019-09, which are referenced
+// with legacyDefsRefPrefix
+const (
+	legacyDefsKeyword   = "definitions"
+	legacyDefsRefPrefix = "#/definitions/"
+)
+
+// PublishedSchemaFile is the schema file Helm validates the values of a chart with
+const PublishedSchemaFile = "values.schema.json"
+
+// ReadPublishedSchema returns the schema shipped with the chart of the Chart.yaml and its content, if
+// it's a dependency (in the charts directory of another chart). It returns nil, if there's none.
+func ReadPublishedSchema(chartPath string) (*Schema, []byte, error) {
+	chartDir := filepath.Dir(chartPath)
+	parentDir := filepath.Dir(chartDir)
+	if filepath.Base(chartPath) != "Chart.yaml" || filepath.Base(parentDir) != "charts" {
+		return nil, nil, nil
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(parentDir), "Chart.yaml")); err != nil {
+		return nil, nil, nil
+	}
+	schemaPath := filepath.Join(chartDir, PublishedSchemaFile)
+	content, err := os.ReadFile(schemaPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	published, err := parsePublishedSchema(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse %s: %w", schemaPath, err)
+	}
+	return published, content, nil
+}
+
+// PackagedDependency is a dependency packaged as archive (e.g. charts/redis-1.0.0.tgz by helm dependency build)
+type PackagedDependency struct {
+	// Path is the path of the archive
+	Path string
+	// Chart is the Chart.yaml of the dependency
+	Chart *chart.ChartFile
+	// Schema is the schema shipped with the dependency and SchemaContent its content
+	Schema        *Schema
+	SchemaContent []byte
+	// Values is the content of the values.yaml of the dependency
+	Values []byte
+}
+
+// ReadPackagedDependency returns the archive of the dependency in the charts directory of the chart
+// in chartDir, which ships a schema. It returns nil, if there's none.
+func ReadPackagedDependency(chartDir string, dep *chart.Dependency) (*PackagedDependency, error) {
+	archives, err := filepath.Glob(filepath.Join(chartDir, "charts", "*.tgz"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(archives)
+	for _, archive := range archives {
+		packaged, err := ReadPackagedChart(archive)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", archive, err)
+		}
+		if packaged != nil && packaged.Chart.Name == dep.Name {
+			return packaged, nil
+		}
+	}
+	return nil, nil
+}
+
+// ReadPackagedChart reads the Chart.yaml and the schema of the chart in the archive, it returns nil if
+// the archive doesn't ship a schema
+func ReadPackagedChart(archive string) (*PackagedDependency, error) {
+	packaged, err := ReadChartArchive(archive)
+	if err != nil || packaged == nil || packaged.Schema == nil {
+		return nil, err
+	}
+	return packaged, nil
+}
+
+// ReadChartArchive reads the Chart.yaml, the values and the schema of the chart in the archive. It returns
+// nil, if the archive doesn't contain a chart, and the schema is nil, if the chart doesn't ship one.
+func ReadChartArchive(archive string) (*PackagedDependency, error) {
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	// the files of the chart are in a directory named after the chart, its dependencies in its charts directory
+	var chartContent, schemaContent, valuesContent []byte
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(path.Clean(header.Name), "./")
+		if header.Typeflag != tar.TypeReg || strings.Count(name, "/") != 1 {
+			continue
+		}
+		switch path.Base(name) {
+		case "Chart.yaml":
+			if chartContent, err = io.ReadAll(tarReader); err != nil {
+				return nil, err
+			}
+		case PublishedSchemaFile:
+			if schemaContent, err = io.ReadAll(tarReader); err != nil {
+				return nil, err
+			}
+		case "values.yaml":
+			if valuesContent, err = io.ReadAll(tarReader); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if chartContent == nil {
+		return nil, nil
+	}
+
+	chartFile, err := chart.ReadChart(bytes.NewReader(chartContent))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse Chart.yaml: %w", err)
+	}
+	packaged := &PackagedDependency{Path: archive, Chart: &chartFile, Values: valuesContent}
+	if schemaContent != nil {
+		if packaged.Schema, err = parsePublishedSchema(schemaContent); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", PublishedSchemaFile, err)
+		}
+		packaged.SchemaContent = schemaContent
+	}
+	return packaged, nil
+}
+
+// parsePublishedSchema parses a schema written by hand or another tool than helm-schema. The definitions of
+// older drafts are moved to $defs, so they are kept when the schema is merged into the schema of a parent.
+func parsePublishedSchema(content []byte) (*Schema, error) {
+	published, err := parseSchema(content)
+	if err != nil {
+		return nil, err
+	}
+	definitions, ok := published.CustomAnnotations[legacyDefsKeyword]
+	if !ok {
+		return published, nil
+	}
+	delete(published.CustomAnnotations, legacyDefsKeyword)
+	definitionsJSON, err := json.Marshal(definitions)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(definitionsJSON, &raw); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", legacyDefsKeyword, err)
+	}
+	for name, definitionJSON := range raw {
+		definition, err := parseSchema(definitionJSON)
+		if err != nil {
+			return nil, fmt.Errorf("invalid definition %s: %w", name, err)
+		}
+		if published.Defs == nil {
+			published.Defs = make(map[string]*Schema)
+		}
+		published.Defs[name] = definition
+	}
+	published.Walk(func(subSchema *Schema) {
+		if strings.HasPrefix(subSchema.Ref, legacyDefsRefPrefix) {
+			subSchema.Ref = defsRefPrefix + strings.TrimPrefix(subSchema.Ref, legacyDefsRefPrefix)
+		}
+	})
+	return published, nil
+}
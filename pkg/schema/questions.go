@@ -0,0 +1,224 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Question is an entry of a rancher questions.yaml
+type Question struct {
+	Variable    string        `yaml:"variable"`
+	Label       string        `yaml:"label"`
+	Description string        `yaml:"description,omitempty"`
+	Type        string        `yaml:"type"`
+	Default     interface{}   `yaml:"default,omitempty"`
+	Required    bool          `yaml:"required,omitempty"`
+	Group       string        `yaml:"group,omitempty"`
+	Options     []interface{} `yaml:"options,omitempty"`
+	Min         *int          `yaml:"min,omitempty"`
+	Max         *int          `yaml:"max,omitempty"`
+	MinLength   *int          `yaml:"min_length,omitempty"`
+	MaxLength   *int          `yaml:"max_length,omitempty"`
+	ShowIf      string        `yaml:"show_if,omitempty"`
+	// Subquestions are only read from existing questions, they are never generated
+	Subquestions []Question `yaml:"subquestions,omitempty"`
+}
+
+// ToQuestions converts the schema into a rancher questions.yaml. Every scalar value becomes
+// a question, grouped by its top-level key. Values next to a boolean "enabled" property (or
+// declared in the then branch of an if with constant properties) are only shown if the condition is met.
+func (s *Schema) ToQuestions() ([]byte, error) {
+	questions := []Question{}
+	s.collectQuestions("", "", "", &questions)
+
+	var buf bytes.Buffer
+	buf.WriteString("# Code generated by helm-schema. DO NOT EDIT.\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]interface{}{"questions": questions}); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Schema) collectQuestions(prefix, group, showIf string, questions *[]Question) {
+	// conditions of the properties in this object
+	conditions := make(map[string]string)
+	if enabled, ok := s.Properties["enabled"]; ok && enabled != nil && slices.Equal(enabled.Type, []string{"boolean"}) && prefix != "" {
+		for name := range s.Properties {
+			if name != "enabled" {
+				conditions[name] = prefix + ".enabled=true"
+			}
+		}
+	}
+	if condition := s.ifCondition(prefix); condition != "" && s.Then != nil {
+		for name := range s.Then.Properties {
+			conditions[name] = condition
+		}
+	}
+
+	for _, name := range s.PropertyNames() {
+		property := s.Properties[name]
+		if property == nil {
+			continue
+		}
+
+		variable := name
+		if prefix != "" {
+			variable = prefix + "." + name
+		}
+		propertyGroup := group
+		if uiGroup, ok := property.CustomAnnotations[UIAnnotationPrefix+UIGroup].(string); ok && uiGroup != "" {
+			propertyGroup = uiGroup
+		} else if propertyGroup == "" {
+			propertyGroup = questionLabel(name, property.Title)
+		}
+		propertyShowIf := showIf
+		if condition, ok := conditions[name]; ok {
+			propertyShowIf = joinConditions(showIf, condition)
+		}
+
+		if property.isObject() {
+			property.collectQuestions(variable, propertyGroup, propertyShowIf, questions)
+			continue
+		}
+
+		questionType := property.questionType()
+		if questionType == "" {
+			continue
+		}
+		question := Question{
+			Variable:    variable,
+			Label:       questionLabel(name, property.Title),
+			Description: property.Description,
+			Type:        questionType,
+			Default:     property.Default,
+			Required:    property.Required.Bool || slices.Contains(s.Required.Strings, name),
+			Group:       propertyGroup,
+			ShowIf:      propertyShowIf,
+		}
+		switch questionType {
+		case "int":
+			question.Min = property.Minimum
+			question.Max = property.Maximum
+		case "string", "password":
+			question.MinLength = property.MinLength
+			question.MaxLength = property.MaxLength
+		case "enum":
+			for _, value := range property.Enum {
+				question.Options = append(question.Options, value)
+			}
+		}
+		*questions = append(*questions, question)
+	}
+}
+
+// questionType returns the rancher type of the schema or an empty string if there is none
+func (s *Schema) questionType() string {
+	if len(s.Enum) > 0 {
+		return "enum"
+	}
+	types := []string{}
+	for _, schemaType := range s.Type {
+		if schemaType != "null" {
+			types = append(types, schemaType)
+		}
+	}
+	if len(types) != 1 {
+		return ""
+	}
+	switch types[0] {
+	case "string":
+		if s.Format == "password" || s.WriteOnly {
+			return "password"
+		}
+		return "string"
+	case "integer":
+		return "int"
+	case "number":
+		return "string"
+	case "boolean":
+		return "boolean"
+	default:
+		return ""
+	}
+}
+
+// ifCondition converts an if with constant properties into a show_if condition
+func (s *Schema) ifCondition(prefix string) string {
+	if s.If == nil || len(s.If.Properties) == 0 {
+		return ""
+	}
+	conditions := []string{}
+	for _, name := range sortedKeys(s.If.Properties) {
+		property := s.If.Properties[name]
+		if property == nil || property.Const == nil {
+			return ""
+		}
+		value, err := json.Marshal(property.Const)
+		if err != nil {
+			return ""
+		}
+		variable := name
+		if prefix != "" {
+			variable = prefix + "." + name
+		}
+		conditions = append(conditions, fmt.Sprintf("%s=%s", variable, strings.Trim(string(value), `"`)))
+	}
+	return strings.Join(conditions, "&&")
+}
+
+func joinConditions(conditions ...string) string {
+	nonEmpty := []string{}
+	for _, condition := range conditions {
+		if condition != "" {
+			nonEmpty = append(nonEmpty, condition)
+		}
+	}
+	return strings.Join(nonEmpty, "&&")
+}
+
+// questionLabel returns the title of the property. If there's no title (or it's
+// just the property name), the name is converted into words (e.g. pullPolicy -> Pull Policy).
+func questionLabel(name, title string) string {
+	if title != "" && title != name {
+		return title
+	}
+
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.' || r == ' ':
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = nil
+			continue
+		case unicode.IsUpper(r) && len(word) > 0 && (unicode.IsLower(word[len(word)-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			words = append(words, string(word))
+			word = nil
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+
+	for i, w := range words {
+		wordRunes := []rune(w)
+		wordRunes[0] = unicode.ToUpper(wordRunes[0])
+		words[i] = string(wordRunes)
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,62 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// addRawKeywords adds the keywords of the json object of a @schema-raw annotation, after validating them
+// against the metaschema (the one of the generated schemas, unless the object contains $schema)
+func (s *Schema) addRawKeywords(content string) error {
+	var raw map[string]interface{}
+	if err := decodeJSON([]byte(strings.TrimSpace(content)), &raw); err != nil {
+		return fmt.Errorf("the schema-raw annotation %s isn't a json object: %w", strings.TrimSpace(content), err)
+	}
+
+	rawSchema, err := decodeSchemaDocument(raw, nil)
+	if err != nil {
+		return fmt.Errorf("invalid schema-raw annotation %s: %w", strings.TrimSpace(content), err)
+	}
+	if rawSchema.Schema == "" {
+		rawSchema.Schema = DraftVersion
+	}
+	if err := rawSchema.ValidateMetaschema(); err != nil {
+		return fmt.Errorf("invalid schema-raw annotation %s: %w", strings.TrimSpace(content), err)
+	}
+
+	if s.raw == nil {
+		s.raw = make(map[string]interface{})
+	}
+	for keyword, value := range raw {
+		s.raw[keyword] = value
+	}
+	return nil
+}
+
+// applyRawKeywords merges the keywords of the @schema-raw annotations into the schema, they replace the
+// generated keywords and the unknown ones are kept as they are
+func (s *Schema) applyRawKeywords() error {
+	if len(s.raw) == 0 {
+		return nil
+	}
+	rawSchema, err := decodeSchemaDocument(s.raw, nil)
+	if err != nil {
+		return err
+	}
+	target := reflect.ValueOf(s).Elem()
+	source := reflect.ValueOf(&rawSchema).Elem()
+	for i := 0; i < target.NumField(); i++ {
+		keyword, _, _ := strings.Cut(target.Type().Field(i).Tag.Get("json"), ",")
+		if _, ok := s.raw[keyword]; ok && keyword != "" && keyword != "-" {
+			target.Field(i).Set(source.Field(i))
+		}
+	}
+	for keyword, value := range rawSchema.CustomAnnotations {
+		if s.CustomAnnotations == nil {
+			s.CustomAnnotations = make(map[string]interface{})
+		}
+		s.CustomAnnotations[keyword] = value
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+	"gopkg.in/yaml.v3"
+)
+
+// The policies inferring which values are required
+const (
+	// RequiredModeAll requires every value, unless it's annotated otherwise
+	RequiredModeAll = "all"
+	// RequiredModeNone doesn't require any value, not even the annotated ones
+	RequiredModeNone = "none"
+	// RequiredModeAnnotated only requires the values annotated with required: true
+	RequiredModeAnnotated = "annotated"
+	// RequiredModeNonNullDefaults requires the values, whose defaults aren't null, and the annotated ones
+	RequiredModeNonNullDefaults = "non-null-defaults"
+)
+
+// RequiredModes contains all supported required modes
+var RequiredModes = []string{RequiredModeAll, RequiredModeNone, RequiredModeAnnotated, RequiredModeNonNullDefaults}
+
+// ChartAnnotationRequiredMode can be used in the annotations of Chart.yaml to override the required mode of the chart
+const ChartAnnotationRequiredMode = "helm-schema/required-mode"
+
+// ValidateRequiredMode returns an error, if the required mode isn't supported
+func ValidateRequiredMode(mode string) error {
+	if !slices.Contains(RequiredModes, mode) {
+		return fmt.Errorf("unsupported required mode %s, use one of %s", mode, strings.Join(RequiredModes, ", "))
+	}
+	return nil
+}
+
+// ForChart returns the config of the chart, the annotation helm-schema/required-mode of its Chart.yaml
+// overrides the required mode
+func (c *SkipAutoGenerationConfig) ForChart(chartFile *chart.ChartFile) (*SkipAutoGenerationConfig, error) {
+	mode, ok := chartFile.Annotations[ChartAnnotationRequiredMode]
+	if !ok {
+		return c, nil
+	}
+	if err := ValidateRequiredMode(mode); err != nil {
+		return nil, fmt.Errorf("invalid annotation %s: %w", ChartAnnotationRequiredMode, err)
+	}
+	config := *c
+	config.Required = false
+	config.RequiredMode = mode
+	return &config, nil
+}
+
+// requiredMode returns the required mode, skipping the auto-generation of required is the annotated mode
+func (c *SkipAutoGenerationConfig) requiredMode() string {
+	if c.Required && (c.RequiredMode == "" || c.RequiredMode == RequiredModeAll) {
+		return RequiredModeAnnotated
+	}
+	if c.RequiredMode == "" {
+		return RequiredModeAll
+	}
+	return c.RequiredMode
+}
+
+// infersRequired returns true, if the value of a key without annotation is required
+func (c *SkipAutoGenerationConfig) infersRequired(valueNode *yaml.Node) bool {
+	switch c.requiredMode() {
+	case RequiredModeAll:
+		return true
+	case RequiredModeNonNullDefaults:
+		return valueNode.Tag != nullTag
+	}
+	return false
+}
+
+// ChartAnnotationKeepRequired can be used in the annotations of Chart.yaml to keep the required properties of
+// the comma-separated dependencies (names or aliases) in the schema of the chart
+const ChartAnnotationKeepRequired = "helm-schema/keep-required"
+
+// KeepsRequired returns true, if the required properties of the dependency are kept in the schema of its parent
+// chart, because its name or alias is one of the names or listed in the annotation helm-schema/keep-required
+func KeepsRequired(parent *chart.ChartFile, dep *chart.Dependency, names []string) bool {
+	if annotation, ok := parent.Annotations[ChartAnnotationKeepRequired]; ok {
+		for _, name := range strings.Split(annotation, ",") {
+			names = append(names, strings.TrimSpace(name))
+		}
+	}
+	return slices.Contains(names, dep.Name) || (dep.Alias != "" && slices.Contains(names, dep.Alias))
+}
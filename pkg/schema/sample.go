@@ -0,0 +1,175 @@
+package schema
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sampleLine is a line of the sample values with its indentation level
+type sampleLine struct {
+	depth     int
+	text      string
+	commented bool
+}
+
+// ToSample returns a values file with the properties of the schema in their order. Properties with a
+// default get the default, required properties without a default get a placeholder fitting their type
+// (e.g. the first enum value) and the other properties are commented out with a placeholder. The
+// descriptions are written as comments above the keys.
+func (s *Schema) ToSample() ([]byte, error) {
+	lines, err := s.sampleProperties(0)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		indent := strings.Repeat("  ", line.depth)
+		if line.commented {
+			buf.WriteString(strings.TrimRight(indent+"# "+line.text, " ") + "\n")
+		} else {
+			buf.WriteString(indent + line.text + "\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Schema) sampleProperties(depth int) ([]sampleLine, error) {
+	lines := []sampleLine{}
+	for _, name := range s.PropertyNames() {
+		property := s.Properties[name]
+		if property == nil {
+			continue
+		}
+		required := slices.Contains(s.Required.Strings, name)
+		key, err := sampleKey(name)
+		if err != nil {
+			return nil, err
+		}
+
+		var propertyLines []sampleLine
+		if property.isObject() && len(property.Properties) > 0 && property.Default == nil {
+			children, err := property.sampleProperties(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			// an optional object is commented out, if all of its children are
+			commented := !required && !slices.ContainsFunc(children, func(line sampleLine) bool { return !line.commented })
+			propertyLines = append(propertyLines, sampleLine{depth: depth, text: key + ":"})
+			propertyLines = append(propertyLines, children...)
+			if commented {
+				for i := range propertyLines {
+					propertyLines[i].commented = true
+				}
+			}
+		} else {
+			value, hasValue := property.sampleDefault()
+			if !hasValue {
+				value = property.samplePlaceholder()
+			}
+			if propertyLines, err = sampleValue(key, value, depth, !hasValue && !required); err != nil {
+				return nil, err
+			}
+		}
+
+		commented := propertyLines[0].commented
+		if property.Description != "" {
+			for _, line := range strings.Split(strings.TrimSpace(property.Description), "\n") {
+				// descriptions of commented out keys are kept apart from the keys by an extra #
+				if commented {
+					line = "# " + line
+				}
+				lines = append(lines, sampleLine{depth: depth, text: line, commented: true})
+			}
+		}
+		lines = append(lines, propertyLines...)
+	}
+	return lines, nil
+}
+
+// sampleKey returns the key quoted like in a values file, if needed
+func sampleKey(name string) (string, error) {
+	key, err := yaml.Marshal(name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(key), "\n"), nil
+}
+
+// sampleValue returns the lines of the key with the value
+func sampleValue(key string, value interface{}, depth int, commented bool) ([]sampleLine, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	valueLines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	isCollection := len(valueLines) > 1 || (value != nil && !isScalar(value) && valueLines[0] != "[]" && valueLines[0] != "{}")
+	if !isCollection {
+		return []sampleLine{{depth: depth, text: key + ": " + valueLines[0], commented: commented}}, nil
+	}
+	lines := []sampleLine{{depth: depth, text: key + ":", commented: commented}}
+	for _, line := range valueLines {
+		lines = append(lines, sampleLine{depth: depth + 1, text: line, commented: commented})
+	}
+	return lines, nil
+}
+
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+// sampleDefault returns the default of the property. The default of null values is the raw
+// value of the values file (e.g. ~), it's returned as null.
+func (s *Schema) sampleDefault() (interface{}, bool) {
+	if s.Default == nil {
+		return nil, false
+	}
+	if raw, ok := s.Default.(string); ok && slices.Contains(s.Type, "null") {
+		if raw == "~" || raw == "null" || (raw == "" && !slices.Contains(s.Type, "string")) {
+			return nil, true
+		}
+	}
+	return s.Default, true
+}
+
+// samplePlaceholder returns a value, which fits the type of the property
+func (s *Schema) samplePlaceholder() interface{} {
+	if s.Const != nil {
+		return s.Const
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+	for _, schemaType := range s.Type {
+		switch schemaType {
+		case "string":
+			if s.MinLength != nil {
+				return strings.Repeat("x", *s.MinLength)
+			}
+			return ""
+		case "integer", "number":
+			switch {
+			case s.Minimum != nil:
+				return *s.Minimum
+			case s.ExclusiveMinimum != nil:
+				return *s.ExclusiveMinimum + 1
+			}
+			return 0
+		case "boolean":
+			return false
+		case "array":
+			return []interface{}{}
+		case "object":
+			return map[string]interface{}{}
+		}
+	}
+	return nil
+}
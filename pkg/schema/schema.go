@@ -1,7 +1,7 @@
 package schema
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,10 +12,11 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dadav/go-jsonpointer"
-	"github.com/ojsef39/helm-schema/pkg/util"
 	"github.com/norwoodj/helm-docs/pkg/helm"
+	"github.com/ojsef39/helm-schema/pkg/util"
 	"github.com/santhosh-tekuri/jsonschema/v6"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -24,12 +25,26 @@ import (
 const (
 	SchemaPrefix  = "# @schema"
 	CommentPrefix = "#"
+	// SchemaRootPrefix delimits the block of the keywords of the root schema in the head comment of the values file
+	SchemaRootPrefix = "# @schema-root"
+	// SchemaRawPrefix starts a line with a json object, which is merged verbatim into the generated schema of the key
+	SchemaRawPrefix = "# @schema-raw:"
+
+	// DraftVersion is the metaschema of the generated schemas
+	DraftVersion = "http://json-schema.org/draft-07/schema#"
 
 	// CustomAnnotationPrefix marks custom annotations.
 	// custom annotations is a map of custom annotations. See introduction of custom annotation: https://json-schema.org/blog/posts/custom-annotations-will-continue
 	CustomAnnotationPrefix = "x-"
 )
 
+const (
+	// PropertyOrderSource keeps the order of the keys in the values file
+	PropertyOrderSource = "source"
+	// PropertyOrderAlpha sorts the properties alphabetically
+	PropertyOrderAlpha = "alpha"
+)
+
 const (
 	nullTag      = "!!null"
 	boolTag      = "!!bool"
@@ -37,6 +52,7 @@ const (
 	intTag       = "!!int"
 	floatTag     = "!!float"
 	timestampTag = "!!timestamp"
+	binaryTag    = "!!binary"
 	arrayTag     = "!!seq"
 	mapTag       = "!!map"
 )
@@ -196,61 +212,87 @@ func (s *Schema) MarshalJSON() ([]byte, error) {
 	}
 
 	// Unmarshal the JSON back into the map
-	if err := json.Unmarshal(aliasJSON, &data); err != nil {
+	if err := decodeJSON(aliasJSON, &data); err != nil {
 		return nil, err
 	}
 
 	// inline the CustomAnnotations fields
 	for key, value := range s.CustomAnnotations {
+		if _, ok := value.(omittedKeyword); ok {
+			delete(data, key)
+			continue
+		}
 		data[key] = value
 	}
 
 	delete(data, "CustomAnnotations")
 
+	// keep the order of the properties, if known
+	if len(s.Properties) > 0 && s.PropertyOrder != nil {
+		properties, err := s.marshalOrderedProperties()
+		if err != nil {
+			return nil, err
+		}
+		data["properties"] = properties
+	}
+
 	// Marshal the final map into JSON
 	return json.Marshal(data)
 }
 
 // Schema struct contains yaml tags for reading, json for writing (creating the jsonschema)
 type Schema struct {
-	AdditionalProperties SchemaOrBool           `yaml:"additionalProperties,omitempty" json:"additionalProperties,omitempty"`
-	Default              interface{}            `yaml:"default,omitempty"              json:"default,omitempty"`
-	Then                 *Schema                `yaml:"then,omitempty"                 json:"then,omitempty"`
-	PatternProperties    map[string]*Schema     `yaml:"patternProperties,omitempty"    json:"patternProperties,omitempty"`
-	Properties           map[string]*Schema     `yaml:"properties,omitempty"           json:"properties,omitempty"`
-	If                   *Schema                `yaml:"if,omitempty"                   json:"if,omitempty"`
-	Minimum              *int                   `yaml:"minimum,omitempty"              json:"minimum,omitempty"`
-	MultipleOf           *int                   `yaml:"multipleOf,omitempty"           json:"multipleOf,omitempty"`
-	ExclusiveMaximum     *int                   `yaml:"exclusiveMaximum,omitempty"     json:"exclusiveMaximum,omitempty"`
-	Items                *Schema                `yaml:"items,omitempty"                json:"items,omitempty"`
-	ExclusiveMinimum     *int                   `yaml:"exclusiveMinimum,omitempty"     json:"exclusiveMinimum,omitempty"`
-	Maximum              *int                   `yaml:"maximum,omitempty"              json:"maximum,omitempty"`
-	Else                 *Schema                `yaml:"else,omitempty"                 json:"else,omitempty"`
-	Pattern              string                 `yaml:"pattern,omitempty"              json:"pattern,omitempty"`
-	Const                interface{}            `yaml:"const,omitempty"                json:"const,omitempty"`
-	Ref                  string                 `yaml:"$ref,omitempty"                 json:"$ref,omitempty"`
-	Schema               string                 `yaml:"$schema,omitempty"              json:"$schema,omitempty"`
-	Id                   string                 `yaml:"$id,omitempty"                  json:"$id,omitempty"`
-	Format               string                 `yaml:"format,omitempty"               json:"format,omitempty"`
-	Description          string                 `yaml:"description,omitempty"          json:"description,omitempty"`
-	Title                string                 `yaml:"title,omitempty"                json:"title,omitempty"`
-	Type                 StringOrArrayOfString  `yaml:"type,omitempty"                 json:"type,omitempty"`
-	AnyOf                []*Schema              `yaml:"anyOf,omitempty"                json:"anyOf,omitempty"`
-	AllOf                []*Schema              `yaml:"allOf,omitempty"                json:"allOf,omitempty"`
-	OneOf                []*Schema              `yaml:"oneOf,omitempty"                json:"oneOf,omitempty"`
-	Not                  *Schema                `yaml:"not,omitempty"                json:"not,omitempty"`
-	Examples             []string               `yaml:"examples,omitempty"             json:"examples,omitempty"`
-	Enum                 []string               `yaml:"enum,omitempty"                 json:"enum,omitempty"`
-	HasData              bool                   `yaml:"-"                              json:"-"`
-	Deprecated           bool                   `yaml:"deprecated,omitempty"           json:"deprecated,omitempty"`
-	ReadOnly             bool                   `yaml:"readOnly,omitempty"           json:"readOnly,omitempty"`
-	WriteOnly            bool                   `yaml:"writeOnly,omitempty"           json:"writeOnly,omitempty"`
-	Required             BoolOrArrayOfString    `yaml:"required,omitempty"             json:"required,omitempty"`
-	CustomAnnotations    map[string]interface{} `yaml:"-"                              json:",omitempty"`
-	MinLength            *int                   `yaml:"minLength,omitempty"              json:"minLength,omitempty"`
-	MaxLength            *int                   `yaml:"maxLength,omitempty"              json:"maxLength,omitempty"`
-	MinItems             *int                   `yaml:"minItems,omitempty"              json:"minItems,omitempty"`
-	MaxItems             *int                   `yaml:"maxItems,omitempty"              json:"maxItems,omitempty"`
+	AdditionalProperties SchemaOrBool          `yaml:"additionalProperties,omitempty" json:"additionalProperties,omitempty"`
+	Default              interface{}           `yaml:"default,omitempty"              json:"default,omitempty"`
+	Then                 *Schema               `yaml:"then,omitempty"                 json:"then,omitempty"`
+	PatternProperties    map[string]*Schema    `yaml:"patternProperties,omitempty"    json:"patternProperties,omitempty"`
+	Properties           map[string]*Schema    `yaml:"properties,omitempty"           json:"properties,omitempty"`
+	If                   *Schema               `yaml:"if,omitempty"                   json:"if,omitempty"`
+	Minimum              *int                  `yaml:"minimum,omitempty"              json:"minimum,omitempty"`
+	MultipleOf           *int                  `yaml:"multipleOf,omitempty"           json:"multipleOf,omitempty"`
+	ExclusiveMaximum     *int                  `yaml:"exclusiveMaximum,omitempty"     json:"exclusiveMaximum,omitempty"`
+	Items                *Schema               `yaml:"items,omitempty"                json:"items,omitempty"`
+	ExclusiveMinimum     *int                  `yaml:"exclusiveMinimum,omitempty"     json:"exclusiveMinimum,omitempty"`
+	Maximum              *int                  `yaml:"maximum,omitempty"              json:"maximum,omitempty"`
+	Else                 *Schema               `yaml:"else,omitempty"                 json:"else,omitempty"`
+	Pattern              string                `yaml:"pattern,omitempty"              json:"pattern,omitempty"`
+	Const                interface{}           `yaml:"const,omitempty"                json:"const,omitempty"`
+	Ref                  string                `yaml:"$ref,omitempty"                 json:"$ref,omitempty"`
+	Schema               string                `yaml:"$schema,omitempty"              json:"$schema,omitempty"`
+	Id                   string                `yaml:"$id,omitempty"                  json:"$id,omitempty"`
+	Format               string                `yaml:"format,omitempty"               json:"format,omitempty"`
+	ContentEncoding      string                `yaml:"contentEncoding,omitempty"      json:"contentEncoding,omitempty"`
+	ContentMediaType     string                `yaml:"contentMediaType,omitempty"     json:"contentMediaType,omitempty"`
+	Description          string                `yaml:"description,omitempty"          json:"description,omitempty"`
+	Title                string                `yaml:"title,omitempty"                json:"title,omitempty"`
+	Type                 StringOrArrayOfString `yaml:"type,omitempty"                 json:"type,omitempty"`
+	AnyOf                []*Schema             `yaml:"anyOf,omitempty"                json:"anyOf,omitempty"`
+	AllOf                []*Schema             `yaml:"allOf,omitempty"                json:"allOf,omitempty"`
+	OneOf                []*Schema             `yaml:"oneOf,omitempty"                json:"oneOf,omitempty"`
+	Not                  *Schema               `yaml:"not,omitempty"                json:"not,omitempty"`
+	Examples             []string              `yaml:"examples,omitempty"             json:"examples,omitempty"`
+	Enum                 []string              `yaml:"enum,omitempty"                 json:"enum,omitempty"`
+	HasData              bool                  `yaml:"-"                              json:"-"`
+	Deprecated           bool                  `yaml:"deprecated,omitempty"           json:"deprecated,omitempty"`
+	ReadOnly             bool                  `yaml:"readOnly,omitempty"           json:"readOnly,omitempty"`
+	WriteOnly            bool                  `yaml:"writeOnly,omitempty"           json:"writeOnly,omitempty"`
+	// Sensitive marks values, whose defaults must not be written to the schema, see MaskSensitive
+	Sensitive *bool `yaml:"sensitive,omitempty" json:"-"`
+	// Skip excludes the value from the generated schema
+	Skip              bool                   `yaml:"skip,omitempty" json:"-"`
+	Required          BoolOrArrayOfString    `yaml:"required,omitempty"             json:"required,omitempty"`
+	CustomAnnotations map[string]interface{} `yaml:"-"                              json:",omitempty"`
+	MinLength         *int                   `yaml:"minLength,omitempty"              json:"minLength,omitempty"`
+	MaxLength         *int                   `yaml:"maxLength,omitempty"              json:"maxLength,omitempty"`
+	MinItems          *int                   `yaml:"minItems,omitempty"              json:"minItems,omitempty"`
+	MaxItems          *int                   `yaml:"maxItems,omitempty"              json:"maxItems,omitempty"`
+	Defs              map[string]*Schema     `yaml:"$defs,omitempty"                 json:"$defs,omitempty"`
+	// PropertyOrder contains the property names in the order they were found
+	PropertyOrder []string `yaml:"-" json:"-"`
+	// raw contains the keywords of the @schema-raw annotations, which are merged verbatim into the generated schema
+	raw map[string]interface{}
+	// Source is the key of the values file the property was generated from, nil if unknown
+	Source *Origin `yaml:"-" json:"-"`
 }
 
 func NewSchema(schemaType string) *Schema {
@@ -303,6 +345,37 @@ func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
 			continue
 		}
 
+		// the hints for form generators are written as x-ui-* annotations
+		if key == UIAnnotation {
+			annotations, err := uiAnnotations(valueNode)
+			if err != nil {
+				return err
+			}
+			for annotation, value := range annotations {
+				alias.CustomAnnotations[annotation] = value
+			}
+			continue
+		}
+
+		// the CEL rules of the validations annotation are written to x-kubernetes-validations
+		if key == ValidationsAnnotation {
+			rules, err := validationRules(valueNode)
+			if err != nil {
+				return err
+			}
+			existing, _ := alias.CustomAnnotations[KubernetesValidationsAnnotation].([]interface{})
+			alias.CustomAnnotations[KubernetesValidationsAnnotation] = append(existing, rules...)
+			continue
+		}
+
+		// the translations of the title and description are written to the x-i18n annotation
+		if ok, err := addTranslation(alias.CustomAnnotations, key, valueNode); ok || err != nil {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
 		// Unmarshal unknown fields into the CustomAnnotations map
 		if !strings.HasPrefix(key, CustomAnnotationPrefix) {
 			continue
@@ -314,11 +387,185 @@ func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
 		alias.CustomAnnotations[key] = value
 	}
 
+	// timestamps would be decoded as time.Time, which is marshaled in another notation
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if valueNode := node.Content[i+1]; valueNode.Tag == timestampTag {
+			switch node.Content[i].Value {
+			case "default":
+				alias.Default = valueNode.Value
+			case "const":
+				alias.Const = valueNode.Value
+			}
+		}
+	}
+
+	// Remember the order of the annotated properties
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == "properties" && node.Content[i+1].Kind == yaml.MappingNode {
+			alias.PropertyOrder = nil
+			for j := 0; j < len(node.Content[i+1].Content)-1; j += 2 {
+				alias.PropertyOrder = append(alias.PropertyOrder, node.Content[i+1].Content[j].Value)
+			}
+		}
+	}
+
+	// the keywords of the registered vocabularies transform the decoded schema
+	if err := (*Schema)(alias).applyVocabularies(node); err != nil {
+		return err
+	}
+
 	// Copy alias to the main struct
 	*s = Schema(*alias)
 	return nil
 }
 
+// UnmarshalJSON custom unmarshal method. It keeps the custom annotations
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	// Create an alias type to avoid recursion
+	type schemaAlias Schema
+	alias := new(schemaAlias)
+	// copy all existing fields
+	*alias = schemaAlias(*s)
+
+	if err := json.Unmarshal(data, alias); err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	for key, value := range raw {
+		if !strings.HasPrefix(key, CustomAnnotationPrefix) {
+			continue
+		}
+		if alias.CustomAnnotations == nil {
+			alias.CustomAnnotations = make(map[string]interface{})
+		}
+		var annotation interface{}
+		if err := decodeJSON(value, &annotation); err != nil {
+			return err
+		}
+		alias.CustomAnnotations[key] = normalizeNumbers(annotation)
+	}
+
+	// json.Unmarshal decodes all numbers as float64, which rounds large integers
+	for key, target := range map[string]*interface{}{"default": &alias.Default, "const": &alias.Const} {
+		if value, ok := raw[key]; ok {
+			var decoded interface{}
+			if err := decodeJSON(value, &decoded); err != nil {
+				return err
+			}
+			*target = normalizeNumbers(decoded)
+		}
+	}
+
+	// additionalProperties is either a bool or a schema
+	if additionalProperties, ok := raw["additionalProperties"]; ok && bytes.HasPrefix(bytes.TrimSpace(additionalProperties), []byte("{")) {
+		additionalSchema := &Schema{}
+		if err := json.Unmarshal(additionalProperties, additionalSchema); err != nil {
+			return err
+		}
+		alias.AdditionalProperties = additionalSchema
+	}
+
+	// Remember the order of the properties
+	if properties, ok := raw["properties"]; ok {
+		order, err := jsonObjectKeys(properties)
+		if err != nil {
+			return err
+		}
+		alias.PropertyOrder = order
+	}
+
+	*s = Schema(*alias)
+	return nil
+}
+
+// SetProperty adds or replaces the property with the given name.
+// New properties are appended to the property order.
+func (s *Schema) SetProperty(name string, property *Schema) {
+	if s.Properties == nil {
+		s.Properties = make(map[string]*Schema)
+	}
+	if _, ok := s.Properties[name]; !ok {
+		s.PropertyOrder = append(s.PropertyOrder, name)
+	}
+	s.Properties[name] = property
+}
+
+// PropertyNames returns the names of all properties. Names with a known order come first,
+// the remaining names are sorted alphabetically.
+func (s *Schema) PropertyNames() []string {
+	names := make([]string, 0, len(s.Properties))
+	// searching the names would be quadratic for objects with thousands of properties
+	added := make(map[string]bool, len(s.Properties))
+	for _, name := range s.PropertyOrder {
+		if _, ok := s.Properties[name]; ok && !added[name] {
+			added[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == len(s.Properties) {
+		return names
+	}
+	for _, name := range sortedKeys(s.Properties) {
+		if !added[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// marshalOrderedProperties marshals the properties as json object in the order of PropertyNames
+func (s *Schema) marshalOrderedProperties() (json.RawMessage, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range s.PropertyNames() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(s.Properties[name])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonObjectKeys returns the keys of the given json object in their original order
+func jsonObjectKeys(data json.RawMessage) ([]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, err
+	}
+
+	keys := []string{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, token.(string))
+
+		// skip the value
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
 // Set sets the HasData field to true
 func (s *Schema) Set() {
 	s.HasData = true
@@ -365,11 +612,15 @@ func (s *Schema) DisableRequiredProperties() {
 
 // ToJson converts the data to raw json
 func (s Schema) ToJson() ([]byte, error) {
-	res, err := json.MarshalIndent(&s, "", "  ")
-	if err != nil {
-		return nil, err
+	return s.ToJsonIndent(DefaultIndent)
+}
+
+// ToJsonIndent converts the data to raw json indented with the indent, an empty indent writes compact json
+func (s Schema) ToJsonIndent(indent string) ([]byte, error) {
+	if indent == "" {
+		return json.Marshal(&s)
 	}
-	return res, nil
+	return json.MarshalIndent(&s, "", indent)
 }
 
 // Validate the schema
@@ -401,6 +652,10 @@ func (s Schema) Validate() error {
 		return fmt.Errorf("cant use format if type is %s. Use type=string", s.Type)
 	}
 
+	if (s.ContentEncoding != "" || s.ContentMediaType != "") && !s.Type.IsEmpty() && !s.Type.Matches("string") {
+		return fmt.Errorf("cant use contentEncoding or contentMediaType if type is %s. Use type=string", s.Type)
+	}
+
 	// Check if type=string if maxLength or minLength is used
 	if s.MaxLength != nil && s.MinLength != nil && *s.MinLength > *s.MaxLength {
 		return errors.New("cant use MinLength > MaxLength")
@@ -496,6 +751,8 @@ var possibleSkipFields = []string{"title", "description", "required", "default",
 
 type SkipAutoGenerationConfig struct {
 	Title, Description, Required, Default, AdditionalProperties bool
+	// RequiredMode is the policy inferring the required values, see RequiredModeAll
+	RequiredMode string
 }
 
 func NewSkipAutoGenerationConfig(flag []string) (*SkipAutoGenerationConfig, error) {
@@ -543,7 +800,7 @@ func typeFromTag(tag string) ([]string, error) {
 		return []string{"integer"}, nil
 	case floatTag:
 		return []string{"number"}, nil
-	case timestampTag:
+	case timestampTag, binaryTag:
 		return []string{"string"}, nil
 	case arrayTag:
 		return []string{"array"}, nil
@@ -553,11 +810,42 @@ func typeFromTag(tag string) ([]string, error) {
 	return []string{}, fmt.Errorf("unsupported yaml tag found: %s", tag)
 }
 
+// nodeType returns the type of the value node. Unlike the tag, it is integer for integers which
+// don't fit into 64 bits (yaml resolves them as floats).
+func nodeType(node *yaml.Node) ([]string, error) {
+	if node.Tag == floatTag && integerLiteral.MatchString(node.Value) {
+		return []string{"integer"}, nil
+	}
+	return typeFromTag(node.Tag)
+}
+
+// inferFormat sets the format of timestamps (date or date-time, if they're valid RFC 3339 timestamps)
+// and the content encoding of binary values, unless they're set already
+func (s *Schema) inferFormat(node *yaml.Node) {
+	switch node.Tag {
+	case timestampTag:
+		if s.Format != "" {
+			return
+		}
+		if _, err := time.Parse(time.DateOnly, node.Value); err == nil {
+			s.Format = "date"
+		} else if _, err := time.Parse(time.RFC3339Nano, node.Value); err == nil {
+			s.Format = "date-time"
+		}
+	case binaryTag:
+		if s.ContentEncoding == "" {
+			s.ContentEncoding = "base64"
+		}
+	}
+}
+
 // FixRequiredProperties iterates over the properties and checks if required has a boolean value.
 // Then the property is added to the parents required property list
 func FixRequiredProperties(schema *Schema) error {
 	if schema.Properties != nil {
-		for propName, propValue := range schema.Properties {
+		// iterate in a stable order, so the required list is deterministic
+		for _, propName := range sortedKeys(schema.Properties) {
+			propValue := schema.Properties[propName]
 			FixRequiredProperties(propValue)
 			if propValue.Required.Bool && !slices.Contains(schema.Required.Strings, propName) {
 				schema.Required.Strings = append(schema.Required.Strings, propName)
@@ -619,17 +907,38 @@ func FixRequiredProperties(schema *Schema) error {
 // GetSchemaFromComment parses the annotations from the given comment
 func GetSchemaFromComment(comment string) (Schema, string, error) {
 	var result Schema
-	scanner := bufio.NewScanner(strings.NewReader(comment))
 	description := []string{}
 	rawSchema := []string{}
 	insideSchemaBlock := false
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, SchemaPrefix) {
+	insideRootBlock := false
+
+	// the lines are cut from the comment instead of scanning it, which would allocate a buffer for every key
+	for rest := comment; rest != ""; {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, SchemaRootPrefix) {
+			insideRootBlock = !insideRootBlock
+			continue
+		}
+		if insideRootBlock {
+			continue
+		}
+		if strings.HasPrefix(line, SchemaRawPrefix) {
+			if err := result.addRawKeywords(strings.TrimPrefix(line, SchemaRawPrefix)); err != nil {
+				return result, "", err
+			}
+			continue
+		}
+		if isSchemaDelimiter(line) {
 			insideSchemaBlock = !insideSchemaBlock
 			continue
 		}
+		// ignore comments suppress findings, they aren't part of the description
+		if _, ok := ignoredRules(line); ok && !insideSchemaBlock {
+			continue
+		}
 		if insideSchemaBlock {
 			content := strings.TrimPrefix(line, CommentPrefix)
 			rawSchema = append(rawSchema, strings.TrimPrefix(strings.TrimPrefix(content, CommentPrefix), " "))
@@ -644,14 +953,63 @@ func GetSchemaFromComment(comment string) (Schema, string, error) {
 			fmt.Errorf("unclosed schema block found in comment: %s", comment)
 	}
 
-	err := yaml.Unmarshal([]byte(strings.Join(rawSchema, "\n")), &result)
-	if err != nil {
-		return result, "", err
+	// most keys have no @schema block, they don't need a yaml parser
+	if len(rawSchema) > 0 {
+		parsed := result
+		if err := yaml.Unmarshal([]byte(strings.Join(rawSchema, "\n")), &parsed); err != nil {
+			return parsed, "", err
+		}
+		result = parsed
 	}
 
 	return result, strings.Join(description, "\n"), nil
 }
 
+// isSchemaDelimiter returns true, if the line opens or closes an @schema block
+func isSchemaDelimiter(line string) bool {
+	return strings.HasPrefix(line, SchemaPrefix) && strings.TrimSpace(strings.TrimPrefix(line, SchemaPrefix)) == ""
+}
+
+// GetRootSchemaFromComment parses the @schema-root block of the head comment of the values file, it returns
+// nil if there is none
+func GetRootSchemaFromComment(comment string) (*Schema, error) {
+	rawSchema := []string{}
+	found := false
+	insideRootBlock := false
+	for _, line := range strings.Split(comment, "\n") {
+		if strings.HasPrefix(line, SchemaRootPrefix) {
+			insideRootBlock = !insideRootBlock
+			found = true
+			continue
+		}
+		if insideRootBlock {
+			content := strings.TrimPrefix(line, CommentPrefix)
+			rawSchema = append(rawSchema, strings.TrimPrefix(content, " "))
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	if insideRootBlock {
+		return nil, fmt.Errorf("unclosed schema-root block found in comment: %s", comment)
+	}
+
+	var result Schema
+	if err := yaml.Unmarshal([]byte(strings.Join(rawSchema, "\n")), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// The expressions cleaning up the comments of the keys, they're compiled once instead of for every key
+var (
+	// leadingCommentsRemover removes the paragraphs of a comment, which are separated from the key by empty lines
+	leadingCommentsRemover = regexp.MustCompile(`(?s)(?m)(?:.*\n{2,})+`)
+	// helmDocsTagsRemover removes the lines with helm-docs @tags, helmDocsPrefixRemover the -- of helm-docs
+	helmDocsTagsRemover   = regexp.MustCompile(`(?ms)(\r\n|\r|\n)?\s*@\w+(\s+--\s)?[^\n\r]*`)
+	helmDocsPrefixRemover = regexp.MustCompile(`(?m)^--\s?`)
+)
+
 // YamlToSchema recursevly parses the given yaml.Node and creates a jsonschema from it
 func YamlToSchema(
 	valuesPath string,
@@ -661,34 +1019,36 @@ func YamlToSchema(
 	dontRemoveHelmDocsPrefix bool,
 	skipAutoGeneration *SkipAutoGenerationConfig,
 	parentRequiredProperties *[]string,
-) *Schema {
+) (*Schema, error) {
 	schema := NewSchema("object")
 
 	switch node.Kind {
 	case yaml.DocumentNode:
 		if len(node.Content) != 1 {
-			log.Fatalf("Strange yaml document found:\n%v\n", node.Content[:])
+			return nil, fmt.Errorf("strange yaml document found:\n%v", node.Content[:])
 		}
 
-		schema.Schema = "http://json-schema.org/draft-07/schema#"
-		schema.Properties = YamlToSchema(
-			valuesPath,
-			node.Content[0],
-			keepFullComment,
-			helmDocsCompatibilityMode,
-			dontRemoveHelmDocsPrefix,
-			skipAutoGeneration,
-			&schema.Required.Strings,
-		).Properties
+		schema.Schema = DraftVersion
+		if node.Content[0].Kind == yaml.MappingNode {
+			if err := addMappingProperties(
+				schema,
+				valuesPath,
+				node.Content[0],
+				keepFullComment,
+				helmDocsCompatibilityMode,
+				dontRemoveHelmDocsPrefix,
+				skipAutoGeneration,
+				&schema.Required.Strings,
+			); err != nil {
+				return nil, err
+			}
+		}
 
 		if _, ok := schema.Properties["global"]; !ok {
 			// global key must be present, otherwise helm lint will fail
-			if schema.Properties == nil {
-				schema.Properties = make(map[string]*Schema)
-			}
-			schema.Properties["global"] = NewSchema(
+			schema.SetProperty("global", NewSchema(
 				"object",
-			)
+			))
 			if !skipAutoGeneration.Title {
 				schema.Properties["global"].Title = "global"
 			}
@@ -701,198 +1061,248 @@ func YamlToSchema(
 		if !skipAutoGeneration.AdditionalProperties {
 			schema.AdditionalProperties = new(bool)
 		}
-	case yaml.MappingNode:
-		for i := 0; i < len(node.Content); i += 2 {
-			keyNode := node.Content[i]
-			valueNode := node.Content[i+1]
 
-			if valueNode.Kind == yaml.AliasNode {
-				valueNode = valueNode.Alias
+		// the keywords of the @schema-root block in the head comment of the file (or of its first key)
+		// take precedence
+		comment := node.HeadComment
+		if node.Content[0].Kind == yaml.MappingNode && len(node.Content[0].Content) > 0 {
+			comment += "\n" + node.Content[0].Content[0].HeadComment
+		}
+		rootAnnotation, err := GetRootSchemaFromComment(comment)
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing the root annotation of %s: %w", valuesPath, err)
+		}
+		if rootAnnotation != nil {
+			if err := schema.overlayKeywords(rootAnnotation); err != nil {
+				return nil, fmt.Errorf("error while applying the root annotation of %s: %w", valuesPath, err)
 			}
+		}
+	case yaml.MappingNode:
+		if err := addMappingProperties(schema, valuesPath, node, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, skipAutoGeneration, parentRequiredProperties); err != nil {
+			return nil, err
+		}
+	}
 
-			comment := keyNode.HeadComment
-			if !keepFullComment {
-				leadingCommentsRemover := regexp.MustCompile(`(?s)(?m)(?:.*\n{2,})+`)
-				comment = leadingCommentsRemover.ReplaceAllString(comment, "")
-			}
+	return schema, nil
+}
 
-			keyNodeSchema, description, err := GetSchemaFromComment(comment)
-			if err != nil {
-				log.Fatalf("Error while parsing comment of key %s: %v", keyNode.Value, err)
-			}
+// addMappingProperties adds the keys of the mapping node as properties to the schema. The properties of nested
+// mappings are added to their parents directly, so no intermediate schemas are built for the values.
+func addMappingProperties(
+	schema *Schema,
+	valuesPath string,
+	node *yaml.Node,
+	keepFullComment bool,
+	helmDocsCompatibilityMode bool,
+	dontRemoveHelmDocsPrefix bool,
+	skipAutoGeneration *SkipAutoGenerationConfig,
+	parentRequiredProperties *[]string,
+) error {
+	// the required properties are looked up in a set, searching them would be quadratic for large mappings
+	required := make(map[string]bool, len(*parentRequiredProperties))
+	for _, name := range *parentRequiredProperties {
+		required[name] = true
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
 
-			if helmDocsCompatibilityMode {
-				_, helmDocsValue := helm.ParseComment(strings.Split(keyNode.HeadComment, "\n"))
-				if helmDocsValue.Default != "" {
-					keyNodeSchema.Set()
-					keyNodeSchema.Default = helmDocsValue.Default
-				}
-				if helmDocsValue.Description != "" {
+		if valueNode.Kind == yaml.AliasNode {
+			valueNode = valueNode.Alias
+		}
+
+		comment := keyNode.HeadComment
+		// the expressions only match comments with paragraphs, most comments are kept without copying them
+		if !keepFullComment && strings.Contains(comment, "\n\n") {
+			comment = leadingCommentsRemover.ReplaceAllString(comment, "")
+		}
+
+		keyNodeSchema, description, err := GetSchemaFromComment(comment)
+		if err != nil {
+			return fmt.Errorf("error while parsing comment of key %s: %w", keyNode.Value, err)
+		}
+
+		if helmDocsCompatibilityMode {
+			_, helmDocsValue := helm.ParseComment(strings.Split(keyNode.HeadComment, "\n"))
+			if helmDocsValue.Default != "" {
+				keyNodeSchema.Set()
+				keyNodeSchema.Default = helmDocsValue.Default
+			}
+			if helmDocsValue.Description != "" {
+				keyNodeSchema.Set()
+				keyNodeSchema.Description = helmDocsDescription(strings.Split(keyNode.HeadComment, "\n"))
+			}
+			if helmDocsValue.ValueType != "" {
+				helmDocsType, err := helmDocsTypeToSchemaType(helmDocsValue.ValueType)
+				if err != nil {
+					log.Warnln(err)
+				} else {
 					keyNodeSchema.Set()
-					keyNodeSchema.Description = helmDocsValue.Description
-				}
-				if helmDocsValue.ValueType != "" {
-					helmDocsType, err := helmDocsTypeToSchemaType(helmDocsValue.ValueType)
-					if err != nil {
-						log.Warnln(err)
-					} else {
-						keyNodeSchema.Set()
-						keyNodeSchema.Type = StringOrArrayOfString{helmDocsType}
-					}
+					keyNodeSchema.Type = StringOrArrayOfString{helmDocsType}
 				}
 			}
+		}
 
-			if !dontRemoveHelmDocsPrefix {
-				// remove all lines containing helm-docs @tags, like @ignored, or one of those:
-				// https://github.com/norwoodj/helm-docs/blob/v1.14.2/pkg/helm/chart_info.go#L18-L24
-				helmDocsTagsRemover := regexp.MustCompile(`(?ms)(\r\n|\r|\n)?\s*@\w+(\s+--\s)?[^\n\r]*`)
+		if !dontRemoveHelmDocsPrefix {
+			// remove all lines containing helm-docs @tags, like @ignored, or one of those:
+			// https://github.com/norwoodj/helm-docs/blob/v1.14.2/pkg/helm/chart_info.go#L18-L24
+			if strings.Contains(description, "@") {
 				description = helmDocsTagsRemover.ReplaceAllString(description, "")
+			}
+			if strings.Contains(description, "--") {
+				description = helmDocsPrefixRemover.ReplaceAllString(description, "")
+			}
+		}
 
-				prefixRemover := regexp.MustCompile(`(?m)^--\s?`)
-				description = prefixRemover.ReplaceAllString(description, "")
-			}
-
-			if keyNodeSchema.Ref != "" {
-				// Check if Ref is a relative file to the values file
-				refParts := strings.Split(keyNodeSchema.Ref, "#")
-				if relFilePath, err := util.IsRelativeFile(valuesPath, refParts[0]); err == nil {
-					var relSchema Schema
-					file, err := os.Open(relFilePath)
-					if err == nil {
-						byteValue, _ := io.ReadAll(file)
-
-						if len(refParts) > 1 {
-							// Found json-pointer
-							var obj interface{}
-							json.Unmarshal(byteValue, &obj)
-							jsonPointerResultRaw, err := jsonpointer.Get(obj, refParts[1])
-							if err != nil {
-								log.Fatal(err)
-							}
-							jsonPointerResultMarshaled, err := json.Marshal(jsonPointerResultRaw)
-							if err != nil {
-								log.Fatal(err)
-							}
-							err = json.Unmarshal(jsonPointerResultMarshaled, &relSchema)
-							if err != nil {
-								log.Fatal(err)
-							}
-						} else {
-							// No json-pointer
-							err = json.Unmarshal(byteValue, &relSchema)
-							if err != nil {
-								log.Fatal(err)
-							}
+		// relative refs can only be resolved if the values were read from a file,
+		// refs within the document (e.g. to the shared definitions) are kept
+		if keyNodeSchema.Ref != "" && !strings.HasPrefix(keyNodeSchema.Ref, "#") && valuesPath != "" {
+			// Check if Ref is a relative file to the values file
+			refParts := strings.Split(keyNodeSchema.Ref, "#")
+			if relFilePath, err := util.IsRelativeFile(valuesPath, refParts[0]); err == nil {
+				var relSchema Schema
+				file, err := os.Open(relFilePath)
+				if err == nil {
+					byteValue, _ := io.ReadAll(file)
+
+					if len(refParts) > 1 {
+						// Found json-pointer
+						var obj interface{}
+						json.Unmarshal(byteValue, &obj)
+						jsonPointerResultRaw, err := jsonpointer.Get(obj, refParts[1])
+						if err != nil {
+							return fmt.Errorf("error while resolving the ref %s of key %s: %w", keyNodeSchema.Ref, keyNode.Value, err)
+						}
+						jsonPointerResultMarshaled, err := json.Marshal(jsonPointerResultRaw)
+						if err != nil {
+							return fmt.Errorf("error while resolving the ref %s of key %s: %w", keyNodeSchema.Ref, keyNode.Value, err)
+						}
+						err = json.Unmarshal(jsonPointerResultMarshaled, &relSchema)
+						if err != nil {
+							return fmt.Errorf("error while resolving the ref %s of key %s: %w", keyNodeSchema.Ref, keyNode.Value, err)
 						}
-						keyNodeSchema = relSchema
-						keyNodeSchema.HasData = true
 					} else {
-						log.Fatal(err)
+						// No json-pointer
+						err = json.Unmarshal(byteValue, &relSchema)
+						if err != nil {
+							return fmt.Errorf("error while resolving the ref %s of key %s: %w", keyNodeSchema.Ref, keyNode.Value, err)
+						}
 					}
+					keyNodeSchema = relSchema
+					keyNodeSchema.HasData = true
 				} else {
-					log.Debug(err)
+					return fmt.Errorf("error while resolving the ref %s of key %s: %w", keyNodeSchema.Ref, keyNode.Value, err)
 				}
+			} else {
+				log.Debug(err)
 			}
+		}
 
-			if keyNodeSchema.HasData {
-				if err := keyNodeSchema.Validate(); err != nil {
-					log.Fatalf(
-						"Error while validating jsonschema of key %s: %v",
-						keyNode.Value,
-						err,
-					)
-				}
-			} else {
-				nodeType, err := typeFromTag(valueNode.Tag)
-				if err != nil {
-					log.Fatal(err)
-				}
-				keyNodeSchema.Type = nodeType
+		if keyNodeSchema.HasData {
+			if err := keyNodeSchema.Validate(); err != nil {
+				return fmt.Errorf("error while validating jsonschema of key %s: %w", keyNode.Value, err)
+			}
+		} else {
+			valueType, err := nodeType(valueNode)
+			if err != nil {
+				return err
 			}
+			keyNodeSchema.Type = valueType
+			keyNodeSchema.inferFormat(valueNode)
+		}
 
-			// only validate or default if $ref is not set
-			if keyNodeSchema.Ref == "" {
+		// only validate or default if $ref is not set
+		if keyNodeSchema.Ref == "" {
 
-				// Add key to required array of parent
-				if keyNodeSchema.Required.Bool || (len(keyNodeSchema.Required.Strings) == 0 && !skipAutoGeneration.Required && !keyNodeSchema.HasData) {
-					if !slices.Contains(*parentRequiredProperties, keyNode.Value) {
-						*parentRequiredProperties = append(*parentRequiredProperties, keyNode.Value)
-					}
+			// Add key to required array of parent
+			if keyNodeSchema.Required.Bool || (len(keyNodeSchema.Required.Strings) == 0 && skipAutoGeneration.infersRequired(valueNode) && !keyNodeSchema.HasData) {
+				if !required[keyNode.Value] {
+					required[keyNode.Value] = true
+					*parentRequiredProperties = append(*parentRequiredProperties, keyNode.Value)
 				}
+			}
 
-				if !skipAutoGeneration.AdditionalProperties && valueNode.Kind == yaml.MappingNode &&
-					(!keyNodeSchema.HasData || keyNodeSchema.AdditionalProperties == nil) {
-					keyNodeSchema.AdditionalProperties = new(bool)
-				}
+			if !skipAutoGeneration.AdditionalProperties && valueNode.Kind == yaml.MappingNode &&
+				(!keyNodeSchema.HasData || keyNodeSchema.AdditionalProperties == nil) {
+				keyNodeSchema.AdditionalProperties = new(bool)
+			}
 
-				// If no title was set, use the key value
-				if keyNodeSchema.Title == "" && !skipAutoGeneration.Title {
-					keyNodeSchema.Title = keyNode.Value
-				}
+			// If no title was set, use the key value
+			if keyNodeSchema.Title == "" && !skipAutoGeneration.Title {
+				keyNodeSchema.Title = keyNode.Value
+			}
 
-				// If no description was set, use the rest of the comment as description
-				if keyNodeSchema.Description == "" && !skipAutoGeneration.Description {
-					keyNodeSchema.Description = description
-				}
+			// If no description was set, use the rest of the comment as description
+			if keyNodeSchema.Description == "" && !skipAutoGeneration.Description {
+				keyNodeSchema.Description = description
+			}
+
+			// If no default value was set, use the values node value as default
+			if !skipAutoGeneration.Default && keyNodeSchema.Default == nil && valueNode.Kind == yaml.ScalarNode {
+				keyNodeSchema.Default = castNodeValueByType(valueNode.Value, keyNodeSchema.Type)
+			}
 
-				// If no default value was set, use the values node value as default
-				if !skipAutoGeneration.Default && keyNodeSchema.Default == nil && valueNode.Kind == yaml.ScalarNode {
-					keyNodeSchema.Default = castNodeValueByType(valueNode.Value, keyNodeSchema.Type)
+			// If the value is another map and no properties are set, get them from default values
+			if valueNode.Kind == yaml.MappingNode && keyNodeSchema.Properties == nil {
+				if err := addMappingProperties(
+					&keyNodeSchema,
+					valuesPath,
+					valueNode,
+					keepFullComment,
+					helmDocsCompatibilityMode,
+					dontRemoveHelmDocsPrefix,
+					skipAutoGeneration,
+					&keyNodeSchema.Required.Strings,
+				); err != nil {
+					return err
 				}
+			} else if valueNode.Kind == yaml.SequenceNode && keyNodeSchema.Items == nil {
+				// If the value is a sequence, but no items are predefined
+				seqSchema := NewSchema("")
+
+				for _, itemNode := range valueNode.Content {
+					if itemNode.Kind == yaml.ScalarNode {
+						itemNodeType, err := nodeType(itemNode)
+						if err != nil {
+							return err
+						}
+						itemSchema := NewSchema(itemNodeType[0])
+						itemSchema.inferFormat(itemNode)
+						seqSchema.AnyOf = append(seqSchema.AnyOf, itemSchema)
+					} else {
+						itemRequiredProperties := []string{}
+						itemSchema, err := YamlToSchema(valuesPath, itemNode, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, skipAutoGeneration, &itemRequiredProperties)
+						if err != nil {
+							return err
+						}
 
-				// If the value is another map and no properties are set, get them from default values
-				if valueNode.Kind == yaml.MappingNode && keyNodeSchema.Properties == nil {
-					keyNodeSchema.Properties = YamlToSchema(
-						valuesPath,
-						valueNode,
-						keepFullComment,
-						helmDocsCompatibilityMode,
-						dontRemoveHelmDocsPrefix,
-						skipAutoGeneration,
-						&keyNodeSchema.Required.Strings,
-					).Properties
-				} else if valueNode.Kind == yaml.SequenceNode && keyNodeSchema.Items == nil {
-					// If the value is a sequence, but no items are predefined
-					seqSchema := NewSchema("")
-
-					for _, itemNode := range valueNode.Content {
-						if itemNode.Kind == yaml.ScalarNode {
-							itemNodeType, err := typeFromTag(itemNode.Tag)
-							if err != nil {
-								log.Fatal(err)
-							}
-							seqSchema.AnyOf = append(seqSchema.AnyOf, NewSchema(itemNodeType[0]))
-						} else {
-							itemRequiredProperties := []string{}
-							itemSchema := YamlToSchema(valuesPath, itemNode, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, skipAutoGeneration, &itemRequiredProperties)
-
-							for _, req := range itemRequiredProperties {
-								itemSchema.Required.Strings = append(itemSchema.Required.Strings, req)
-							}
-
-							if !skipAutoGeneration.AdditionalProperties && itemNode.Kind == yaml.MappingNode && (!itemSchema.HasData || itemSchema.AdditionalProperties == nil) {
-								itemSchema.AdditionalProperties = new(bool)
-							}
-
-							seqSchema.AnyOf = append(seqSchema.AnyOf, itemSchema)
+						for _, req := range itemRequiredProperties {
+							itemSchema.Required.Strings = append(itemSchema.Required.Strings, req)
 						}
-					}
-					keyNodeSchema.Items = seqSchema
 
-					// Because the `required` field isn't valid jsonschema (but just a helper boolean)
-					// we must convert them to valid requiredProperties fields
-					FixRequiredProperties(&keyNodeSchema)
+						if !skipAutoGeneration.AdditionalProperties && itemNode.Kind == yaml.MappingNode && (!itemSchema.HasData || itemSchema.AdditionalProperties == nil) {
+							itemSchema.AdditionalProperties = new(bool)
+						}
+
+						seqSchema.AnyOf = append(seqSchema.AnyOf, itemSchema)
+					}
 				}
-			}
+				keyNodeSchema.Items = seqSchema
 
-			if schema.Properties == nil {
-				schema.Properties = make(map[string]*Schema)
+				// Because the `required` field isn't valid jsonschema (but just a helper boolean)
+				// we must convert them to valid requiredProperties fields
+				FixRequiredProperties(&keyNodeSchema)
 			}
-			schema.Properties[keyNode.Value] = &keyNodeSchema
 		}
-	}
 
-	return schema
+		if err := keyNodeSchema.applyRawKeywords(); err != nil {
+			return fmt.Errorf("error while merging the raw keywords of key %s: %w", keyNode.Value, err)
+		}
+
+		keyNodeSchema.Source = &Origin{Source: OriginValue, File: valuesPath, Line: keyNode.Line}
+		schema.SetProperty(keyNode.Value, &keyNodeSchema)
+	}
+	return nil
 }
 
 func helmDocsTypeToSchemaType(helmDocsType string) (string, error) {
@@ -930,11 +1340,14 @@ func castNodeValueByType(rawValue string, fieldType StringOrArrayOfString) any {
 				return false
 			}
 		case "integer":
-			v, err := strconv.Atoi(rawValue)
-			if err == nil {
+			if v, ok := parseInteger(rawValue); ok {
 				return v
 			}
 		case "number":
+			// integers are valid numbers, parsing them as float would round the large ones
+			if v, ok := parseInteger(rawValue); ok {
+				return v
+			}
 			v, err := strconv.ParseFloat(rawValue, 64)
 			if err == nil {
 				return v
@@ -944,3 +1357,13 @@ func castNodeValueByType(rawValue string, fieldType StringOrArrayOfString) any {
 
 	return rawValue
 }
+
+// sortedKeys returns the keys of the given map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -1,10 +1,20 @@
 package schema
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/magiconair/properties/assert"
+	"github.com/ojsef39/helm-schema/pkg/chart"
 	"gopkg.in/yaml.v3"
 )
 
@@ -210,3 +220,2687 @@ x-custom-foo: bar
 	assert.Equal(t, schema.Type, StringOrArrayOfString{"string"})
 	assert.Equal(t, schema.CustomAnnotations["x-custom-foo"], "bar")
 }
+
+func TestUnmarshalJSON(t *testing.T) {
+	jsonData := `{"type": "string", "x-custom-foo": "bar"}`
+
+	var schema Schema
+	if err := json.Unmarshal([]byte(jsonData), &schema); err != nil {
+		t.Fatalf("Error unmarshaling JSON: %v", err)
+	}
+	assert.Equal(t, schema.Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, schema.CustomAnnotations["x-custom-foo"], "bar")
+}
+
+func TestCache(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "cache")
+
+	cache, err := LoadCache(cacheFile, "options")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	cache.Set("Chart.yaml", CacheEntry{InputHash: Hash([]byte("foo"))})
+	if err := cache.Save(cacheFile); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	cache, err = LoadCache(cacheFile, "options")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if entry, ok := cache.Get("Chart.yaml"); !ok || entry.InputHash != Hash([]byte("foo")) {
+		t.Errorf("Expected to find the cached entry, but got %v", entry)
+	}
+
+	cache, err = LoadCache(cacheFile, "other-options")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if _, ok := cache.Get("Chart.yaml"); ok {
+		t.Errorf("Expected the cache to be invalidated by changed options")
+	}
+
+	if Hash([]byte("ab"), []byte("c")) == Hash([]byte("a"), []byte("bc")) {
+		t.Errorf("Expected different hashes for shifted parts")
+	}
+
+	// the schemas of the formats, which can't be read back, are read from the cache
+	outputPath := filepath.Join(t.TempDir(), "values.schema.cue")
+	if err := os.WriteFile(outputPath, []byte("#Values: {}\n"), 0o644); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	cache.Set("Chart.yaml", CacheEntry{
+		InputHash:  Hash([]byte("foo")),
+		SchemaHash: Hash([]byte("#Values: {}\n")),
+		Schema:     []byte(`{"type": "object", "title": "cached"}`),
+	})
+	cachedSchema, ok := readCachedSchema(cache, "Chart.yaml", Hash([]byte("foo")), outputPath)
+	if !ok {
+		t.Fatalf("Expected to find the cached schema")
+	}
+	assert.Equal(t, cachedSchema.Title, "cached")
+	if err := os.WriteFile(outputPath, []byte("#Values: {...}\n"), 0o644); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if _, ok := readCachedSchema(cache, "Chart.yaml", Hash([]byte("foo")), outputPath); ok {
+		t.Errorf("Expected the cache to be invalidated by the changed output file")
+	}
+}
+
+func TestPropertyOrder(t *testing.T) {
+	var values yaml.Node
+	if err := yaml.Unmarshal([]byte("zeta: 1\nalpha: 2\n"), &values); err != nil {
+		t.Fatalf("Error unmarshaling YAML: %v", err)
+	}
+	skipConfig, _ := NewSkipAutoGenerationConfig([]string{})
+
+	tests := []struct {
+		mode     string
+		expected []string
+	}{
+		{mode: PropertyOrderSource, expected: []string{"zeta", "alpha", "global"}},
+		{mode: PropertyOrderAlpha, expected: []string{"alpha", "global", "zeta"}},
+	}
+	for _, test := range tests {
+		s, err := YamlToSchema("values.yaml", &values, false, false, false, skipConfig, nil)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		s.ApplyPropertyOrder(test.mode, true)
+		assert.Equal(t, s.PropertyNames(), test.expected)
+		assert.Equal(t, s.Properties["zeta"].CustomAnnotations["x-order"], 0)
+
+		// the order must survive a round trip
+		jsonStr, err := s.ToJson()
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		var roundTrip Schema
+		if err := json.Unmarshal(jsonStr, &roundTrip); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		assert.Equal(t, roundTrip.PropertyNames(), test.expected)
+	}
+}
+
+func TestOutputConfigPath(t *testing.T) {
+	result := &Result{
+		ChartPath: filepath.Join("repo", "charts", "foo", "Chart.yaml"),
+		Chart:     &chart.ChartFile{Name: "foo", Version: "1.0.0"},
+	}
+
+	tests := []struct {
+		file, dir, layout string
+		expected          string
+	}{
+		{
+			file:     "{{ .Chart.Name }}-{{ .Chart.Version }}.schema.json",
+			layout:   OutputLayoutMirror,
+			expected: filepath.Join("repo", "charts", "foo", "foo-1.0.0.schema.json"),
+		},
+		{
+			file:     "values.schema.json",
+			dir:      "schemas",
+			layout:   OutputLayoutMirror,
+			expected: filepath.Join("schemas", "charts", "foo", "values.schema.json"),
+		},
+		{
+			file:     "{{ .Chart.Name }}.schema.json",
+			dir:      "schemas",
+			layout:   OutputLayoutFlat,
+			expected: filepath.Join("schemas", "foo.schema.json"),
+		},
+	}
+
+	for _, test := range tests {
+		outputConfig, err := NewOutputConfig(test.file, test.dir, test.layout, "repo")
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		outputPath, err := outputConfig.Path(result)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		assert.Equal(t, outputPath, test.expected)
+	}
+
+	outputConfig, _ := NewOutputConfig("{{ .Chart.DoesNotExist }}", "", OutputLayoutMirror, ".")
+	if _, err := outputConfig.Path(result); err == nil {
+		t.Errorf("Expected an error for an unknown field")
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	results := []*Result{
+		{
+			ChartPath:  filepath.Join("charts", "foo", "Chart.yaml"),
+			ValuesPath: filepath.Join("charts", "foo", "values.yaml"),
+			OutputPath: filepath.Join("charts", "foo", "values.schema.json"),
+			Chart:      &chart.ChartFile{Name: "foo", Version: "1.0.0"},
+		},
+	}
+
+	catalog, err := NewCatalog(results, ".")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, len(catalog.Schemas), 1)
+	assert.Equal(t, catalog.Schemas[0].URL, "./charts/foo/values.schema.json")
+	assert.Equal(t, catalog.Schemas[0].FileMatch, []string{"charts/foo/values.yaml"})
+	assert.Equal(t, catalog.Schemas[0].Versions["1.0.0"], "./charts/foo/values.schema.json")
+
+	vscode, err := catalog.ToJson(CatalogFormatVSCode)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(vscode), "{\n  \"./charts/foo/values.schema.json\": [\n    \"charts/foo/values.yaml\"\n  ]\n}")
+}
+
+func TestApplyChartMetadata(t *testing.T) {
+	result := &Result{
+		Chart: &chart.ChartFile{Name: "foo", Version: "1.0.0", AppVersion: "2.0.0"},
+	}
+	if err := result.ApplyChartMetadata("https://example.org/{{ .Chart.Name }}/{{ .Chart.Version }}.json", true); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, result.Schema.Id, "https://example.org/foo/1.0.0.json")
+	assert.Equal(t, result.Schema.CustomAnnotations[ChartMetadataAnnotation], map[string]interface{}{
+		"name":       "foo",
+		"version":    "1.0.0",
+		"appVersion": "2.0.0",
+	})
+
+	result.Chart.Annotations = map[string]string{ChartAnnotationId: "urn:{{ .Chart.Name }}"}
+	if err := result.ApplyChartMetadata("https://example.org/ignored.json", false); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, result.Schema.Id, "urn:foo")
+}
+
+func TestApplyGeneratedBy(t *testing.T) {
+	dir := t.TempDir()
+	chartPath := filepath.Join(dir, "Chart.yaml")
+	valuesPath := filepath.Join(dir, "values.yaml")
+	if err := os.WriteFile(chartPath, []byte("name: foo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(valuesPath, []byte("foo: bar\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result := &Result{ChartPath: chartPath, ValuesPath: valuesPath, Chart: &chart.ChartFile{Name: "foo"}}
+	if err := result.ApplyGeneratedBy("1.2.3"); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, result.Schema.CustomAnnotations[GeneratedByAnnotation], map[string]interface{}{
+		"name":    "helm-schema",
+		"version": "1.2.3",
+		"inputs": map[string]interface{}{
+			"Chart.yaml":  "sha256:57a831cda8328d650d98260a376106976a6ba4a5b21b8b2fadb2796e88debcf1",
+			"values.yaml": "sha256:1dabc4e3cbbd6a0818bd460f3a6c9855bfe95d506c74726bc0f2edb0aecb1f4e",
+		},
+	})
+
+	// a bare values file only has the values as input
+	bare := &Result{ChartPath: valuesPath, ValuesPath: valuesPath, Chart: &chart.ChartFile{Name: "foo"}}
+	if err := bare.ApplyGeneratedBy("1.2.3"); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	inputs := bare.Schema.CustomAnnotations[GeneratedByAnnotation].(map[string]interface{})["inputs"].(map[string]interface{})
+	assert.Equal(t, len(inputs), 1)
+}
+
+func TestApplyRootKeywords(t *testing.T) {
+	s := &Schema{Schema: "http://json-schema.org/draft-07/schema#", Type: []string{"object"}}
+	s.ApplyRootKeywords("https://json-schema.org/draft/2020-12/schema", map[string]interface{}{
+		"$comment": "do not edit",
+		"x-Owner":  "platform",
+	})
+
+	jsonBytes, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(jsonBytes, &data); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, data["$schema"], "https://json-schema.org/draft/2020-12/schema")
+	assert.Equal(t, data["$comment"], "do not edit")
+	assert.Equal(t, data["x-Owner"], "platform")
+
+	s.ApplyRootKeywords("", nil)
+	assert.Equal(t, s.Schema, "https://json-schema.org/draft/2020-12/schema")
+}
+
+func TestToMarkdown(t *testing.T) {
+	s := &Schema{
+		Type:     []string{"object"},
+		Required: NewBoolOrArrayOfString([]string{"image"}, false),
+		Properties: map[string]*Schema{
+			"image": {
+				Type:        []string{"object"},
+				Description: "The image | to use",
+				Properties: map[string]*Schema{
+					"tag": {Type: []string{"string"}, Default: "latest"},
+				},
+			},
+			"replicas": {Type: []string{"integer"}, Default: 1, Description: "Number\nof pods"},
+		},
+	}
+
+	table, err := s.ToMarkdown(MarkdownFormatTable)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(table), `| Key | Type | Default | Required | Description |
+|-----|------|---------|----------|-------------|
+| `+"`image`"+` | object |  | yes | The image \| to use |
+| `+"`image.tag`"+` | string | `+"`\"latest\"`"+` |  |  |
+| `+"`replicas`"+` | integer | `+"`1`"+` |  | Number<br>of pods |
+`)
+
+	tree, err := s.ToMarkdown(MarkdownFormatTree)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(tree), "- **image** (object, required): The image | to use\n"+
+		"  - **tag** (string) Default: `\"latest\"`\n"+
+		"- **replicas** (integer): Number of pods Default: `1`\n")
+
+	if _, err := s.ToMarkdown("html"); err == nil {
+		t.Errorf("Expected an error for an unsupported format")
+	}
+}
+
+func TestInjectMarkdown(t *testing.T) {
+	content := []byte("# Chart\n\n" + MarkdownBeginMarker + "\nold\n" + MarkdownEndMarker + "\nfooter\n")
+	injected, err := InjectMarkdown(content, []byte("new\n"))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(injected), "# Chart\n\n"+MarkdownBeginMarker+"\nnew\n"+MarkdownEndMarker+"\nfooter\n")
+
+	if _, err := InjectMarkdown([]byte("# Chart\n"), []byte("new\n")); err == nil {
+		t.Errorf("Expected an error if the markers are missing")
+	}
+}
+
+func TestToHTML(t *testing.T) {
+	s := &Schema{
+		Type: []string{"object"},
+		Properties: map[string]*Schema{
+			"image": {
+				Type: []string{"object"},
+				Properties: map[string]*Schema{
+					"tag": {Type: []string{"string"}, Default: "latest", Description: "<b>tag</b>"},
+				},
+			},
+		},
+	}
+
+	page, err := s.ToHTML("foo", "my chart")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	for _, expected := range []string{
+		"<title>foo</title>",
+		"<p>my chart</p>",
+		`<details id="image.tag"`,
+		"&lt;b&gt;tag&lt;/b&gt;",
+		"Default: <code>&#34;latest&#34;</code>",
+	} {
+		if !strings.Contains(string(page), expected) {
+			t.Errorf("Expected html page to contain %s", expected)
+		}
+	}
+
+	index, err := HTMLIndex("charts", []HTMLIndexEntry{{Name: "foo", Version: "1.0.0", Path: "foo/values.html"}})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if !strings.Contains(string(index), `<a href="foo/values.html">foo</a>`) {
+		t.Errorf("Expected index to link to the chart page")
+	}
+}
+
+func TestToTypeScript(t *testing.T) {
+	s := &Schema{
+		Type:                 []string{"object"},
+		AdditionalProperties: false,
+		Required:             NewBoolOrArrayOfString([]string{"image"}, false),
+		Properties: map[string]*Schema{
+			"image": {
+				Type:                 []string{"object"},
+				AdditionalProperties: false,
+				Properties: map[string]*Schema{
+					"pull-policy": {Type: []string{"string"}, Enum: []string{"Always", "IfNotPresent"}},
+				},
+			},
+			"ports":    {Type: []string{"array"}, Items: &Schema{Type: []string{"integer"}}},
+			"replicas": {Type: []string{"integer", "null"}, Default: 1, Description: "Number of pods"},
+			"labels":   {Type: []string{"object"}, AdditionalProperties: &Schema{Type: []string{"string"}}},
+		},
+	}
+
+	ts, err := s.ToTypeScript(TypeName("my-chart"))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(ts), `// Code generated by helm-schema. DO NOT EDIT.
+
+export interface MyChartValues {
+  image: {
+    "pull-policy"?: "Always" | "IfNotPresent";
+  };
+  labels?: { [key: string]: string };
+  ports?: number[];
+  /**
+   * Number of pods
+   * @default 1
+   */
+  replicas?: number | null;
+}
+`)
+}
+
+func TestToGo(t *testing.T) {
+	s := &Schema{
+		Type:     []string{"object"},
+		Required: NewBoolOrArrayOfString([]string{"image"}, false),
+		Properties: map[string]*Schema{
+			"image": {
+				Type:        []string{"object"},
+				Description: "The image to use",
+				Required:    NewBoolOrArrayOfString([]string{"repository"}, false),
+				Properties: map[string]*Schema{
+					"repository":  {Type: []string{"string"}},
+					"pull-policy": {Type: []string{"string"}},
+				},
+			},
+			"ports":    {Type: []string{"array"}, Items: &Schema{Type: []string{"integer"}}},
+			"replicas": {Type: []string{"integer", "null"}},
+			"labels":   {Type: []string{"object"}, AdditionalProperties: &Schema{Type: []string{"string"}}},
+		},
+	}
+
+	code, err := s.ToGo("values", TypeName("my-chart"))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(code), "// Code generated by helm-schema. DO NOT EDIT.\n\n"+
+		"package values\n\n"+
+		"type MyChartValues struct {\n"+
+		"\t// The image to use\n"+
+		"\tImage    MyChartValuesImage `json:\"image\" yaml:\"image\"`\n"+
+		"\tLabels   map[string]string  `json:\"labels,omitempty\" yaml:\"labels,omitempty\"`\n"+
+		"\tPorts    []int64            `json:\"ports,omitempty\" yaml:\"ports,omitempty\"`\n"+
+		"\tReplicas *int64             `json:\"replicas,omitempty\" yaml:\"replicas,omitempty\"`\n"+
+		"}\n\n"+
+		"// The image to use\n"+
+		"type MyChartValuesImage struct {\n"+
+		"\tPullPolicy *string `json:\"pull-policy,omitempty\" yaml:\"pull-policy,omitempty\"`\n"+
+		"\tRepository string  `json:\"repository\" yaml:\"repository\"`\n"+
+		"}\n")
+}
+
+func TestToCue(t *testing.T) {
+	minimum, maxLength := 1, 63
+	s := &Schema{
+		Type:                 []string{"object"},
+		AdditionalProperties: false,
+		Required:             NewBoolOrArrayOfString([]string{"name"}, false),
+		Properties: map[string]*Schema{
+			"name":        {Type: []string{"string"}, MaxLength: &maxLength, Pattern: "^[a-z]+$"},
+			"replicas":    {Type: []string{"integer"}, Minimum: &minimum, Default: 1, Description: "Number of pods"},
+			"pull-policy": {Type: []string{"string"}, Enum: []string{"Always", "IfNotPresent"}},
+			"labels":      {Type: []string{"object"}, AdditionalProperties: &Schema{Type: []string{"string"}}},
+			"ports":       {Type: []string{"array", "null"}, Items: &Schema{Type: []string{"integer"}}},
+		},
+	}
+
+	cue, err := s.ToCue(TypeName("my-chart"))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(cue), `// Code generated by helm-schema. DO NOT EDIT.
+
+import (
+	"strings"
+)
+
+#MyChartValues: {
+	labels?: {[string]: string}
+	name: string & =~"^[a-z]+$" & strings.MaxRunes(63)
+	ports?: [...int] | null
+	"pull-policy"?: "Always" | "IfNotPresent"
+	// Number of pods
+	replicas?: *1 | int & >=1
+}
+`)
+}
+
+func TestToOpenAPI(t *testing.T) {
+	exclusiveMaximum := 10
+	s := &Schema{
+		Schema: "http://json-schema.org/draft-07/schema#",
+		Type:   []string{"object"},
+		Properties: map[string]*Schema{
+			"replicas": {Type: []string{"integer", "null"}, ExclusiveMaximum: &exclusiveMaximum},
+			"port":     {Type: []string{"integer", "string"}, Examples: []string{"80"}},
+			"mode":     {Const: "fast", CustomAnnotations: map[string]interface{}{"x-ui": "select", "foo": "bar"}},
+			"labels":   {Type: []string{"object"}, AdditionalProperties: &Schema{Type: []string{"string", "null"}}},
+		},
+	}
+
+	openAPI, err := s.ToOpenAPI()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(openAPI, &data); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, data, map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"replicas": map[string]interface{}{"type": "integer", "nullable": true, "maximum": float64(10), "exclusiveMaximum": true},
+			"port": map[string]interface{}{
+				"anyOf":   []interface{}{map[string]interface{}{"type": "integer"}, map[string]interface{}{"type": "string"}},
+				"example": "80",
+			},
+			"mode": map[string]interface{}{"enum": []interface{}{"fast"}, "x-ui": "select"},
+			"labels": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string", "nullable": true},
+			},
+		},
+	})
+
+	// the original schema isn't modified
+	assert.Equal(t, s.Schema, "http://json-schema.org/draft-07/schema#")
+	assert.Equal(t, []string(s.Properties["replicas"].Type), []string{"integer", "null"})
+}
+
+func TestToCRD(t *testing.T) {
+	result := &Result{
+		Chart: &chart.ChartFile{Name: "my-app", Annotations: map[string]string{ChartAnnotationCRDVersion: "v1"}},
+		Schema: Schema{
+			Type:                 []string{"object"},
+			AdditionalProperties: false,
+			Properties: map[string]*Schema{
+				"port":   {Type: []string{"integer", "string"}},
+				"labels": {Type: []string{"object"}, AdditionalProperties: &Schema{Type: []string{"string"}}},
+				"extra":  {Type: []string{"object"}},
+			},
+		},
+	}
+
+	if _, err := result.CRDOptions(CRDOptions{}); err == nil {
+		t.Errorf("Expected an error without a group")
+	}
+	opts, err := result.CRDOptions(CRDOptions{Group: "example.org", Version: "v1alpha1"})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, opts, CRDOptions{Group: "example.org", Kind: "MyApp", Version: "v1"})
+
+	crd, err := result.Schema.ToCRD(opts)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(crd), `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: myapps.example.org
+spec:
+  group: example.org
+  names:
+    kind: MyApp
+    listKind: MyAppList
+    plural: myapps
+    singular: myapp
+  scope: Namespaced
+  versions:
+    - name: v1
+      schema:
+        openAPIV3Schema:
+          properties:
+            spec:
+              properties:
+                values:
+                  properties:
+                    extra:
+                      type: object
+                      x-kubernetes-preserve-unknown-fields: true
+                    labels:
+                      additionalProperties:
+                        type: string
+                      type: object
+                    port:
+                      x-kubernetes-int-or-string: true
+                  type: object
+              type: object
+          type: object
+      served: true
+      storage: true
+`)
+}
+
+func TestToQuestions(t *testing.T) {
+	s := &Schema{
+		Type: []string{"object"},
+		Properties: map[string]*Schema{
+			"ingress": {
+				Type: []string{"object"},
+				Properties: map[string]*Schema{
+					"enabled":  {Type: []string{"boolean"}, Title: "enabled"},
+					"hostName": {Type: []string{"string"}, Default: "example.org"},
+				},
+			},
+			"mode": {Type: []string{"string"}, Enum: []string{"a", "b"}, Title: "Operating mode"},
+		},
+		Required: NewBoolOrArrayOfString([]string{"mode"}, false),
+		If:       &Schema{Properties: map[string]*Schema{"mode": {Const: "b"}}},
+		Then:     &Schema{Properties: map[string]*Schema{"ingress": {}}},
+	}
+
+	questions, err := s.ToQuestions()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(questions), `# Code generated by helm-schema. DO NOT EDIT.
+questions:
+  - variable: ingress.enabled
+    label: Enabled
+    type: boolean
+    group: Ingress
+    show_if: mode=b
+  - variable: ingress.hostName
+    label: Host Name
+    type: string
+    default: example.org
+    group: Ingress
+    show_if: mode=b&&ingress.enabled=true
+  - variable: mode
+    label: Operating mode
+    type: enum
+    required: true
+    group: Operating mode
+    options:
+      - a
+      - b
+`)
+}
+
+func TestAnnotateValues(t *testing.T) {
+	values := `# The image to use
+image:
+  repository: nginx
+  # @schema
+  # type: string
+  # @schema
+  tag: "1.0"
+list: [80]
+`
+	minLength := 1
+	s := &Schema{
+		Type: []string{"object"},
+		Properties: map[string]*Schema{
+			"image": {
+				Type:        []string{"object"},
+				Description: "The image to use",
+				Properties: map[string]*Schema{
+					"repository": {Type: []string{"string"}, MinLength: &minLength},
+					"tag":        {Type: []string{"string"}, Enum: []string{"1.0", "2.0"}},
+				},
+				Required: NewBoolOrArrayOfString([]string{"repository"}, false),
+			},
+			"list":    {Type: []string{"array"}, Items: &Schema{Type: []string{"integer"}}},
+			"missing": {Type: []string{"string"}},
+		},
+	}
+
+	annotated, missing, err := AnnotateValues([]byte(values), s)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, missing, []string{"missing"})
+	assert.Equal(t, string(annotated), `# The image to use
+# @schema
+# additionalProperties: true
+# type: object
+# @schema
+image:
+  # @schema
+  # minLength: 1
+  # required: true
+  # type: string
+  # @schema
+  repository: nginx
+  # @schema
+  # type: string
+  # @schema
+  tag: "1.0"
+# @schema
+# items:
+#   type: integer
+# type: array
+# @schema
+list: [80]
+`)
+}
+
+func TestQuestionsToSchema(t *testing.T) {
+	questions := `questions:
+- variable: image.tag
+  label: Image Tag
+  type: enum
+  options: ["1.0", "2.0"]
+  required: true
+- variable: ingress.enabled
+  type: boolean
+  subquestions:
+  - variable: ingress.replicas
+    type: int
+    min: 1
+`
+	s, err := QuestionsToSchema([]byte(questions))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["image"].Properties["tag"].Title, "Image Tag")
+	assert.Equal(t, s.Properties["image"].Properties["tag"].Enum, []string{"1.0", "2.0"})
+	assert.Equal(t, s.Properties["image"].Required.Strings, []string{"tag"})
+	assert.Equal(t, s.Properties["ingress"].Properties["enabled"].Type, StringOrArrayOfString{"boolean"})
+	assert.Equal(t, s.Properties["ingress"].Properties["replicas"].Type, StringOrArrayOfString{"integer"})
+	assert.Equal(t, *s.Properties["ingress"].Properties["replicas"].Minimum, 1)
+}
+
+func TestApplyBitnamiParams(t *testing.T) {
+	values := `## Bitnami image
+## @param image.registry [default: REGISTRY_NAME] Image registry
+## @param image.digest [string,nullable] Image digest
+##
+image:
+  registry: docker.io
+  digest: ""
+  # @schema
+  # description: explicit
+  # type: string
+  # @schema
+  # @param image.tag [nullable] Image tag
+  tag: "1.0"
+## @param pullSecrets [array] Registry secret names
+pullSecrets: []
+`
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := GenerateSchema("values.yaml", []byte(values), false, false, false, false, true, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	image := s.Properties["image"]
+	assert.Equal(t, image.Description, "Bitnami image")
+	assert.Equal(t, image.Properties["registry"].Description, "Image registry")
+	assert.Equal(t, image.Properties["registry"].Default, "docker.io")
+	assert.Equal(t, image.Properties["digest"].Description, "Image digest")
+	assert.Equal(t, image.Properties["digest"].Type, StringOrArrayOfString{"string", "null"})
+	assert.Equal(t, image.Properties["tag"].Description, "explicit")
+	assert.Equal(t, image.Properties["tag"].Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, s.Properties["pullSecrets"].Description, "Registry secret names")
+	assert.Equal(t, s.Properties["pullSecrets"].Type, StringOrArrayOfString{"array"})
+}
+
+func TestApplySidecarAnnotations(t *testing.T) {
+	values := `# The image
+image:
+  # @schema
+  # type: string
+  # minLength: 2
+  # @schema
+  tag: "1.0"
+  repository: nginx
+"dotted.key": 1
+`
+	sidecar := `image.tag:
+  enum: ["1.0", "2.0"]
+  type: null
+/image/repository:
+  description: The repository
+/dotted.key:
+  type: integer
+  minimum: 0
+`
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(values), &node); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if err := ApplySidecarAnnotations(&node, []byte(sidecar)); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := YamlToSchema("values.yaml", &node, false, false, false, skipConfig, nil)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	image := s.Properties["image"]
+	assert.Equal(t, image.Description, "The image")
+	assert.Equal(t, image.Properties["tag"].Enum, []string{"1.0", "2.0"})
+	assert.Equal(t, *image.Properties["tag"].MinLength, 2)
+	assert.Equal(t, image.Properties["tag"].Type.IsEmpty(), true)
+	assert.Equal(t, image.Properties["repository"].Description, "The repository")
+	assert.Equal(t, s.Properties["dotted.key"].Type, StringOrArrayOfString{"integer"})
+	assert.Equal(t, *s.Properties["dotted.key"].Minimum, 0)
+}
+
+func TestApplyPatch(t *testing.T) {
+	newSchema := func() *Schema {
+		s := NewSchema("object")
+		s.SetProperty("zeta", NewSchema("integer"))
+		s.SetProperty("alpha", NewSchema("string"))
+		s.Properties["alpha"].Enum = []string{"a"}
+		return s
+	}
+
+	s := newSchema()
+	err := s.ApplyPatch([]byte(`[
+  {"op": "replace", "path": "/properties/zeta/type", "value": "number"},
+  {"op": "add", "path": "/properties/alpha/enum/-", "value": "b"},
+  {"op": "add", "path": "/properties/alpha/propertyNames", "value": {"pattern": "^a"}},
+  {"op": "test", "path": "/type", "value": "object"}
+]`))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["zeta"].Type, StringOrArrayOfString{"number"})
+	assert.Equal(t, s.Properties["alpha"].Enum, []string{"a", "b"})
+	assert.Equal(t, s.Properties["alpha"].CustomAnnotations["propertyNames"], map[string]interface{}{"pattern": "^a"})
+	assert.Equal(t, s.PropertyNames(), []string{"zeta", "alpha"})
+
+	s = newSchema()
+	if err := s.ApplyPatch([]byte(`{"properties": {"zeta": null, "alpha": {"minLength": 1}}}`)); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.PropertyNames(), []string{"alpha"})
+	assert.Equal(t, *s.Properties["alpha"].MinLength, 1)
+
+	s = newSchema()
+	if err := s.ApplyPatch([]byte(`[{"op": "remove", "path": "/properties/missing"}]`)); err == nil {
+		t.Errorf("Expected an error for a missing path")
+	}
+
+	dir := t.TempDir()
+	s = newSchema()
+	if err := s.ApplySchemaPatchFile(dir); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.PropertyNames(), []string{"zeta", "alpha"})
+	if err := os.WriteFile(filepath.Join(dir, SchemaPatchFile), []byte(`{"properties": {"zeta": null}}`), 0o644); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if err := s.ApplySchemaPatchFile(dir); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.PropertyNames(), []string{"alpha"})
+}
+
+func TestPostProcess(t *testing.T) {
+	result := &Result{
+		Chart:  &chart.ChartFile{Name: "foo", Version: "1.0.0"},
+		Schema: *NewSchema("object"),
+	}
+	if err := result.PostProcess(`printf '{"type": "object", "x-chart": "%s", "propertyNames": {"maxLength": 3}}' "$HELM_SCHEMA_CHART_NAME"`); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, result.Schema.CustomAnnotations["x-chart"], "foo")
+	assert.Equal(t, result.Schema.CustomAnnotations["propertyNames"], map[string]interface{}{"maxLength": 3})
+
+	if err := result.PostProcess("echo invalid"); err == nil {
+		t.Errorf("Expected an error for an invalid output")
+	}
+	if err := result.PostProcess("exit 1"); err == nil {
+		t.Errorf("Expected an error for a failing command")
+	}
+}
+
+func TestValidateValues(t *testing.T) {
+	s := NewSchema("object")
+	s.SetProperty("replicas", NewSchema("integer"))
+	s.Required.Strings = []string{"replicas"}
+
+	if err := s.ValidateValues("values.schema.json", []byte("replicas: 1\n")); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if err := s.ValidateValues("values.schema.json", []byte("replicas: one\n")); err == nil {
+		t.Errorf("Expected an error for a string instead of an integer")
+	}
+	if err := s.ValidateValues("values.schema.json", []byte("")); err == nil {
+		t.Errorf("Expected an error for the missing required property")
+	}
+}
+
+func TestValidateUntrustedValuesJSON(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret.json")
+	if err := os.WriteFile(secret, []byte(`{"type": "integer"}`), 0o644); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	schemaURL := "file://" + filepath.ToSlash(filepath.Join(dir, "values.schema.json"))
+	fileRef := []byte(`{"properties": {"port": {"$ref": "secret.json"}}}`)
+
+	// trusted schemas read the files of their references
+	if err := ValidateValuesJSON(fileRef, schemaURL, []byte("port: x\n")); err == nil {
+		t.Errorf("Expected an error for a string instead of an integer")
+	}
+	if err := ValidateUntrustedValuesJSON(fileRef, schemaURL, []byte("port: 80\n")); err == nil || !strings.Contains(err.Error(), "isn't allowed") {
+		t.Errorf("Expected an error for the reference to a file, but got %v", err)
+	}
+
+	localRef := []byte(`{"$schema": "http://json-schema.org/draft-07/schema#", "definitions": {"port": {"type": "integer"}}, "properties": {"port": {"$ref": "#/definitions/port"}}}`)
+	if err := ValidateUntrustedValuesJSON(localRef, schemaURL, []byte("port: 80\n")); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if err := ValidateUntrustedValuesJSON(localRef, schemaURL, []byte("port: x\n")); err == nil {
+		t.Errorf("Expected an error for a string instead of an integer")
+	}
+}
+
+func TestGenerateSchemaWithoutValuesFile(t *testing.T) {
+	values := `# @schema
+# $ref: definitions.json#/port
+# @schema
+port: 80
+`
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	// the relative ref can't be resolved and is kept
+	assert.Equal(t, s.Properties["port"].Ref, "definitions.json#/port")
+}
+
+func TestGenerateSchemaInvalidAnnotations(t *testing.T) {
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "definitions.json"), []byte(`{"port": {"type": "integer"}}`), 0o644); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	for _, values := range []string{
+		"# @schema\n# type: [\n# @schema\nport: 80\n",
+		"# @schema\n# type: map\n# @schema\nport: 80\n",
+		"# @schema\n# $ref: definitions.json#/missing\n# @schema\nport: 80\n",
+		"list:\n  - # @schema\n    # minLength: x\n    # @schema\n    name: foo\n",
+	} {
+		// the errors are returned instead of exiting
+		if _, err := GenerateSchema(filepath.Join(dir, "values.yaml"), []byte(values), false, false, false, false, false, skipConfig); err == nil {
+			t.Errorf("Expected an error for the values %q", values)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	oldSchema, err := ReadSchemaFile([]byte(`{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["a"],
+  "properties": {
+    "a": {"type": ["string", "null"], "default": "x"},
+    "b": {"type": "integer", "minimum": 1},
+    "c": {"type": "string", "enum": ["x", "y"]},
+    "removed": {"type": "string"},
+    "list": {"type": "array", "items": {"type": "object", "properties": {"name": {"type": "string"}}}}
+  }
+}`))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	newSchema, err := ReadSchemaFile([]byte(`{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["a", "d"],
+  "properties": {
+    "a": {"type": "string", "default": "y"},
+    "b": {"type": "number", "minimum": 5},
+    "c": {"type": "string", "enum": ["x", "z"]},
+    "d": {"type": "boolean"},
+    "e": {"type": "boolean"},
+    "list": {"type": "array", "items": {"type": "object", "properties": {"name": {"type": "integer"}}}}
+  }
+}`))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	var got []string
+	for _, change := range Diff(oldSchema, newSchema) {
+		got = append(got, change.Kind+" "+change.Path+": "+change.Message)
+	}
+	assert.Equal(t, got, []string{
+		"breaking a: type was narrowed",
+		"modified a: default was changed",
+		"additive b: type was widened",
+		"breaking b: minimum was tightened",
+		"breaking c: enum values were removed",
+		"additive c: enum values were added",
+		"breaking d: required property was added",
+		"additive e: property was added",
+		"breaking list[].name: type was changed",
+		"breaking removed: property was removed",
+	})
+	assert.Equal(t, HasBreakingChanges(Diff(oldSchema, oldSchema)), false)
+}
+
+func TestChangelog(t *testing.T) {
+	changes := []Change{
+		{Path: "image.tag", Kind: ChangeBreaking, Message: messagePropertyRemoved, Old: "1.0"},
+		{Path: "replicas", Kind: ChangeModified, Message: "default was changed", Old: 1, New: 2},
+		{Path: "replicas", Kind: ChangeModified, Message: messageDescriptionChanged, Old: "", New: "The replicas"},
+		{Path: "resources", Kind: ChangeAdditive, Message: messagePropertyAdded, New: map[string]interface{}{}},
+	}
+	assert.Equal(t, Changelog("mychart", changes), "## mychart\n"+
+		"\n### Added\n\n- `resources` (default: `{}`)\n"+
+		"\n### Removed\n\n- **Breaking:** `image.tag` (default was: `\"1.0\"`)\n"+
+		"\n### Changed\n\n- `replicas`: default was changed from `1` to `2`\n")
+	assert.Equal(t, Changelog("mychart", nil), "")
+}
+
+func TestNewDependencyGraph(t *testing.T) {
+	results := []*Result{
+		{ChartPath: "app/Chart.yaml", Chart: &chart.ChartFile{Name: "app", Version: "1.0.0", Dependencies: []*chart.Dependency{
+			{Name: "db", Version: "^2.0.0", Alias: "database", Condition: "database.enabled"},
+		}}},
+		{ChartPath: "app/charts/db/Chart.yaml", Chart: &chart.ChartFile{Name: "db", Version: "2.1.0"}},
+	}
+	graph, err := NewDependencyGraph(results)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, graph.Edges[0].To, "app/charts/db/Chart.yaml")
+	assert.Equal(t, graph.Order, []string{"app/charts/db/Chart.yaml", "app/Chart.yaml"})
+	assert.Equal(t, graph.Circular, false)
+
+	results[1].Chart.Version = "3.0.0"
+	graph, err = NewDependencyGraph(results)
+	if err == nil {
+		t.Fatal("Expected an error, because the dependency is missing")
+	}
+	assert.Equal(t, graph.Edges[0].To, "")
+	assert.Equal(t, graph.Circular, true)
+	assert.Equal(t, len(graph.Order), 2)
+	if !strings.Contains(graph.Dot(), `"app/Chart.yaml" -> "missing: db ^2.0.0"`) {
+		t.Errorf("Expected the missing dependency in the dot graph, but got: %s", graph.Dot())
+	}
+}
+
+func TestTopoSortCircularError(t *testing.T) {
+	results := []*Result{
+		{ChartPath: "a/Chart.yaml", Chart: &chart.ChartFile{Name: "a", Version: "1.0.0", Dependencies: []*chart.Dependency{{Name: "b", Version: "1.0.0"}}}},
+		{ChartPath: "b/Chart.yaml", Chart: &chart.ChartFile{Name: "b", Version: "1.0.0", Dependencies: []*chart.Dependency{{Name: "a", Version: "1.0.0"}}}},
+		{ChartPath: "c/Chart.yaml", Chart: &chart.ChartFile{Name: "c", Version: "1.0.0", Dependencies: []*chart.Dependency{{Name: "d", Version: "1.0.0"}}}},
+	}
+	sorted, err := TopoSort(results, nil)
+	circularErr, ok := err.(*CircularError)
+	if !ok {
+		t.Fatalf("Expected a CircularError, but got this: %v", err)
+	}
+	assert.Equal(t, len(sorted), 3)
+	assert.Equal(t, circularErr.Cycle, []string{"a", "b", "a"})
+	assert.Equal(t, circularErr.ChartPaths, []string{"c/Chart.yaml", "a/Chart.yaml", "b/Chart.yaml"})
+	if !strings.Contains(err.Error(), "a -> b -> a (a/Chart.yaml, b/Chart.yaml)") || !strings.Contains(err.Error(), "c requires d 1.0.0 (c/Chart.yaml)") {
+		t.Errorf("Expected the cycle and the missing dependency in the error, but got: %s", err)
+	}
+}
+
+func TestExplainKeywords(t *testing.T) {
+	content := []byte(`ingress:
+  # Annotations of the ingress
+  # @schema
+  # type: object
+  # @schema
+  annotations: {}
+  host: example.org
+`)
+	origins, found, err := ExplainKeywords("values.yaml", content, []string{"ingress", "annotations"}, []string{"type", "description", "title"}, false)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, found, true)
+	assert.Equal(t, origins, []Origin{
+		{Keyword: "type", Source: OriginAnnotation, File: "values.yaml", Line: 4},
+		{Keyword: "description", Source: OriginComment, File: "values.yaml", Line: 2},
+		{Keyword: "title", Source: OriginGenerated},
+	})
+
+	origins, _, err = ExplainKeywords("values.yaml", content, []string{"ingress", "host"}, []string{"default", "type"}, false)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, origins[0], Origin{Keyword: "default", Source: OriginValue, File: "values.yaml", Line: 7})
+
+	_, found, err = ExplainKeywords("values.yaml", content, []string{"ingress", "tls"}, []string{"type"}, false)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, found, false)
+}
+
+func TestInferAnnotations(t *testing.T) {
+	content := []byte(`# The replicas
+replicas: 1
+image:
+  # @schema
+  # enum: [a, b]
+  # @schema
+  pullPolicy: a
+`)
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := GenerateSchema("", content, false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	annotated, count, err := InferAnnotations(content, s, []string{"type", "default"})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, count, 2)
+	assert.Equal(t, string(annotated), `# The replicas
+# @schema
+# type: integer
+# default: 1
+# required: true
+# @schema
+replicas: 1
+# @schema
+# type: object
+# required: true
+# @schema
+image:
+  # @schema
+  # enum: [a, b]
+  # @schema
+  pullPolicy: a
+`)
+
+	// the schema stays the same and annotating again doesn't change anything
+	annotatedSchema, err := GenerateSchema("", annotated, false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	schemaJSON, _ := s.ToJson()
+	annotatedSchemaJSON, _ := annotatedSchema.ToJson()
+	assert.Equal(t, string(annotatedSchemaJSON), string(schemaJSON))
+	_, count, err = InferAnnotations(annotated, annotatedSchema, []string{"type", "default"})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, count, 0)
+
+	if _, _, err := InferAnnotations(content, s, []string{"pattern"}); err == nil {
+		t.Fatal("Expected an error for an unsupported keyword")
+	}
+}
+
+func TestAddValueAnnotations(t *testing.T) {
+	content := []byte(`# The replicas
+replicas: 1
+image:
+  # @schema
+  # minLength: 1
+  # @schema
+  tag: "1.25"
+token: ~
+`)
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := GenerateSchema("", content, false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	values, err := UndocumentedValues(content, s)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	paths := []string{}
+	for _, value := range values {
+		paths = append(paths, value.Path)
+	}
+	assert.Equal(t, paths, []string{"image", "image.tag", "token"})
+	assert.Equal(t, values[1].MissingType, true)
+	assert.Equal(t, values[2].MissingType, true)
+
+	annotated, err := AddValueAnnotations(content, []ValueAnnotation{
+		{Value: values[1], Type: StringOrArrayOfString{"string"}},
+		{Value: values[2], Description: "The token", Type: StringOrArrayOfString{"string", "null"}},
+	})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(annotated), `# The replicas
+replicas: 1
+image:
+  # @schema
+  # minLength: 1
+  # type: string
+  # @schema
+  tag: "1.25"
+# The token
+# @schema
+# type: [string, null]
+# required: true
+# @schema
+token: ~
+`)
+}
+
+func TestMergeValues(t *testing.T) {
+	merged, err := MergeValues([]byte("replicas: 1\nimage:\n  tag: a\n  repository: b\nextra: 1\n"), []byte("image:\n  tag: c\nextra: null\n"))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(merged), `{"image":{"repository":"b","tag":"c"},"replicas":1}`)
+
+	if _, err := MergeValues([]byte("replicas: 1\n"), []byte("- a\n")); err == nil {
+		t.Fatal("Expected an error for a fixture, which isn't a mapping")
+	}
+}
+
+func TestValidateMetaschema(t *testing.T) {
+	s := &Schema{
+		Schema: "http://json-schema.org/draft-07/schema#",
+		Type:   StringOrArrayOfString{"object"},
+		Properties: map[string]*Schema{
+			"name": {Type: StringOrArrayOfString{"string"}, Pattern: "^[a-z]+$"},
+		},
+	}
+	if err := s.ValidateMetaschema(); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	s.Properties["name"].Pattern = "[a"
+	err := s.ValidateMetaschema()
+	if err == nil {
+		t.Fatal("Expected an error for an invalid pattern")
+	}
+	if !strings.Contains(err.Error(), "#/properties/name/pattern") {
+		t.Errorf("Expected the location of the pattern in the error, but got %v", err)
+	}
+}
+
+func TestToSample(t *testing.T) {
+	minLength := 3
+	s := &Schema{
+		Type:     StringOrArrayOfString{"object"},
+		Required: BoolOrArrayOfString{Strings: []string{"replicas", "image", "policy"}},
+		Properties: map[string]*Schema{
+			"replicas": {Type: StringOrArrayOfString{"integer"}, Default: 1, Description: "Number of replicas"},
+			"image": {
+				Type:     StringOrArrayOfString{"object"},
+				Required: BoolOrArrayOfString{Strings: []string{"tag"}},
+				Properties: map[string]*Schema{
+					"tag":        {Type: StringOrArrayOfString{"string"}, MinLength: &minLength},
+					"pullPolicy": {Type: StringOrArrayOfString{"string"}},
+				},
+			},
+			"policy":      {Enum: []string{"Always", "Never"}},
+			"annotations": {Type: StringOrArrayOfString{"object"}, Description: "Extra annotations"},
+			"name":        {Type: StringOrArrayOfString{"string", "null"}, Default: "~"},
+		},
+		PropertyOrder: []string{"replicas", "image", "policy", "annotations", "name"},
+	}
+	s.Properties["image"].PropertyOrder = []string{"tag", "pullPolicy"}
+
+	sample, err := s.ToSample()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(sample), `# Number of replicas
+replicas: 1
+image:
+  tag: xxx
+  # pullPolicy: ""
+policy: Always
+# # Extra annotations
+# annotations: {}
+name: null
+`)
+}
+
+func TestRandomValidValues(t *testing.T) {
+	minimum, maximum, minItems := 1, 5, 1
+	s := &Schema{
+		Type:                 StringOrArrayOfString{"object"},
+		AdditionalProperties: false,
+		Required:             BoolOrArrayOfString{Strings: []string{"name", "replicas", "policy", "hosts"}},
+		Properties: map[string]*Schema{
+			"name":     {Type: StringOrArrayOfString{"string"}, Pattern: "^[a-z][a-z0-9-]{2,8}$"},
+			"replicas": {Type: StringOrArrayOfString{"integer"}, Minimum: &minimum, Maximum: &maximum},
+			"policy":   {Enum: []string{"Always", "Never"}},
+			"hosts":    {Type: StringOrArrayOfString{"array"}, MinItems: &minItems, Items: &Schema{Type: StringOrArrayOfString{"string"}, Format: "hostname"}},
+			"debug":    {Type: StringOrArrayOfString{"boolean", "null"}},
+		},
+	}
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 20; i++ {
+		content, err := s.RandomValidValues("values.schema.json", r, 1)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		if err := s.ValidateValues("values.schema.json", content); err != nil {
+			t.Fatalf("Expected valid values, but got %v for %s", err, content)
+		}
+	}
+}
+
+func TestUIAnnotations(t *testing.T) {
+	values := `db:
+  # @schema
+  # type: string
+  # ui:
+  #   widget: password
+  #   order: 1
+  # x-ui-help: The password
+  # @schema
+  password: ""
+  # @schema
+  # type: string
+  # ui:
+  #   group: Connection
+  #   advanced: true
+  #   order: 0
+  # @schema
+  host: localhost
+  port: 5432
+`
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["db"].Properties["password"].CustomAnnotations["x-ui-widget"], "password")
+
+	uiSchema, err := s.ToUISchema()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(uiSchema), `{
+  "db": {
+    "host": {
+      "ui:options": {
+        "advanced": true,
+        "group": "Connection"
+      }
+    },
+    "password": {
+      "ui:help": "The password",
+      "ui:widget": "password"
+    },
+    "ui:order": [
+      "host",
+      "password",
+      "port",
+      "*"
+    ]
+  }
+}
+`)
+
+	var invalid Schema
+	if err := yaml.Unmarshal([]byte("ui:\n  widgt: password\n"), &invalid); err == nil {
+		t.Fatal("Expected an error for an unsupported ui hint")
+	}
+	if err := yaml.Unmarshal([]byte("ui:\n  order: first\n"), &invalid); err == nil {
+		t.Fatal("Expected an error for an invalid order")
+	}
+}
+
+func TestMaskSensitive(t *testing.T) {
+	values := `# @schema
+# sensitive: true
+# @schema
+adminKey: hunter2
+db:
+  password: s3cret
+  existingSecret: db-credentials
+  passwordSecretName: db
+  # @schema
+  # sensitive: false
+  # @schema
+  token: public
+  tokens: []
+`
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.MaskSensitive(false), 1)
+	assert.Equal(t, s.Properties["adminKey"].Default, nil)
+	assert.Equal(t, s.Properties["adminKey"].WriteOnly, true)
+	assert.Equal(t, s.Properties["db"].Properties["password"].Default, "s3cret")
+
+	assert.Equal(t, s.MaskSensitive(true), 2)
+	db := s.Properties["db"]
+	assert.Equal(t, db.Properties["password"].Default, nil)
+	assert.Equal(t, db.Properties["password"].CustomAnnotations[SensitiveAnnotation], true)
+	assert.Equal(t, db.Properties["existingSecret"].Default, "db-credentials")
+	assert.Equal(t, db.Properties["passwordSecretName"].Default, "db")
+	assert.Equal(t, db.Properties["token"].Default, "public")
+	assert.Equal(t, db.Properties["tokens"].WriteOnly, false)
+
+	schemaJSON, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, strings.Contains(string(schemaJSON), "s3cret") || strings.Contains(string(schemaJSON), "hunter2"), false)
+}
+
+func TestNormalizeDescription(t *testing.T) {
+	description := "\nThe **image** of the `app`.  \nSee [the docs](https://example.org).\n\n\n- first\n- _second_\n\n```yaml\nimage:\n\n  tag: latest\n```\n"
+	assert.Equal(t, NormalizeDescription(description, DescriptionFormatMarkdown),
+		"The **image** of the `app`.\nSee [the docs](https://example.org).\n\n- first\n- _second_\n\n```yaml\nimage:\n\n  tag: latest\n```")
+	assert.Equal(t, NormalizeDescription(description, DescriptionFormatPlain),
+		"The image of the app. See the docs (https://example.org).\n\n- first\n- second\n\n    image:\n    \n      tag: latest")
+	assert.Equal(t, NormalizeDescription("snake_case_name", DescriptionFormatPlain), "snake_case_name")
+}
+
+func TestHelmDocsDescription(t *testing.T) {
+	comment := []string{
+		"# Ignored comment",
+		"# -- (int) The replicas.",
+		"# Use 0 to",
+		"# @default -- 1",
+		"#   - scale down",
+	}
+	assert.Equal(t, helmDocsDescription(comment), "The replicas.\nUse 0 to\n  - scale down")
+	assert.Equal(t, helmDocsDescription([]string{"# replicaCount -- The replicas", "# of the app"}), "The replicas\nof the app")
+	assert.Equal(t, helmDocsDescription([]string{"# Not helm-docs"}), "")
+}
+
+func TestLocalize(t *testing.T) {
+	values := `# @schema
+# title.de: Replikas
+# description.de: Anzahl der Pods
+# description.fr: Nombre de pods
+# @schema
+# The number of pods
+replicas: 1
+`
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	replicas := s.Properties["replicas"]
+	assert.Equal(t, replicas.Description, "The number of pods")
+	assert.Equal(t, replicas.CustomAnnotations[I18nAnnotation], map[string]interface{}{
+		"de": map[string]interface{}{"title": "Replikas", "description": "Anzahl der Pods"},
+		"fr": map[string]interface{}{"description": "Nombre de pods"},
+	})
+
+	if err := s.Localize("fr"); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, replicas.Title, "replicas")
+	assert.Equal(t, replicas.Description, "Nombre de pods")
+	_, ok := replicas.CustomAnnotations[I18nAnnotation]
+	assert.Equal(t, ok, false)
+
+	var invalid Schema
+	err = yaml.Unmarshal([]byte("description.123456: text"), &invalid)
+	assert.Equal(t, err != nil, true)
+}
+
+func TestResolveSharedDefs(t *testing.T) {
+	defsFile := filepath.Join(t.TempDir(), SharedDefsFile)
+	defsContent := `image:
+  type: object
+  properties:
+    tag:
+      $ref: "#/$defs/tag"
+tag:
+  type: string
+unused:
+  type: string
+loop:
+  type: object
+  properties:
+    child:
+      $ref: "#/$defs/loop"
+`
+	if err := os.WriteFile(defsFile, []byte(defsContent), 0644); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	defs, err := ReadSharedDefs(defsFile)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	values := `# @schema
+# $ref: "#/$defs/image"
+# @schema
+image: {}
+`
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	bundled, err := GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if err := bundled.ResolveSharedDefs(defs, DefsModeBundle); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, sortedKeys(bundled.Defs), []string{"image", "tag"})
+	assert.Equal(t, bundled.Properties["image"].Ref, "#/$defs/image")
+
+	inlined, err := GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if err := inlined.ResolveSharedDefs(defs, DefsModeInline); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, len(inlined.Defs), 0)
+	assert.Equal(t, inlined.Properties["image"].Properties["tag"].Type, StringOrArrayOfString{"string"})
+
+	circular := &Schema{Properties: map[string]*Schema{"tree": {Ref: "#/$defs/loop"}}}
+	assert.Equal(t, circular.ResolveSharedDefs(defs, DefsModeInline) != nil, true)
+	missing := &Schema{Properties: map[string]*Schema{"other": {Ref: "#/$defs/missing"}}}
+	assert.Equal(t, missing.ResolveSharedDefs(defs, DefsModeBundle) != nil, true)
+}
+
+func TestDedupe(t *testing.T) {
+	values := `frontend:
+  image:
+    repository: nginx
+    tag: latest
+backend:
+  image:
+    repository: nginx
+    tag: latest
+worker:
+  image:
+    repository: busybox
+`
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	deduped, count, err := s.Dedupe()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, count, 2)
+	assert.Equal(t, len(deduped.Defs), 1)
+	name := sortedKeys(deduped.Defs)[0]
+	assert.Equal(t, strings.HasPrefix(name, "image-"), true)
+	assert.Equal(t, deduped.Properties["frontend"].Properties["image"].Ref, "#/$defs/"+name)
+	assert.Equal(t, deduped.Properties["backend"].Properties["image"].Ref, "#/$defs/"+name)
+	assert.Equal(t, deduped.Properties["worker"].Properties["image"].Ref, "")
+	assert.Equal(t, deduped.Defs[name].Properties["tag"].Default, "latest")
+	// the original schema is kept
+	assert.Equal(t, s.Properties["frontend"].Properties["image"].Ref, "")
+
+	if err := deduped.ValidateValues("file:///values.schema.json", []byte("frontend:\n  image:\n    tag: 1\n")); err == nil {
+		t.Fatalf("Expected the deduplicated schema to reject an invalid tag")
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "common.json"), []byte(`{"$defs": {"port": {"type": "integer", "maximum": 65535}}}`), 0644); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s := &Schema{
+		Type: StringOrArrayOfString{"object"},
+		Properties: map[string]*Schema{
+			"image": {Ref: "#/$defs/image", Description: "The image"},
+			"port":  {Ref: "common.json#/$defs/port"},
+		},
+		Defs: map[string]*Schema{
+			"image": {Type: StringOrArrayOfString{"object"}, Properties: map[string]*Schema{"tag": {Ref: "#/$defs/tag"}}},
+			"tag":   {Type: StringOrArrayOfString{"string"}},
+		},
+	}
+	if err := s.Flatten("file://"+filepath.ToSlash(filepath.Join(dir, "values.yaml")), nil); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, len(s.Defs), 0)
+	assert.Equal(t, s.Properties["image"].Description, "The image")
+	assert.Equal(t, s.Properties["image"].Properties["tag"].Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, *s.Properties["port"].Maximum, 65535)
+
+	circular := &Schema{
+		Properties: map[string]*Schema{"tree": {Ref: "#/$defs/node"}},
+		Defs:       map[string]*Schema{"node": {Properties: map[string]*Schema{"child": {Ref: "#/$defs/node"}}}},
+	}
+	assert.Equal(t, circular.Flatten("", nil) != nil, true)
+	remote := &Schema{Properties: map[string]*Schema{"chart": {Ref: "https://example.org/chart.json"}}}
+	assert.Equal(t, remote.Flatten("", nil) != nil, true)
+}
+
+func TestToJsonIndent(t *testing.T) {
+	s := &Schema{Type: StringOrArrayOfString{"object"}, Properties: map[string]*Schema{"name": {Type: StringOrArrayOfString{"string"}}}}
+
+	compact, err := s.ToJsonIndent("")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(compact), `{"properties":{"name":{"required":[],"type":"string"}},"required":[],"type":"object"}`)
+
+	indented, err := s.ToJsonIndent("    ")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, strings.Split(string(indented), "\n")[1], `    "properties": {`)
+
+	result := &Result{Chart: &chart.ChartFile{Name: "app"}, Schema: *s}
+	openAPI, err := result.MarshalIndent(OutputFormatOpenAPI, "")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, strings.Contains(string(openAPI), "\n"), false)
+}
+
+func TestToYAML(t *testing.T) {
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	values := "image:\n  tag: \"1.0\"\nreplicas: 1\n"
+	s, err := GenerateSchema("charts/app/values.yaml", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	content, err := s.ToYAML("charts/app")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	yamlSchema := string(content)
+	assert.Equal(t, strings.Contains(yamlSchema, "  # values.yaml:1\n  image:\n"), true)
+	assert.Equal(t, strings.Contains(yamlSchema, "      # values.yaml:2\n      tag:\n"), true)
+	assert.Equal(t, strings.Contains(yamlSchema, "default: \"1.0\"\n"), true)
+
+	// the yaml is a representation of the same schema
+	var decoded, expected interface{}
+	if err := yaml.Unmarshal(content, &decoded); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	schemaJSON, _ := json.Marshal(s)
+	_ = json.Unmarshal(schemaJSON, &expected)
+	decodedJSON, _ := json.Marshal(decoded)
+	expectedJSON, _ := json.Marshal(expected)
+	assert.Equal(t, string(decodedJSON), string(expectedJSON))
+}
+
+func TestParseValues(t *testing.T) {
+	values := `base: &base
+  # @schema
+  # minimum: 1
+  # @schema
+  replicas: 1
+  name: base
+merged:
+  <<: *base
+  name: merged
+list:
+  - *base
+---
+base:
+  port: 80
+# @schema
+# required: true
+# @schema
+token: ""
+`
+	node, err := ParseValues([]byte(values))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := YamlToSchema("", node, false, false, false, skipConfig, nil)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	merged := s.Properties["merged"]
+	assert.Equal(t, merged.PropertyNames(), []string{"replicas", "name"})
+	assert.Equal(t, merged.Properties["name"].Default, "merged")
+	assert.Equal(t, *merged.Properties["replicas"].Minimum, 1)
+	assert.Equal(t, s.Properties["list"].Items.AnyOf[0].Properties["name"].Default, "base")
+
+	// the keys of the following documents are optional, unless annotated otherwise
+	assert.Equal(t, s.Properties["base"].Properties["port"].Type, StringOrArrayOfString{"integer"})
+	assert.Equal(t, s.Properties["base"].Required.Strings, []string{"name"})
+	assert.Equal(t, s.Required.Strings, []string{"base", "merged", "list", "token"})
+
+	_, err = ParseValues([]byte("merged:\n  <<: 1\n"))
+	assert.Equal(t, err != nil, true)
+}
+
+func TestIntegerPrecision(t *testing.T) {
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	values := `big: 9223372036854775807
+unsigned: 18446744073709551615
+huge: 123456789012345678901234567890
+hex: 0x1F
+ratio: 1.5
+# @schema
+# type: number
+# @schema
+either: 9007199254740993
+`
+	s, err := GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["huge"].Type, StringOrArrayOfString{"integer"})
+	assert.Equal(t, s.Properties["hex"].Default, 31)
+	assert.Equal(t, s.Properties["ratio"].Type, StringOrArrayOfString{"number"})
+	assert.Equal(t, s.Properties["either"].Type, StringOrArrayOfString{"number"})
+
+	// the defaults survive the round trip through json (e.g. of the cache)
+	schemaJSON, err := s.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	var decoded Schema
+	if err := json.Unmarshal(schemaJSON, &decoded); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	decodedJSON, err := decoded.ToJson()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(decodedJSON), string(schemaJSON))
+	for _, literal := range []string{"9223372036854775807", "18446744073709551615", "123456789012345678901234567890", "9007199254740993"} {
+		assert.Equal(t, strings.Contains(string(schemaJSON), `"default": `+literal+",\n"), true)
+	}
+	assert.Equal(t, decoded.Properties["big"].Default, 9223372036854775807)
+}
+
+func TestTimestampsAndBinary(t *testing.T) {
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	values := `released: 2001-12-14
+updated: 2001-12-14T21:59:43.10-05:00
+spaced: 2001-12-14 21:59:43.10 -5
+cert: !!binary R0lGODlhDAAMAIQAAP
+# @schema
+# type: string
+# default: 2020-01-01
+# @schema
+annotated: 2001-12-14
+`
+	s, err := GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Properties["released"].Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, s.Properties["released"].Format, "date")
+	assert.Equal(t, s.Properties["released"].Default, "2001-12-14")
+	assert.Equal(t, s.Properties["updated"].Format, "date-time")
+	// the format would reject the default, which isn't a valid RFC 3339 timestamp
+	assert.Equal(t, s.Properties["spaced"].Format, "")
+	assert.Equal(t, s.Properties["cert"].Type, StringOrArrayOfString{"string"})
+	assert.Equal(t, s.Properties["cert"].ContentEncoding, "base64")
+	assert.Equal(t, s.Properties["annotated"].Format, "")
+	assert.Equal(t, s.Properties["annotated"].Default, "2020-01-01")
+
+	openAPI, err := s.openAPICopy()
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, openAPI.Properties["cert"].Format, "byte")
+	assert.Equal(t, openAPI.Properties["cert"].ContentEncoding, "")
+}
+
+func TestExcludeValues(t *testing.T) {
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	values := `replicas: 1
+# @schema
+# skip: true
+# @schema
+internal:
+  debug: true
+experimental:
+  featureA: true
+  featureB: false
+  stable: 1
+`
+	s, err := GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	_, ok := s.Properties["internal"]
+	assert.Equal(t, ok, false)
+	assert.Equal(t, s.PatternProperties["^internal$"] != nil, true)
+
+	assert.Equal(t, s.ExcludeValues([]string{"experimental.feature*"}), 2)
+	experimental := s.Properties["experimental"]
+	assert.Equal(t, experimental.PropertyNames(), []string{"stable"})
+	assert.Equal(t, experimental.Required.Strings, []string{"stable"})
+	assert.Equal(t, s.Required.Strings, []string{"replicas", "experimental"})
+
+	// the excluded values are still valid
+	if err := s.ValidateValues("file:///values.schema.json", []byte(values)); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	assert.Equal(t, ValidateExcludePatterns([]string{"foo.[a"}) != nil, true)
+}
+
+func TestRequiredMode(t *testing.T) {
+	values := `
+replicas: 1
+# @schema
+# required: true
+# @schema
+existingSecret: null
+nodeSelector: null
+image:
+  tag: latest
+`
+	tests := []struct {
+		mode     string
+		required []string
+	}{
+		{RequiredModeAll, []string{"replicas", "existingSecret", "nodeSelector", "image"}},
+		{RequiredModeNone, []string{}},
+		{RequiredModeAnnotated, []string{"existingSecret"}},
+		{RequiredModeNonNullDefaults, []string{"replicas", "existingSecret", "image"}},
+	}
+	for _, test := range tests {
+		skipConfig, _ := NewSkipAutoGenerationConfig([]string{})
+		skipConfig.RequiredMode = test.mode
+		s, err := GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		assert.Equal(t, s.Required.Strings, test.required)
+	}
+
+	// skipping the auto-generation of required equals the annotated mode
+	skipConfig, _ := NewSkipAutoGenerationConfig([]string{"required"})
+	s, err := GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.Required.Strings, []string{"existingSecret"})
+
+	chartFile := &chart.ChartFile{Annotations: map[string]string{ChartAnnotationRequiredMode: RequiredModeNone}}
+	chartConfig, err := skipConfig.ForChart(chartFile)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, chartConfig.requiredMode(), RequiredModeNone)
+	assert.Equal(t, skipConfig.requiredMode(), RequiredModeAnnotated)
+
+	chartFile.Annotations[ChartAnnotationRequiredMode] = "some"
+	if _, err := skipConfig.ForChart(chartFile); err == nil {
+		t.Fatal("Expected an error for an invalid required mode")
+	}
+	assert.Equal(t, ValidateRequiredMode("some") != nil, true)
+}
+
+func TestKeepsRequired(t *testing.T) {
+	parent := &chart.ChartFile{Annotations: map[string]string{ChartAnnotationKeepRequired: "common, backend"}}
+	assert.Equal(t, KeepsRequired(parent, &chart.Dependency{Name: "common"}, nil), true)
+	assert.Equal(t, KeepsRequired(parent, &chart.Dependency{Name: "api", Alias: "backend"}, nil), true)
+	assert.Equal(t, KeepsRequired(parent, &chart.Dependency{Name: "redis"}, nil), false)
+	assert.Equal(t, KeepsRequired(&chart.ChartFile{}, &chart.Dependency{Name: "redis"}, []string{"redis"}), true)
+
+	skipConfig, _ := NewSkipAutoGenerationConfig([]string{})
+	s, err := GenerateSchema("", []byte("image:\n  tag: latest\n"), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	dep := &chart.Dependency{Name: "common"}
+	withoutRequired, err := DependencySchema(dep, s, "", false)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, withoutRequired.Properties["image"].Required.Strings, []string{})
+	// the schema of the chart is inlined into other parents, which may keep its required properties
+	assert.Equal(t, s.Properties["image"].Required.Strings, []string{"tag"})
+
+	withRequired, err := DependencySchema(dep, s, "", true)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, withRequired.Required.Strings, []string{"image"})
+	assert.Equal(t, withRequired.Properties["image"].Required.Strings, []string{"tag"})
+}
+
+func TestDependencySchemaAlias(t *testing.T) {
+	skipConfig, _ := NewSkipAutoGenerationConfig([]string{})
+	s, err := GenerateSchema("", []byte("image:\n  tag: latest\n"), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	plain, err := DependencySchema(&chart.Dependency{Name: "redis"}, s, "A redis", false)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, plain.Title, "redis")
+	assert.Equal(t, plain.Description, "A redis")
+	assert.Equal(t, plain.CustomAnnotations[AliasOfAnnotation], nil)
+
+	cache, err := DependencySchema(&chart.Dependency{Name: "redis", Alias: "cache"}, s, "A redis", false)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	queue, err := DependencySchema(&chart.Dependency{Name: "redis", Alias: "queue"}, s, "A redis", false)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, cache.Title, "cache")
+	assert.Equal(t, cache.CustomAnnotations[AliasOfAnnotation], "redis")
+	assert.Equal(t, DependencyKey(&chart.Dependency{Name: "redis", Alias: "queue"}), "queue")
+
+	// the aliases don't share their subschemas
+	parent := &Schema{}
+	parent.SetProperty("cache", cache)
+	parent.SetProperty("queue", queue)
+	assert.Equal(t, parent.ExcludeValues([]string{"cache.image"}), 1)
+	assert.Equal(t, queue.PropertyNames(), []string{"image", "global"})
+	assert.Equal(t, s.PropertyNames(), []string{"image", "global"})
+}
+
+func TestPublishedSchemas(t *testing.T) {
+	dir := t.TempDir()
+	published := `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "definitions": {"port": {"type": "integer", "minimum": 1}},
+  "properties": {"port": {"$ref": "#/definitions/port"}, "name": {"type": "string", "propertyNames": {"pattern": "^a"}}},
+  "required": ["port"]
+}`
+	chartDir := filepath.Join(dir, "charts", "db")
+	if err := os.MkdirAll(chartDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string]string{
+		filepath.Join(dir, "Chart.yaml"):                  "apiVersion: v2\nname: app\nversion: 1.0.0\n",
+		filepath.Join(chartDir, "Chart.yaml"):             "apiVersion: v2\nname: db\nversion: 1.0.0\n",
+		filepath.Join(chartDir, PublishedSchemaFile):      published,
+		filepath.Join(dir, "values", PublishedSchemaFile): published,
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, content, err := ReadPublishedSchema(filepath.Join(chartDir, "Chart.yaml"))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, string(content), published)
+	assert.Equal(t, s.Properties["port"].Ref, "#/$defs/port")
+	assert.Equal(t, *s.Defs["port"].Minimum, 1)
+	assert.Equal(t, s.Properties["name"].CustomAnnotations["propertyNames"], map[string]interface{}{"pattern": "^a"})
+	assert.Equal(t, s.Required.Strings, []string{"port"})
+
+	// only dependencies use their published schemas
+	s, _, err = ReadPublishedSchema(filepath.Join(dir, "Chart.yaml"))
+	if err != nil || s != nil {
+		t.Fatalf("Expected no published schema of the parent chart, but got %v (%v)", s, err)
+	}
+
+	// packaged dependencies are read from their archives
+	var archive bytes.Buffer
+	gzipWriter := gzip.NewWriter(&archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, content := range map[string]string{
+		"redis/Chart.yaml":                           "apiVersion: v2\nname: redis\nversion: 1.0.0\ndescription: Redis\n",
+		"redis/" + PublishedSchemaFile:               `{"properties": {"auth": {"type": "boolean"}}}`,
+		"redis/values.yaml":                          "auth: true\n",
+		"redis/charts/common/" + PublishedSchemaFile: `{}`,
+	} {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "charts", "redis-1.0.0.tgz"), archive.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	packaged, err := ReadPackagedDependency(dir, &chart.Dependency{Name: "redis"})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, packaged.Chart.Description, "Redis")
+	assert.Equal(t, packaged.Schema.PropertyNames(), []string{"auth"})
+	assert.Equal(t, string(packaged.Values), "auth: true\n")
+	packaged, err = ReadPackagedDependency(dir, &chart.Dependency{Name: "postgres"})
+	if err != nil || packaged != nil {
+		t.Fatalf("Expected no packaged postgres, but got %v (%v)", packaged, err)
+	}
+
+	// archives of charts without a schema are only read by ReadChartArchive
+	archive.Reset()
+	gzipWriter = gzip.NewWriter(&archive)
+	tarWriter = tar.NewWriter(gzipWriter)
+	chartContent := "apiVersion: v2\nname: postgres\nversion: 1.0.0\n"
+	if err := tarWriter.WriteHeader(&tar.Header{Name: "postgres/Chart.yaml", Mode: 0o644, Size: int64(len(chartContent)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tarWriter.Write([]byte(chartContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	postgresArchive := filepath.Join(dir, "postgres-1.0.0.tgz")
+	if err := os.WriteFile(postgresArchive, archive.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if packaged, err = ReadPackagedChart(postgresArchive); err != nil || packaged != nil {
+		t.Fatalf("Expected no packaged chart without a schema, but got %v (%v)", packaged, err)
+	}
+	packaged, err = ReadChartArchive(postgresArchive)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, packaged.Chart.Name, "postgres")
+	assert.Equal(t, packaged.Schema == nil, true)
+}
+
+func TestMergeDependency(t *testing.T) {
+	skipConfig, _ := NewSkipAutoGenerationConfig([]string{})
+	tests := []struct {
+		strategy string
+		tagType  StringOrArrayOfString
+		conflict string
+	}{
+		{DependencyMergeDependencyWins, StringOrArrayOfString{"string"}, "redis.image.tag is integer in the values of the chart and string in the dependency, using string"},
+		{DependencyMergeParentWins, StringOrArrayOfString{"integer"}, "redis.image.tag is integer in the values of the chart and string in the dependency, using integer"},
+		{DependencyMergeUnion, StringOrArrayOfString{"string", "integer"}, "redis.image.tag is integer in the values of the chart and string in the dependency, using string, integer"},
+	}
+	for _, test := range tests {
+		parent, err := GenerateSchema("", []byte("redis:\n  image:\n    tag: 7\n  extra: true\n"), false, false, false, false, false, skipConfig)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		dependency, err := GenerateSchema("", []byte("image:\n  tag: latest\n  pullPolicy: Always\n"), false, false, false, false, false, skipConfig)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		depSchema, err := DependencySchema(&chart.Dependency{Name: "redis"}, dependency, "", false)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+
+		conflicts, err := parent.MergeDependency("redis", depSchema, test.strategy)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		assert.Equal(t, conflicts, []string{test.conflict, "redis.extra isn't a value of the dependency"})
+		image := parent.Properties["redis"].Properties["image"]
+		assert.Equal(t, image.Properties["tag"].Type, test.tagType)
+		assert.Equal(t, image.PropertyNames(), []string{"tag", "pullPolicy"})
+		_, ok := parent.Properties["redis"].Properties["extra"]
+		assert.Equal(t, ok, true)
+	}
+}
+
+func TestWorkerTimings(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), []byte("apiVersion: v2\nname: timed\nversion: 1.0.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "values.yaml"), []byte("# @schema\n# type: integer\n# @schema\nreplicas: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outputConfig, err := NewOutputConfig("values.schema.json", "", OutputLayoutMirror, dir)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	queue := make(chan string, 1)
+	results := make(chan Result, 1)
+	queue <- filepath.Join(dir, "Chart.yaml")
+	close(queue)
+	Worker(false, false, false, false, false, false, false, []string{"values.yaml"}, &SkipAutoGenerationConfig{}, outputConfig, nil, queue, results)
+
+	result := <-results
+	if len(result.Errors) > 0 {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", result.Errors)
+	}
+	timings := result.Timings
+	assert.Equal(t, timings.Read > 0 && timings.Parse > 0 && timings.Infer > 0, true)
+	assert.Equal(t, timings.Total(), timings.Read+timings.Parse+timings.Infer)
+}
+
+func TestFindOutputCollisions(t *testing.T) {
+	dir := t.TempDir()
+	for _, chartDir := range []string{"a", "b", "umbrella/charts"} {
+		if err := os.MkdirAll(filepath.Join(dir, chartDir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join("..", "..", "a"), filepath.Join(dir, "umbrella", "charts", "a")); err != nil {
+		t.Skipf("symlinks aren't supported: %s", err)
+	}
+	result := func(name, chartDir, outputPath string) *Result {
+		return &Result{
+			ChartPath:  filepath.Join(dir, chartDir, "Chart.yaml"),
+			Chart:      &chart.ChartFile{Name: name},
+			OutputPath: filepath.Join(dir, outputPath),
+		}
+	}
+
+	a := result("a", "a", "out/values.schema.json")
+	// the same chart found through the symlink in the umbrella chart
+	symlinkedA := result("a", "umbrella/charts/a", "out/values.schema.json")
+	b := result("b", "b", "out/values.schema.json")
+	other := result("b", "b", "out/b.schema.json")
+
+	collisions := FindOutputCollisions([]*Result{a, symlinkedA, b, other})
+	assert.Equal(t, len(collisions), 1)
+	assert.Equal(t, collisions[0].Result, b)
+	assert.Equal(t, collisions[0].Other, a)
+	assert.Equal(t, collisions[0].Path, filepath.Join(resolvePath(dir), "out", "values.schema.json"))
+}
+
+func TestValueFileNames(t *testing.T) {
+	defaults := []string{"values.yaml"}
+	tests := []struct {
+		annotations map[string]string
+		expected    []string
+	}{
+		{annotations: nil, expected: defaults},
+		{annotations: map[string]string{ChartAnnotationValues: ""}, expected: defaults},
+		{annotations: map[string]string{ChartAnnotationValues: "values.base.yaml, values.yaml"}, expected: []string{"values.base.yaml", "values.yaml"}},
+		{annotations: map[string]string{ChartAnnotationValues: "defaults.yaml,"}, expected: []string{"defaults.yaml"}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, ValueFileNames(&chart.ChartFile{Annotations: test.annotations}, defaults), test.expected)
+	}
+	assert.Equal(t, ValueFileNames(nil, defaults), defaults)
+}
+
+func TestPatchCondition(t *testing.T) {
+	result := &Result{Chart: &chart.ChartFile{Name: "redis"}}
+	result.Schema.SetProperty("metrics", &Schema{Type: []string{"object"}, Title: "metrics"})
+	result.Schema.Properties["metrics"].SetProperty("enabled", &Schema{Type: []string{"string"}, Description: "Enables the exporter"})
+
+	patch := ConditionPatch{Type: []string{"boolean"}, Description: DefaultConditionDescription}
+	if err := result.PatchCondition("redis.enabled", []string{"enabled"}, patch); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, result.Schema.Properties["enabled"], &Schema{Type: []string{"boolean"}, Title: "enabled", Description: DefaultConditionDescription})
+
+	// the existing property keeps its type and description
+	if err := result.PatchCondition("redis.metrics.enabled", []string{"metrics", "enabled"}, patch); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, result.Schema.Properties["metrics"].Properties["enabled"], &Schema{Type: []string{"string"}, Description: "Enables the exporter"})
+
+	patch = ConditionPatch{Type: []string{"boolean", "null"}, Description: "Enables {{ .Chart.Name }}, if {{ .Condition }} is true"}
+	if err := result.PatchCondition("redis.tls.enabled", []string{"tls", "enabled"}, patch); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, result.Schema.Properties["tls"].Type, StringOrArrayOfString{"object"})
+	assert.Equal(t, result.Schema.Properties["tls"].Properties["enabled"].Type, StringOrArrayOfString{"boolean", "null"})
+	assert.Equal(t, result.Schema.Properties["tls"].Properties["enabled"].Description, "Enables redis, if redis.tls.enabled is true")
+
+	patch.Description = "{{ .Missing }}"
+	if err := result.PatchCondition("redis.enabled", []string{"enabled"}, patch); err == nil {
+		t.Fatal("Expected an error for the invalid template")
+	}
+}
+
+func TestRootAnnotation(t *testing.T) {
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	for _, values := range []string{
+		"# @schema-root\n# title: My chart\n# additionalProperties: true\n# @schema-root\n\n# The image\nimage: nginx\n",
+		// without an empty line, the block is part of the comment of the first key
+		"# @schema-root\n# title: My chart\n# additionalProperties: true\n# @schema-root\n# The image\nimage: nginx\n",
+	} {
+		var node yaml.Node
+		if err := yaml.Unmarshal([]byte(values), &node); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		s, err := YamlToSchema("values.yaml", &node, false, false, false, skipConfig, nil)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		assert.Equal(t, s.Title, "My chart")
+		assert.Equal(t, s.AdditionalProperties, true)
+		assert.Equal(t, s.Properties["image"].Title, "image")
+		assert.Equal(t, s.Properties["image"].Description, "The image")
+	}
+
+	root, err := GetRootSchemaFromComment("# The values")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if root != nil {
+		t.Fatalf("Expected no root schema, but got %v", root)
+	}
+	if _, err := GetRootSchemaFromComment("# @schema-root\n# title: unclosed"); err == nil {
+		t.Fatal("Expected an error for the unclosed block")
+	}
+	if _, err := GenerateSchema("values.yaml", []byte("# @schema-root\n# title: unclosed\nimage: nginx\n"), false, false, false, false, false, skipConfig); err == nil {
+		t.Fatal("Expected an error for the values with the unclosed block")
+	}
+}
+
+func TestRawKeywords(t *testing.T) {
+	skipConfig, err := NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	values := `# The labels
+# @schema-raw: {"propertyNames": {"pattern": "^[a-z]+$"}, "maxItems": 3}
+# @schema-raw: {"title": "Labels"}
+labels:
+  app: web
+`
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(values), &node); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := YamlToSchema("values.yaml", &node, false, false, false, skipConfig, nil)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	labels := s.Properties["labels"]
+	assert.Equal(t, labels.Title, "Labels")
+	assert.Equal(t, labels.Description, "The labels")
+	assert.Equal(t, *labels.MaxItems, 3)
+	assert.Equal(t, labels.CustomAnnotations["propertyNames"], map[string]interface{}{"pattern": "^[a-z]+$"})
+	assert.Equal(t, labels.Properties["app"].Type, StringOrArrayOfString{"string"})
+
+	for _, comment := range []string{
+		`# @schema-raw: [1]`,
+		`# @schema-raw: {"minLength": "x"}`,
+		`# @schema-raw: {"type": "map"}`,
+	} {
+		if _, _, err := GetSchemaFromComment(comment); err == nil {
+			t.Fatalf("Expected an error for %s", comment)
+		}
+	}
+}
+
+type teamVocabulary struct{}
+
+func (teamVocabulary) Keywords() []string { return []string{"team"} }
+
+func (teamVocabulary) Apply(_ string, value *yaml.Node, s *Schema) error {
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("the team must be a string, not %s", value.Tag)
+	}
+	if s.CustomAnnotations == nil {
+		s.CustomAnnotations = make(map[string]interface{})
+	}
+	s.CustomAnnotations["x-team"] = value.Value
+	s.Description = strings.TrimSpace(s.Description + " (owned by " + value.Value + ")")
+	return nil
+}
+
+func TestVocabulary(t *testing.T) {
+	if err := RegisterVocabulary(teamVocabulary{}); err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	t.Cleanup(func() { UnregisterVocabulary(teamVocabulary{}) })
+
+	s, _, err := GetSchemaFromComment("# @schema\n# team: platform\n# description: The image\n# @schema")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, s.CustomAnnotations["x-team"], "platform")
+	assert.Equal(t, s.Description, "The image (owned by platform)")
+
+	if _, _, err := GetSchemaFromComment("# @schema\n# team: [a, b]\n# @schema"); err == nil {
+		t.Fatal("Expected an error for the invalid team")
+	}
+	if err := RegisterVocabulary(teamVocabulary{}); err == nil {
+		t.Fatal("Expected an error for the registered keyword")
+	}
+	for _, keyword := range []string{"type", "skip", UIAnnotation} {
+		if err := RegisterVocabulary(keywordVocabulary(keyword)); err == nil {
+			t.Fatalf("Expected an error for the reserved keyword %s", keyword)
+		}
+	}
+}
+
+type keywordVocabulary string
+
+func (v keywordVocabulary) Keywords() []string { return []string{string(v)} }
+
+func (keywordVocabulary) Apply(string, *yaml.Node, *Schema) error { return nil }
+
+func TestPolicy(t *testing.T) {
+	dir := t.TempDir()
+	policyFile := filepath.Join(dir, "policy.yaml")
+	content := `rules: [closed-objects, descriptions]
+require:
+  "*.tag": [pattern]
+commands:
+  - 'test "$HELM_SCHEMA_CHART_NAME" = web || echo "wrong chart"'
+  - 'echo "image must be titled"; echo "tag too"; exit 1'
+`
+	if err := os.WriteFile(policyFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := ReadPolicy(policyFile)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	result := &Result{Chart: &chart.ChartFile{Name: "web"}, Schema: *NewSchema("object")}
+	result.Schema.AdditionalProperties = new(bool)
+	image := NewSchema("object")
+	image.Description = "The image"
+	image.SetProperty("tag", &Schema{Type: []string{"string"}, Description: "The tag"})
+	image.SetProperty("repository", &Schema{Type: []string{"string"}, Pattern: "^[a-z]+$"})
+	result.Schema.SetProperty("image", image)
+
+	violations, err := policy.Evaluate(result)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	messages := []string{}
+	for _, violation := range violations {
+		messages = append(messages, violation.String())
+	}
+	command := policy.Commands[1]
+	assert.Equal(t, messages, []string{
+		"closed-objects: image: the object allows additional properties",
+		"require: image.tag: the property doesn't set pattern",
+		"descriptions: image.repository: the property has no description",
+		command + ": image must be titled",
+		command + ": tag too",
+	})
+
+	// the violations of properties acknowledged in the values file aren't returned
+	result.ValuesPath = filepath.Join(dir, "values.yaml")
+	values := "# helm-schema:ignore closed-objects\nimage:\n  tag: v1 # helm-schema:ignore require\n  repository: nginx\n"
+	if err := os.WriteFile(result.ValuesPath, []byte(values), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	policy.Commands = nil
+	violations, err = policy.Evaluate(result)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, violations, []PolicyViolation{{Rule: PolicyRuleDescriptions, Path: "image.repository", Message: "the property has no description"}})
+
+	for _, content := range []string{"rules: [unknown]", "unknown: true", "require:\n  \"[\": [pattern]"} {
+		if err := os.WriteFile(policyFile, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadPolicy(policyFile); err == nil {
+			t.Errorf("Expected an error for the policy %s", content)
+		}
+	}
+}
+
+func TestValidationsAnnotation(t *testing.T) {
+	s, _, err := GetSchemaFromComment(`# @schema
+# validations:
+#   - rule: self.minReplicas <= self.maxReplicas
+#     message: minReplicas must not exceed maxReplicas
+#     reason: FieldValueInvalid
+#   - "!self.enabled || self.maxReplicas > 0"
+# @schema`)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"rule": "self.minReplicas <= self.maxReplicas", "message": "minReplicas must not exceed maxReplicas", "reason": "FieldValueInvalid"},
+		map[string]interface{}{"rule": "!self.enabled || self.maxReplicas > 0"},
+	}
+	assert.Equal(t, s.CustomAnnotations[KubernetesValidationsAnnotation], expected)
+
+	root := NewSchema("object")
+	root.SetProperty("autoscaling", &s)
+	root.RenameValidations("x-cel-rules")
+	assert.Equal(t, root.Properties["autoscaling"].CustomAnnotations["x-cel-rules"], expected)
+	_, ok := root.Properties["autoscaling"].CustomAnnotations[KubernetesValidationsAnnotation]
+	assert.Equal(t, ok, false)
+
+	for _, validations := range []string{
+		"validations: ''",
+		"validations: [{message: no rule}]",
+		"validations: [{rule: self > 0, reason: Unknown}]",
+		"validations: [{rule: self > 0, severity: high}]",
+	} {
+		if _, _, err := GetSchemaFromComment("# @schema\n# " + validations + "\n# @schema"); err == nil {
+			t.Errorf("Expected an error for %s", validations)
+		}
+	}
+}
+
+func TestValidateChartFile(t *testing.T) {
+	tests := []struct {
+		content    string
+		violations []ValuesError
+	}{
+		{
+			content: "apiVersion: v2\nname: app\nversion: 1.2.3-rc.1\ntype: library\ndependencies:\n  - name: redis\n    alias: cache\n    import-values: [data, {child: a, parent: b}]\nannotations:\n  category: Database\n",
+		},
+		{
+			content: "apiVersion: v1\nname: legacy\nversion: v1.0\nappVersion: 1.0\n",
+		},
+		{
+			content:    "apiVersion: v2\nname: app\nversion: 1.10\n",
+			violations: []ValuesError{{Pointer: "/version", Message: "got number, want string"}},
+		},
+		{
+			content:    "apiVersion: v2\nname: app\nversion: 1.0.0\ndependencies:\n  - name: redis\n    alias: my.cache\n",
+			violations: []ValuesError{{Pointer: "/dependencies/0/alias", Message: "'my.cache' does not match pattern '^[a-zA-Z0-9_-]+$'"}},
+		},
+		{
+			content:    "apiVersion: v1\nname: legacy\nversion: 1.0.0\ndependencies: []\n",
+			violations: []ValuesError{{Pointer: "", Message: "additional properties 'dependencies' not allowed"}},
+		},
+		{
+			content:    "apiVersion: v3\nname: app\nversion: 1.0.0\n",
+			violations: []ValuesError{{Pointer: "/apiVersion", Message: "value must be one of 'v1', 'v2'"}},
+		},
+		{
+			content:    "apiVersion: v2\nname: app\n",
+			violations: []ValuesError{{Pointer: "", Message: "missing property 'version'"}},
+		},
+	}
+
+	for _, test := range tests {
+		err := ValidateChartFile([]byte(test.content))
+		if test.violations == nil {
+			if err != nil {
+				t.Errorf("Wasn't expecting an error for %q, but got this: %v", test.content, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("Was expecting an error for %q", test.content)
+			continue
+		}
+		assert.Equal(t, ValuesErrors(err), test.violations)
+	}
+
+	if _, err := ChartFileSchema("v3"); err == nil {
+		t.Errorf("Was expecting an error for an unsupported apiVersion")
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	s, err := GenerateSchema("", []byte(`# @schema
+# title: Replicas
+# @schema
+# -- number of replicas
+replicas: 1
+image:
+  # -- the repository
+  repository: nginx
+  tag: v1
+ports:
+  - name: http
+    port: 80
+redis:
+  enabled: true
+`), false, false, false, false, false, &SkipAutoGenerationConfig{Title: true})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	// the list of ports is a single value, the annotated replicas have no inferred type
+	coverage := s.Coverage("redis", "global")
+	assert.Equal(t, coverage.Values, 4)
+	assert.Equal(t, coverage.Described, 2)
+	assert.Equal(t, coverage.Typed, 3)
+	assert.Equal(t, coverage.WithTitles, 1)
+	assert.Equal(t, coverage.Description, 50.0)
+	assert.Equal(t, coverage.Type, 75.0)
+	assert.Equal(t, coverage.Title, 25.0)
+
+	assert.Equal(t, NewSchema("object").Coverage().Description, 100.0)
+}
+
+func TestSuppressions(t *testing.T) {
+	suppressions, err := ReadSuppressions([]byte(`# helm-schema:ignore unused
+
+# helm-schema:ignore descriptions types
+legacy:
+  enabled: true
+image:
+  # -- the tag
+  # helm-schema:ignore pattern,unused
+  tag: v1
+  pullPolicy: Always # helm-schema:ignore descriptions
+  # helm-schema:ignored types
+  repository: nginx
+`))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, suppressions, Suppressions{
+		"legacy":           {"descriptions", "types"},
+		"image.tag":        {"pattern", "unused"},
+		"image.pullPolicy": {"descriptions"},
+	})
+	assert.Equal(t, suppressions.Suppressed("legacy.enabled", "types"), true)
+	assert.Equal(t, suppressions.Suppressed("legacy", "unused"), false)
+	assert.Equal(t, suppressions.Suppressed("image.tag", "unused"), true)
+	assert.Equal(t, suppressions.Suppressed("image", "unused"), false)
+
+	// ignore comments aren't part of the description
+	_, description, err := GetSchemaFromComment("# -- the tag\n# helm-schema:ignore descriptions")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, description, "-- the tag")
+}
+
+func TestInconsistencies(t *testing.T) {
+	results := []*Result{}
+	for _, chartValues := range []struct {
+		name   string
+		values string
+	}{
+		{"api", `image:
+  # @schema
+  # enum: [Always, Never]
+  # @schema
+  pullPolicy: Always
+replicas: 1.5
+ports: 80
+`},
+		{"web", `image:
+  # @schema
+  # enum: [Always, IfNotPresent]
+  # @schema
+  pullPolicy: Always
+replicas: 1
+ports: [80]
+redis:
+  enabled: true
+`},
+	} {
+		name := chartValues.name
+		s, err := GenerateSchema("", []byte(chartValues.values), false, false, false, false, false, &SkipAutoGenerationConfig{})
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		chartFile := &chart.ChartFile{Name: name, Version: "1.0.0"}
+		if name == "web" {
+			chartFile.Dependencies = []*chart.Dependency{{Name: "redis"}}
+		}
+		results = append(results, &Result{ChartPath: name + "/Chart.yaml", Chart: chartFile, Schema: *s})
+	}
+
+	// integers are numbers and the values of the dependencies aren't compared
+	inconsistencies, shared, err := Inconsistencies(results)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	assert.Equal(t, shared, 5)
+	assert.Equal(t, len(inconsistencies), 2)
+	assert.Equal(t, inconsistencies[0].Path, "image.pullPolicy")
+	assert.Equal(t, inconsistencies[0].Rule, InconsistencyEnum)
+	assert.Equal(t, inconsistencies[0].Charts[0].Enum, []string{"Always", "Never"})
+	assert.Equal(t, inconsistencies[0].Charts[1].Enum, []string{"Always", "IfNotPresent"})
+	assert.Equal(t, inconsistencies[1].Path, "ports")
+	assert.Equal(t, inconsistencies[1].Rule, InconsistencyType)
+	assert.Equal(t, inconsistencies[1].Charts[0].Types, []string{"integer"})
+	assert.Equal(t, inconsistencies[1].Charts[1].Types, []string{"array"})
+}
+
+func TestStats(t *testing.T) {
+	s, err := GenerateSchema("", []byte(`# @schema
+# required: true
+# @schema
+replicas: 1
+image:
+  # @schema
+  # required: true
+  # @schema
+  repository: nginx
+  tag: ~
+ports:
+  - name: http
+    port: 80
+`), false, false, false, false, false, &SkipAutoGenerationConfig{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	// the properties of the items are counted, but the list is a single leaf like global, the annotated values
+	// have no inferred type
+	stats := s.Stats()
+	assert.Equal(t, stats.Properties, 8)
+	assert.Equal(t, stats.Depth, 2)
+	assert.Equal(t, stats.Required, 7)
+	assert.Equal(t, stats.Leaves, 5)
+	assert.Equal(t, stats.Typed, 3)
+	assert.Equal(t, stats.Untyped, 2)
+}
+
+// benchmarkValues returns a values file with the given number of services, each one has 15 lines with comments,
+// nested mappings and lists like the values of real charts
+func benchmarkValues(services int) []byte {
+	var values strings.Builder
+	for i := 0; i < services; i++ {
+		fmt.Fprintf(&values, `# -- the service %d
+service%d:
+  # -- whether the service is deployed
+  enabled: true
+  image:
+    # -- the repository of the image
+    repository: nginx
+    tag: "1.%d"
+    pullPolicy: IfNotPresent
+  ports:
+    - name: http
+      port: 80
+  resources: {}
+  annotations:
+    team: platform
+`, i, i, i)
+	}
+	return []byte(values.String())
+}
+
+// BenchmarkGenerateSchema generates the schemas of values files with up to 30k lines. The allocated bytes per
+// line (B/line) stay the same for all sizes, so the memory grows linearly with the values file.
+func BenchmarkGenerateSchema(b *testing.B) {
+	for _, services := range []int{100, 500, 2000} {
+		content := benchmarkValues(services)
+		lines := bytes.Count(content, []byte("\n"))
+		b.Run(fmt.Sprintf("%d lines", lines), func(b *testing.B) {
+			b.ReportAllocs()
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			for i := 0; i < b.N; i++ {
+				if _, err := GenerateSchema("", content, false, false, false, false, false, &SkipAutoGenerationConfig{}); err != nil {
+					b.Fatalf("Wasn't expecting an error, but got this: %v", err)
+				}
+			}
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/float64(b.N*lines), "B/line")
+		})
+	}
+}
+
+// BenchmarkGenerateSchemaWideMapping generates the schema of a mapping with 20k keys, whose properties and
+// required keys are looked up in sets instead of searching them
+func BenchmarkGenerateSchemaWideMapping(b *testing.B) {
+	var values strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&values, "key%d: value\n", i)
+	}
+	content := []byte(values.String())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s, err := GenerateSchema("", content, false, false, false, false, false, &SkipAutoGenerationConfig{})
+		if err != nil {
+			b.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		if len(s.PropertyNames()) != 20001 {
+			b.Fatalf("Expected 20001 properties, but got %d", len(s.PropertyNames()))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package schema
+
+import (
+	"regexp"
+	"slices"
+)
+
+// SensitiveAnnotation is the custom annotation marking sensitive values
+const SensitiveAnnotation = CustomAnnotationPrefix + "sensitive"
+
+var (
+	// sensitiveKey matches the names of keys, which likely contain a secret
+	sensitiveKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|api[-_]?key|private[-_]?key|credential)`)
+	// sensitiveKeyException matches the names of keys, which reference a secret instead of containing it
+	sensitiveKeyException = regexp.MustCompile(`(?i)(^existing|(name|ref|file|path)$)`)
+)
+
+// MaskSensitive removes the defaults of the sensitive properties, marks them as writeOnly and adds the
+// x-sensitive annotation, so default passwords or tokens of the values file don't end up in published
+// schemas. Properties are sensitive, if they are annotated with sensitive: true (or x-sensitive: true).
+// With detect, properties with names like password, secret or token are sensitive as well, unless they
+// are annotated with sensitive: false or reference a secret (e.g. existingSecret or tokenSecretName).
+// It returns the number of masked properties.
+func (s *Schema) MaskSensitive(detect bool) int {
+	count := 0
+	s.Walk(func(subSchema *Schema) {
+		for name, property := range subSchema.Properties {
+			if property == nil || !property.isSensitive(name, detect) {
+				continue
+			}
+			property.Default = nil
+			property.WriteOnly = true
+			if property.CustomAnnotations == nil {
+				property.CustomAnnotations = make(map[string]interface{})
+			}
+			property.CustomAnnotations[SensitiveAnnotation] = true
+			count++
+		}
+	})
+	return count
+}
+
+func (s *Schema) isSensitive(name string, detect bool) bool {
+	if s.Sensitive != nil {
+		return *s.Sensitive
+	}
+	if sensitive, ok := s.CustomAnnotations[SensitiveAnnotation].(bool); ok {
+		return sensitive
+	}
+	if !detect || !sensitiveKey.MatchString(name) || sensitiveKeyException.MatchString(name) {
+		return false
+	}
+	// objects and lists of secrets (e.g. secrets: {}) have no default to hide
+	return !slices.Contains(s.Type, "object") && !slices.Contains(s.Type, "array")
+}
@@ -0,0 +1,170 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// SidecarAnnotationsFile contains the annotations of values which can't be annotated
+// in the values file itself. It's read from the directory of the values file.
+const SidecarAnnotationsFile = "values.schema.annotations.yaml"
+
+// ReadSidecarAnnotations returns the content of the sidecar annotations file
+// next to the values file or nil if there is none
+func ReadSidecarAnnotations(valuesPath string) ([]byte, error) {
+	if valuesPath == "" {
+		// the values weren't read from a file (e.g. from stdin)
+		return nil, nil
+	}
+	content, err := os.ReadFile(filepath.Join(filepath.Dir(valuesPath), SidecarAnnotationsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return content, err
+}
+
+// ApplySidecarAnnotations merges the annotations of the sidecar file into the comments of the values.
+// The keys of the sidecar file are either dotted paths (image.tag) or json pointers (/image/tag),
+// the values are the keywords of the annotation. They override the keywords of inline annotations.
+func ApplySidecarAnnotations(node *yaml.Node, content []byte) error {
+	var sidecar yaml.Node
+	if err := yaml.Unmarshal(content, &sidecar); err != nil {
+		return fmt.Errorf("could not read %s: %w", SidecarAnnotationsFile, err)
+	}
+	if len(sidecar.Content) == 0 {
+		return nil
+	}
+	entries := sidecar.Content[0]
+	if entries.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s must contain a mapping of paths to annotations", SidecarAnnotationsFile)
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		node = node.Content[0]
+	}
+
+	for i := 0; i+1 < len(entries.Content); i += 2 {
+		path := entries.Content[i].Value
+		var keywords map[string]interface{}
+		if err := entries.Content[i+1].Decode(&keywords); err != nil {
+			return fmt.Errorf("invalid annotation of %s in %s: %w", path, SidecarAnnotationsFile, err)
+		}
+
+		keyNode := findKeyNode(node, sidecarPath(path))
+		if keyNode == nil {
+			log.Warnf("The path %s of %s doesn't exist in the values", path, SidecarAnnotationsFile)
+			continue
+		}
+		if err := mergeAnnotation(keyNode, keywords); err != nil {
+			return fmt.Errorf("could not merge the annotation of %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// sidecarPath splits the dotted path or json pointer into its segments
+func sidecarPath(path string) []string {
+	if !strings.HasPrefix(path, "/") {
+		return strings.Split(path, ".")
+	}
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+	}
+	return segments
+}
+
+// findKeyNode returns the key node of the path or nil if it doesn't exist.
+// Items of sequences are selected by their index.
+func findKeyNode(node *yaml.Node, path []string) *yaml.Node {
+	var keyNode *yaml.Node
+	for _, segment := range path {
+		if node == nil {
+			return nil
+		}
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+		switch node.Kind {
+		case yaml.MappingNode:
+			var valueNode *yaml.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == segment {
+					keyNode, valueNode = node.Content[i], node.Content[i+1]
+					break
+				}
+			}
+			if valueNode == nil {
+				return nil
+			}
+			node = valueNode
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node.Content) {
+				return nil
+			}
+			// the items itself have no key, which could be annotated
+			keyNode, node = nil, node.Content[index]
+		default:
+			return nil
+		}
+	}
+	return keyNode
+}
+
+// mergeAnnotation merges the keywords into the @schema block of the head comment of the key
+func mergeAnnotation(keyNode *yaml.Node, keywords map[string]interface{}) error {
+	description := []string{}
+	rawSchema := []string{}
+	insideSchemaBlock := false
+	if keyNode.HeadComment != "" {
+		for _, line := range strings.Split(keyNode.HeadComment, "\n") {
+			if isSchemaDelimiter(line) {
+				insideSchemaBlock = !insideSchemaBlock
+				continue
+			}
+			if insideSchemaBlock {
+				content := strings.TrimPrefix(line, CommentPrefix)
+				rawSchema = append(rawSchema, strings.TrimPrefix(strings.TrimPrefix(content, CommentPrefix), " "))
+			} else {
+				description = append(description, line)
+			}
+		}
+	}
+
+	merged := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(strings.Join(rawSchema, "\n")), &merged); err != nil {
+		return err
+	}
+	if merged == nil {
+		merged = make(map[string]interface{})
+	}
+	for key, value := range keywords {
+		merged[key] = value
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(merged); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	// the block is appended, so it isn't cut off with the leading comments
+	lines := append(description, SchemaPrefix)
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		lines = append(lines, CommentPrefix+" "+line)
+	}
+	keyNode.HeadComment = strings.Join(append(lines, SchemaPrefix), "\n")
+	return nil
+}
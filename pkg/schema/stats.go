@@ -0,0 +1,45 @@
+package schema
+
+// Stats are the statistics of a schema
+type Stats struct {
+	// Properties counts the properties at all levels, including the ones of the items of lists and of compositions
+	Properties int `json:"properties"`
+	// Depth is the deepest level of nested properties, 1 for a schema with only top-level properties
+	Depth int `json:"depth"`
+	// Required counts the required properties at all levels
+	Required int `json:"required"`
+	// Leaves counts the leaf values like Coverage does, Typed and Untyped split them by their types
+	Leaves  int `json:"leaves"`
+	Typed   int `json:"typed"`
+	Untyped int `json:"untyped"`
+}
+
+// Stats returns the statistics of the schema
+func (s *Schema) Stats() Stats {
+	coverage := s.Coverage()
+	stats := Stats{Leaves: coverage.Values, Typed: coverage.Typed, Untyped: coverage.Values - coverage.Typed}
+	s.addStats(&stats, 0)
+	return stats
+}
+
+// addStats counts the properties of the schema at the level and the ones below it
+func (s *Schema) addStats(stats *Stats, level int) {
+	if s == nil {
+		return
+	}
+	stats.Required += len(s.Required.Strings)
+	if len(s.Properties) > 0 && level+1 > stats.Depth {
+		stats.Depth = level + 1
+	}
+	for _, name := range s.PropertyNames() {
+		stats.Properties++
+		s.Properties[name].addStats(stats, level+1)
+	}
+	// the items of a list and the compositions are on the level of the schema
+	s.Items.addStats(stats, level)
+	for _, compositions := range [][]*Schema{s.AnyOf, s.OneOf, s.AllOf} {
+		for _, composition := range compositions {
+			composition.addStats(stats, level)
+		}
+	}
+}
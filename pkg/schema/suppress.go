@@ -0,0 +1,95 @@
+package schema
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IgnoreCommentPrefix starts a comment of a key in the values file, which suppresses the findings of the rules
+// with the IDs following it (e.g. # helm-schema:ignore descriptions, unused) for the value and the values below it
+const IgnoreCommentPrefix = "helm-schema:ignore"
+
+// Suppressions maps the dotted paths of values (e.g. image.tag) to the IDs of the rules suppressed for them
+type Suppressions map[string][]string
+
+// ReadSuppressions returns the rules suppressed by ignore comments (see IgnoreCommentPrefix) in the values file.
+// The comments are either on the line of the key or directly above it.
+func ReadSuppressions(content []byte) (Suppressions, error) {
+	values, err := ParseValues(content)
+	if err != nil {
+		return nil, err
+	}
+	suppressions := make(Suppressions)
+	for _, document := range values.Content {
+		suppressions.collect(document, nil)
+	}
+	return suppressions, nil
+}
+
+// Suppressions returns the suppressions of the values file of the result, there are none without one
+func (r *Result) Suppressions() (Suppressions, error) {
+	if r.ValuesPath == "" {
+		return Suppressions{}, nil
+	}
+	content, err := os.ReadFile(r.ValuesPath)
+	if err != nil {
+		return nil, err
+	}
+	return ReadSuppressions(content)
+}
+
+// collect adds the suppressions of the keys of the mapping and the mappings below it
+func (s Suppressions) collect(node *yaml.Node, path []string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		keyPath := append(slices.Clone(path), keyNode.Value)
+
+		// only the paragraph directly above the key belongs to it
+		headComment := keyNode.HeadComment
+		if paragraph := strings.LastIndex(headComment, "\n\n"); paragraph >= 0 {
+			headComment = headComment[paragraph+2:]
+		}
+		comments := strings.Split(headComment, "\n")
+		comments = append(comments, keyNode.LineComment, valueNode.LineComment)
+		for _, comment := range comments {
+			if rules, ok := ignoredRules(comment); ok {
+				dotted := strings.Join(keyPath, ".")
+				s[dotted] = append(s[dotted], rules...)
+			}
+		}
+		s.collect(valueNode, keyPath)
+	}
+}
+
+// Suppressed returns true if the rule is suppressed for the value with the dotted path or one of its parents
+func (s Suppressions) Suppressed(path, rule string) bool {
+	for {
+		if slices.Contains(s[path], rule) {
+			return true
+		}
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			return false
+		}
+		path = path[:i]
+	}
+}
+
+// ignoredRules returns the IDs of the rules of an ignore comment, the IDs are separated by commas or spaces
+func ignoredRules(comment string) ([]string, bool) {
+	comment = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(comment), CommentPrefix))
+	rest, found := strings.CutPrefix(comment, IgnoreCommentPrefix)
+	if !found || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return nil, false
+	}
+	rules := strings.FieldsFunc(rest, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	return rules, true
+}
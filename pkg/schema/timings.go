@@ -0,0 +1,24 @@
+package schema
+
+import "time"
+
+// Timings are the durations of the phases of generating the schema of a chart
+type Timings struct {
+	// Read is the duration of reading the Chart.yaml, the values and the optional files of the chart
+	Read time.Duration
+	// Parse is the duration of parsing the values and their annotations
+	Parse time.Duration
+	// Infer is the duration of generating the schema from the parsed values
+	Infer time.Duration
+	// Merge is the duration of adding the schemas of the dependencies
+	Merge time.Duration
+	// PostProcess is the duration of the changes of the schema after merging (e.g. flattening the $refs)
+	PostProcess time.Duration
+	// Write is the duration of serializing and writing the schema
+	Write time.Duration
+}
+
+// Total returns the sum of the durations of all phases
+func (t Timings) Total() time.Duration {
+	return t.Read + t.Parse + t.Infer + t.Merge + t.PostProcess + t.Write
+}
@@ -2,25 +2,48 @@ package schema
 
 import (
 	"fmt"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
-	"github.com/ojsef39/helm-schema/pkg/chart"
 	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/ojsef39/helm-schema/pkg/chart"
 )
 
-// TopoSort uses topological sorting to sort the results
-func TopoSort(results []*Result) ([]*Result, error) {
+// DependencyLookup returns the packaged chart of a dependency of the chart of the result, which isn't among the
+// results, or nil if there's none
+type DependencyLookup func(result *Result, dep *chart.Dependency) (*PackagedDependency, error)
+
+// TopoSort uses topological sorting to sort the results. The packaged dependencies are looked up with
+// ReadPackagedDependency, if findPackaged is nil.
+func TopoSort(results []*Result, findPackaged DependencyLookup) ([]*Result, error) {
+	if findPackaged == nil {
+		findPackaged = func(result *Result, dep *chart.Dependency) (*PackagedDependency, error) {
+			return ReadPackagedDependency(filepath.Dir(result.ChartPath), dep)
+		}
+	}
 	// Map result identifier to result
 	lookup := make(map[string][]*Result)
 
 	// Map result identifier to dependencies identifiers
 	todo := make(map[string]mapset.Set[chart.Dependency])
 
+	chartNames := make(map[string]bool)
+	for _, result := range results {
+		chartNames[result.Chart.Name] = true
+	}
+
 	// Create the work queue
 	for _, result := range results {
 		dependencies := mapset.NewSet[chart.Dependency]()
 		for _, dep := range result.Chart.Dependencies {
+			// the schemas of packaged dependencies aren't generated, so they don't need to be sorted
+			if !chartNames[dep.Name] {
+				if packaged, err := findPackaged(result, dep); err == nil && packaged != nil {
+					continue
+				}
+			}
 			dependencies.Add(*dep)
 		}
 		resultId := fmt.Sprintf("%s|%s", result.Chart.Name, result.Chart.Version)
@@ -43,15 +66,18 @@ func TopoSort(results []*Result) ([]*Result, error) {
 		// if no items are ready, we are stuck
 		if ready.Cardinality() == 0 {
 			// append unsorted to sorted items and return them
-			for name := range todo {
+			for _, name := range sortedKeys(todo) {
 				sorted = append(sorted, lookup[name]...)
 			}
 
-			return sorted, &CircularError{fmt.Sprintf("circular or missing dependency found: %v - Please build and untar all your helm dependencies: helm dep build && ls charts/*.tgz |xargs -n1 tar -C charts/ -xzf", todo)}
+			return sorted, newCircularError(lookup, todo)
 		}
 
 		// remove ready items from todo list and add to sorted list
-		for name := range ready.Iter() {
+		// the items are sorted by name, so the result is deterministic
+		readyNames := ready.ToSlice()
+		slices.Sort(readyNames)
+		for _, name := range readyNames {
 			delete(todo, name)
 			sorted = append(sorted, lookup[name]...)
 
@@ -81,3 +107,126 @@ func TopoSort(results []*Result) ([]*Result, error) {
 	}
 	return sorted, nil
 }
+
+// newCircularError describes why the remaining charts can't be sorted: a cycle of
+// dependencies between them or dependencies, which aren't satisfied by any chart
+func newCircularError(lookup map[string][]*Result, todo map[string]mapset.Set[chart.Dependency]) *CircularError {
+	remaining := sortedKeys(todo)
+	err := &CircularError{}
+
+	// edges between the remaining charts
+	edges := make(map[string][]string)
+	var missing []string
+	for _, id := range remaining {
+		deps := todo[id].ToSlice()
+		slices.SortFunc(deps, func(a, b chart.Dependency) int {
+			return strings.Compare(a.Name+"|"+a.Version, b.Name+"|"+b.Version)
+		})
+		for _, dep := range deps {
+			targets := satisfyingIds(remaining, dep)
+			if len(targets) == 0 {
+				missing = append(missing, fmt.Sprintf("%s requires %s %s (%s)", chartName(id), dep.Name, dep.Version, chartPaths(lookup[id])))
+				err.addChartPaths(lookup[id])
+			}
+			edges[id] = append(edges[id], targets...)
+		}
+	}
+
+	if cycle := findCycle(remaining, edges); len(cycle) > 0 {
+		var paths []string
+		for _, id := range cycle[:len(cycle)-1] {
+			err.Cycle = append(err.Cycle, chartName(id))
+			paths = append(paths, chartPaths(lookup[id]))
+			err.addChartPaths(lookup[id])
+		}
+		err.Cycle = append(err.Cycle, chartName(cycle[len(cycle)-1]))
+		err.msg = fmt.Sprintf("circular dependency found: %s (%s)", strings.Join(err.Cycle, " -> "), strings.Join(paths, ", "))
+	}
+	if len(missing) > 0 {
+		if err.msg != "" {
+			err.msg += "; "
+		}
+		err.msg += fmt.Sprintf("missing dependency found: %s - Please build and untar all your helm dependencies: helm dep build && ls charts/*.tgz |xargs -n1 tar -C charts/ -xzf", strings.Join(missing, ", "))
+	}
+	if err.msg == "" {
+		err.msg = fmt.Sprintf("circular or missing dependency found: %v", todo)
+	}
+	return err
+}
+
+// satisfyingIds returns the identifiers of the charts matching the name and version constraint of the dependency
+func satisfyingIds(ids []string, dep chart.Dependency) []string {
+	c, err := semver.NewConstraint(dep.Version)
+	if err != nil {
+		return nil
+	}
+	var satisfying []string
+	for _, id := range ids {
+		nameVersion := strings.Split(id, "|")
+		if nameVersion[0] != dep.Name {
+			continue
+		}
+		if sem, err := semver.NewVersion(nameVersion[1]); err == nil && c.Check(sem) {
+			satisfying = append(satisfying, id)
+		}
+	}
+	return satisfying
+}
+
+// findCycle returns the first cycle found with a depth first search, the first
+// node is repeated at the end (e.g. a, b, a)
+func findCycle(ids []string, edges map[string][]string) []string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var stack []string
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, next := range edges[id] {
+			switch state[next] {
+			case visiting:
+				start := slices.Index(stack, next)
+				return append(slices.Clone(stack[start:]), next)
+			case 0:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = visited
+		return nil
+	}
+	for _, id := range ids {
+		if state[id] == 0 {
+			if cycle := visit(id); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+func chartName(id string) string {
+	return strings.Split(id, "|")[0]
+}
+
+func chartPaths(results []*Result) string {
+	var paths []string
+	for _, result := range results {
+		paths = append(paths, result.ChartPath)
+	}
+	return strings.Join(paths, ", ")
+}
+
+func (e *CircularError) addChartPaths(results []*Result) {
+	for _, result := range results {
+		if !slices.Contains(e.ChartPaths, result.ChartPath) {
+			e.ChartPaths = append(e.ChartPaths, result.ChartPath)
+		}
+	}
+}
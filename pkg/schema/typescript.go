@@ -0,0 +1,240 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var typeScriptIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// ToTypeScript converts the schema into a typescript definition exporting
+// an interface with the given name
+func (s *Schema) ToTypeScript(name string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by helm-schema. DO NOT EDIT.\n\n")
+	writeTypeScriptDoc(&buf, s, "")
+
+	if s.isObject() && !s.hasSubSchemas() {
+		fmt.Fprintf(&buf, "export interface %s %s\n", name, s.typeScriptType(""))
+	} else {
+		fmt.Fprintf(&buf, "export type %s = %s;\n", name, s.typeScriptType(""))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// TypeName converts the name of a chart into an exported type name (e.g. my-chart -> MyChartValues)
+func TypeName(chartName string) string {
+	name := pascalCase(chartName)
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		name = "Chart" + name
+	}
+	return name + "Values"
+}
+
+// pascalCase removes all characters which aren't letters or digits and
+// uppercases the first letter of every word
+func pascalCase(name string) string {
+	var result strings.Builder
+	upperNext := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upperNext = true
+			continue
+		}
+		if upperNext {
+			r = unicode.ToUpper(r)
+			upperNext = false
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
+
+func (s *Schema) isObject() bool {
+	return len(s.Type) == 1 && s.Type[0] == "object"
+}
+
+func (s *Schema) hasSubSchemas() bool {
+	return len(s.AnyOf) > 0 || len(s.OneOf) > 0 || len(s.AllOf) > 0
+}
+
+// typeScriptType returns the typescript type of the schema. Nested
+// object literals are indented with the given indent.
+func (s *Schema) typeScriptType(indent string) string {
+	if s == nil {
+		return "unknown"
+	}
+
+	if s.Const != nil {
+		return typeScriptLiteral(s.Const)
+	}
+	if len(s.Enum) > 0 {
+		literals := make([]string, len(s.Enum))
+		for i, value := range s.Enum {
+			literals[i] = s.typeScriptEnumLiteral(value)
+		}
+		return strings.Join(literals, " | ")
+	}
+
+	var tsType string
+	switch {
+	case len(s.AnyOf) > 0:
+		tsType = s.typeScriptSubSchemas(s.AnyOf, " | ", indent)
+	case len(s.OneOf) > 0:
+		tsType = s.typeScriptSubSchemas(s.OneOf, " | ", indent)
+	case len(s.Type) > 0:
+		types := make([]string, len(s.Type))
+		for i, schemaType := range s.Type {
+			types[i] = s.typeScriptPrimitive(schemaType, indent)
+		}
+		tsType = strings.Join(types, " | ")
+	default:
+		tsType = "unknown"
+	}
+
+	if len(s.AllOf) > 0 {
+		intersection := s.typeScriptSubSchemas(s.AllOf, " & ", indent)
+		if tsType == "unknown" {
+			return intersection
+		}
+		return "(" + tsType + ") & " + intersection
+	}
+	return tsType
+}
+
+func (s *Schema) typeScriptSubSchemas(subSchemas []*Schema, separator, indent string) string {
+	types := make([]string, len(subSchemas))
+	for i, subSchema := range subSchemas {
+		types[i] = subSchema.typeScriptType(indent)
+		if strings.Contains(types[i], " ") && !strings.HasPrefix(types[i], "{") {
+			types[i] = "(" + types[i] + ")"
+		}
+	}
+	return strings.Join(types, separator)
+}
+
+func (s *Schema) typeScriptPrimitive(schemaType, indent string) string {
+	switch schemaType {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "null":
+		return "null"
+	case "array":
+		if s.Items == nil {
+			return "unknown[]"
+		}
+		itemType := s.Items.typeScriptType(indent)
+		if strings.Contains(itemType, " ") && !strings.HasPrefix(itemType, "{") {
+			return "Array<" + itemType + ">"
+		}
+		return itemType + "[]"
+	case "object":
+		return s.typeScriptObject(indent)
+	default:
+		return "unknown"
+	}
+}
+
+func (s *Schema) typeScriptObject(indent string) string {
+	additionalType := ""
+	switch additionalProperties := s.AdditionalProperties.(type) {
+	case *Schema:
+		additionalType = additionalProperties.typeScriptType(indent + "  ")
+	case bool:
+		if additionalProperties {
+			additionalType = "unknown"
+		}
+	case nil:
+		additionalType = "unknown"
+	}
+
+	if len(s.Properties) == 0 {
+		if additionalType == "" {
+			return "{}"
+		}
+		return "{ [key: string]: " + additionalType + " }"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	for _, name := range s.PropertyNames() {
+		property := s.Properties[name]
+		if property == nil {
+			continue
+		}
+		writeTypeScriptDoc(&buf, property, indent+"  ")
+
+		key := name
+		if !typeScriptIdentifier.MatchString(name) {
+			quoted, _ := json.Marshal(name)
+			key = string(quoted)
+		}
+		optional := "?"
+		if property.Required.Bool || slices.Contains(s.Required.Strings, name) {
+			optional = ""
+		}
+		fmt.Fprintf(&buf, "%s  %s%s: %s;\n", indent, key, optional, property.typeScriptType(indent+"  "))
+	}
+	if additionalType != "" {
+		// the declared properties must be assignable to the index signature
+		fmt.Fprintf(&buf, "%s  [key: string]: unknown;\n", indent)
+	}
+	buf.WriteString(indent + "}")
+	return buf.String()
+}
+
+func typeScriptLiteral(value interface{}) string {
+	literal, err := json.Marshal(value)
+	if err != nil {
+		return "unknown"
+	}
+	return string(literal)
+}
+
+// typeScriptEnumLiteral returns the literal of the enum value. The enum values
+// are stored as strings, so they are only quoted if the schema allows strings.
+func (s *Schema) typeScriptEnumLiteral(value string) string {
+	if value == "null" {
+		return "null"
+	}
+	if !slices.Contains(s.Type, "string") && json.Valid([]byte(value)) {
+		return value
+	}
+	return typeScriptLiteral(value)
+}
+
+// writeTypeScriptDoc writes the description, default and deprecation of the schema as jsdoc comment
+func writeTypeScriptDoc(buf *bytes.Buffer, s *Schema, indent string) {
+	lines := []string{}
+	if s.Description != "" {
+		lines = append(lines, strings.Split(strings.TrimSpace(s.Description), "\n")...)
+	}
+	if s.Default != nil {
+		if defaultJSON, err := json.Marshal(s.Default); err == nil {
+			lines = append(lines, "@default "+string(defaultJSON))
+		}
+	}
+	if s.Deprecated {
+		lines = append(lines, "@deprecated")
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	buf.WriteString(indent + "/**\n")
+	for _, line := range lines {
+		line = strings.ReplaceAll(line, "*/", "*\\/")
+		fmt.Fprintf(buf, "%s * %s\n", indent, strings.TrimRight(line, " "))
+	}
+	buf.WriteString(indent + " */\n")
+}
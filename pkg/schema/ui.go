@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UIAnnotation is the key of the annotation with the hints for form generators
+const UIAnnotation = "ui"
+
+// UIAnnotationPrefix is the prefix of the custom annotations, which contain the hints for form generators
+const UIAnnotationPrefix = CustomAnnotationPrefix + "ui-"
+
+// The hints of the ui annotation
+const (
+	UIWidget      = "widget"
+	UIPlaceholder = "placeholder"
+	UIGroup       = "group"
+	UIOrder       = "order"
+	UIAdvanced    = "advanced"
+)
+
+// uiHints are the hints of the ui annotation, the ones not listed here can be set as x-ui-<hint> annotations
+var uiHints = []string{UIWidget, UIPlaceholder, UIGroup, UIOrder, UIAdvanced}
+
+// uiAnnotations returns the custom annotations of the hints of the ui annotation
+func uiAnnotations(node *yaml.Node) (map[string]interface{}, error) {
+	var hints map[string]interface{}
+	if err := node.Decode(&hints); err != nil {
+		return nil, fmt.Errorf("the %s annotation must be a mapping: %w", UIAnnotation, err)
+	}
+	annotations := make(map[string]interface{})
+	for hint, value := range hints {
+		valid := false
+		switch hint {
+		case UIWidget, UIPlaceholder, UIGroup:
+			_, valid = value.(string)
+		case UIOrder:
+			_, valid = value.(int)
+		case UIAdvanced:
+			_, valid = value.(bool)
+		default:
+			return nil, fmt.Errorf("unsupported %s hint %s, use one of %s or an %s<hint> annotation", UIAnnotation, hint, strings.Join(uiHints, ", "), UIAnnotationPrefix)
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid value of the %s hint %s: %v", UIAnnotation, hint, value)
+		}
+		annotations[UIAnnotationPrefix+hint] = value
+	}
+	return annotations, nil
+}
+
+// ToUISchema converts the x-ui-* annotations of the schema into a uiSchema of react-jsonschema-form.
+// Every x-ui-<hint> becomes ui:<hint> of the property, except the group and advanced hints, which
+// are written to ui:options, and the order, which sorts the properties in ui:order of the parent.
+func (s *Schema) ToUISchema() ([]byte, error) {
+	uiSchema := s.uiSchema()
+	if uiSchema == nil {
+		uiSchema = map[string]interface{}{}
+	}
+	content, err := json.MarshalIndent(uiSchema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+// uiSchema returns the uiSchema of the schema or nil if it has no hints
+func (s *Schema) uiSchema() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+	uiSchema := make(map[string]interface{})
+	options := make(map[string]interface{})
+	for key, value := range s.CustomAnnotations {
+		hint, ok := strings.CutPrefix(key, UIAnnotationPrefix)
+		if !ok {
+			continue
+		}
+		switch hint {
+		case UIGroup, UIAdvanced:
+			options[hint] = value
+		case UIOrder:
+			// the order is written to the parent
+		default:
+			uiSchema["ui:"+hint] = value
+		}
+	}
+	if len(options) > 0 {
+		uiSchema["ui:options"] = options
+	}
+
+	names := s.PropertyNames()
+	ordered := false
+	for _, name := range names {
+		property := s.Properties[name]
+		if property == nil {
+			continue
+		}
+		if _, ok := property.CustomAnnotations[UIAnnotationPrefix+UIOrder]; ok {
+			ordered = true
+		}
+		if propertyUISchema := property.uiSchema(); propertyUISchema != nil {
+			uiSchema[name] = propertyUISchema
+		}
+	}
+	if ordered {
+		// properties without an order keep their position after the ordered ones
+		slices.SortStableFunc(names, func(a, b string) int {
+			return cmp.Compare(s.Properties[a].uiOrder(), s.Properties[b].uiOrder())
+		})
+		uiSchema["ui:order"] = append(names, "*")
+	}
+	if items := s.Items.uiSchema(); items != nil {
+		uiSchema["items"] = items
+	}
+
+	if len(uiSchema) == 0 {
+		return nil
+	}
+	return uiSchema
+}
+
+// uiOrder returns the order hint of the schema, schemas without one are sorted last
+func (s *Schema) uiOrder() int {
+	if s != nil {
+		switch order := s.CustomAnnotations[UIAnnotationPrefix+UIOrder].(type) {
+		case int:
+			return order
+		case float64:
+			return int(order)
+		}
+	}
+	return math.MaxInt
+}
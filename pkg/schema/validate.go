@@ -0,0 +1,192 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidateValues validates the content of a values file against the schema.
+// The schemaURL is used to resolve relative references of the schema.
+func (s *Schema) ValidateValues(schemaURL string, content []byte) error {
+	schemaJSON, err := s.ToJson()
+	if err != nil {
+		return err
+	}
+	return ValidateValuesJSON(schemaJSON, schemaURL, content)
+}
+
+// ValidateValuesJSON validates the content of a values file against the given jsonschema document
+func ValidateValuesJSON(schemaJSON []byte, schemaURL string, content []byte) error {
+	return validateValuesJSON(schemaJSON, schemaURL, content, nil)
+}
+
+// ValidateUntrustedValuesJSON validates the content of a values file like ValidateValuesJSON against a jsonschema
+// document of an untrusted source (e.g. a request to the server). Its references can't load files or urls, only
+// the ones within the document and to the metaschemas are resolved.
+func ValidateUntrustedValuesJSON(schemaJSON []byte, schemaURL string, content []byte) error {
+	return validateValuesJSON(schemaJSON, schemaURL, content, rejectingLoader{})
+}
+
+// rejectingLoader refuses to load any document
+type rejectingLoader struct{}
+
+func (rejectingLoader) Load(url string) (any, error) {
+	return nil, fmt.Errorf("loading %s isn't allowed", url)
+}
+
+// validateValuesJSON validates the values against the jsonschema document, whose references are loaded with
+// the loader (nil for the default one reading files)
+func validateValuesJSON(schemaJSON []byte, schemaURL string, content []byte, loader jsonschema.URLLoader) error {
+	schemaDoc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	if err != nil {
+		return err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if loader != nil {
+		compiler.UseLoader(loader)
+	}
+	if err := compiler.AddResource(schemaURL, schemaDoc); err != nil {
+		return err
+	}
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return fmt.Errorf("could not compile the jsonschema: %w", err)
+	}
+
+	// convert the yaml into json values, like helm does before validating
+	var values interface{}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return err
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	valuesDoc, err := jsonschema.UnmarshalJSON(bytes.NewReader(valuesJSON))
+	if err != nil {
+		return err
+	}
+
+	return compiled.Validate(valuesDoc)
+}
+
+// ValidateMetaschema validates the schema against the metaschema of its draft (given by $schema), so
+// invalid keywords (e.g. "type": "map" passed through by an annotation) are found before it's written.
+// References can't always be resolved (e.g. the ones to other hosts), so only violations of the
+// metaschema and invalid patterns are returned as errors.
+func (s *Schema) ValidateMetaschema() error {
+	schemaJSON, err := s.ToJson()
+	if err != nil {
+		return err
+	}
+	schemaDoc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	if err != nil {
+		return err
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(metaschemaCheckURL, schemaDoc); err != nil {
+		return err
+	}
+	_, err = compiler.Compile(metaschemaCheckURL)
+	var validationErr *jsonschema.SchemaValidationError
+	var regexErr *jsonschema.InvalidRegexError
+	switch {
+	case errors.As(err, &validationErr):
+		violations := []string{}
+		if cause, ok := validationErr.Err.(*jsonschema.ValidationError); ok {
+			collectViolations(cause, message.NewPrinter(language.English), &violations)
+		}
+		if len(violations) == 0 {
+			return validationErr
+		}
+		return fmt.Errorf("the jsonschema doesn't match its metaschema: %s", strings.Join(violations, ", "))
+	case errors.As(err, &regexErr):
+		return fmt.Errorf("the jsonschema contains an invalid pattern: %w", regexErr)
+	}
+	return nil
+}
+
+// collectViolations adds the violations without causes (the ones with causes only summarize them)
+// with the location in the schema
+func collectViolations(err *jsonschema.ValidationError, printer *message.Printer, violations *[]string) {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			collectViolations(cause, printer, violations)
+		}
+		return
+	}
+	location := "#"
+	for _, token := range err.InstanceLocation {
+		location += "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+	}
+	*violations = append(*violations, location+": "+err.ErrorKind.LocalizedString(printer))
+}
+
+// metaschemaCheckURL is the location the schema is compiled at to validate it against its metaschema
+const metaschemaCheckURL = "file:///values.schema.json"
+
+// ValuesError is a violation of the schema by the values
+type ValuesError struct {
+	// Pointer is the json pointer of the invalid value (e.g. /image/tag)
+	Pointer string
+	Message string
+}
+
+// ValuesErrors returns the violations of the schema in an error returned by ValidateValues.
+// Other errors are returned as a single violation of the root.
+func ValuesErrors(err error) []ValuesError {
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []ValuesError{{Pointer: "", Message: err.Error()}}
+	}
+
+	var valuesErrors []ValuesError
+	for _, unit := range validationErr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		// the message is only exposed in its json form
+		var message string
+		messageJSON, marshalErr := json.Marshal(unit.Error)
+		if marshalErr != nil || json.Unmarshal(messageJSON, &message) != nil {
+			message = unit.KeywordLocation
+		}
+		valuesErrors = append(valuesErrors, ValuesError{Pointer: unit.InstanceLocation, Message: message})
+	}
+	if len(valuesErrors) == 0 {
+		valuesErrors = append(valuesErrors, ValuesError{Pointer: "", Message: err.Error()})
+	}
+	return valuesErrors
+}
+
+// MergeValues merges the override values into the values like helm does when it's given a values
+// file with -f: mappings are merged, other values are replaced and null removes a key. The merged
+// values are returned as json, which can be validated with ValidateValues.
+func MergeValues(content, override []byte) ([]byte, error) {
+	var values, overrideValues interface{}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(override, &overrideValues); err != nil {
+		return nil, err
+	}
+	if overrideValues == nil {
+		overrideValues = map[string]interface{}{}
+	}
+	if _, ok := overrideValues.(map[string]interface{}); !ok {
+		return nil, errors.New("the values must be a mapping")
+	}
+	return json.Marshal(applyMergePatch(values, overrideValues))
+}
@@ -0,0 +1,92 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Vocabulary adds custom keywords to the @schema annotations (e.g. team: platform), which transform the
+// schema of the annotated key. Embedders register their vocabularies with RegisterVocabulary before
+// generating schemas.
+type Vocabulary interface {
+	// Keywords returns the annotation keywords of the vocabulary
+	Keywords() []string
+	// Apply transforms the schema of the annotation with the value of one of the keywords, the other
+	// keywords of the annotation are already decoded
+	Apply(keyword string, value *yaml.Node, s *Schema) error
+}
+
+var (
+	vocabulariesMu sync.RWMutex
+	vocabularies   = make(map[string]Vocabulary)
+)
+
+// RegisterVocabulary adds the keywords of the vocabulary to the annotations. It fails for keywords of
+// jsonschema, of helm-schema itself or of another registered vocabulary.
+func RegisterVocabulary(vocabulary Vocabulary) error {
+	vocabulariesMu.Lock()
+	defer vocabulariesMu.Unlock()
+
+	reserved := annotationKeywords()
+	for _, keyword := range vocabulary.Keywords() {
+		if keyword == "" || slices.Contains(reserved, keyword) {
+			return fmt.Errorf("the keyword %q of the vocabulary is reserved", keyword)
+		}
+		if _, ok := vocabularies[keyword]; ok {
+			return fmt.Errorf("the keyword %q is already registered by another vocabulary", keyword)
+		}
+	}
+	for _, keyword := range vocabulary.Keywords() {
+		vocabularies[keyword] = vocabulary
+	}
+	return nil
+}
+
+// UnregisterVocabulary removes the keywords of the vocabulary from the annotations
+func UnregisterVocabulary(vocabulary Vocabulary) {
+	vocabulariesMu.Lock()
+	defer vocabulariesMu.Unlock()
+	for _, keyword := range vocabulary.Keywords() {
+		delete(vocabularies, keyword)
+	}
+}
+
+// lookupVocabulary returns the registered vocabulary of the keyword
+func lookupVocabulary(keyword string) (Vocabulary, bool) {
+	vocabulariesMu.RLock()
+	defer vocabulariesMu.RUnlock()
+	vocabulary, ok := vocabularies[keyword]
+	return vocabulary, ok
+}
+
+// annotationKeywords returns the keywords the annotations already support
+func annotationKeywords() []string {
+	keywords := append(schemaKeywords(), UIAnnotation, ValidationsAnnotation)
+	t := reflect.TypeOf(Schema{})
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ","); name != "" && name != "-" && !slices.Contains(keywords, name) {
+			keywords = append(keywords, name)
+		}
+	}
+	return keywords
+}
+
+// applyVocabularies transforms the schema with the keywords of the registered vocabularies in the annotation
+func (s *Schema) applyVocabularies(node *yaml.Node) error {
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		keyword := node.Content[i].Value
+		vocabulary, ok := lookupVocabulary(keyword)
+		if !ok {
+			continue
+		}
+		if err := vocabulary.Apply(keyword, node.Content[i+1], s); err != nil {
+			return fmt.Errorf("invalid %s annotation: %w", keyword, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package schema
+
+// Walk calls fn for the schema and all of its subschemas (depth-first, parents first)
+func (s *Schema) Walk(fn func(*Schema)) {
+	if s == nil {
+		return
+	}
+
+	fn(s)
+
+	for _, name := range s.PropertyNames() {
+		s.Properties[name].Walk(fn)
+	}
+	for _, pattern := range sortedKeys(s.PatternProperties) {
+		s.PatternProperties[pattern].Walk(fn)
+	}
+	if additionalProperties, ok := s.AdditionalProperties.(*Schema); ok {
+		additionalProperties.Walk(fn)
+	}
+	s.Items.Walk(fn)
+	for _, subSchema := range s.AnyOf {
+		subSchema.Walk(fn)
+	}
+	for _, subSchema := range s.AllOf {
+		subSchema.Walk(fn)
+	}
+	for _, subSchema := range s.OneOf {
+		subSchema.Walk(fn)
+	}
+	s.Not.Walk(fn)
+	s.If.Walk(fn)
+	s.Then.Walk(fn)
+	s.Else.Walk(fn)
+	for _, name := range sortedKeys(s.Defs) {
+		s.Defs[name].Walk(fn)
+	}
+}
+
+// ApplyPropertyOrder sets the order of all properties in the schema. With the mode
+// "source" the order of the values file is kept, with "alpha" the properties are
+// sorted alphabetically. If addOrderHint is true, the position of every property
+// is added as x-order annotation.
+func (s *Schema) ApplyPropertyOrder(mode string, addOrderHint bool) {
+	s.Walk(func(subSchema *Schema) {
+		if addOrderHint {
+			for i, name := range subSchema.PropertyNames() {
+				property := subSchema.Properties[name]
+				if property == nil {
+					continue
+				}
+				if _, ok := property.CustomAnnotations["x-order"]; ok {
+					continue
+				}
+				if property.CustomAnnotations == nil {
+					property.CustomAnnotations = make(map[string]interface{})
+				}
+				property.CustomAnnotations["x-order"] = i
+			}
+		}
+		if mode == PropertyOrderAlpha {
+			subSchema.PropertyOrder = nil
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UndocumentedValue is a value of the values file, whose property has no description or no type
+type UndocumentedValue struct {
+	// Path of the value in the dotted notation of helm (e.g. image.tag)
+	Path string
+	// Line is the 1-based line of the key in the values file
+	Line int
+	// Value is the raw value of scalars, it's empty for objects and arrays
+	Value              string
+	MissingDescription bool
+	MissingType        bool
+	column             int
+	annotated          bool
+	required           bool
+}
+
+// ValueAnnotation contains the description and type the author entered for an undocumented value
+type ValueAnnotation struct {
+	Value       UndocumentedValue
+	Description string
+	Type        StringOrArrayOfString
+}
+
+// UndocumentedValues returns the values in the order of the values file, whose property in the schema
+// generated from it has no description or no type. Values which are only null (e.g. placeholders) count
+// as values without a type, the ones with an enum, const, $ref or composition don't need a type.
+func UndocumentedValues(content []byte, s *Schema) ([]UndocumentedValue, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("the values file must contain a mapping, found %s", root.Tag)
+	}
+	values := []UndocumentedValue{}
+	collectUndocumentedValues(root, s, "", &values)
+	return values, nil
+}
+
+func collectUndocumentedValues(node *yaml.Node, s *Schema, prefix string, values *[]UndocumentedValue) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if valueNode.Kind == yaml.AliasNode {
+			valueNode = valueNode.Alias
+		}
+		property := s.Properties[keyNode.Value]
+		if property == nil {
+			continue
+		}
+		path := keyNode.Value
+		if prefix != "" {
+			path = prefix + "." + keyNode.Value
+		}
+
+		untyped := property.Type.IsEmpty() || slices.Equal(property.Type, StringOrArrayOfString{"null"})
+		needsType := property.Ref == "" && len(property.Enum) == 0 && property.Const == nil &&
+			len(property.AnyOf) == 0 && len(property.OneOf) == 0 && len(property.AllOf) == 0
+		value := UndocumentedValue{
+			Path:               path,
+			Line:               keyNode.Line,
+			MissingDescription: property.Description == "",
+			MissingType:        untyped && needsType,
+			column:             keyNode.Column,
+			annotated:          strings.Contains(keyNode.HeadComment, SchemaPrefix),
+			required:           slices.Contains(s.Required.Strings, keyNode.Value),
+		}
+		if valueNode.Kind == yaml.ScalarNode {
+			value.Value = valueNode.Value
+		}
+		if value.MissingDescription || value.MissingType {
+			*values = append(*values, value)
+		}
+
+		// the children of flow mappings are on the same line, they can't be annotated
+		if valueNode.Kind == yaml.MappingNode && valueNode.Style&yaml.FlowStyle == 0 {
+			collectUndocumentedValues(valueNode, property, path, values)
+		}
+	}
+}
+
+// AddValueAnnotations writes the descriptions as comments and the types as @schema annotations
+// above the keys of the values. The type is added to an existing annotation of the key. Keys with
+// annotations aren't required by default, so new annotations of required keys contain required: true.
+func AddValueAnnotations(content []byte, annotations []ValueAnnotation) ([]byte, error) {
+	lines := strings.Split(string(content), "\n")
+	insertions := make(map[int][]string)
+	for _, annotation := range annotations {
+		value := annotation.Value
+		if value.Line < 1 || value.Line > len(lines) {
+			return nil, fmt.Errorf("the line %d of %s doesn't exist", value.Line, value.Path)
+		}
+		if err := annotation.Type.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid type of %s: %w", value.Path, err)
+		}
+		indent := strings.Repeat(" ", value.column-1)
+		keyIndex := value.Line - 1
+
+		if annotation.Description != "" {
+			for _, line := range strings.Split(strings.TrimSpace(annotation.Description), "\n") {
+				insertions[keyIndex] = append(insertions[keyIndex], strings.TrimRight(indent+CommentPrefix+" "+line, " "))
+			}
+		}
+		if len(annotation.Type) == 0 {
+			continue
+		}
+		typeLine := indent + CommentPrefix + " type: " + annotation.Type[0]
+		if len(annotation.Type) > 1 {
+			typeLine = indent + CommentPrefix + " type: [" + strings.Join(annotation.Type, ", ") + "]"
+		}
+		if value.annotated {
+			// add the type in front of the end of the existing annotation
+			if end := annotationEnd(lines, keyIndex); end >= 0 {
+				insertions[end] = append(insertions[end], typeLine)
+				continue
+			}
+		}
+		block := []string{indent + SchemaPrefix, typeLine}
+		if value.required {
+			block = append(block, indent+CommentPrefix+" required: true")
+		}
+		insertions[keyIndex] = append(insertions[keyIndex], append(block, indent+SchemaPrefix)...)
+	}
+	return insertAnnotations(content, insertions), nil
+}
+
+// annotationEnd returns the index of the line closing the @schema block in the head comment
+// of the key at the index or -1 if there is none
+func annotationEnd(lines []string, keyIndex int) int {
+	for i := keyIndex - 1; i >= 0 && strings.HasPrefix(strings.TrimSpace(lines[i]), CommentPrefix); i-- {
+		if isSchemaDelimiter(strings.TrimSpace(lines[i])) {
+			return i
+		}
+	}
+	return -1
+}
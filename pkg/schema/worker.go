@@ -2,14 +2,17 @@ package schema
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/ojsef39/helm-schema/pkg/chart"
 	"github.com/ojsef39/helm-schema/pkg/util"
-	"gopkg.in/yaml.v3"
 )
 
 type Result struct {
@@ -18,77 +21,180 @@ type Result struct {
 	Chart      *chart.ChartFile
 	Schema     Schema
 	Errors     []error
+	// OutputPath is the path the schema will be written to
+	OutputPath string
+	// InputHash is the hash of the Chart.yaml and values file content
+	InputHash string
+	// Cached is true if the schema was read from the existing output file
+	// instead of being generated
+	Cached bool
+	// Timings are the durations of the phases of generating the schema
+	Timings Timings
+}
+
+// ChartAnnotationValues can be used in the annotations of Chart.yaml to set the comma-separated names of the
+// values files of the chart, the first existing one is used
+const ChartAnnotationValues = "helm-schema/values"
+
+// ValueFileNames returns the names of the values files of the chart, the annotation helm-schema/values of its
+// Chart.yaml overrides the default names
+func ValueFileNames(chartFile *chart.ChartFile, defaultNames []string) []string {
+	if chartFile == nil {
+		return defaultNames
+	}
+	names := []string{}
+	for _, name := range strings.Split(chartFile.Annotations[ChartAnnotationValues], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return defaultNames
+	}
+	return names
 }
 
 func Worker(
-	dryRun, uncomment, addSchemaReference, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix bool,
+	dryRun, uncomment, addSchemaReference, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, bitnamiCompatibilityMode bool,
 	valueFileNames []string,
 	skipAutoGenerationConfig *SkipAutoGenerationConfig,
-	outFile string,
+	outputConfig *OutputConfig,
+	cache *Cache,
 	queue <-chan string,
 	results chan<- Result,
 ) {
 	for chartPath := range queue {
 		result := Result{ChartPath: chartPath}
+		readStart := time.Now()
 
 		chartBasePath := filepath.Dir(chartPath)
-		file, err := os.Open(chartPath)
-		if err != nil {
-			result.Errors = append(result.Errors, err)
-			results <- result
-			continue
+		// a values file queued directly has no Chart.yaml, it gets a chart named after its directory
+		bareValuesFile := filepath.Base(chartPath) != "Chart.yaml"
+		var chartContent, requirementsContent []byte
+		if bareValuesFile {
+			absBasePath, err := filepath.Abs(chartBasePath)
+			if err != nil {
+				result.Errors = append(result.Errors, err)
+				results <- result
+				continue
+			}
+			result.Chart = &chart.ChartFile{Name: filepath.Base(absBasePath)}
+		} else {
+			var err error
+			chartContent, err = os.ReadFile(chartPath)
+			if err != nil {
+				result.Errors = append(result.Errors, err)
+				results <- result
+				continue
+			}
+
+			chart, err := chart.ReadChart(bytes.NewReader(chartContent))
+			if err != nil {
+				result.Errors = append(result.Errors, err)
+				results <- result
+				continue
+			}
+			result.Chart = &chart
+			if requirementsContent, err = result.Chart.ReadRequirements(chartBasePath); err != nil {
+				result.Errors = append(result.Errors, err)
+				results <- result
+				continue
+			}
 		}
 
-		chart, err := chart.ReadChart(file)
+		var err error
+		result.OutputPath, err = outputConfig.Path(&result)
 		if err != nil {
 			result.Errors = append(result.Errors, err)
 			results <- result
 			continue
 		}
-		result.Chart = &chart
 
-		var valuesPath string
-		var valuesFound bool
-		errorsWeMaybeCanIgnore := []error{}
+		valuesPath := chartPath
+		if !bareValuesFile {
+			var valuesFound bool
+			errorsWeMaybeCanIgnore := []error{}
 
-		for _, possibleValueFileName := range valueFileNames {
-			valuesPath = filepath.Join(chartBasePath, possibleValueFileName)
-			_, err := os.Stat(valuesPath)
-			if err != nil {
-				if !os.IsNotExist(err) {
-					errorsWeMaybeCanIgnore = append(errorsWeMaybeCanIgnore, err)
+			for _, possibleValueFileName := range ValueFileNames(result.Chart, valueFileNames) {
+				valuesPath = filepath.Join(chartBasePath, possibleValueFileName)
+				_, err := os.Stat(valuesPath)
+				if err != nil {
+					if !os.IsNotExist(err) {
+						errorsWeMaybeCanIgnore = append(errorsWeMaybeCanIgnore, err)
+					}
+					continue
 				}
+				valuesFound = true
+				break
+			}
+
+			if !valuesFound {
+				result.Errors = append(result.Errors, errorsWeMaybeCanIgnore...)
+				result.Errors = append(result.Errors, errors.New("no values file found"))
+				results <- result
 				continue
 			}
-			valuesFound = true
-			break
 		}
+		result.ValuesPath = valuesPath
 
-		if !valuesFound {
-			result.Errors = append(result.Errors, errorsWeMaybeCanIgnore...)
-			result.Errors = append(result.Errors, errors.New("no values file found"))
+		valuesFile, err := os.Open(valuesPath)
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+			results <- result
+			continue
+		}
+		content, err := util.ReadFileAndFixNewline(valuesFile)
+		if err != nil {
+			result.Errors = append(result.Errors, err)
 			results <- result
 			continue
 		}
-		result.ValuesPath = valuesPath
 
-		valuesFile, err := os.Open(valuesPath)
+		sidecarContent, err := ReadSidecarAnnotations(valuesPath)
 		if err != nil {
 			result.Errors = append(result.Errors, err)
 			results <- result
 			continue
 		}
-		content, err := util.ReadFileAndFixNewline(valuesFile)
+		patchContent, err := ReadSchemaPatch(chartBasePath)
 		if err != nil {
 			result.Errors = append(result.Errors, err)
 			results <- result
 			continue
 		}
+		// the optional files are only hashed if they exist, so the hashes of charts without them stay the same
+		hashParts := [][]byte{chartContent, content}
+		if requirementsContent != nil {
+			hashParts = append(hashParts, []byte("requirements"), requirementsContent)
+		}
+		if sidecarContent != nil {
+			hashParts = append(hashParts, []byte(SidecarAnnotationsFile), sidecarContent)
+		}
+		if patchContent != nil {
+			hashParts = append(hashParts, []byte(SchemaPatchFile), patchContent)
+		}
+		result.InputHash = Hash(hashParts...)
+
+		// Reuse the existing output file, if nothing changed since the last run
+		if cache != nil {
+			if cachedSchema, ok := readCachedSchema(cache, chartPath, result.InputHash, result.OutputPath); ok {
+				result.Schema = *cachedSchema
+				result.Cached = true
+				results <- result
+				continue
+			}
+		}
 
 		// Check if we need to add a schema reference
 		if addSchemaReference {
-			schemaRef := `# yaml-language-server: $schema=values.schema.json`
-			if !strings.Contains(string(content), schemaRef) {
+			schemaFile, err := filepath.Rel(filepath.Dir(valuesPath), result.OutputPath)
+			if err != nil {
+				result.Errors = append(result.Errors, err)
+				results <- result
+				continue
+			}
+			schemaRef := `# yaml-language-server: $schema=` + filepath.ToSlash(schemaFile)
+			if !bytes.Contains(content, []byte(schemaRef)) {
 				err = util.PrefixFirstYamlDocument(schemaRef, valuesPath)
 				if err != nil {
 					result.Errors = append(result.Errors, err)
@@ -98,27 +204,179 @@ func Worker(
 			}
 		}
 
-		// Optional preprocessing
-		if uncomment {
-			// Remove comments from valid yaml
-			content, err = util.RemoveCommentsFromYaml(bytes.NewReader(content))
-			if err != nil {
-				result.Errors = append(result.Errors, err)
-				results <- result
-				continue
-			}
+		chartConfig, err := skipAutoGenerationConfig.ForChart(result.Chart)
+		if err != nil {
+			result.Errors = append(result.Errors, err)
+			results <- result
+			continue
 		}
-
-		var values yaml.Node
-		err = yaml.Unmarshal(content, &values)
+		result.Timings.Read = time.Since(readStart)
+		valuesSchema, err := generateSchema(valuesPath, content, uncomment, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, bitnamiCompatibilityMode, chartConfig, &result.Timings)
 		if err != nil {
 			result.Errors = append(result.Errors, err)
 			results <- result
 			continue
 		}
-
-		result.Schema = *YamlToSchema(valuesPath, &values, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, skipAutoGenerationConfig, nil)
+		result.Schema = *valuesSchema
 
 		results <- result
 	}
 }
+
+const (
+	// OutputLayoutMirror mirrors the chart directories below the output directory
+	OutputLayoutMirror = "mirror"
+	// OutputLayoutFlat writes all schemas directly into the output directory
+	OutputLayoutFlat = "flat"
+)
+
+// OutputConfig describes where the schemas are written to
+type OutputConfig struct {
+	// File is a go template of the file name (relative to the chart or output directory)
+	File string
+	// Dir is an optional directory, all schemas will be written to
+	Dir string
+	// Layout of the schemas in Dir, either OutputLayoutMirror or OutputLayoutFlat
+	Layout string
+	// ChartSearchRoot is used to mirror the chart directories in Dir
+	ChartSearchRoot string
+}
+
+func NewOutputConfig(file, dir, layout, chartSearchRoot string) (*OutputConfig, error) {
+	if layout != OutputLayoutMirror && layout != OutputLayoutFlat {
+		return nil, fmt.Errorf("unsupported output layout %s, use %s or %s", layout, OutputLayoutMirror, OutputLayoutFlat)
+	}
+	if _, err := template.New("output-file").Parse(file); err != nil {
+		return nil, fmt.Errorf("invalid output file template %s: %w", file, err)
+	}
+	return &OutputConfig{File: file, Dir: dir, Layout: layout, ChartSearchRoot: chartSearchRoot}, nil
+}
+
+// Path renders the output file template with the data of the given result and
+// returns the path the schema of the result should be written to
+func (c *OutputConfig) Path(result *Result) (string, error) {
+	fileName, err := util.RenderTemplate("output file", c.File, result)
+	if err != nil {
+		return "", err
+	}
+
+	chartDir := filepath.Dir(result.ChartPath)
+	if c.Dir == "" {
+		return filepath.Join(chartDir, fileName), nil
+	}
+
+	if c.Layout == OutputLayoutFlat {
+		return filepath.Join(c.Dir, fileName), nil
+	}
+
+	relChartDir, err := filepath.Rel(c.ChartSearchRoot, chartDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.Dir, relChartDir, fileName), nil
+}
+
+// GenerateSchema creates the jsonschema from the content of the given values file
+func GenerateSchema(
+	valuesPath string,
+	content []byte,
+	uncomment, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, bitnamiCompatibilityMode bool,
+	skipAutoGenerationConfig *SkipAutoGenerationConfig,
+) (*Schema, error) {
+	return generateSchema(valuesPath, content, uncomment, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, bitnamiCompatibilityMode, skipAutoGenerationConfig, nil)
+}
+
+// generateSchema creates the jsonschema like GenerateSchema and adds the durations of parsing and inferring
+// to the timings, which can be nil
+func generateSchema(
+	valuesPath string,
+	content []byte,
+	uncomment, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, bitnamiCompatibilityMode bool,
+	skipAutoGenerationConfig *SkipAutoGenerationConfig,
+	timings *Timings,
+) (*Schema, error) {
+	parseStart := time.Now()
+	content, optionalLines, err := uncommentValues(content, uncomment)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := ParseValues(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := markOptionalKeys(values, optionalLines); err != nil {
+		return nil, err
+	}
+
+	sidecarContent, err := ReadSidecarAnnotations(valuesPath)
+	if err != nil {
+		return nil, err
+	}
+	if sidecarContent != nil {
+		if err := ApplySidecarAnnotations(values, sidecarContent); err != nil {
+			return nil, err
+		}
+	}
+	if timings != nil {
+		timings.Parse = time.Since(parseStart)
+	}
+
+	inferStart := time.Now()
+	valuesSchema, err := YamlToSchema(valuesPath, values, keepFullComment, helmDocsCompatibilityMode, dontRemoveHelmDocsPrefix, skipAutoGenerationConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+	if bitnamiCompatibilityMode {
+		ApplyBitnamiParams(values, valuesSchema)
+	}
+	// the values annotated with skip: true
+	valuesSchema.ExcludeValues(nil)
+	if skipAutoGenerationConfig.requiredMode() == RequiredModeNone {
+		valuesSchema.DisableRequiredProperties()
+	}
+	if timings != nil {
+		timings.Infer = time.Since(inferStart)
+	}
+	return valuesSchema, nil
+}
+
+// uncommentValues includes the commented-out yaml of the values. If the values contain blocks marked with
+// # @uncomment-start and # @uncomment-end, only these are uncommented (independent of uncomment),
+// otherwise all comments containing valid yaml are removed if uncomment is set. It also returns the
+// lines of the top level keys of the marked blocks, which aren't set by default.
+func uncommentValues(content []byte, uncomment bool) ([]byte, []int, error) {
+	content, keyLines, err := util.UncommentMarkedBlocks(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	if uncomment && keyLines == nil {
+		// Remove comments from valid yaml
+		content, err = util.RemoveCommentsFromYaml(bytes.NewReader(content))
+	}
+	return content, keyLines, err
+}
+
+// readCachedSchema returns the schema of the output file, if the cache entry
+// matches the input hash and the output file hasn't been modified
+func readCachedSchema(cache *Cache, chartPath, inputHash, outputPath string) (*Schema, bool) {
+	entry, ok := cache.Get(chartPath)
+	if !ok || entry.InputHash != inputHash {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil || Hash(content) != entry.SchemaHash {
+		return nil, false
+	}
+	if entry.Schema != nil {
+		content = entry.Schema
+	}
+
+	var cachedSchema Schema
+	if err := json.Unmarshal(content, &cachedSchema); err != nil {
+		return nil, false
+	}
+
+	return &cachedSchema, true
+}
@@ -0,0 +1,102 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ToYAML converts the schema into yaml with the same keys as its json. The properties generated from a
+// values file are preceded by a comment with the file and line of their key, the paths of the files are
+// relative to the directory dir (the one the yaml is written to).
+func (s *Schema) ToYAML(dir string) ([]byte, error) {
+	content, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	// a yaml node keeps the order of the keys
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	blockStyle(&doc)
+	addSourceComments(doc.Content[0], s, dir)
+	doc.HeadComment = "Code generated by helm-schema. DO NOT EDIT."
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// blockStyle removes the json styles (flow collections and quoted strings) of the node and its children
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// addSourceComments adds the origins of the properties of the schema as comments to the mapping node of its yaml
+func addSourceComments(node *yaml.Node, s *Schema, dir string) {
+	if node.Kind != yaml.MappingNode || s == nil {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "properties":
+			if value.Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				property, ok := s.Properties[value.Content[j].Value]
+				if !ok {
+					continue
+				}
+				if comment := sourceComment(property.Source, dir); comment != "" {
+					value.Content[j].HeadComment = comment
+				}
+				addSourceComments(value.Content[j+1], property, dir)
+			}
+		case "items":
+			addSourceComments(value, s.Items, dir)
+		case "anyOf", "allOf", "oneOf":
+			subSchemas := map[string][]*Schema{"anyOf": s.AnyOf, "allOf": s.AllOf, "oneOf": s.OneOf}[key.Value]
+			for j, item := range value.Content {
+				if j < len(subSchemas) {
+					addSourceComments(item, subSchemas[j], dir)
+				}
+			}
+		}
+	}
+}
+
+// sourceComment returns the comment describing the origin (e.g. values.yaml:12)
+func sourceComment(origin *Origin, dir string) string {
+	if origin == nil || origin.Line == 0 {
+		return ""
+	}
+	file := origin.File
+	if file == "" {
+		return fmt.Sprintf("line %d", origin.Line)
+	}
+	if absFile, err := filepath.Abs(file); err == nil {
+		if absDir, err := filepath.Abs(dir); err == nil {
+			if relative, err := filepath.Rel(absDir, absFile); err == nil {
+				file = relative
+			}
+		}
+	}
+	return filepath.ToSlash(file) + ":" + strconv.Itoa(origin.Line)
+}
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+	"github.com/ojsef39/helm-schema/pkg/util"
+)
+
+// The directories of the store containing the remote documents and the archives of the charts
+const (
+	documentsDir = "documents"
+	chartsDir    = "charts"
+)
+
+// Store is a local directory containing the remote documents (e.g. referenced by $refs) and the charts of
+// remote dependencies, so schemas can be generated without network access
+type Store struct {
+	Dir string
+}
+
+// New returns the store in the directory
+func New(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// IsRemote returns true, if the repository of the dependency is a chart repository served by http or https
+func IsRemote(dep *chart.Dependency) bool {
+	return strings.HasPrefix(dep.Repository, "https://") || strings.HasPrefix(dep.Repository, "http://")
+}
+
+// IsOCI returns true, if the repository of the dependency is an OCI registry (oci://)
+func IsOCI(dep *chart.Dependency) bool {
+	return strings.HasPrefix(dep.Repository, "oci://")
+}
+
+// documentPath returns the path of the document with the url in the store
+func (s *Store) documentPath(u *url.URL) string {
+	name := u.Path
+	if name == "" || strings.HasSuffix(name, "/") {
+		name += "index"
+	}
+	return filepath.Join(s.Dir, documentsDir, u.Scheme, escapeHost(u.Host), filepath.FromSlash(path.Clean("/"+name)))
+}
+
+// repositoryDir returns the directory of the charts of the repository in the store
+func (s *Store) repositoryDir(repository string) (string, error) {
+	u, err := url.Parse(repository)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, chartsDir, escapeHost(u.Host), filepath.FromSlash(path.Clean("/"+u.Path))), nil
+}
+
+// escapeHost replaces the port separator, which isn't allowed in the file names of all systems
+func escapeHost(host string) string {
+	return strings.ReplaceAll(host, ":", "_")
+}
+
+// Transport returns a transport, which serves the documents of the store and fails for all others
+// instead of accessing the network
+func (s *Store) Transport() http.RoundTripper {
+	return &offlineTransport{store: s}
+}
+
+type offlineTransport struct {
+	store *Store
+}
+
+// RoundTrip returns the document of the store
+func (t *offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return nil, fmt.Errorf("%s %s needs network access", req.Method, req.URL)
+	}
+	content, err := os.ReadFile(t.store.documentPath(req.URL))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s isn't in the local store %s, populate it with helm-schema cache warm", req.URL, t.store.Dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          io.NopCloser(bytes.NewReader(content)),
+		ContentLength: int64(len(content)),
+		Request:       req,
+	}, nil
+}
+
+// Recorder returns a transport, which adds the documents successfully fetched with the base transport to the store
+func (s *Store) Recorder(base http.RoundTripper) http.RoundTripper {
+	return &recordingTransport{store: s, base: base}
+}
+
+type recordingTransport struct {
+	store *Store
+	base  http.RoundTripper
+}
+
+// RoundTrip sends the request and stores the document of the response
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	response, err := t.base.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet || response.StatusCode != http.StatusOK {
+		return response, err
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(t.store.documentPath(req.URL), content); err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(content))
+	return response, nil
+}
+
+// FindChart returns the path of the archive of the newest version of the chart of the dependency in the
+// store, which matches the version constraint of the dependency. It returns an empty path, if there's none.
+func (s *Store) FindChart(dep *chart.Dependency) (string, error) {
+	constraint, err := versionConstraint(dep)
+	if err != nil {
+		return "", err
+	}
+	dir, err := s.repositoryDir(dep.Repository)
+	if err != nil {
+		return "", err
+	}
+	archives, err := filepath.Glob(filepath.Join(dir, dep.Name+"-*.tgz"))
+	if err != nil {
+		return "", err
+	}
+	var newest *semver.Version
+	found := ""
+	for _, archive := range archives {
+		version, err := semver.NewVersion(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(archive), dep.Name+"-"), ".tgz"))
+		if err != nil || !constraint.Check(version) {
+			// e.g. the archive of another chart with the name as prefix
+			continue
+		}
+		if newest == nil || version.GreaterThan(newest) {
+			newest, found = version, archive
+		}
+	}
+	return found, nil
+}
+
+// index is the index.yaml of a chart repository
+type index struct {
+	Entries map[string][]struct {
+		Version string   `yaml:"version"`
+		URLs    []string `yaml:"urls"`
+	} `yaml:"entries"`
+}
+
+// Warm downloads the newest version of the chart of the dependency, which matches its version constraint,
+// from its repository into the store. It returns the path of the archive.
+func (s *Store) Warm(client *http.Client, dep *chart.Dependency) (string, error) {
+	constraint, err := versionConstraint(dep)
+	if err != nil {
+		return "", err
+	}
+	repository, err := url.Parse(strings.TrimSuffix(dep.Repository, "/") + "/")
+	if err != nil {
+		return "", err
+	}
+	content, err := get(client, repository.ResolveReference(&url.URL{Path: "index.yaml"}).String())
+	if err != nil {
+		return "", err
+	}
+	var repositoryIndex index
+	if err := yaml.Unmarshal(content, &repositoryIndex); err != nil {
+		return "", fmt.Errorf("could not parse the index of %s: %w", dep.Repository, err)
+	}
+
+	var newest *semver.Version
+	archiveURL := ""
+	for _, entry := range repositoryIndex.Entries[dep.Name] {
+		version, err := semver.NewVersion(entry.Version)
+		if err != nil || len(entry.URLs) == 0 || !constraint.Check(version) {
+			continue
+		}
+		if newest == nil || version.GreaterThan(newest) {
+			newest, archiveURL = version, entry.URLs[0]
+		}
+	}
+	if newest == nil {
+		return "", fmt.Errorf("the repository %s has no version of %s matching %s", dep.Repository, dep.Name, dep.Version)
+	}
+
+	dir, err := s.repositoryDir(dep.Repository)
+	if err != nil {
+		return "", err
+	}
+	archive := filepath.Join(dir, fmt.Sprintf("%s-%s.tgz", dep.Name, newest.Original()))
+	if _, err := os.Stat(archive); err == nil {
+		return archive, nil
+	}
+	reference, err := url.Parse(archiveURL)
+	if err != nil {
+		return "", err
+	}
+	if content, err = get(client, repository.ResolveReference(reference).String()); err != nil {
+		return "", err
+	}
+	return archive, writeFile(archive, content)
+}
+
+// AddChart writes the archive of the version of the chart of the dependency (e.g. pulled from an OCI registry)
+// into the store and returns its path, so FindChart finds it
+func (s *Store) AddChart(dep *chart.Dependency, version string, content []byte) (string, error) {
+	dir, err := s.repositoryDir(dep.Repository)
+	if err != nil {
+		return "", err
+	}
+	archive := filepath.Join(dir, fmt.Sprintf("%s-%s.tgz", dep.Name, version))
+	return archive, writeFile(archive, content)
+}
+
+// versionConstraint returns the version constraint of the dependency, a missing version matches all versions
+func versionConstraint(dep *chart.Dependency) (*semver.Constraints, error) {
+	version := dep.Version
+	if version == "" {
+		version = "*"
+	}
+	constraint, err := semver.NewConstraint(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %s of dependency %s: %w", dep.Version, dep.Name, err)
+	}
+	return constraint, nil
+}
+
+// get returns the content of the document with the url
+func get(client *http.Client, documentURL string) ([]byte, error) {
+	response, err := client.Get(documentURL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch %s: unexpected status %s", documentURL, response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+// writeFile writes the file atomically, so concurrent downloads don't corrupt it, and creates its directory
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, content, 0o644)
+}
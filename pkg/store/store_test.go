@@ -0,0 +1,114 @@
+package store
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ojsef39/helm-schema/pkg/chart"
+)
+
+func TestWarm(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/charts/index.yaml":
+			io.WriteString(w, "entries:\n  redis:\n    - version: 1.3.0\n      urls: [redis-1.3.0.tgz]\n    - version: 1.2.1\n      urls: [redis-1.2.1.tgz]\n    - version: 1.2.0\n      urls: [redis-1.2.0.tgz]\n")
+		case "/charts/redis-1.2.1.tgz":
+			downloads++
+			io.WriteString(w, "archive")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := New(t.TempDir())
+	dep := &chart.Dependency{Name: "redis", Version: "~1.2.0", Repository: server.URL + "/charts"}
+	archive, err := s.Warm(server.Client(), dep)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if filepath.Base(archive) != "redis-1.2.1.tgz" {
+		t.Errorf("Was expecting redis-1.2.1.tgz, but got %s", archive)
+	}
+	// the archive is only downloaded once
+	if _, err := s.Warm(server.Client(), dep); err != nil || downloads != 1 {
+		t.Errorf("Was expecting a single download, but got %d (%v)", downloads, err)
+	}
+
+	tests := []struct {
+		dep   *chart.Dependency
+		found bool
+	}{
+		{dep: dep, found: true},
+		{dep: &chart.Dependency{Name: "redis", Repository: server.URL + "/charts"}, found: true},
+		{dep: &chart.Dependency{Name: "redis", Version: "~1.3.0", Repository: server.URL + "/charts"}},
+		{dep: &chart.Dependency{Name: "redis", Version: "~1.2.0", Repository: server.URL + "/other"}},
+	}
+	for _, test := range tests {
+		found, err := s.FindChart(test.dep)
+		if err != nil {
+			t.Errorf("Wasn't expecting an error, but got this: %v", err)
+		}
+		if (found == archive) != test.found {
+			t.Errorf("Was expecting found to be %t for %s %s, but got %s", test.found, test.dep.Version, test.dep.Repository, found)
+		}
+	}
+
+	if _, err := s.Warm(server.Client(), &chart.Dependency{Name: "redis", Version: "~2.0.0", Repository: server.URL + "/charts"}); err == nil {
+		t.Errorf("Was expecting an error for a missing version")
+	}
+}
+
+func TestAddChart(t *testing.T) {
+	s := New(t.TempDir())
+	dep := &chart.Dependency{Name: "app", Version: "~1.0.0", Repository: "oci://ghcr.io/org/charts"}
+	archive, err := s.AddChart(dep, "1.0.2", []byte("archive"))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	found, err := s.FindChart(dep)
+	if err != nil || found != archive {
+		t.Errorf("Was expecting %s, but got %s (%v)", archive, found, err)
+	}
+	if !IsOCI(dep) || IsRemote(dep) {
+		t.Errorf("Was expecting an OCI dependency")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"type": "string"}`)
+	}))
+	defer server.Close()
+
+	s := New(t.TempDir())
+	offline := &http.Client{Transport: s.Transport()}
+	if _, err := offline.Get(server.URL + "/schemas/name.json"); err == nil {
+		t.Errorf("Was expecting an error for a document, which isn't in the store")
+	}
+
+	recorder := &http.Client{Transport: s.Recorder(http.DefaultTransport)}
+	response, err := recorder.Get(server.URL + "/schemas/name.json")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	response.Body.Close()
+
+	server.Close()
+	response, err = offline.Get(server.URL + "/schemas/name.json")
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if string(content) != `{"type": "string"}` {
+		t.Errorf("Was expecting the recorded document, but got %s", content)
+	}
+}
@@ -0,0 +1,285 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template/parse"
+)
+
+// Reference is a value referenced in a template
+type Reference struct {
+	// Path of the value in the dotted notation of helm (e.g. image.tag)
+	Path string
+	File string
+	// Line is the 1-based line of the reference in the file
+	Line int
+	// Usage is how the template uses the value, empty if it's only rendered or passed on
+	Usage string
+}
+
+// templateExtensions are the extensions of the files helm renders
+var templateExtensions = []string{".yaml", ".yml", ".tpl", ".txt", ".json"}
+
+// ChartReferences returns the values referenced in the templates directory of the chart.
+// The templates of the dependencies in the charts directory aren't part of the chart.
+func ChartReferences(chartDir string) ([]Reference, error) {
+	templatesDir := filepath.Join(chartDir, "templates")
+	if _, err := os.Stat(templatesDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	references := []Reference{}
+	err := filepath.WalkDir(templatesDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !slices.Contains(templateExtensions, filepath.Ext(path)) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fileReferences, err := ParseReferences(path, string(content))
+		if err != nil {
+			return err
+		}
+		references = append(references, fileReferences...)
+		return nil
+	})
+	return references, err
+}
+
+// dot is the value of the dot (or a variable) while walking a template
+type dot struct {
+	// root is true for the context of the chart (with .Values)
+	root bool
+	// values is true if the dot is a value, whose path is known
+	values bool
+	// dynamic is true if the dot is an item of a value (e.g. in a range), so only the path of the value is known
+	dynamic bool
+	path    string
+	// ref is the index (starting with 1) of the reference, which returned the value, 0 for none
+	ref int
+}
+
+type walker struct {
+	file       string
+	content    string
+	references []Reference
+	variables  map[string]dot
+}
+
+// ParseReferences returns the values referenced in the template. The functions of helm and sprig
+// aren't known, so they aren't checked. Defined templates are expected to get the context of the
+// chart as dot, like it's passed in most charts (e.g. include "chart.labels" .).
+func ParseReferences(name, content string) ([]Reference, error) {
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	treeSet := make(map[string]*parse.Tree)
+	if _, err := tree.Parse(content, "", "", treeSet); err != nil {
+		return nil, fmt.Errorf("could not parse template %s: %w", name, err)
+	}
+
+	w := &walker{file: name, content: content}
+	names := make([]string, 0, len(treeSet))
+	for treeName := range treeSet {
+		names = append(names, treeName)
+	}
+	slices.Sort(names)
+	for _, treeName := range names {
+		if root := treeSet[treeName].Root; root != nil {
+			w.variables = map[string]dot{"$": {root: true}}
+			w.walk(root, dot{root: true})
+		}
+	}
+	return w.references, nil
+}
+
+// add records the reference of the value, an empty path references all values
+func (w *walker) add(value dot, pos parse.Pos) dot {
+	line := 1 + strings.Count(w.content[:min(int(pos), len(w.content))], "\n")
+	w.references = append(w.references, Reference{Path: value.path, File: w.file, Line: line})
+	value.ref = len(w.references)
+	return value
+}
+
+// use sets the usage of the reference, which returned the value. Items of values (e.g. in a
+// range) have a path of the value, so their usage is unknown.
+func (w *walker) use(value dot, usage string) {
+	if usage == "" || value.ref == 0 || value.dynamic || w.references[value.ref-1].Usage != "" {
+		return
+	}
+	w.references[value.ref-1].Usage = usage
+}
+
+func (w *walker) walk(node parse.Node, current dot) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			w.walk(child, current)
+		}
+	case *parse.ActionNode:
+		w.pipe(node.Pipe, current)
+	case *parse.IfNode:
+		w.use(w.pipe(node.Pipe, current), UsageCondition)
+		w.walk(node.List, current)
+		w.walk(node.ElseList, current)
+	case *parse.WithNode:
+		inner := w.pipe(node.Pipe, current)
+		w.walk(node.List, inner)
+		w.walk(node.ElseList, current)
+	case *parse.RangeNode:
+		value := w.pipe(node.Pipe, current)
+		w.use(value, UsageRange)
+		item := dot{}
+		if value.values {
+			item = dot{values: true, dynamic: true, path: value.path}
+		}
+		// the variables of the range are the key (or index) and item
+		for _, variable := range node.Pipe.Decl {
+			w.variables[variable.Ident[0]] = dot{}
+		}
+		if len(node.Pipe.Decl) > 0 {
+			w.variables[node.Pipe.Decl[len(node.Pipe.Decl)-1].Ident[0]] = item
+		}
+		w.walk(node.List, item)
+		w.walk(node.ElseList, current)
+	case *parse.TemplateNode:
+		w.pipe(node.Pipe, current)
+	}
+}
+
+// pipe records the references of the pipe and returns the value of its last command
+func (w *walker) pipe(pipe *parse.PipeNode, current dot) dot {
+	if pipe == nil {
+		return current
+	}
+	// the value of a command is passed as last argument to the next one
+	result := dot{}
+	for _, cmd := range pipe.Cmds {
+		result = w.command(cmd, current, result)
+	}
+	for _, variable := range pipe.Decl {
+		w.variables[variable.Ident[0]] = result
+	}
+	return result
+}
+
+// command records the references of the command and returns its value, if it's a value
+func (w *walker) command(cmd *parse.CommandNode, current, piped dot) dot {
+	if len(cmd.Args) == 0 {
+		return dot{}
+	}
+	ident, isFunction := cmd.Args[0].(*parse.IdentifierNode)
+	if isFunction && ident.Ident == "index" && len(cmd.Args) > 1 {
+		// index .Values "a" "b" accesses a.b
+		value := w.resolve(cmd.Args[1], current)
+		for _, arg := range cmd.Args[2:] {
+			key, ok := arg.(*parse.StringNode)
+			if !ok || !value.values || value.dynamic {
+				// the key isn't known, so all children of the value could be used
+				break
+			}
+			value = dot{values: true, path: joinPath(value.path, key.Text)}
+		}
+		if value.values {
+			value = w.add(value, cmd.Args[1].Position())
+		}
+		for _, arg := range cmd.Args[2:] {
+			w.arg(arg, current)
+		}
+		return value
+	}
+
+	var value dot
+	for i, arg := range cmd.Args {
+		value = w.arg(arg, current)
+		if isFunction && i > 0 {
+			w.use(value, functionUsages[ident.Ident])
+		}
+		if i > 0 || len(cmd.Args) > 1 {
+			value = dot{}
+		}
+	}
+	if isFunction {
+		w.use(piped, functionUsages[ident.Ident])
+	}
+	return value
+}
+
+// arg records the references of the argument and returns its value
+func (w *walker) arg(arg parse.Node, current dot) dot {
+	switch arg := arg.(type) {
+	case *parse.PipeNode:
+		return w.pipe(arg, current)
+	case *parse.FieldNode, *parse.VariableNode, *parse.ChainNode, *parse.DotNode:
+		value := w.resolve(arg, current)
+		if value.values {
+			value = w.add(value, arg.Position())
+		}
+		return value
+	}
+	return dot{}
+}
+
+// resolve returns the value of the node
+func (w *walker) resolve(node parse.Node, current dot) dot {
+	switch node := node.(type) {
+	case *parse.DotNode:
+		return current
+	case *parse.FieldNode:
+		return field(current, node.Ident)
+	case *parse.VariableNode:
+		variable, ok := w.variables[node.Ident[0]]
+		if !ok {
+			return dot{}
+		}
+		return field(variable, node.Ident[1:])
+	case *parse.ChainNode:
+		var value dot
+		if pipe, ok := node.Node.(*parse.PipeNode); ok {
+			value = w.pipe(pipe, current)
+		} else {
+			value = w.resolve(node.Node, current)
+		}
+		return field(value, node.Field)
+	case *parse.PipeNode:
+		return w.pipe(node, current)
+	}
+	return dot{}
+}
+
+// field returns the value of the fields of the value
+func field(value dot, fields []string) dot {
+	if len(fields) == 0 {
+		return value
+	}
+	if value.root {
+		if fields[0] != "Values" {
+			return dot{}
+		}
+		return dot{values: true, path: strings.Join(fields[1:], ".")}
+	}
+	if !value.values {
+		return dot{}
+	}
+	if value.dynamic {
+		// the fields of items can't be mapped to the values file
+		return value
+	}
+	return dot{values: true, path: joinPath(value.path, strings.Join(fields, "."))}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,123 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+func TestParseReferences(t *testing.T) {
+	content := `{{- define "chart.name" -}}{{ .Values.nameOverride | default .Chart.Name }}{{- end }}
+image: {{ .Values.image.repository }}:{{ .Values.image.tag | quote }}
+{{- with .Values.podAnnotations }}
+{{ toYaml .extra }}
+{{- end }}
+{{- range $i, $host := .Values.hosts }}
+{{ $host.name }}
+{{- end }}
+{{ index .Values "service" "port" }}
+{{ $.Values.a.b }}
+{{ $config := .Values.config }}{{ $config.level }}
+`
+	references, err := ParseReferences("test.yaml", content)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	paths := []string{}
+	for _, reference := range references {
+		paths = append(paths, reference.Path)
+	}
+	assert.Equal(t, paths, []string{
+		"nameOverride", "image.repository", "image.tag", "podAnnotations", "podAnnotations.extra", "hosts", "hosts",
+		"service.port", "a.b", "config", "config.level",
+	})
+	assert.Equal(t, references[0].Line, 1)
+	assert.Equal(t, references[7].Line, 9)
+}
+
+func TestAnalyze(t *testing.T) {
+	values := []byte(`image:
+  repository: nginx
+  tag: latest
+podAnnotations: {}
+unused: 1
+service:
+  port: 80
+  legacy: 8080
+redis:
+  enabled: true
+global:
+  registry: docker.io
+`)
+	references := []Reference{
+		{Path: "image", File: "t.yaml", Line: 1},
+		{Path: "podAnnotations.extra", File: "t.yaml", Line: 2},
+		{Path: "service.port", File: "t.yaml", Line: 3},
+		{Path: "service.name", File: "t.yaml", Line: 4},
+		{Path: "missing.deep", File: "t.yaml", Line: 5},
+		{Path: "global.other", File: "t.yaml", Line: 6},
+	}
+	findings, err := Analyze("values.yaml", values, references, []string{"redis"})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	type result struct {
+		kind, path string
+		line       int
+	}
+	results := []result{}
+	for _, finding := range findings {
+		results = append(results, result{finding.Kind, finding.Path, finding.Line})
+	}
+	assert.Equal(t, results, []result{
+		{FindingUndefined, "service.name", 4},
+		{FindingUndefined, "missing.deep", 5},
+		{FindingUnused, "unused", 5},
+		{FindingUnused, "service.legacy", 8},
+	})
+}
+
+func TestCheckTypes(t *testing.T) {
+	values := `replicas: "3"
+enabled: ~
+resources: ~
+hosts: []
+name: test
+`
+	content := `{{ add .Values.replicas 1 }}
+{{- if .Values.enabled }}{{ end }}
+{{ .Values.resources | toYaml }}
+{{ range .Values.hosts }}{{ . }}{{ end }}
+{{ if .Values.name }}{{ .Values.name }}{{ end }}
+`
+	references, err := ParseReferences("test.yaml", content)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	skipConfig, err := schema.NewSkipAutoGenerationConfig([]string{})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	s, err := schema.GenerateSchema("", []byte(values), false, false, false, false, false, skipConfig)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+
+	findings := CheckTypes(s, references)
+	kinds := map[string]string{}
+	for _, finding := range findings {
+		kinds[finding.Path] = finding.Kind
+	}
+	assert.Equal(t, kinds, map[string]string{
+		"replicas":  FindingTypeConflict,
+		"enabled":   FindingUntyped,
+		"resources": FindingUntyped,
+	})
+
+	assert.Equal(t, RefineTypes(s, references), 2)
+	assert.Equal(t, []string(s.Properties["enabled"].Type), []string{"boolean", "null"})
+	assert.Equal(t, []string(s.Properties["resources"].Type), []string{"object", "array", "null"})
+	assert.Equal(t, []string(s.Properties["replicas"].Type), []string{"string"})
+}
@@ -0,0 +1,155 @@
+package templates
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+// The usages of values in templates, which hint at their type
+const (
+	// UsageCondition is a value used in if, and, or or not, which works with any type, but is usually a boolean
+	UsageCondition = "condition"
+	// UsageRange is a value iterated with range
+	UsageRange = "range"
+	// UsageSerialize is a value serialized with toYaml, toJson or similar
+	UsageSerialize = "serialize"
+	// UsageArithmetic is a value used in a calculation, e.g. with add
+	UsageArithmetic = "arithmetic"
+)
+
+// FindingTypeConflict is a value, whose type in the schema doesn't fit its usage in the templates
+const FindingTypeConflict = "type"
+
+// FindingUntyped is a value without a type in the schema (e.g. a null placeholder), whose type can be inferred from the templates
+const FindingUntyped = "untyped"
+
+// functionUsages are the usages of the arguments of the functions of helm and sprig
+var functionUsages = map[string]string{
+	"and": UsageCondition, "or": UsageCondition, "not": UsageCondition,
+	"toYaml": UsageSerialize, "toJson": UsageSerialize, "toPrettyJson": UsageSerialize, "toRawJson": UsageSerialize, "toToml": UsageSerialize,
+	"add": UsageArithmetic, "add1": UsageArithmetic, "sub": UsageArithmetic, "mul": UsageArithmetic, "div": UsageArithmetic,
+	"mod": UsageArithmetic, "max": UsageArithmetic, "min": UsageArithmetic, "floor": UsageArithmetic, "ceil": UsageArithmetic,
+	"round": UsageArithmetic, "addf": UsageArithmetic, "add1f": UsageArithmetic, "subf": UsageArithmetic, "mulf": UsageArithmetic,
+	"divf": UsageArithmetic, "maxf": UsageArithmetic, "minf": UsageArithmetic,
+}
+
+// usageTypes are the types, which fit the usage. Conditions work with any type.
+var usageTypes = map[string][]string{
+	UsageCondition:  {"boolean"},
+	UsageRange:      {"array", "object"},
+	UsageSerialize:  {"object", "array"},
+	UsageArithmetic: {"integer", "number"},
+}
+
+// usageDescriptions describe the usages in the findings
+var usageDescriptions = map[string]string{
+	UsageCondition:  "as condition",
+	UsageRange:      "in a range",
+	UsageSerialize:  "as object",
+	UsageArithmetic: "as number",
+}
+
+// usage is the usage of a value in the templates
+type usage struct {
+	name      string
+	reference Reference
+}
+
+// usages returns the first usage of every path, a usage hinting at a type wins over conditions
+func usages(references []Reference) ([]string, map[string]usage) {
+	paths := []string{}
+	found := make(map[string]usage)
+	for _, reference := range references {
+		if reference.Usage == "" || reference.Path == "" {
+			continue
+		}
+		existing, ok := found[reference.Path]
+		if !ok {
+			paths = append(paths, reference.Path)
+		}
+		if !ok || (existing.name == UsageCondition && reference.Usage != UsageCondition) {
+			found[reference.Path] = usage{name: reference.Usage, reference: reference}
+		}
+	}
+	return paths, found
+}
+
+// InferTypes returns the types of the values inferred from their usage in the templates
+func InferTypes(references []Reference) map[string][]string {
+	_, found := usages(references)
+	types := make(map[string][]string)
+	for path, u := range found {
+		types[path] = usageTypes[u.name]
+	}
+	return types
+}
+
+// CheckTypes compares the types of the properties in the schema generated from the values file with
+// the usage of the values in the templates. Declared types, which don't fit the usage (e.g. a string
+// used in add) are reported as conflicts. Properties without a type (or only null) are reported with
+// the type inferred from the templates. Conditions are only used to infer types, as they work with any type.
+func CheckTypes(s *schema.Schema, references []Reference) []Finding {
+	paths, found := usages(references)
+	findings := []Finding{}
+	for _, path := range paths {
+		u := found[path]
+		property := lookupProperty(s, path)
+		if property == nil {
+			continue
+		}
+		declared := slices.DeleteFunc(slices.Clone([]string(property.Type)), func(t string) bool { return t == "null" })
+		expected := usageTypes[u.name]
+		switch {
+		case len(declared) == 0 && property.Ref == "" && len(property.AnyOf) == 0 && len(property.OneOf) == 0:
+			findings = append(findings, Finding{
+				Kind:    FindingUntyped,
+				Path:    path,
+				File:    u.reference.File,
+				Line:    u.reference.Line,
+				Message: fmt.Sprintf("%s has no type, but is used %s, so it could be %s", path, usageDescriptions[u.name], strings.Join(expected, " or ")),
+			})
+		case u.name != UsageCondition && len(declared) > 0 && !slices.ContainsFunc(declared, func(t string) bool { return slices.Contains(expected, t) }):
+			findings = append(findings, Finding{
+				Kind:    FindingTypeConflict,
+				Path:    path,
+				File:    u.reference.File,
+				Line:    u.reference.Line,
+				Message: fmt.Sprintf("%s is used %s, but its type is %s", path, usageDescriptions[u.name], strings.Join(declared, ", ")),
+			})
+		}
+	}
+	return findings
+}
+
+// RefineTypes sets the types inferred from the templates on the properties of the schema without
+// a type (or only null). Null stays allowed, so the null placeholders of the values file stay valid.
+// It returns the number of refined properties.
+func RefineTypes(s *schema.Schema, references []Reference) int {
+	inferred := InferTypes(references)
+	count := 0
+	for _, finding := range CheckTypes(s, references) {
+		if finding.Kind != FindingUntyped {
+			continue
+		}
+		lookupProperty(s, finding.Path).Type = append(slices.Clone(inferred[finding.Path]), "null")
+		count++
+	}
+	return count
+}
+
+// lookupProperty returns the property of the path in the schema or nil if it doesn't exist
+func lookupProperty(s *schema.Schema, path string) *schema.Schema {
+	property := s
+	for _, key := range strings.Split(path, ".") {
+		if property.Properties == nil {
+			return nil
+		}
+		if property = property.Properties[key]; property == nil {
+			return nil
+		}
+	}
+	return property
+}
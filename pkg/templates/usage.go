@@ -0,0 +1,144 @@
+package templates
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FindingUndefined is a value used in a template, which isn't defined in the values file,
+	// so it doesn't reach the schema (and is rejected by it, if additional properties aren't allowed)
+	FindingUndefined = "undefined"
+	// FindingUnused is a value defined in the values file, which isn't used by any template
+	FindingUnused = "unused"
+)
+
+// Finding is a problem found by comparing the values with the references in the templates
+type Finding struct {
+	Kind string `json:"kind"`
+	// Path of the value in the dotted notation of helm (e.g. image.tag)
+	Path string `json:"path"`
+	// File and Line of the reference in a template or of the key in the values file
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// definedValue is a value of the values file
+type definedValue struct {
+	path string
+	line int
+	// children is true, if the value is a mapping with keys
+	children bool
+}
+
+// Analyze compares the values with the references of the templates. Values used in the templates, but
+// missing in the values file are reported, unless the closest defined parent is empty or no mapping
+// (e.g. podAnnotations: {}), as its content isn't known. Defined values which aren't used are reported,
+// unless a parent or child is used. The values of the ignored top level keys (e.g. of the
+// dependencies and global) aren't reported as unused.
+func Analyze(valuesPath string, content []byte, references []Reference, ignored []string) ([]Finding, error) {
+	values, err := schema.ParseValues(content)
+	if err != nil {
+		return nil, err
+	}
+	defined := []definedValue{}
+	if len(values.Content) == 1 {
+		if values.Content[0].Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("the values file must contain a mapping, found %s", values.Content[0].Tag)
+		}
+		collectValues(values.Content[0], "", &defined)
+	}
+	definedPaths := make(map[string]definedValue)
+	for _, value := range defined {
+		definedPaths[value.path] = value
+	}
+
+	findings := []Finding{}
+	reported := make(map[string]bool)
+	for _, reference := range references {
+		if reference.Path == "" || reported[reference.Path] || isIgnored(reference.Path, []string{"global"}) {
+			continue
+		}
+		if _, ok := definedPaths[reference.Path]; ok {
+			continue
+		}
+		if parent, ok := closestParent(reference.Path, definedPaths); ok && !parent.children {
+			continue
+		}
+		reported[reference.Path] = true
+		findings = append(findings, Finding{
+			Kind:    FindingUndefined,
+			Path:    reference.Path,
+			File:    reference.File,
+			Line:    reference.Line,
+			Message: fmt.Sprintf("%s is used in the template, but isn't defined in %s", reference.Path, valuesPath),
+		})
+	}
+
+	ignored = append(slices.Clone(ignored), "global")
+	unused := []string{}
+	for _, value := range defined {
+		if isIgnored(value.path, ignored) || isUsed(value.path, references) {
+			continue
+		}
+		// only the topmost unused value is reported
+		if slices.ContainsFunc(unused, func(path string) bool { return strings.HasPrefix(value.path, path+".") }) {
+			continue
+		}
+		unused = append(unused, value.path)
+		findings = append(findings, Finding{
+			Kind:    FindingUnused,
+			Path:    value.path,
+			File:    valuesPath,
+			Line:    value.line,
+			Message: fmt.Sprintf("%s is defined, but isn't used in any template", value.path),
+		})
+	}
+	return findings, nil
+}
+
+func collectValues(node *yaml.Node, prefix string, defined *[]definedValue) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if valueNode.Kind == yaml.AliasNode {
+			valueNode = valueNode.Alias
+		}
+		path := joinPath(prefix, keyNode.Value)
+		isMapping := valueNode.Kind == yaml.MappingNode
+		*defined = append(*defined, definedValue{path: path, line: keyNode.Line, children: isMapping && len(valueNode.Content) > 0})
+		if isMapping {
+			collectValues(valueNode, path, defined)
+		}
+	}
+}
+
+// closestParent returns the closest defined parent of the path
+func closestParent(path string, definedPaths map[string]definedValue) (definedValue, bool) {
+	for i := strings.LastIndex(path, "."); i > 0; i = strings.LastIndex(path[:i], ".") {
+		if parent, ok := definedPaths[path[:i]]; ok {
+			return parent, true
+		}
+	}
+	return definedValue{}, false
+}
+
+// isUsed returns true if the path, one of its parents or one of its children is referenced
+func isUsed(path string, references []Reference) bool {
+	for _, reference := range references {
+		if reference.Path == "" || reference.Path == path ||
+			strings.HasPrefix(path, reference.Path+".") || strings.HasPrefix(reference.Path, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// isIgnored returns true if the first key of the path is one of the ignored keys
+func isIgnored(path string, ignored []string) bool {
+	return slices.Contains(ignored, strings.SplitN(path, ".", 2)[0])
+}
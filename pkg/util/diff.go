@@ -0,0 +1,187 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffEdits limits the edits the diff searches for, texts differing more are shown as replaced completely
+const maxDiffEdits = 4096
+
+// The kinds of the operations of an edit script
+const (
+	opEqual  = ' '
+	opDelete = '-'
+	opInsert = '+'
+)
+
+// lineOp is an operation of the edit script turning the old lines into the new ones
+type lineOp struct {
+	kind byte
+	line string
+}
+
+// UnifiedDiff returns the unified diff of the old and the new content with the number of context lines
+// around the changes. It's empty, if the contents are equal. A missing old file is diffed as /dev/null.
+func UnifiedDiff(oldName, newName string, oldContent, newContent []byte, context int, oldMissing bool) string {
+	if !oldMissing && string(oldContent) == string(newContent) {
+		return ""
+	}
+	ops := diffLines(splitLines(oldContent), splitLines(newContent))
+
+	var diff strings.Builder
+	if oldMissing {
+		oldName = "/dev/null"
+	}
+	fmt.Fprintf(&diff, "--- %s\n+++ %s\n", oldName, newName)
+
+	// the positions of the operations in the old and new lines
+	oldLines := make([]int, len(ops)+1)
+	newLines := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLines[i+1], newLines[i+1] = oldLines[i], newLines[i]
+		if op.kind != opInsert {
+			oldLines[i+1]++
+		}
+		if op.kind != opDelete {
+			newLines[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == opEqual {
+			i++
+			continue
+		}
+		// a hunk ends, when more than two times the context lines are equal
+		start := max(0, i-context)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			equal := end
+			for equal < len(ops) && ops[equal].kind == opEqual {
+				equal++
+			}
+			if equal == len(ops) || equal-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+			end = equal
+		}
+
+		fmt.Fprintf(&diff, "@@ -%s +%s @@\n", hunkRange(oldLines[start], oldLines[end]-oldLines[start]),
+			hunkRange(newLines[start], newLines[end]-newLines[start]))
+		for _, op := range ops[start:end] {
+			diff.WriteByte(op.kind)
+			diff.WriteString(op.line)
+			diff.WriteByte('\n')
+		}
+		i = end
+	}
+	return diff.String()
+}
+
+// hunkRange returns the range of a hunk, which starts after the line start and contains count lines
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// an empty range refers to the line before it
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines returns the lines of the content without their line breaks
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script between the lines (Myers' algorithm)
+func diffLines(a, b []string) []lineOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// the furthest reaching paths before every number of edits, for backtracking
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= n+m && !found; d++ {
+		if d > maxDiffEdits {
+			return replaceLines(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	var ops []lineOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		// the window of the trace starts at k = -d-1
+		prev := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && prev(k-1) < prev(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := prev(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, lineOp{kind: opEqual, line: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, lineOp{kind: opInsert, line: b[y-1]})
+			} else {
+				ops = append(ops, lineOp{kind: opDelete, line: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	// the operations were collected from the end
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replaceLines returns the edit script deleting all old and inserting all new lines
+func replaceLines(a, b []string) []lineOp {
+	ops := make([]lineOp, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, lineOp{kind: opDelete, line: line})
+	}
+	for _, line := range b {
+		ops = append(ops, lineOp{kind: opInsert, line: line})
+	}
+	return ops
+}
@@ -2,10 +2,13 @@ package util
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -18,7 +21,11 @@ func ReadFileAndFixNewline(reader io.Reader) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	return []byte(strings.ReplaceAll(string(content), "\r\n", "\n")), nil
+	// the content is only copied if it has windows newlines, the values files can be huge
+	if !bytes.Contains(content, []byte("\r\n")) {
+		return content, nil
+	}
+	return bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n")), nil
 }
 
 func appendAndNL(to, from *[]byte) {
@@ -58,7 +65,36 @@ func PrefixFirstYamlDocument(line, file string) error {
 	}
 
 	newContent := line + eol + string(content)
-	return os.WriteFile(file, []byte(newContent), perm)
+	return WriteFileAtomic(file, []byte(newContent), perm)
+}
+
+// WriteFileAtomic writes the data to a temporary file in the same directory and renames it
+// afterwards, so readers never see a partially written file
+func WriteFileAtomic(file string, data []byte, perm os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmpFile.Name()
+
+	// cleanup if anything goes wrong
+	defer os.Remove(tmpName)
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, file)
 }
 
 // RemoveCommentsFromYaml tries to remove comments if they contain valid yaml
@@ -148,6 +184,59 @@ func RemoveCommentsFromYaml(reader io.Reader) ([]byte, error) {
 	return result, nil
 }
 
+var (
+	uncommentStartMatcher = regexp.MustCompile(`^(\s*)#\s*@uncomment-start\s*$`)
+	uncommentEndMatcher   = regexp.MustCompile(`^\s*#\s*@uncomment-end\s*$`)
+)
+
+// UncommentMarkedBlocks removes one level of comments from the lines between # @uncomment-start and
+// # @uncomment-end, so commented-out examples can be included selectively. The comment prefix is
+// expected at the indentation of the start marker. The markers are replaced by empty comments, so
+// the lines keep their numbers. It also returns the (1-based) numbers of the lines, which contain the
+// top level keys of the blocks, or nil if there are no markers.
+func UncommentMarkedBlocks(content []byte) ([]byte, []int, error) {
+	if !bytes.Contains(content, []byte("@uncomment-")) {
+		return content, nil, nil
+	}
+	lines := strings.Split(string(content), "\n")
+	prefix := ""
+	start := 0
+	var keyLines []int
+	for i, line := range lines {
+		if matches := uncommentStartMatcher.FindStringSubmatch(line); matches != nil {
+			if prefix != "" {
+				return nil, nil, fmt.Errorf("line %d: @uncomment-start inside the block of line %d", i+1, start+1)
+			}
+			prefix = matches[1] + "#"
+			start = i
+			if keyLines == nil {
+				keyLines = []int{}
+			}
+			lines[i] = prefix
+			continue
+		}
+		if uncommentEndMatcher.MatchString(line) {
+			if prefix == "" {
+				return nil, nil, fmt.Errorf("line %d: @uncomment-end without @uncomment-start", i+1)
+			}
+			lines[i] = prefix
+			prefix = ""
+			continue
+		}
+		if prefix != "" && strings.HasPrefix(line, prefix) {
+			uncommented := strings.TrimPrefix(strings.TrimPrefix(line, prefix), " ")
+			if uncommented != "" && !strings.ContainsAny(uncommented[:1], " \t#-") {
+				keyLines = append(keyLines, i+1)
+			}
+			lines[i] = prefix[:len(prefix)-1] + uncommented
+		}
+	}
+	if prefix != "" {
+		return nil, nil, fmt.Errorf("line %d: @uncomment-start without @uncomment-end", start+1)
+	}
+	return []byte(strings.Join(lines, "\n")), keyLines, nil
+}
+
 // IsRelativeFile checks if the given string is a relative path to a file
 func IsRelativeFile(root, relPath string) (string, error) {
 	if !path.IsAbs(relPath) {
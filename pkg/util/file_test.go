@@ -2,6 +2,10 @@ package util
 
 import (
 	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -35,3 +39,179 @@ func TestReadFileAndFixNewline(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "values.schema.json")
+
+	for _, content := range []string{"foo", "bar"} {
+		if err := WriteFileAtomic(file, []byte(content), 0644); err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		written, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		if string(written) != content {
+			t.Errorf("Was expecting %s, but got %s", content, written)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected the temporary files to be removed, but found %d files", len(entries))
+	}
+}
+
+func TestUncommentMarkedBlocks(t *testing.T) {
+	content := `replicas: 1
+# unrelated: comment
+# @uncomment-start
+# ingress:
+#   # the host
+#   host: example.org
+# @uncomment-end
+service:
+  # @uncomment-start
+  # nodePort: 30080
+  # @uncomment-end
+`
+	expected := `replicas: 1
+# unrelated: comment
+#
+ingress:
+  # the host
+  host: example.org
+#
+service:
+  #
+  nodePort: 30080
+  #
+`
+	uncommented, keyLines, err := UncommentMarkedBlocks([]byte(content))
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	if string(uncommented) != expected {
+		t.Errorf("Was expecting %s, but got %s", expected, uncommented)
+	}
+	if len(keyLines) != 2 || keyLines[0] != 4 || keyLines[1] != 10 {
+		t.Errorf("Was expecting the key lines [4 10], but got %v", keyLines)
+	}
+
+	if _, keyLines, _ := UncommentMarkedBlocks([]byte("# foo: bar\n")); keyLines != nil {
+		t.Errorf("Wasn't expecting key lines without markers, but got %v", keyLines)
+	}
+	for _, invalid := range []string{"# @uncomment-start\n# foo: bar\n", "# @uncomment-end\n"} {
+		if _, _, err := UncommentMarkedBlocks([]byte(invalid)); err == nil {
+			t.Errorf("Was expecting an error for %q", invalid)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name       string
+		old, new   string
+		oldMissing bool
+		diff       string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			diff: "",
+		},
+		{
+			name: "changed line",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			new:  "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			diff: "--- old\n+++ new\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "separate hunks",
+			old:  "a\n1\n2\n3\n4\n5\n6\n7\nb\n",
+			new:  "A\n1\n2\n3\n4\n5\n6\n7\nB\n",
+			diff: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-b\n+B\n",
+		},
+		{
+			name: "inserted lines",
+			old:  "a\nb\n",
+			new:  "a\nx\ny\nb\n",
+			diff: "--- old\n+++ new\n@@ -1,2 +1,4 @@\n a\n+x\n+y\n b\n",
+		},
+		{
+			name:       "new file",
+			new:        "a\n",
+			oldMissing: true,
+			diff:       "--- /dev/null\n+++ new\n@@ -0,0 +1 @@\n+a\n",
+		},
+	}
+	for _, test := range tests {
+		diff := UnifiedDiff("old", "new", []byte(test.old), []byte(test.new), 3, test.oldMissing)
+		if diff != test.diff {
+			t.Errorf("%s: wanted diff\n%s\nbut got\n%s", test.name, test.diff, diff)
+		}
+	}
+}
+
+func TestWalkFollowingSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"shared/common/Chart.yaml", "umbrella/Chart.yaml"} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, path), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "umbrella", "charts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "..", "shared", "common"), filepath.Join(dir, "umbrella", "charts", "common")); err != nil {
+		t.Skipf("symlinks aren't supported: %s", err)
+	}
+	// a loop back to the root
+	if err := os.Symlink(filepath.Join("..", ".."), filepath.Join(dir, "shared", "common", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	walkCharts := func(followSymlinks bool) ([]string, int) {
+		charts := []string{}
+		loops := 0
+		err := Walk(dir, followSymlinks, func(path string, info os.FileInfo, err error) error {
+			var loopErr *SymlinkLoopError
+			if errors.As(err, &loopErr) {
+				loops++
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if info.Name() == "Chart.yaml" {
+				relPath, _ := filepath.Rel(dir, path)
+				charts = append(charts, filepath.ToSlash(relPath))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+		}
+		return charts, loops
+	}
+
+	charts, loops := walkCharts(false)
+	if strings.Join(charts, ",") != "shared/common/Chart.yaml,umbrella/Chart.yaml" || loops != 0 {
+		t.Errorf("Unexpected charts %v without following symlinks", charts)
+	}
+	charts, loops = walkCharts(true)
+	if strings.Join(charts, ",") != "shared/common/Chart.yaml,umbrella/Chart.yaml,umbrella/charts/common/Chart.yaml" {
+		t.Errorf("Unexpected charts %v following symlinks", charts)
+	}
+	if loops != 2 {
+		t.Errorf("Expected 2 symlink loops, but got %d", loops)
+	}
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// git runs the git command with the given arguments inside of dir and returns stdout
+func git(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// GitChangedFiles returns the absolute paths of all files which changed since the given ref.
+// This includes uncommitted and untracked files of the working tree. The paths don't contain
+// symlinks, so they have to be compared with paths passed through filepath.EvalSymlinks.
+func GitChangedFiles(dir, ref string) ([]string, error) {
+	topLevel, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	// git resolves the symlinks of the top level, resolve them again in case it's reported differently
+	topLevel, err = filepath.EvalSymlinks(strings.TrimSpace(topLevel))
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := git(dir, "diff", "--name-only", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := git(dir, "ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(changed+"\n"+untracked, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, filepath.Join(topLevel, filepath.FromSlash(line)))
+		}
+	}
+	return files, nil
+}
+
+// GitFileAt returns the content of the file inside of dir at the given ref. If the file
+// didn't exist at the ref, exists is false.
+func GitFileAt(dir, ref, file string) (content []byte, exists bool, err error) {
+	object := ref + ":./" + filepath.ToSlash(file)
+	if _, err := git(dir, "cat-file", "-e", object); err != nil {
+		if _, refErr := git(dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); refErr != nil {
+			return nil, false, fmt.Errorf("unknown git ref %s", ref)
+		}
+		return nil, false, nil
+	}
+	output, err := git(dir, "show", object)
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(output), true, nil
+}
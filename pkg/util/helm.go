@@ -0,0 +1,63 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// helmPath mirrors the lookup of helm's directories: first the helm specific
+// environment variable, then the xdg variable and then the os specific default
+func helmPath(helmEnvVar, xdgEnvVar string, defaultBase func(home string) string, elem ...string) string {
+	if base := os.Getenv(helmEnvVar); base != "" {
+		return filepath.Join(base, filepath.Join(elem...))
+	}
+	base := os.Getenv(xdgEnvVar)
+	if base == "" {
+		home, _ := os.UserHomeDir()
+		base = defaultBase(home)
+	}
+	return filepath.Join(base, "helm", filepath.Join(elem...))
+}
+
+// HelmConfigPath returns the path of the given element in helm's config directory
+func HelmConfigPath(elem ...string) string {
+	return helmPath("HELM_CONFIG_HOME", "XDG_CONFIG_HOME", func(home string) string {
+		switch runtime.GOOS {
+		case "darwin":
+			return filepath.Join(home, "Library", "Preferences")
+		case "windows":
+			return os.Getenv("APPDATA")
+		}
+		return filepath.Join(home, ".config")
+	}, elem...)
+}
+
+// HelmCachePath returns the path of the given element in helm's cache directory
+func HelmCachePath(elem ...string) string {
+	return helmPath("HELM_CACHE_HOME", "XDG_CACHE_HOME", func(home string) string {
+		switch runtime.GOOS {
+		case "darwin":
+			return filepath.Join(home, "Library", "Caches")
+		case "windows":
+			return os.Getenv("TEMP")
+		}
+		return filepath.Join(home, ".cache")
+	}, elem...)
+}
+
+// HelmRegistryConfig returns the path of helm's registry credentials file
+func HelmRegistryConfig() string {
+	if config := os.Getenv("HELM_REGISTRY_CONFIG"); config != "" {
+		return config
+	}
+	return HelmConfigPath("registry", "config.json")
+}
+
+// HelmRepositoryConfig returns the path of helm's repositories file
+func HelmRepositoryConfig() string {
+	if config := os.Getenv("HELM_REPOSITORY_CONFIG"); config != "" {
+		return config
+	}
+	return HelmConfigPath("repositories.yaml")
+}
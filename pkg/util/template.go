@@ -0,0 +1,21 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// RenderTemplate renders the given go template with the data. Missing keys are an error.
+func RenderTemplate(name, text string, data interface{}) (string, error) {
+	tpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template %s: %w", name, text, err)
+	}
+
+	var rendered strings.Builder
+	if err := tpl.Execute(&rendered, data); err != nil {
+		return "", fmt.Errorf("could not render %s template %s: %w", name, text, err)
+	}
+	return rendered.String(), nil
+}
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SymlinkLoopError is passed to the walk function for symlinks to a directory containing them, which
+// aren't followed
+type SymlinkLoopError struct {
+	Path   string
+	Target string
+}
+
+func (e *SymlinkLoopError) Error() string {
+	return fmt.Sprintf("not following symlink %s to its parent directory %s", e.Path, e.Target)
+}
+
+// Walk walks the file tree like filepath.Walk. With followSymlinks, symlinks to directories are walked as
+// well, the paths of their files are below the symlink. A symlinked directory is walked once for every
+// symlink to it (e.g. a chart shared by several umbrella charts), but never inside of itself: the symlinks
+// to a directory, which is walked already on the way to them, are reported with a SymlinkLoopError.
+func Walk(root string, followSymlinks bool, fn filepath.WalkFunc) error {
+	if !followSymlinks {
+		return filepath.Walk(root, fn)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walk(root, info, map[string]string{}, fn)
+	}
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// walk walks the path, ancestors are the ids of the directories above it mapped to their paths
+func walk(path string, info os.FileInfo, ancestors map[string]string, fn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+
+	id, err := dirID(path, info)
+	if err != nil {
+		return fn(path, info, err)
+	}
+	if ancestor, ok := ancestors[id]; ok {
+		return fn(path, info, &SymlinkLoopError{Path: path, Target: ancestor})
+	}
+	if err := fn(path, info, nil); err != nil {
+		if errors.Is(err, filepath.SkipDir) {
+			return nil
+		}
+		return err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if err := fn(path, info, err); err != nil && !errors.Is(err, filepath.SkipDir) {
+			return err
+		}
+		return nil
+	}
+	ancestors[id] = path
+	defer delete(ancestors, id)
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(path, entry.Name())
+		// the symlinks are resolved, so they are walked like the directories and files they point to
+		entryInfo, err := os.Stat(entryPath)
+		if err != nil {
+			if err := fn(entryPath, nil, err); err != nil {
+				if errors.Is(err, filepath.SkipDir) {
+					continue
+				}
+				return err
+			}
+			continue
+		}
+		if err := walk(entryPath, entryInfo, ancestors, fn); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				if entryInfo.IsDir() {
+					continue
+				}
+				// skipping a file skips the rest of its directory
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// dirID returns the absolute path of the directory with all symlinks resolved, as there are no inodes
+func dirID(path string, _ os.FileInfo) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(resolved)
+}
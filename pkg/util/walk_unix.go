@@ -0,0 +1,18 @@
+//go:build unix
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// dirID returns the device and inode of the directory, which identify it independent of the symlinks to it
+func dirID(path string, info os.FileInfo) (string, error) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", fmt.Errorf("could not read the inode of %s", path)
+	}
+	return fmt.Sprintf("%d:%d", stat.Dev, stat.Ino), nil
+}
@@ -0,0 +1,151 @@
+// Package validate validates helm values against the jsonschemas generated by helm-schema. It's meant to be
+// embedded, e.g. in validating admission webhooks checking the values of HelmReleases or Applications in
+// the cluster, so the compiled schemas are cached and the violations are returned instead of errors.
+package validate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"github.com/ojsef39/helm-schema/pkg/schema"
+)
+
+// DefaultCacheSize is the number of compiled schemas cached by the validator of Validate
+const DefaultCacheSize = 128
+
+// schemaURL is the location the schemas are compiled at, relative $refs to other files can't be resolved
+const schemaURL = "file:///values.schema.json"
+
+// Violation is a violation of the schema by the values
+type Violation struct {
+	// Pointer is the json pointer of the invalid value (e.g. /image/tag), it's empty for the root
+	Pointer string
+	Message string
+}
+
+func (v Violation) String() string {
+	pointer := v.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return pointer + ": " + v.Message
+}
+
+// Validator validates values against schemas. The compiled schemas are cached by their content, the oldest
+// one is evicted when the cache is full. It's safe for concurrent use.
+type Validator struct {
+	mu       sync.Mutex
+	size     int
+	compiled map[[sha256.Size]byte]*jsonschema.Schema
+	order    [][sha256.Size]byte
+}
+
+// NewValidator returns a validator caching up to size compiled schemas (DefaultCacheSize, if it's not positive)
+func NewValidator(size int) *Validator {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &Validator{size: size, compiled: make(map[[sha256.Size]byte]*jsonschema.Schema)}
+}
+
+var defaultValidator = NewValidator(DefaultCacheSize)
+
+// Validate validates the values against the jsonschema with a shared validator, see Validator.Validate
+func Validate(schemaJSON []byte, values map[string]any) []Violation {
+	return defaultValidator.Validate(schemaJSON, values)
+}
+
+// Validate returns the violations of the jsonschema by the values, there are none for valid values. Like helm,
+// the values should be the ones of the release merged into the values of the chart (see Merge), otherwise
+// the values required by the schema, which the chart sets, are missing. An invalid schema or values, which
+// can't be converted to json, are a single violation of the root.
+func (v *Validator) Validate(schemaJSON []byte, values map[string]any) []Violation {
+	compiled, err := v.compile(schemaJSON)
+	if err != nil {
+		return []Violation{{Message: fmt.Sprintf("invalid jsonschema: %s", err)}}
+	}
+
+	if values == nil {
+		values = map[string]any{}
+	}
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return []Violation{{Message: fmt.Sprintf("invalid values: %s", err)}}
+	}
+	valuesDoc, err := jsonschema.UnmarshalJSON(bytes.NewReader(valuesJSON))
+	if err != nil {
+		return []Violation{{Message: fmt.Sprintf("invalid values: %s", err)}}
+	}
+	if err := compiled.Validate(valuesDoc); err != nil {
+		violations := []Violation{}
+		for _, valuesErr := range schema.ValuesErrors(err) {
+			violations = append(violations, Violation{Pointer: valuesErr.Pointer, Message: valuesErr.Message})
+		}
+		return violations
+	}
+	return nil
+}
+
+// compile returns the compiled schema from the cache or compiles and caches it
+func (v *Validator) compile(schemaJSON []byte) (*jsonschema.Schema, error) {
+	key := sha256.Sum256(schemaJSON)
+	v.mu.Lock()
+	compiled, ok := v.compiled[key]
+	v.mu.Unlock()
+	if ok {
+		return compiled, nil
+	}
+
+	schemaDoc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	if err != nil {
+		return nil, err
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaURL, schemaDoc); err != nil {
+		return nil, err
+	}
+	if compiled, err = compiler.Compile(schemaURL); err != nil {
+		return nil, err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.compiled[key]; !ok {
+		if len(v.order) >= v.size {
+			delete(v.compiled, v.order[0])
+			v.order = v.order[1:]
+		}
+		v.compiled[key] = compiled
+		v.order = append(v.order, key)
+	}
+	return compiled, nil
+}
+
+// Merge merges the overrides in their order into the values (e.g. the values of a release into the ones of
+// its chart) like helm does: mappings are merged, other values are replaced and null removes a key. The
+// values aren't modified.
+func Merge(values map[string]any, overrides ...map[string]any) (map[string]any, error) {
+	merged, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	for _, override := range overrides {
+		overrideJSON, err := json.Marshal(override)
+		if err != nil {
+			return nil, err
+		}
+		if merged, err = schema.MergeValues(merged, overrideJSON); err != nil {
+			return nil, err
+		}
+	}
+	result := map[string]any{}
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package validate
+
+import (
+	"testing"
+)
+
+const testSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["image"],
+  "properties": {
+    "replicas": {"type": "integer", "minimum": 1},
+    "image": {
+      "type": "object",
+      "properties": {"tag": {"type": "string", "pattern": "^v"}}
+    }
+  }
+}`
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		values     map[string]any
+		violations []Violation
+	}{
+		{
+			values: map[string]any{"replicas": 2, "image": map[string]any{"tag": "v1"}},
+		},
+		{
+			values:     map[string]any{"replicas": 0, "image": map[string]any{"tag": "v1"}},
+			violations: []Violation{{Pointer: "/replicas", Message: "minimum: got 0, want 1"}},
+		},
+		{
+			values:     map[string]any{"image": map[string]any{"tag": "latest"}},
+			violations: []Violation{{Pointer: "/image/tag", Message: "'latest' does not match pattern '^v'"}},
+		},
+		{
+			values:     map[string]any{},
+			violations: []Violation{{Pointer: "", Message: "missing property 'image'"}},
+		},
+	}
+
+	validator := NewValidator(1)
+	for _, test := range tests {
+		violations := validator.Validate([]byte(testSchema), test.values)
+		if len(violations) != len(test.violations) {
+			t.Fatalf("Was expecting %v for %v, but got %v", test.violations, test.values, violations)
+		}
+		for i, violation := range violations {
+			if violation != test.violations[i] {
+				t.Errorf("Was expecting %v for %v, but got %v", test.violations[i], test.values, violation)
+			}
+		}
+	}
+	if len(validator.compiled) != 1 {
+		t.Errorf("Was expecting a single cached schema, but got %d", len(validator.compiled))
+	}
+
+	// the oldest schema is evicted from a full cache
+	if violations := validator.Validate([]byte(`{"type": "object"}`), nil); len(violations) != 0 {
+		t.Errorf("Wasn't expecting violations, but got %v", violations)
+	}
+	if len(validator.compiled) != 1 || len(validator.order) != 1 {
+		t.Errorf("Was expecting a single cached schema, but got %d", len(validator.compiled))
+	}
+
+	violations := Validate([]byte(`{"type": "map"}`), nil)
+	if len(violations) != 1 || violations[0].Pointer != "" {
+		t.Errorf("Was expecting a violation of the root for an invalid schema, but got %v", violations)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	values := map[string]any{"replicas": 1, "image": map[string]any{"repository": "nginx", "tag": "v1"}, "debug": true}
+	merged, err := Merge(values, map[string]any{"image": map[string]any{"tag": "v2"}}, map[string]any{"debug": nil})
+	if err != nil {
+		t.Fatalf("Wasn't expecting an error, but got this: %v", err)
+	}
+	// debug isn't allowed by the schema, but removed by the override
+	if violations := Validate([]byte(testSchema), merged); len(violations) != 0 {
+		t.Errorf("Wasn't expecting violations, but got %v", violations)
+	}
+	image, _ := merged["image"].(map[string]any)
+	if image["repository"] != "nginx" || image["tag"] != "v2" {
+		t.Errorf("Was expecting the merged image, but got %v", merged["image"])
+	}
+	if _, ok := merged["debug"]; ok {
+		t.Errorf("Was expecting null to remove debug, but got %v", merged)
+	}
+	if _, ok := values["debug"]; !ok {
+		t.Errorf("Wasn't expecting the values to be modified")
+	}
+}